@@ -1,23 +1,233 @@
 package main
 
 import (
+	"context"
+	"embed"
+	"flag"
 	"fmt"
+	"go1f/pkg/api"
 	"go1f/pkg/config"
 	"go1f/pkg/db"
 	"go1f/pkg/server"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
 )
 
+// version - версия приложения для --version, задается при сборке:
+//
+//	go build -ldflags "-X main.version=1.4.0"
+//
+// Без неё выводится ревизия VCS, из которой собран бинарный файл.
+var version string
+
+// Коды завершения для разовых административных операций.
+const (
+	exitOK    = 0 // операция выполнена успешно
+	exitError = 1 // операция завершилась ошибкой
+)
+
+// embeddedWeb - каталог веб-интерфейса, встроенный в бинарный файл:
+// сервер отдает его независимо от рабочего каталога.
+//
+//go:embed web
+var embeddedWeb embed.FS
+
+// webFiles возвращает файлы веб-интерфейса: каталог dir (TODO_WEB_DIR),
+// если он задан, иначе встроенную копию каталога web.
+func webFiles(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	files, err := fs.Sub(embeddedWeb, "web")
+	if err != nil {
+		panic(err) // каталог web встроен при сборке
+	}
+	return files
+}
+
+// adminOptions содержит параметры разовых административных операций из флагов командной строки.
+type adminOptions struct {
+	migrate    bool   // применить схему БД и выйти
+	backupPath string // путь для резервной копии БД
+	check      bool   // проверить конфигурацию и целостность БД
+	importPath string // путь к JSON-файлу для импорта задач
+	purgeDays  int    // удалить задачи, помеченные удаленными раньше этого числа дней
+	repair     bool   // исправить даты задач в устаревших форматах
+}
+
+// requested сообщает, запрошена ли хотя бы одна административная операция.
+func (o adminOptions) requested() bool {
+	return o.migrate || o.backupPath != "" || o.check || o.importPath != "" || o.purgeDays > 0 || o.repair
+}
+
+// appVersion возвращает версию приложения: version или ревизию VCS из
+// информации о сборке ("dev", если её нет, например при go run).
+func appVersion() string {
+	if version != "" {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	var revision, modified string
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value[:min(len(s.Value), 12)]
+		case "vcs.modified":
+			if s.Value == "true" {
+				modified = "-dirty"
+			}
+		}
+	}
+	if revision == "" {
+		return "dev"
+	}
+	return revision + modified
+}
+
+// usage выводит справку --help: назначение флагов и порядок настроек.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Использование: %s [флаги]\n\n", os.Args[0])
+	fmt.Fprintln(out, "Планировщик задач: веб-интерфейс и API. Настройки читаются из флагов,")
+	fmt.Fprintln(out, "переменных окружения TODO_*, файла .env и значений по умолчанию - в этом порядке.")
+	fmt.Fprintln(out, "Без флагов операций запускается сервер.")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Флаги:")
+	flag.PrintDefaults()
+}
+
 func main() {
 
+	var opts adminOptions
+	var showVersion, printConfig bool
+	config.BindFlags(flag.CommandLine)
+	flag.BoolVar(&showVersion, "version", false, "вывести версию и выйти")
+	flag.BoolVar(&printConfig, "print-config", false, "вывести действующую конфигурацию в JSON (без паролей и ключей) и выйти")
+	flag.BoolVar(&opts.migrate, "migrate", false, "применить схему БД и выйти")
+	flag.StringVar(&opts.backupPath, "backup", "", "сохранить резервную копию БД в указанный файл и выйти")
+	flag.BoolVar(&opts.check, "check", false, "проверить конфигурацию и целостность БД и выйти")
+	flag.StringVar(&opts.importPath, "import", "", "импортировать задачи из JSON-файла и выйти")
+	flag.IntVar(&opts.purgeDays, "purge", 0, "окончательно удалить задачи, удаленные больше указанного числа дней назад, и выйти")
+	flag.BoolVar(&opts.repair, "repair", false, "исправить даты задач в устаревших форматах и выйти")
+	flag.Usage = usage
+	flag.Parse()
+
+	if showVersion {
+		fmt.Printf("todo-app %s (%s)\n", appVersion(), runtime.Version())
+		return
+	}
+
 	// Загружаем настройки сервера
-	config.ConfigServer()
+	conf, err := config.Load()
+	if err != nil {
+		// Список переменных читается проще без экранирования журнала
+		fmt.Fprintf(os.Stderr, "Сервер не запущен, %v\n", err)
+		os.Exit(exitError)
+	}
+	if printConfig {
+		if err := conf.WriteJSON(os.Stdout); err != nil {
+			os.Exit(exitError)
+		}
+		return
+	}
 
 	// Создаем БД
-	db.InitDB()
-	defer db.CloseDB()
+	store, err := db.InitDB(conf)
+	if err != nil {
+		slog.Error("Ошибка при инициализации БД", "error", err)
+		os.Exit(exitError)
+	}
+
+	// Выполняем разовую операцию вместо запуска сервера
+	if opts.requested() {
+		code := runAdmin(context.Background(), opts, conf, store, os.Stdout)
+		db.CloseDB()
+		os.Exit(code)
+	}
+
+	// Запускаем сервер, Run возвращается после плавной остановки
+	if err := server.Run(conf, store, webFiles(conf.WebDir)); err != nil {
+		fmt.Println("Server is not running....", err)
+	}
+
+	// БД закрываем только когда сервер остановлен и все запросы завершены
+	db.CloseDB()
+}
+
+// runAdmin выполняет запрошенные административные операции над уже открытым
+// по конфигурации conf хранилищем store.
+// Результат пишется в out в виде, пригодном для писем cron.
+// Возвращает код завершения процесса.
+func runAdmin(ctx context.Context, opts adminOptions, conf config.Config, store *db.Store, out io.Writer) int {
+
+	if opts.migrate {
+		// Схема применяется в db.InitDB, здесь только сообщаем об успехе
+		fmt.Fprintln(out, "Схема БД актуальна")
+	}
+
+	if opts.check {
+		fmt.Fprintf(out, "Конфигурация: адрес %v, БД %v, лимит задач %v\n",
+			conf.ListenAddr, conf.PathToDB, conf.LimitTask)
+		if err := store.CheckIntegrity(ctx); err != nil {
+			fmt.Fprintf(out, "Ошибка проверки БД: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintln(out, "Целостность БД: ok")
+	}
 
-	// Запускаем сервер
-	if err := server.Run(); err != nil {
-		fmt.Println("Server is not running....")
+	if opts.repair {
+		report, err := store.RepairDates(ctx)
+		if err != nil {
+			fmt.Fprintf(out, "Ошибка проверки дат: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(out, "Проверено задач: %v, исправлено дат: %v\n", report.Checked, report.Fixed)
+		if len(report.Invalid) > 0 {
+			fmt.Fprintf(out, "Неверная дата у задач: %v\n", strings.Join(report.Invalid, ", "))
+		}
 	}
+
+	if opts.backupPath != "" {
+		if err := store.Backup(ctx, opts.backupPath); err != nil {
+			fmt.Fprintf(out, "Ошибка резервного копирования: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(out, "Резервная копия сохранена в %v\n", opts.backupPath)
+	}
+
+	if opts.importPath != "" {
+		f, err := os.Open(opts.importPath)
+		if err != nil {
+			fmt.Fprintf(out, "Ошибка открытия файла импорта: %v\n", err)
+			return exitError
+		}
+		defer f.Close()
+
+		n, err := api.ImportTasks(ctx, store, f)
+		if err != nil {
+			fmt.Fprintf(out, "Ошибка импорта: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(out, "Импортировано задач: %v\n", n)
+	}
+
+	if opts.purgeDays > 0 {
+		n, err := store.PurgeDeleted(ctx, time.Now().AddDate(0, 0, -opts.purgeDays))
+		if err != nil {
+			fmt.Fprintf(out, "Ошибка очистки удаленных задач: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(out, "Окончательно удалено задач: %v\n", n)
+	}
+
+	return exitOK
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go1f/pkg/config"
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupDB создает временную БД для теста и закрывает её по завершении.
+// Возвращает конфигурацию с этой БД, её хранилище и каталог.
+func setupDB(t *testing.T) (config.Config, *db.Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	conf := config.Config{PathToDB: filepath.Join(dir, "scheduler.db"), LimitTask: 50}
+	store, err := db.InitDB(conf)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.CloseDB() })
+	return conf, store, dir
+}
+
+func TestRunAdmin(t *testing.T) {
+	conf, store, dir := setupDB(t)
+
+	t.Run("migrate", func(t *testing.T) {
+		var out bytes.Buffer
+		assert.Equal(t, exitOK, runAdmin(t.Context(), adminOptions{migrate: true}, conf, store, &out))
+		assert.Contains(t, out.String(), "Схема БД актуальна")
+	})
+
+	t.Run("check", func(t *testing.T) {
+		var out bytes.Buffer
+		assert.Equal(t, exitOK, runAdmin(t.Context(), adminOptions{check: true}, conf, store, &out))
+		assert.Contains(t, out.String(), "Целостность БД: ok")
+	})
+
+	t.Run("import", func(t *testing.T) {
+		path := filepath.Join(dir, "backup.json")
+		data := `{"tasks":[{"date":"20990101","title":"Первая"},{"date":"20990102","title":"Вторая","repeat":"d 2"}]}`
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+		var out bytes.Buffer
+		assert.Equal(t, exitOK, runAdmin(t.Context(), adminOptions{importPath: path}, conf, store, &out))
+		assert.Contains(t, out.String(), "Импортировано задач: 2")
+
+		tasks, err := store.GetTasks(t.Context(), 10)
+		require.NoError(t, err)
+		assert.Len(t, tasks, 2)
+	})
+
+	t.Run("import invalid", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.json")
+		data := `[{"date":"20990101","title":"Хорошая"},{"date":"20990101","title":""}]`
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+		var out bytes.Buffer
+		assert.Equal(t, exitError, runAdmin(t.Context(), adminOptions{importPath: path}, conf, store, &out))
+		assert.Contains(t, out.String(), "задача #2")
+
+		tasks, err := store.GetTasks(t.Context(), 10)
+		require.NoError(t, err)
+		assert.Len(t, tasks, 2, "при ошибке импорт не должен добавлять задачи")
+	})
+
+	t.Run("backup", func(t *testing.T) {
+		path := filepath.Join(dir, "out.db")
+		var out bytes.Buffer
+		assert.Equal(t, exitOK, runAdmin(t.Context(), adminOptions{backupPath: path}, conf, store, &out))
+		assert.FileExists(t, path)
+
+		// повторное копирование в существующий файл должно завершиться ошибкой
+		out.Reset()
+		assert.Equal(t, exitError, runAdmin(t.Context(), adminOptions{backupPath: path}, conf, store, &out))
+		assert.Contains(t, out.String(), "Ошибка резервного копирования")
+	})
+	t.Run("purge", func(t *testing.T) {
+		var out bytes.Buffer
+		assert.Equal(t, exitOK, runAdmin(t.Context(), adminOptions{purgeDays: 30}, conf, store, &out))
+		assert.Contains(t, out.String(), "Окончательно удалено задач: 0")
+	})
+	t.Run("repair", func(t *testing.T) {
+		_, err := store.AddTask(t.Context(), &db.Task{Date: "2099-01-03", Title: "Старый формат"})
+		require.NoError(t, err)
+
+		var out bytes.Buffer
+		assert.Equal(t, exitOK, runAdmin(t.Context(), adminOptions{repair: true}, conf, store, &out))
+		assert.Contains(t, out.String(), "исправлено дат: 1")
+	})
+}
+
+func TestWebFiles(t *testing.T) {
+	get := func(files fs.FS, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		http.FileServerFS(files).ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// по умолчанию index.html отдается из встроенной копии
+	index, err := os.ReadFile(filepath.Join("web", "index.html"))
+	require.NoError(t, err)
+	w := get(webFiles(""), "/")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, string(index), w.Body.String())
+	assert.Equal(t, http.StatusOK, get(webFiles(""), "/login.html").Code)
+
+	// каталог TODO_WEB_DIR заменяет встроенную копию целиком
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<h1>dev</h1>"), 0o644))
+	w = get(webFiles(dir), "/")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<h1>dev</h1>", w.Body.String())
+	assert.Equal(t, http.StatusNotFound, get(webFiles(dir), "/login.html").Code)
+}
@@ -1,23 +1,241 @@
 // Package api предоставляет функционал для работы API сервиса.
 package api
 
-import "net/http"
+import (
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
 
-// Init инициализирует маршруты HTTP-сервера.
+	"go1f/pkg/config"
+	"go1f/pkg/metrics"
+)
+
+// webFiles - файлы веб-интерфейса, которые сервер отдает по "/" (см. Init).
+var webFiles fs.FS
+
+// current - конфигурация приложения, переданная в Init или UpdateConfig.
+// Хранится указателем, чтобы перечитанная по SIGHUP конфигурация
+// подменялась атомарно, пока обработчики обслуживают запросы.
+var current atomic.Pointer[config.Config]
+
+func init() {
+	current.Store(&config.Config{})
+}
+
+// conf возвращает действующую конфигурацию приложения.
+// Обработчик, которому нужно несколько согласованных настроек,
+// читает conf один раз.
+func conf() *config.Config {
+	return current.Load()
+}
+
+// UpdateConfig заменяет конфигурацию, с которой работают обработчики,
+// на c, например перечитанную по SIGHUP. Новые настройки действуют
+// со следующего обращения к ним, запросы не прерываются. Middleware, собранные
+// в Init (CORS, ограничение времени запроса), продолжают работать с прежними.
+func UpdateConfig(c config.Config) {
+	current.Store(&c)
+}
+
+// Init инициализирует API и возвращает корневой обработчик сервера:
+// маршрутизатор из routes, обернутый в общие middleware.
+//
+// Обработчики работают с задачами через s, файлы веб-интерфейса
+// отдаются из files, остальные настройки (лимиты, пароль, срок жизни токена)
+// берутся из c.
+// Начальное состояние режима обслуживания берется из TODO_MAINTENANCE.
+func Init(s TaskStore, files fs.FS, c config.Config) http.Handler {
+	store = s
+	webFiles = files
+	current.Store(&c)
+	setMaintenance(c.Maintenance, "")
+	signinLimiter = newLoginLimiter(config.DefaultSigninFails, config.DefaultSigninWindow, time.Now)
+	SetSigninLimit(c.SigninFailures, c.SigninWindow)
+
+	key, err := loadSigningKey()
+	if err != nil {
+		slog.Warn("Не удалось загрузить ключ подписи токенов, после перезапуска потребуется войти заново",
+			"error", err)
+	} else {
+		signingKey = key
+	}
+
+	if c.Metrics {
+		go runTaskMetrics(metricsRefreshInterval)
+	}
+
+	return requestID(compress(cors(requestTimeout(maintenanceMode(limitBody(routes())), c.Timeout))))
+}
+
+// routes создает маршрутизатор с обработчиками API.
 //
-// Регистрирует следующие обработчики:
+// Маршруты привязаны к методам, на запрос другим методом маршрутизатор
+// отвечает 405 с заголовком Allow и JSON-ошибкой:
 //   - GET /api/nextdate - обработчик для получения следующей даты
-//   - /api/task - обработчик для работы с отдельной задачей (CRUD операции)
-//   - /api/tasks - обработчик для получения списка задач
-//   - /api/task/done - обработчик для отметки задачи как выполненной
-//   - /api/signin - обработчик для выполнения аутентификации пользователя по паролю
-//   - / - обработчик для обслуживания статических файлов из директории "web"
-func Init() {
-	http.HandleFunc("/api/nextdate", nextDayHandler)
-	http.HandleFunc("/api/task", auth(taskHandler))
-	http.HandleFunc("/api/tasks", auth(tasksHandler))
-	http.HandleFunc("/api/task/done", auth(handleDoneTask))
-	http.HandleFunc("/api/signin", handleSignIn)
-
-	http.Handle("/", http.FileServer(http.Dir("web"))) //последним идет обработчик для статичных файлов, чтобы не перекрывать остальные
+//   - POST /api/repeat/parse - перевод фразы вроде "каждые 3 дня" в правило повторения
+//   - GET /api/nextdates - обработчик для получения нескольких ближайших дат
+//   - GET, POST, PUT, PATCH, DELETE /api/task - работа с отдельной задачей (CRUD операции)
+//   - GET /api/tasks - обработчик для получения списка задач
+//   - GET /api/tasks/facets - обработчик для получения количества задач по фильтрам
+//   - GET /api/tasks/forecast - обработчик для прогноза выполнений задач на интервал
+//   - POST /api/tasks/delete - обработчик для удаления нескольких задач
+//   - POST /api/tasks/reschedule - обработчик для переноса просроченных задач на сегодня
+//   - GET /api/stats - обработчик для получения статистики выполнений
+//   - GET /api/sync - обработчик разностной синхронизации (изменения с момента since)
+//   - GET /api/changes - обработчик синхронизации по номерам изменений (после курсора since)
+//   - GET /api/export - выгрузка всех задач в файл JSON
+//   - POST /api/import - загрузка задач из файла выгрузки
+//   - GET /api/calendar.ics - календарь задач для подписки, аутентификация токеном календаря
+//   - POST, DELETE /api/calendar/token - выпуск и отзыв токена календаря
+//   - POST /api/task/done - обработчик для отметки задачи как выполненной
+//   - POST /api/task/undone - обработчик для снятия отметки о выполнении
+//   - POST /api/task/skip - обработчик для пропуска одного выполнения повторяющейся задачи
+//   - POST /api/task/postpone - обработчик для переноса задачи на несколько дней вперед
+//   - POST /api/task/clone - обработчик для создания копии задачи
+//   - POST /api/task/from-template - обработчик для создания задачи из шаблона
+//   - POST /api/task/quick - обработчик для создания задачи из одной строки текста
+//   - GET, POST, PUT, DELETE /api/templates - работа с шаблонами задач
+//   - GET, POST, PUT, DELETE /api/webhooks - работа с вебхуками событий задач
+//   - GET /api/events - поток изменений задач (Server-Sent Events)
+//   - POST /api/signin - обработчик для выполнения аутентификации пользователя по паролю
+//   - POST /api/refresh - продление действующего токена
+//   - POST /api/logout - удаление куки с токеном
+//   - POST /api/users - регистрация пользователя администратором
+//   - POST, DELETE /api/apikeys - выпуск и отзыв ключей API
+//   - GET /api/health - проверка живости сервиса, без аутентификации
+//   - GET /api/ready - проверка готовности (доступности БД), без аутентификации
+//   - POST /api/admin/maintenance_mode - включение и выключение режима обслуживания, только для администратора
+//   - POST /api/admin/backup - резервная копия БД, только для администратора
+//   - GET /api/admin/backup/download - скачивание резервной копии, только для администратора
+//   - POST /api/admin/notify/test - проверочное сообщение Telegram, только для администратора
+//   - GET /api/admin/runtime - горутины, куча и время работы процесса (только при TODO_DEBUG=1)
+//   - /debug/pprof/ - профилировщик net/http/pprof (только при TODO_DEBUG=1, см. registerDebug)
+//   - GET /metrics - метрики в формате Prometheus, без аутентификации (только при TODO_METRICS=1)
+//   - / - обработчик для обслуживания файлов веб-интерфейса (см. webFiles)
+func routes() http.Handler {
+	// API обслуживает отдельный маршрутизатор, чтобы обработчик статических
+	// файлов "/" не перехватывал запросы к API неподходящим методом
+	mux := http.NewServeMux()
+
+	handle(mux, http.MethodGet, "/api/nextdate", http.HandlerFunc(nextDayHandler))
+	handle(mux, http.MethodGet, "/api/nextdates", http.HandlerFunc(nextDatesHandler))
+	handle(mux, http.MethodPost, "/api/repeat/parse", http.HandlerFunc(repeatParseHandler))
+	handle(mux, http.MethodGet, "/api/task", auth(handleGetTask))
+	handle(mux, http.MethodPost, "/api/task", auth(handlePostTask))
+	handle(mux, http.MethodPut, "/api/task", auth(handlePutTask))
+	handle(mux, http.MethodPatch, "/api/task", auth(handlePatchTask))
+	handle(mux, http.MethodDelete, "/api/task", auth(handleDeleteTask))
+	handle(mux, http.MethodGet, "/api/tasks", auth(tasksHandler))
+	handle(mux, http.MethodGet, "/api/tasks/facets", auth(facetsHandler))
+	handle(mux, http.MethodGet, "/api/tasks/forecast", auth(forecastHandler))
+	handle(mux, http.MethodPost, "/api/tasks/delete", auth(batchDeleteHandler))
+	handle(mux, http.MethodPost, "/api/tasks/reschedule", auth(rescheduleHandler))
+	handle(mux, http.MethodGet, "/api/stats", auth(statsHandler))
+	handle(mux, http.MethodGet, "/api/sync", auth(syncHandler))
+	handle(mux, http.MethodGet, "/api/changes", auth(changesHandler))
+	handle(mux, http.MethodGet, "/api/export", auth(exportHandler))
+	handle(mux, http.MethodPost, "/api/import", auth(importHandler))
+	handle(mux, http.MethodGet, calendarPath, http.HandlerFunc(calendarHandler))
+	handle(mux, http.MethodPost, "/api/calendar/token", auth(handleCreateCalendarToken))
+	handle(mux, http.MethodDelete, "/api/calendar/token", auth(handleDeleteCalendarToken))
+	handle(mux, http.MethodPost, "/api/task/done", auth(handleDoneTask))
+	handle(mux, http.MethodPost, "/api/task/undone", auth(handleUndoneTask))
+	handle(mux, http.MethodPost, "/api/task/skip", auth(handleSkipTask))
+	handle(mux, http.MethodPost, "/api/task/postpone", auth(handlePostponeTask))
+	handle(mux, http.MethodPost, "/api/task/clone", auth(handleCloneTask))
+	handle(mux, http.MethodPost, "/api/task/from-template", auth(handleTaskFromTemplate))
+	handle(mux, http.MethodPost, "/api/task/quick", auth(handleQuickTask))
+	handle(mux, http.MethodGet, "/api/templates", auth(handleGetTemplates))
+	handle(mux, http.MethodPost, "/api/templates", auth(handlePostTemplate))
+	handle(mux, http.MethodPut, "/api/templates", auth(handlePutTemplate))
+	handle(mux, http.MethodDelete, "/api/templates", auth(handleDeleteTemplate))
+	handle(mux, http.MethodGet, "/api/webhooks", auth(handleGetWebhooks))
+	handle(mux, http.MethodPost, "/api/webhooks", auth(handlePostWebhook))
+	handle(mux, http.MethodPut, "/api/webhooks", auth(handlePutWebhook))
+	handle(mux, http.MethodDelete, "/api/webhooks", auth(handleDeleteWebhook))
+	handle(mux, http.MethodGet, eventsPath, auth(eventsHandler))
+	handle(mux, http.MethodPost, "/api/signin", http.HandlerFunc(handleSignIn))
+	handle(mux, http.MethodPost, "/api/refresh", http.HandlerFunc(handleRefresh))
+	handle(mux, http.MethodPost, "/api/logout", http.HandlerFunc(handleLogout))
+	handle(mux, http.MethodPost, "/api/users", auth(usersHandler))
+	handle(mux, http.MethodPost, "/api/apikeys", auth(manageKeys(handleCreateAPIKey)))
+	handle(mux, http.MethodDelete, "/api/apikeys", auth(manageKeys(handleDeleteAPIKey)))
+	handle(mux, http.MethodGet, "/api/health", http.HandlerFunc(healthHandler))
+	handle(mux, http.MethodGet, "/api/ready", http.HandlerFunc(readyHandler))
+	handle(mux, http.MethodPost, "/api/admin/maintenance_mode", auth(adminOnly(maintenanceHandler)))
+	handle(mux, http.MethodPost, "/api/admin/backup", auth(adminOnly(backupHandler)))
+	handle(mux, http.MethodGet, "/api/admin/backup/download", auth(adminOnly(backupDownloadHandler)))
+	handle(mux, http.MethodPost, "/api/admin/notify/test", auth(adminOnly(notifyTestHandler)))
+
+	root := http.NewServeMux()
+	if conf().Debug {
+		registerDebug(root, mux)
+	}
+	root.Handle("/api/", methodNotAllowed(mux))
+	if conf().Metrics {
+		root.Handle("GET /metrics", metrics.Handler())
+	}
+	if webFiles != nil {
+		handle(root, "", "/", http.FileServerFS(webFiles))
+	}
+
+	return root
+}
+
+// handle регистрирует в mux обработчик запросов method к path,
+// при пустом method - запросов любым методом.
+// Паника обработчика превращается в ответ 500, каждый запрос попадает
+// в журнал, а при включенных метриках учитывается и в них.
+func handle(mux *http.ServeMux, method, path string, h http.Handler) {
+	h = logRequests(recoverPanic(h))
+	if conf().Metrics {
+		h = metrics.Instrument(path, h)
+	}
+	pattern := path
+	if method != "" {
+		pattern = method + " " + path
+	}
+	mux.Handle(pattern, h)
+}
+
+// methodNotAllowed заменяет текстовый ответ 405 маршрутизатора mux
+// на JSON-ошибку в формате остальных ответов API. Заголовок Allow
+// со списком допустимых методов сохраняется.
+func methodNotAllowed(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(&methodErrorWriter{ResponseWriter: w, r: r}, r)
+	})
 }
+
+// methodErrorWriter перехватывает ответ 405 и отправляет вместо него ошибку API.
+type methodErrorWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	replaced bool
+}
+
+func (w *methodErrorWriter) WriteHeader(code int) {
+	if code != http.StatusMethodNotAllowed {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.replaced = true
+	w.Header().Del("X-Content-Type-Options")
+	sendAPIError(w.ResponseWriter, w.r, CodeMethodNotAllowed, "Method not allowed", code)
+}
+
+func (w *methodErrorWriter) Write(b []byte) (int, error) {
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (w *methodErrorWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
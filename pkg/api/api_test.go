@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutesMethodNotAllowed(t *testing.T) {
+	setupDB(t)
+
+	for _, tc := range []struct {
+		method, target, allow string
+	}{
+		{http.MethodPost, "/api/nextdate", "GET, HEAD"},
+		{http.MethodPost, "/api/tasks", "GET, HEAD"},
+		{http.MethodPost, "/api/tasks/facets", "GET, HEAD"},
+		{http.MethodPost, "/api/tasks/forecast", "GET, HEAD"},
+		{http.MethodGet, "/api/tasks/delete", "POST"},
+		{http.MethodPost, "/api/sync", "GET, HEAD"},
+		{http.MethodGet, "/api/task/done", "POST"},
+		{http.MethodGet, "/api/task/undone", "POST"},
+		{http.MethodGet, "/api/signin", "POST"},
+		{http.MethodPost, "/api/health", "GET, HEAD"},
+		{http.MethodPost, "/api/ready", "GET, HEAD"},
+		{http.MethodGet, "/api/admin/maintenance_mode", "POST"},
+		{http.MethodOptions, "/api/task", "DELETE, GET, HEAD, PATCH, POST, PUT"},
+	} {
+		t.Run(tc.method+" "+tc.target, func(t *testing.T) {
+			w := doRequest(t, apiHandler, tc.method, tc.target, nil)
+			require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+			assert.Equal(t, tc.allow, w.Header().Get("Allow"))
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			// ответ ровно один: обработчик не продолжил работу после ошибки
+			var resp ErrorResponse
+			dec := json.NewDecoder(w.Body)
+			require.NoError(t, dec.Decode(&resp))
+			assert.Equal(t, "Method not allowed", resp.Error)
+			assert.False(t, dec.More())
+		})
+	}
+}
+
+func TestWebFiles(t *testing.T) {
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	prev := webFiles
+	t.Cleanup(func() { webFiles = prev })
+	webFiles = fstest.MapFS{
+		"index.html":    {Data: []byte("<h1>Задачи</h1>")},
+		"css/style.css": {Data: []byte("body{}")},
+	}
+	w := get("/")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<h1>Задачи</h1>", w.Body.String())
+	assert.Equal(t, "body{}", get("/css/style.css").Body.String())
+	assert.Equal(t, http.StatusNotFound, get("/missing.js").Code)
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go1f/pkg/db"
+)
+
+// Параметры ключей API.
+const (
+	apiKeyHeader    = "X-Api-Key" // заголовок с ключом
+	apiKeyPrefix    = "todo_"     // префикс ключа, чтобы его было легко найти в конфигурации
+	apiKeySize      = 32          // байт случайной части ключа
+	apiKeyCacheTTL  = time.Minute // время жизни записи кэша ключей
+	apiKeyCacheSize = 256         // записей в кэше, при переполнении кэш очищается
+	maxKeyNameLen   = 64          // символов в подписи ключа
+)
+
+// APIKeyReq - тело запроса POST /api/apikeys.
+type APIKeyReq struct {
+	Name     string `json:"name"`
+	CanAdmin bool   `json:"can_admin"`
+}
+
+// APIKeyResp - созданный ключ API. Key возвращается только один раз.
+type APIKeyResp struct {
+	ID       int64  `json:"id"`
+	Key      string `json:"key"`
+	Name     string `json:"name"`
+	CanAdmin bool   `json:"can_admin"`
+}
+
+// apiKeyCtxKey - ключ контекста запроса, выполненного с ключом API.
+type apiKeyCtxKey struct{}
+
+// withAPIKey возвращает контекст запроса от имени владельца ключа key.
+func withAPIKey(ctx context.Context, key db.APIKey) context.Context {
+	return context.WithValue(db.WithUser(ctx, key.UserID), apiKeyCtxKey{}, key)
+}
+
+// apiKeyFrom возвращает ключ API, с которым выполнен запрос.
+// ok = false, если запрос аутентифицирован токеном.
+func apiKeyFrom(ctx context.Context) (key db.APIKey, ok bool) {
+	key, ok = ctx.Value(apiKeyCtxKey{}).(db.APIKey)
+	return key, ok
+}
+
+// hashAPIKey возвращает SHA-256 ключа в hex - так ключ хранится в БД.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// apiKeyEntry - запись кэша ключей API.
+type apiKeyEntry struct {
+	key     db.APIKey
+	expires time.Time
+}
+
+// apiKeyCache - кэш найденных ключей API по их хешу,
+// чтобы не обращаться к БД на каждый запрос скрипта.
+// Ненайденные ключи не кэшируются.
+type apiKeyCache struct {
+	mu      sync.Mutex
+	entries map[string]apiKeyEntry
+}
+
+// apiKeys - кэш ключей API сервера.
+var apiKeys = &apiKeyCache{entries: make(map[string]apiKeyEntry)}
+
+// lookup возвращает ключ API по хешу hash из кэша или из БД.
+func (c *apiKeyCache) lookup(ctx context.Context, hash string) (db.APIKey, error) {
+	now := clock()
+
+	c.mu.Lock()
+	e, ok := c.entries[hash]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.key, nil
+	}
+
+	key, err := store.APIKeyByHash(ctx, hash)
+	if err != nil {
+		return db.APIKey{}, err
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= apiKeyCacheSize {
+		clear(c.entries)
+	}
+	c.entries[hash] = apiKeyEntry{key: key, expires: now.Add(apiKeyCacheTTL)}
+	c.mu.Unlock()
+	return key, nil
+}
+
+// forget удаляет из кэша отозванный ключ с идентификатором id.
+func (c *apiKeyCache) forget(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for hash, e := range c.entries {
+		if e.key.ID == id {
+			delete(c.entries, hash)
+		}
+	}
+}
+
+// checkAPIKey проверяет ключ API из заголовка X-Api-Key.
+// Возвращает найденный ключ или сообщение об ошибке.
+func checkAPIKey(r *http.Request, plain string) (db.APIKey, string, bool) {
+	key, err := apiKeys.lookup(r.Context(), hashAPIKey(plain))
+	if errors.Is(err, db.ErrAPIKeyNotFound) {
+		return db.APIKey{}, "Неверный ключ API", false
+	}
+	if err != nil {
+		slog.Error("Ошибка при проверке ключа API", "err", err)
+		return db.APIKey{}, "Ошибка при проверке ключа API", false
+	}
+	return key, "", true
+}
+
+// manageKeys пропускает к управлению ключами API запросы с токеном
+// и с ключом, у которого установлен флаг can_admin.
+func manageKeys(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if key, ok := apiKeyFrom(r.Context()); ok && !key.CanAdmin {
+			sendAPIError(w, r, CodeForbidden, "Ключ API не может управлять ключами", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleCreateAPIKey обрабатывает POST-запрос /api/apikeys - выпуск ключа API.
+//
+// Принимает необязательный JSON вида {"name":"cron","can_admin":false}.
+// Ключ генерируется случайно, в БД сохраняется только его SHA-256,
+// сам ключ возвращается один раз со статусом 201:
+//
+//	{"id":1,"key":"todo_...","name":"cron","can_admin":false}
+func handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req APIKeyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		sendDecodeError(w, r, err)
+		return
+	}
+	if len([]rune(req.Name)) > maxKeyNameLen {
+		sendAPIError(w, r, CodeBadRequest, "Слишком длинное имя ключа", http.StatusBadRequest)
+		return
+	}
+
+	buf := make([]byte, apiKeySize)
+	if _, err := rand.Read(buf); err != nil {
+		sendAPIError(w, r, CodeInternal, "Ошибка при создании ключа", http.StatusInternalServerError)
+		return
+	}
+	plain := apiKeyPrefix + hex.EncodeToString(buf)
+
+	key, err := store.CreateAPIKey(r.Context(), hashAPIKey(plain), req.Name, req.CanAdmin)
+	if err != nil {
+		slog.Error("Ошибка при сохранении ключа API", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка при создании ключа", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, r, APIKeyResp{ID: key.ID, Key: plain, Name: key.Name, CanAdmin: key.CanAdmin}, http.StatusCreated)
+}
+
+// handleDeleteAPIKey обрабатывает DELETE-запрос /api/apikeys?id=N - отзыв ключа API.
+// Отозвать можно только свой ключ, чужой или несуществующий дает 404.
+func handleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	idParam := r.URL.Query().Get("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil || id <= 0 {
+		sendAPIError(w, r, CodeBadRequest, "Неверный идентификатор ключа", http.StatusBadRequest)
+		return
+	}
+
+	err = store.DeleteAPIKey(r.Context(), idParam)
+	if errors.Is(err, db.ErrAPIKeyNotFound) {
+		sendAPIError(w, r, CodeNotFound, "Ключ не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("Ошибка при удалении ключа API", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка при удалении ключа", http.StatusInternalServerError)
+		return
+	}
+	apiKeys.forget(id)
+
+	sendJSON(w, r, struct{}{}, http.StatusOK)
+}
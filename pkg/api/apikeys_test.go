@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// doKeyRequest выполняет запрос к API с ключом key в заголовке X-Api-Key.
+func doKeyRequest(t *testing.T, key, method, target string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	r := httptest.NewRequest(method, target, &buf)
+	r.Header.Set(apiKeyHeader, key)
+	w := httptest.NewRecorder()
+	apiHandler(w, r)
+	return w
+}
+
+// createKey выпускает ключ API с токеном token.
+func createKey(t *testing.T, token string, req APIKeyReq) APIKeyResp {
+	t.Helper()
+	w := doAuthRequest(t, token, http.MethodPost, "/api/apikeys", req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp APIKeyResp
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+func TestAPIKeys(t *testing.T) {
+	setupDB(t)
+	usePassword(t, "secret", time.Hour)
+	token := signIn(t, "", "secret")
+
+	plain := createKey(t, token, APIKeyReq{Name: "cron"})
+	assert.Regexp(t, "^"+apiKeyPrefix+"[0-9a-f]{64}$", plain.Key)
+	admin := createKey(t, token, APIKeyReq{Name: "ops", CanAdmin: true})
+
+	// в БД хранится только хеш
+	_, err := store.APIKeyByHash(t.Context(), plain.Key)
+	require.Error(t, err)
+	stored, err := store.APIKeyByHash(t.Context(), hashAPIKey(plain.Key))
+	require.NoError(t, err)
+	assert.Equal(t, "cron", stored.Name)
+
+	today := time.Now().Format("20060102")
+	w := doKeyRequest(t, plain.Key, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "Из cron"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = doAuthRequest(t, token, http.MethodGet, "/api/tasks?limit=10", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Из cron")
+
+	assert.Equal(t, http.StatusUnauthorized, doKeyRequest(t, "todo_wrong", http.MethodGet, "/api/tasks", nil).Code)
+
+	// управлять ключами может только ключ с can_admin
+	w = doKeyRequest(t, plain.Key, http.MethodPost, "/api/apikeys", APIKeyReq{})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doKeyRequest(t, plain.Key, http.MethodDelete, fmt.Sprintf("/api/apikeys?id=%d", plain.ID), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doKeyRequest(t, admin.Key, http.MethodPost, "/api/apikeys", APIKeyReq{Name: "extra"})
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// отозванный ключ сразу перестает работать, несмотря на кэш
+	w = doKeyRequest(t, admin.Key, http.MethodDelete, fmt.Sprintf("/api/apikeys?id=%d", plain.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, doKeyRequest(t, plain.Key, http.MethodGet, "/api/tasks", nil).Code)
+
+	w = doAuthRequest(t, token, http.MethodDelete, fmt.Sprintf("/api/apikeys?id=%d", plain.ID), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doAuthRequest(t, token, http.MethodDelete, "/api/apikeys?id=abc", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
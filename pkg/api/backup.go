@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"go1f/pkg/db"
+)
+
+// BackupResp - созданная резервная копия БД.
+type BackupResp struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"` // байт
+}
+
+// adminOnly пропускает запросы администратора - пользователя по умолчанию.
+// Запрос с ключом API дополнительно должен иметь флаг can_admin.
+func adminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, withKey := apiKeyFrom(r.Context())
+		if db.UserID(r.Context()) != db.DefaultUserID || withKey && !key.CanAdmin {
+			sendAPIError(w, r, CodeForbidden, "Операция доступна только администратору", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// backupHandler обрабатывает POST-запрос /api/admin/backup.
+//
+// Сохраняет согласованную копию БД командой VACUUM INTO в каталог TODO_BACKUP_DIR
+// (по умолчанию - рядом с файлом БД) под именем backup-YYYYMMDD-HHMMSS.db
+// и возвращает 201:
+//
+//	{"name":"backup-20250701-100000.db","size":24576}
+//
+// Возможные ошибки:
+//   - 409: копия с таким именем уже есть (повторный запрос в ту же секунду)
+//   - 501: СУБД не поддерживает копирование (PostgreSQL копирует pg_dump)
+func backupHandler(w http.ResponseWriter, r *http.Request) {
+	dir := conf().BackupDir
+	name, err := store.BackupToDir(r.Context(), dir, localNow())
+	switch {
+	case errors.Is(err, db.ErrBackupExists):
+		sendAPIError(w, r, CodeConflict, "Резервная копия с таким именем уже есть, повторите позже", http.StatusConflict)
+		return
+	case errors.Is(err, db.ErrNotSupported):
+		sendAPIError(w, r, CodeNotImplemented, "Резервное копирование не поддерживается для этой СУБД", http.StatusNotImplemented)
+		return
+	case err != nil:
+		slog.Error("Ошибка резервного копирования", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка резервного копирования", http.StatusInternalServerError)
+		return
+	}
+
+	resp := BackupResp{Name: name}
+	if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+		resp.Size = info.Size()
+	}
+	slog.Info("Создана резервная копия БД", "name", name, "size", resp.Size)
+	sendJSON(w, r, resp, http.StatusCreated)
+}
+
+// backupDownloadHandler обрабатывает GET-запрос /api/admin/backup/download?name=...
+//
+// Отдает файлом резервную копию из каталога TODO_BACKUP_DIR. Принимается только
+// имя копии вида backup-YYYYMMDD-HHMMSS.db, а файл открывается через os.Root,
+// поэтому ни путь, ни ссылка не выводят за пределы каталога копий.
+func backupDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if !db.IsBackupName(name) {
+		sendAPIError(w, r, CodeBadRequest, "Неверное имя резервной копии", http.StatusBadRequest)
+		return
+	}
+
+	root, err := os.OpenRoot(conf().BackupDir)
+	if err != nil {
+		sendAPIError(w, r, CodeNotFound, "Резервная копия не найдена", http.StatusNotFound)
+		return
+	}
+	defer root.Close()
+	f, err := root.Open(name)
+	if err != nil {
+		sendAPIError(w, r, CodeNotFound, "Резервная копия не найдена", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		sendAPIError(w, r, CodeNotFound, "Резервная копия не найдена", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", "attachment; filename="+name)
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
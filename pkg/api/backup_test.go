@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useBackupDir направляет резервные копии во временный каталог до конца теста.
+func useBackupDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "backups")
+	useConf(t).BackupDir = dir
+	return dir
+}
+
+func TestBackupEndpoints(t *testing.T) {
+	setupDB(t)
+	dir := useBackupDir(t)
+	usePassword(t, "secret", time.Hour)
+	_, err := store.AddTask(t.Context(), &db.Task{Date: "20990101", Title: "В копии"})
+	require.NoError(t, err)
+	token := signIn(t, "", "secret")
+
+	w := doAuthRequest(t, token, http.MethodPost, "/api/admin/backup", nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp BackupResp
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.True(t, db.IsBackupName(resp.Name), resp.Name)
+	data, err := os.ReadFile(filepath.Join(dir, resp.Name))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), resp.Size)
+
+	w = doAuthRequest(t, token, http.MethodGet, "/api/admin/backup/download?name="+resp.Name, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "attachment; filename="+resp.Name, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, data, w.Body.Bytes())
+
+	// отдаются только копии из каталога копий
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "scheduler.db"), []byte("живая БД"), 0o600))
+	for name, code := range map[string]int{
+		"":                          http.StatusBadRequest,
+		"scheduler.db":              http.StatusBadRequest,
+		"../backups/" + resp.Name:   http.StatusBadRequest,
+		"%2Fetc%2Fpasswd":           http.StatusBadRequest,
+		"backup-20000101-000000.db": http.StatusNotFound,
+		resp.Name + "%00":           http.StatusBadRequest,
+	} {
+		w = doAuthRequest(t, token, http.MethodGet, "/api/admin/backup/download?name="+name, nil)
+		assert.Equal(t, code, w.Code, name)
+	}
+	// ссылка на файл вне каталога не открывается
+	outside := "backup-20000101-000001.db"
+	secret := filepath.Join(t.TempDir(), outside)
+	require.NoError(t, os.WriteFile(secret, []byte("секрет"), 0o600))
+	require.NoError(t, os.Symlink(secret, filepath.Join(dir, outside)))
+	w = doAuthRequest(t, token, http.MethodGet, "/api/admin/backup/download?name="+outside, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestBackupAdminOnly(t *testing.T) {
+	setupDB(t)
+	useBackupDir(t)
+	usePassword(t, "secret", time.Hour)
+	adminToken := signIn(t, "", "secret")
+	w := doAuthRequest(t, adminToken, http.MethodPost, "/api/users", UserReq{Login: "partner", Password: "partner-pass"})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	partnerToken := signIn(t, "partner", "partner-pass")
+	w = doAuthRequest(t, partnerToken, http.MethodPost, "/api/admin/backup", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// ключ API администратора без can_admin не делает копий
+	key := createKey(t, adminToken, APIKeyReq{Name: "cron"})
+	w = doKeyRequest(t, key.Key, http.MethodPost, "/api/admin/backup", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	key = createKey(t, adminToken, APIKeyReq{Name: "ops", CanAdmin: true})
+	w = doKeyRequest(t, key.Key, http.MethodPost, "/api/admin/backup", nil)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/taskdate"
+)
+
+// BatchDeleteReq - тело запроса /api/tasks/delete.
+type BatchDeleteReq struct {
+	IDs []string `json:"ids"`
+}
+
+// BatchDeleteResp - результат пакетного удаления.
+type BatchDeleteResp struct {
+	Deleted int      `json:"deleted"`
+	Missing []string `json:"missing"`
+}
+
+// batchDeleteHandler обрабатывает POST-запрос /api/tasks/delete.
+//
+// Принимает JSON вида {"ids":["1","2","3"]} и удаляет задачи в одной транзакции:
+// либо удаляются все найденные задачи, либо при ошибке БД ни одна.
+// Возвращает количество удаленных задач и ID, которые не найдены:
+//
+//	{"deleted":2,"missing":["3"]}
+//
+// Пустой список или нечисловой ID - 400.
+func batchDeleteHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req BatchDeleteReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendDecodeError(w, r, err)
+		return
+	}
+	if len(req.IDs) == 0 {
+		sendAPIError(w, r, CodeBadRequest, "список ids не должен быть пустым", http.StatusBadRequest)
+		return
+	}
+	for _, id := range req.IDs {
+		if _, err := parseID(id); err != nil {
+			sendErr(w, r, err)
+			return
+		}
+	}
+
+	deleted, missing, err := store.DeleteTasks(r.Context(), req.IDs)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при пакетном удалении задач", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка удаления", http.StatusInternalServerError)
+		return
+	}
+
+	for _, param := range req.IDs {
+		if !slices.Contains(missing, param) {
+			id, _ := parseID(param) // проверен выше
+			notify(r.Context(), EventDeleted, id, nil)
+		}
+	}
+	sendJSON(w, r, BatchDeleteResp{Deleted: deleted, Missing: missing}, http.StatusOK)
+}
+
+// RescheduleReq - тело запроса /api/tasks/reschedule.
+// Пока поддерживается только перенос просроченных задач на сегодня,
+// пустые Filter и To означают то же самое.
+type RescheduleReq struct {
+	Filter           string `json:"filter"`            // "overdue"
+	To               string `json:"to"`                // "today"
+	IncludeRepeating bool   `json:"include_repeating"` // переносить и повторяющиеся задачи
+}
+
+// RescheduleResp - результат переноса задач.
+type RescheduleResp struct {
+	Moved int      `json:"moved"`
+	IDs   []string `json:"ids"`
+}
+
+// rescheduleHandler обрабатывает POST-запрос /api/tasks/reschedule.
+//
+// Переносит все просроченные невыполненные задачи на сегодняшнюю дату в одной
+// транзакции. Повторяющиеся задачи по умолчанию не трогаются, а с
+// "include_repeating":true переносятся на ближайшую дату по правилу, начиная
+// с сегодняшней; задачи с завершенным правилом остаются на месте.
+// Возвращает количество и ID перенесенных задач:
+//
+//	{"moved":2,"ids":["1","5"]}
+//
+// Неизвестные filter или to - 400.
+func rescheduleHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req RescheduleReq
+	if !decodeTask(w, r, &req) {
+		return
+	}
+	if req.Filter != "" && req.Filter != db.DueOverdue {
+		sendAPIError(w, r, CodeBadRequest, "Поддерживается только filter=overdue", http.StatusBadRequest)
+		return
+	}
+	if req.To != "" && req.To != "today" {
+		sendAPIError(w, r, CodeBadRequest, "Поддерживается только to=today", http.StatusBadRequest)
+		return
+	}
+
+	now := localNow()
+	var nextDate func(db.Task) (string, error)
+	if req.IncludeRepeating {
+		nextDate = func(task db.Task) (string, error) { return rescheduleDate(now, task) }
+	}
+	ids, err := store.RescheduleTasks(r.Context(), now.Format(taskdate.DateFormat), nextDate)
+	if errors.Is(err, errNextDate) {
+		slog.ErrorContext(r.Context(), "Ошибка при пересчете даты задачи из БД", "err", err)
+		sendAPIError(w, r, CodeInternal, "ошибка при расчете новой даты", http.StatusInternalServerError)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при переносе просроченных задач", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка сохранения", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, r, RescheduleResp{Moved: len(ids), IDs: ids}, http.StatusOK)
+}
+
+// rescheduleDate возвращает ближайшую дату повторяющейся задачи не раньше
+// сегодняшней. NextDate ищет дату строго после now, поэтому отсчет ведется
+// от вчерашнего дня. Для завершенного правила или правила, все даты которого
+// исключены, возвращает пустую строку - задача не переносится.
+func rescheduleDate(now time.Time, task db.Task) (string, error) {
+	date, err := taskdate.NextDateExcluding(now.AddDate(0, 0, -1), task.Date, task.Repeat, task.Exclude)
+	if errors.Is(err, taskdate.ErrRepeatFinished) || errors.Is(err, taskdate.ErrAllExcluded) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errNextDate, err)
+	}
+	return date, nil
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchDelete(t *testing.T) {
+	setupDB(t)
+	var ids []string
+	for _, title := range []string{"Первая", "Вторая"} {
+		id, err := store.AddTask(t.Context(), &db.Task{Date: "20240101", Title: title})
+		require.NoError(t, err)
+		ids = append(ids, fmt.Sprint(id))
+	}
+
+	w := doRequest(t, batchDeleteHandler, http.MethodPost, "/api/tasks/delete",
+		map[string]any{"ids": append(ids, "100500")})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]any{"deleted": float64(2), "missing": []any{"100500"}}, decodeBody(t, w))
+
+	for _, id := range ids {
+		_, err := store.GetTaskID(t.Context(), mustID(t, id))
+		assert.ErrorIs(t, err, db.ErrTaskNotFound)
+	}
+
+	for _, body := range []any{
+		map[string]any{"ids": []string{}},
+		map[string]any{},
+		map[string]any{"ids": []string{"1", "abc"}},
+	} {
+		w := doRequest(t, batchDeleteHandler, http.MethodPost, "/api/tasks/delete", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestReschedule(t *testing.T) {
+	setupDB(t)
+	now := time.Date(2025, 7, 10, 10, 0, 0, 0, time.UTC)
+	useClock(t, &now)
+	var ids []int64
+	for _, task := range []db.Task{
+		{Date: "20250701", Title: "Просрочена"},
+		{Date: "20250708", Title: "Ежедневная", Repeat: "d 1"},
+		{Date: "20250702", Title: "Раз в неделю", Repeat: "d 7"},
+		{Date: "20250710", Title: "Сегодня"},
+		{Date: "20250720", Title: "Будущая"},
+	} {
+		id, err := store.AddTask(t.Context(), &task)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	dates := func() []string {
+		var result []string
+		for _, id := range ids {
+			task, err := store.GetTaskID(t.Context(), id)
+			require.NoError(t, err)
+			result = append(result, task.Date)
+		}
+		return result
+	}
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/tasks/reschedule",
+		map[string]any{"filter": "overdue", "to": "today"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, map[string]any{"moved": float64(1), "ids": []any{fmt.Sprint(ids[0])}}, decodeBody(t, w))
+	assert.Equal(t, []string{"20250710", "20250708", "20250702", "20250710", "20250720"}, dates())
+
+	// повторяющиеся - на ближайшую дату по правилу, не раньше сегодняшней
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/tasks/reschedule",
+		map[string]any{"include_repeating": true})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, map[string]any{"moved": float64(2), "ids": []any{fmt.Sprint(ids[1]), fmt.Sprint(ids[2])}}, decodeBody(t, w))
+	assert.Equal(t, []string{"20250710", "20250710", "20250716", "20250710", "20250720"}, dates())
+
+	for _, body := range []any{
+		map[string]any{"filter": "today"},
+		map[string]any{"to": "tomorrow"},
+	} {
+		w := doRequest(t, apiHandler, http.MethodPost, "/api/tasks/reschedule", body)
+		assertAPIError(t, w, CodeBadRequest)
+	}
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/tasks/reschedule", map[string]any{"filtr": "overdue"})
+	assertAPIError(t, w, CodeUnknownField)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go1f/pkg/config"
+)
+
+// bodyLimits - ограничения тела для маршрутов, которым нужно больше
+// TODO_MAX_BODY_BYTES.
+var bodyLimits = map[string]int64{
+	"/api/import": maxImportSize,
+}
+
+// limitBody - middleware, ограничивающее тело запросов к /api/ размером
+// TODO_MAX_BODY_BYTES или ограничением маршрута из bodyLimits.
+// Статические файлы тело не читают и не ограничиваются.
+//
+// Запрос с Content-Length больше ограничения сразу получает 413; остальные
+// читают тело через http.MaxBytesReader, и обработчик получает
+// *http.MaxBytesError (см. sendDecodeError). В обоих случаях сервер закрывает
+// соединение после ответа, не дочитывая лишнее тело.
+//
+// Глубину вложенности JSON encoding/json ограничивает сам (10000 уровней),
+// а размер тела ограничивает и память, нужную на разбор.
+func limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limit, ok := bodyLimits[r.URL.Path]
+		if !ok {
+			limit = maxRequestBody()
+		}
+		if r.ContentLength > limit {
+			w.Header().Set("Connection", "close")
+			sendBodyTooLarge(w, r, limit)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// maxRequestBody возвращает TODO_MAX_BODY_BYTES или значение по умолчанию,
+// если конфигурация не загружена.
+func maxRequestBody() int64 {
+	if limit := conf().MaxRequestBody; limit > 0 {
+		return limit
+	}
+	return config.DefaultMaxRequest
+}
+
+// sendDecodeError отвечает на ошибку чтения JSON-тела запроса:
+// 413, если тело больше ограничения, иначе 400 с текстом ошибки разбора.
+func sendDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		sendBodyTooLarge(w, r, tooLarge.Limit)
+		return
+	}
+	sendAPIError(w, r, CodeInvalidJSON, "Неверный формат JSON: "+err.Error(), http.StatusBadRequest)
+}
+
+// sendBodyTooLarge отвечает 413 с ограничением limit в тексте ошибки.
+func sendBodyTooLarge(w http.ResponseWriter, r *http.Request, limit int64) {
+	size := fmt.Sprintf("%d байт", limit)
+	switch {
+	case limit%(1<<20) == 0:
+		size = fmt.Sprintf("%d МБ", limit>>20)
+	case limit%(1<<10) == 0:
+		size = fmt.Sprintf("%d КБ", limit>>10)
+	}
+	sendAPIError(w, r, CodeBodyTooLarge, "Тело запроса больше "+size, http.StatusRequestEntityTooLarge)
+}
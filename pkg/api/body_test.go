@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useMaxRequestBody задает TODO_MAX_BODY_BYTES до конца теста.
+func useMaxRequestBody(t *testing.T, limit int64) {
+	t.Helper()
+	useConf(t).MaxRequestBody = limit
+}
+
+// postBody отправляет body методом POST; без known длина тела
+// не передается и оно идет частями (chunked).
+func postBody(t *testing.T, url string, body []byte, known bool) *http.Response {
+	t.Helper()
+	var r io.Reader = bytes.NewReader(body)
+	if !known {
+		r = io.MultiReader(r) // длина неизвестна клиенту
+	}
+	req, err := http.NewRequest(http.MethodPost, url, r)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestLimitBody(t *testing.T) {
+	setupDB(t)
+	useMaxRequestBody(t, 1024)
+	srv := httptest.NewServer(limitBody(routes()))
+	t.Cleanup(srv.Close)
+
+	large, err := json.Marshal(map[string]string{
+		"date": "20990101", "title": "Большая", "comment": strings.Repeat("x", 8<<10),
+	})
+	require.NoError(t, err)
+
+	for _, path := range []string{"/api/task", "/api/signin"} {
+		for _, known := range []bool{true, false} {
+			resp := postBody(t, srv.URL+path, large, known)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode, path)
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body), path)
+			assert.Equal(t, string(CodeBodyTooLarge), body["code"], path)
+			assert.Equal(t, "Тело запроса больше 1 КБ", body["error"], path)
+			// недочитанное тело не остается в соединении
+			assert.True(t, resp.Close, path)
+		}
+	}
+
+	// тело в пределах ограничения и следующий запрос обрабатываются как обычно
+	resp := postBody(t, srv.URL+"/api/task", []byte(`{"date":"20990101","title":"Маленькая"}`), true)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// у импорта свое ограничение
+	resp = postBody(t, srv.URL+"/api/import", append([]byte("[]"), bytes.Repeat([]byte(" "), 8<<10)...), true)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestLimitBodySkipsStatic(t *testing.T) {
+	useMaxRequestBody(t, 16)
+	h := limitBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		w.Write(data)
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(strings.Repeat("x", 64))))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 64, w.Body.Len())
+}
+
+func TestDecodeDeepJSON(t *testing.T) {
+	setupDB(t)
+	deep := strings.Repeat("[", 20000) + strings.Repeat("]", 20000)
+	w := httptest.NewRecorder()
+	limitBody(routes()).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/tasks/delete", strings.NewReader(deep)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, decodeBody(t, w)["error"], "depth")
+}
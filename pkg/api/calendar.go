@@ -0,0 +1,191 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go1f/pkg/db"
+	"go1f/pkg/taskdate"
+)
+
+// Параметры токена календаря.
+const (
+	calendarTokenPrefix = "cal_" // префикс токена, чтобы отличать его от ключей API
+	calendarTokenSize   = 32     // байт случайной части токена
+	calendarPath        = "/api/calendar.ics"
+)
+
+// Параметры записи календаря по RFC 5545.
+const (
+	icsLineLen  = 75 // наибольшая длина строки в байтах без CRLF
+	icsStampFmt = "20060102T150405Z"
+)
+
+// CalendarTokenResp - выпущенный токен календаря и адрес ленты с ним.
+// Токен возвращается только один раз.
+type CalendarTokenResp struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// handleCreateCalendarToken обрабатывает POST-запрос /api/calendar/token.
+//
+// Выпускает случайный токен для подписки на календарь задач пользователя
+// и возвращает его один раз со статусом 201:
+//
+//	{"token":"cal_...","url":"/api/calendar.ics?token=cal_..."}
+//
+// В БД хранится только SHA-256 токена. Прежний токен перестает действовать.
+func handleCreateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	buf := make([]byte, calendarTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		sendAPIError(w, r, CodeInternal, "Ошибка при создании токена календаря", http.StatusInternalServerError)
+		return
+	}
+	token := calendarTokenPrefix + hex.EncodeToString(buf)
+
+	if err := store.SetCalendarToken(r.Context(), hashAPIKey(token)); err != nil {
+		slog.Error("Ошибка при сохранении токена календаря", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка при создании токена календаря", http.StatusInternalServerError)
+		return
+	}
+	resp := CalendarTokenResp{Token: token, URL: calendarPath + "?" + url.Values{"token": {token}}.Encode()}
+	sendJSON(w, r, resp, http.StatusCreated)
+}
+
+// handleDeleteCalendarToken обрабатывает DELETE-запрос /api/calendar/token -
+// отзыв токена календаря. Без выпущенного токена отвечает 404.
+func handleDeleteCalendarToken(w http.ResponseWriter, r *http.Request) {
+	err := store.DeleteCalendarToken(r.Context())
+	if errors.Is(err, db.ErrCalendarTokenNotFound) {
+		sendAPIError(w, r, CodeNotFound, "Токен календаря не выпущен", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("Ошибка при удалении токена календаря", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка при удалении токена календаря", http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, r, struct{}{}, http.StatusOK)
+}
+
+// calendarHandler обрабатывает GET-запрос /api/calendar.ics.
+//
+// Отдает невыполненные задачи пользователя в формате iCalendar (RFC 5545)
+// для подписки из календарей: каждая задача - событие на весь день своей даты,
+// правило повтора переводится в RRULE (см. taskdate.RRule), исключенные даты -
+// в EXDATE. Задачи с правилом, которое перевести нельзя, показываются одним событием.
+//
+// Календари не передают куки и заголовки, поэтому пользователь определяется
+// по параметру token (см. handleCreateCalendarToken). Если пароль не задан,
+// токен не нужен.
+func calendarHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if credential() != "" {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			sendAPIError(w, r, CodeUnauthorized, "Требуется токен календаря", http.StatusUnauthorized)
+			return
+		}
+		userID, err := store.CalendarTokenUser(ctx, hashAPIKey(token))
+		if errors.Is(err, db.ErrCalendarTokenNotFound) {
+			sendAPIError(w, r, CodeUnauthorized, "Неверный токен календаря", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			slog.Error("Ошибка при проверке токена календаря", "err", err)
+			sendAPIError(w, r, CodeDBError, "Ошибка при проверке токена календаря", http.StatusInternalServerError)
+			return
+		}
+		ctx = db.WithUser(ctx, userID)
+	}
+
+	var b strings.Builder
+	stamp := clock().UTC().Format(icsStampFmt)
+	icsLine(&b, "BEGIN", "VCALENDAR")
+	icsLine(&b, "VERSION", "2.0")
+	icsLine(&b, "PRODID", "-//go1f//Task Scheduler//RU")
+	icsLine(&b, "CALSCALE", "GREGORIAN")
+	icsLine(&b, "X-WR-CALNAME", "Задачи")
+	err := store.StreamTasks(ctx, func(task *db.Task) error {
+		if !task.Completed {
+			writeEvent(&b, task, stamp)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Error("Ошибка при чтении задач для календаря", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка при получении задач", http.StatusInternalServerError)
+		return
+	}
+	icsLine(&b, "END", "VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=UTF-8")
+	w.Write([]byte(b.String()))
+}
+
+// writeEvent записывает задачу событием VEVENT. Задача с неверной датой пропускается.
+func writeEvent(b *strings.Builder, task *db.Task, stamp string) {
+	if _, err := time.Parse(taskdate.DateFormat, task.Date); err != nil {
+		return
+	}
+	uid := task.UID
+	if uid == "" {
+		uid = "task-" + task.ID
+	}
+
+	icsLine(b, "BEGIN", "VEVENT")
+	icsLine(b, "UID", uid)
+	icsLine(b, "DTSTAMP", stamp)
+	icsLine(b, "DTSTART;VALUE=DATE", task.Date)
+	icsLine(b, "SUMMARY", icsText(task.Title))
+	if task.Comment != "" {
+		icsLine(b, "DESCRIPTION", icsText(task.Comment))
+	}
+	if len(task.Tags) > 0 {
+		tags := make([]string, len(task.Tags))
+		for i, tag := range task.Tags {
+			tags[i] = icsText(tag)
+		}
+		icsLine(b, "CATEGORIES", strings.Join(tags, ","))
+	}
+	if rule, ok := taskdate.RRule(task.Repeat); ok {
+		icsLine(b, "RRULE", rule)
+		if len(task.Exclude) > 0 {
+			icsLine(b, "EXDATE;VALUE=DATE", strings.Join(task.Exclude, ","))
+		}
+	}
+	icsLine(b, "END", "VEVENT")
+}
+
+// icsText экранирует значение текстового свойства календаря.
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// icsLine записывает свойство календаря name:value строкой с окончанием CRLF.
+// Длинная строка сворачивается: продолжение начинается с пробела, а каждая
+// часть вместе с ним не длиннее icsLineLen байт. Символы UTF-8 не разрываются.
+func icsLine(b *strings.Builder, name, value string) {
+	line := name + ":" + value
+	limit := icsLineLen
+	for len(line) > limit {
+		cut := limit
+		for !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = icsLineLen - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
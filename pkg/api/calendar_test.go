@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestICSLine(t *testing.T) {
+	value := icsText(strings.Repeat("Длинный комментарий; с запятой, ", 5))
+	var b strings.Builder
+	icsLine(&b, "DESCRIPTION", value)
+
+	out := b.String()
+	require.True(t, strings.HasSuffix(out, "\r\n"))
+	lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+	require.Greater(t, len(lines), 1)
+	for i, line := range lines {
+		assert.LessOrEqual(t, len(line), icsLineLen, i)
+		assert.True(t, utf8.ValidString(line), i)
+		if i > 0 {
+			assert.True(t, strings.HasPrefix(line, " "), i)
+		}
+	}
+	// свернутые строки разворачиваются обратно
+	assert.Equal(t, "DESCRIPTION:"+value, strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""))
+
+	assert.Equal(t, `a\\b\;c\,d\ne`, icsText("a\\b;c,d\ne"))
+}
+
+func TestCalendarFeed(t *testing.T) {
+	setupDB(t)
+	usePassword(t, "secret", time.Hour)
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	useClock(t, &now)
+
+	for _, task := range []db.Task{
+		{Date: "20250701", Title: "Планерка", Comment: "Зал 2, этаж 3", Repeat: "w 1,3", Exclude: []string{"20250707"}},
+		{Date: "20250705", Title: "Разовая", Tags: []string{"дом"}},
+		{Date: "20250710", Title: "Выполненная"},
+	} {
+		_, err := store.AddTask(t.Context(), &task)
+		require.NoError(t, err)
+		if task.Title == "Выполненная" {
+			require.NoError(t, store.SetCompleted(t.Context(), mustID(t, task.ID), true))
+		}
+	}
+
+	// без токена и с неверным токеном календарь недоступен
+	w := doRequest(t, apiHandler, http.MethodGet, "/api/calendar.ics", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/calendar.ics?token=cal_123", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	token := signIn(t, "", "secret")
+	w = doAuthRequest(t, token, http.MethodPost, "/api/calendar/token", nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp CalendarTokenResp
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Regexp(t, "^"+calendarTokenPrefix+"[0-9a-f]{64}$", resp.Token)
+	assert.Equal(t, "/api/calendar.ics?token="+resp.Token, resp.URL)
+
+	w = doRequest(t, apiHandler, http.MethodGet, resp.URL, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/calendar; charset=UTF-8", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.NotContains(t, strings.ReplaceAll(body, "\r\n", ""), "\n", "все строки оканчиваются CRLF")
+	assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(body, "END:VCALENDAR\r\n"))
+	assert.Equal(t, 2, strings.Count(body, "BEGIN:VEVENT"))
+	assert.Contains(t, body, "DTSTAMP:20250701T100000Z\r\n")
+	assert.Contains(t, body, "DTSTART;VALUE=DATE:20250701\r\nSUMMARY:Планерка\r\nDESCRIPTION:Зал 2\\, этаж 3\r\n")
+	assert.Contains(t, body, "RRULE:FREQ=WEEKLY;BYDAY=MO,WE\r\nEXDATE;VALUE=DATE:20250707\r\n")
+	assert.Contains(t, body, "SUMMARY:Разовая\r\nCATEGORIES:дом\r\nEND:VEVENT")
+	assert.NotContains(t, body, "Выполненная")
+
+	// новый токен заменяет прежний, отозванный токен не действует
+	w = doAuthRequest(t, token, http.MethodPost, "/api/calendar/token", nil)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var second CalendarTokenResp
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&second))
+	w = doRequest(t, apiHandler, http.MethodGet, resp.URL, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doAuthRequest(t, token, http.MethodDelete, "/api/calendar/token", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(t, apiHandler, http.MethodGet, second.URL, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doAuthRequest(t, token, http.MethodDelete, "/api/calendar/token", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCalendarFeedUsers(t *testing.T) {
+	setupDB(t)
+	usePassword(t, "secret", time.Hour)
+	adminToken := signIn(t, "", "secret")
+	w := doAuthRequest(t, adminToken, http.MethodPost, "/api/users", UserReq{Login: "partner", Password: "partner-pass"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	partnerToken := signIn(t, "partner", "partner-pass")
+
+	w = doAuthRequest(t, adminToken, http.MethodPost, "/api/task", map[string]any{"date": "20990101", "title": "Моя"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = doAuthRequest(t, partnerToken, http.MethodPost, "/api/task", map[string]any{"date": "20990101", "title": "Чужая"})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// лента по токену календаря показывает задачи его владельца
+	w = doAuthRequest(t, partnerToken, http.MethodPost, "/api/calendar/token", nil)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var resp CalendarTokenResp
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	w = doRequest(t, apiHandler, http.MethodGet, resp.URL, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "SUMMARY:Чужая")
+	assert.NotContains(t, w.Body.String(), "SUMMARY:Моя")
+}
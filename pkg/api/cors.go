@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Ответ на предварительный запрос CORS.
+const (
+	corsMethods = "GET, POST, PUT, PATCH, DELETE"
+	corsHeaders = "Authorization, Content-Type"
+	corsMaxAge  = "600" // секунд браузер не повторяет предварительный запрос
+)
+
+// corsExposed - заголовки ответов API, которые скрипт с другого источника
+// может прочитать кроме простых.
+const corsExposed = "Content-Disposition, Retry-After"
+
+// cors - middleware CORS для запросов к /api/ со страниц источников из
+// TODO_CORS_ORIGINS.
+//
+// Разрешенному источнику ответ возвращает его в Access-Control-Allow-Origin
+// ("*", если разрешены все), а на предварительный запрос OPTIONS отвечает
+// 204 со списком методов и заголовков, не передавая его маршрутизатору.
+// Для неразрешенного источника заголовки CORS не добавляются, и браузер
+// не отдаст ответ скрипту. Без TODO_CORS_ORIGINS next возвращается как есть.
+func cors(next http.Handler) http.Handler {
+	c := conf().CORS
+	if !c.Enabled() {
+		return next
+	}
+	anyOrigin := slices.Contains(c.Origins, "*")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		if !anyOrigin {
+			h.Add("Vary", "Origin") // ответ зависит от источника
+		}
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+		if !anyOrigin && !slices.Contains(c.Origins, strings.ToLower(origin)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if anyOrigin {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if c.Credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			h.Set("Access-Control-Expose-Headers", corsExposed)
+			next.ServeHTTP(w, r)
+			return
+		}
+		h.Set("Access-Control-Allow-Methods", corsMethods)
+		h.Set("Access-Control-Allow-Headers", corsHeaders)
+		h.Set("Access-Control-Max-Age", corsMaxAge)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
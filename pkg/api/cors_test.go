@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go1f/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useCORS задает настройки CORS до конца теста.
+func useCORS(t *testing.T, c config.CORSConfig) {
+	t.Helper()
+	useConf(t).CORS = c
+}
+
+// serveOrigin выполняет запрос к h со страницы источника origin.
+func serveOrigin(h http.Handler, method, target, origin string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	r.Header.Set("Origin", origin)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestCORSPreflight(t *testing.T) {
+	setupDB(t)
+	useCORS(t, config.CORSConfig{Origins: []string{"https://app.example.com"}, Credentials: true})
+	h := cors(routes())
+
+	w := serveOrigin(h, http.MethodOptions, "/api/task", "https://app.example.com",
+		"Access-Control-Request-Method", "PUT",
+		"Access-Control-Request-Headers", "authorization, content-type")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, corsMethods, w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Content-Type")
+	assert.Contains(t, w.Header().Values("Vary"), "Origin")
+	assert.Empty(t, w.Body.String())
+
+	// OPTIONS без Access-Control-Request-Method - не предварительный запрос
+	w = serveOrigin(h, http.MethodOptions, "/api/task", "https://app.example.com")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	setupDB(t)
+	useCORS(t, config.CORSConfig{Origins: []string{"https://app.example.com", "http://localhost:5173"}})
+	h := cors(routes())
+
+	w := serveOrigin(h, http.MethodGet, "/api/tasks", "http://localhost:5173")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "http://localhost:5173", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, corsExposed, w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Contains(t, w.Header().Values("Vary"), "Origin")
+
+	// запросы без Origin, например со своей страницы, не меняются
+	w = serveOrigin(h, http.MethodGet, "/api/tasks", "")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	setupDB(t)
+	useCORS(t, config.CORSConfig{Origins: []string{"https://app.example.com"}})
+	h := cors(routes())
+
+	for _, method := range []string{http.MethodGet, http.MethodOptions} {
+		w := serveOrigin(h, method, "/api/tasks", "https://evil.example.com",
+			"Access-Control-Request-Method", "DELETE")
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), method)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"), method)
+		assert.NotEqual(t, http.StatusNoContent, w.Code, method)
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	setupDB(t)
+	useCORS(t, config.CORSConfig{Origins: []string{"*"}})
+
+	w := serveOrigin(cors(routes()), http.MethodGet, "/api/tasks", "https://any.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Vary"))
+}
+
+func TestCORSDisabled(t *testing.T) {
+	useCORS(t, config.CORSConfig{})
+	next := http.NotFoundHandler()
+	w := serveOrigin(cors(next), http.MethodOptions, "/api/task", "https://app.example.com",
+		"Access-Control-Request-Method", "PUT")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
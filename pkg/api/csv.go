@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/taskdate"
+)
+
+// csvBOM - метка порядка байтов UTF-8 в начале CSV: по ней Excel
+// узнает кодировку и правильно показывает кириллицу.
+const csvBOM = "\ufeff"
+
+// csvColumns - колонки выгрузки задач в CSV.
+var csvColumns = []string{"id", "date", "title", "comment", "repeat"}
+
+// csvDateFormat - формат даты, в котором даты задач приходят из таблиц.
+const csvDateFormat = "02.01.2006"
+
+// csvExport - выгрузка в CSV с колонками csvColumns и строкой заголовка.
+// Выполненность, теги и прочие поля задачи в CSV не попадают.
+func csvExport(w io.Writer) exportFormat {
+	cw := csv.NewWriter(w)
+	return exportFormat{
+		ext:         "csv",
+		contentType: "text/csv; charset=UTF-8",
+		begin: func() error {
+			if _, err := io.WriteString(w, csvBOM); err != nil {
+				return err
+			}
+			return cw.Write(csvColumns)
+		},
+		encode: func(task *db.Task) error {
+			return cw.Write([]string{task.ID, task.Date, task.Title, task.Comment, task.Repeat})
+		},
+		end: func() error {
+			cw.Flush()
+			return cw.Error()
+		},
+	}
+}
+
+// parseCSV разбирает задачи из CSV в формате csvExport. Разделителем может быть
+// запятая или точка с запятой (так сохраняет Excel в русской локали), порядок
+// колонок задает строка заголовка, колонка id и неизвестные колонки игнорируются.
+// Даты вида DD.MM.YYYY переводятся в YYYYMMDD.
+// Возвращает задачи и номера строк файла, в которых они записаны.
+func parseCSV(data []byte) ([]*db.Task, []int, error) {
+	data = bytes.TrimPrefix(data, []byte(csvBOM))
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+
+	head, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("пустой файл CSV")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("неверный формат CSV: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range head {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, nil, errors.New("в CSV нет колонки title")
+	}
+
+	var (
+		tasks []*db.Task
+		lines []int
+	)
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("неверный формат CSV: %w", err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue // пустые строки в конце таблицы
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		task := &db.Task{
+			Date:    field("date"),
+			Title:   field("title"),
+			Comment: field("comment"),
+			Repeat:  field("repeat"),
+		}
+		if t, err := time.Parse(csvDateFormat, task.Date); err == nil {
+			task.Date = t.Format(taskdate.DateFormat)
+		}
+		line, _ := r.FieldPos(0)
+		tasks = append(tasks, task)
+		lines = append(lines, line)
+	}
+	return tasks, lines, nil
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportCSV(t *testing.T) {
+	setupDB(t)
+	task := db.Task{Date: "20990101", Title: "Купить молоко, хлеб", Comment: "в \"Пятерочке\"\nили рядом", Repeat: "d 7"}
+	_, err := store.AddTask(t.Context(), &task)
+	require.NoError(t, err)
+
+	w := doRequest(t, exportHandler, http.MethodGet, "/api/export?format=csv", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	assert.Regexp(t, `^attachment; filename=tasks-\d{8}\.csv$`, w.Header().Get("Content-Disposition"))
+
+	body := w.Body.Bytes()
+	require.True(t, bytes.HasPrefix(body, []byte(csvBOM)))
+	records, err := csv.NewReader(bytes.NewReader(body[len(csvBOM):])).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		csvColumns,
+		{task.ID, "20990101", "Купить молоко, хлеб", "в \"Пятерочке\"\nили рядом", "d 7"},
+	}, records)
+
+	// выгрузка загружается обратно
+	setupDB(t)
+	r := httptest.NewRequest(http.MethodPost, "/api/import?format=csv", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	importHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(1), decodeBody(t, w)["imported"])
+	tasks, err := store.GetTasksPage(t.Context(), 10, 0, db.TaskFilter{})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, task.Comment, tasks[0].Comment)
+
+	w = doRequest(t, exportHandler, http.MethodGet, "/api/export?format=xlsx", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestParseCSV(t *testing.T) {
+	// Excel в русской локали: точка с запятой, BOM, даты DD.MM.YYYY, свой порядок колонок
+	data := csvBOM + "Title;Date;Repeat\r\n" +
+		"Полить цветы;01.07.2099;d 3\r\n" +
+		";;\r\n" +
+		"\"Отчет; квартальный\";20991001;\r\n"
+	tasks, lines, err := parseCSV([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, db.Task{Date: "20990701", Title: "Полить цветы", Repeat: "d 3"}, *tasks[0])
+	assert.Equal(t, db.Task{Date: "20991001", Title: "Отчет; квартальный"}, *tasks[1])
+	assert.Equal(t, []int{2, 4}, lines)
+
+	_, _, err = parseCSV([]byte("date,comment\n20990101,без названия\n"))
+	assert.ErrorContains(t, err, "title")
+	_, _, err = parseCSV(nil)
+	assert.Error(t, err)
+	_, _, err = parseCSV([]byte("title\n\"незакрытая кавычка\n"))
+	assert.Error(t, err)
+}
+
+func TestImportCSVErrors(t *testing.T) {
+	setupDB(t)
+	data := "id,date,title,comment,repeat\n" +
+		"1,20990101,Первая,\"комментарий\nв две строки\",\n" +
+		"2,20990101,,,\n" +
+		"3,32.01.2099,Неверная дата,,\n" +
+		"4,20990102,Вторая,,\n"
+	r := httptest.NewRequest(http.MethodPost, "/api/import?format=csv&dry_run=1", strings.NewReader(data))
+	w := httptest.NewRecorder()
+	importHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	resp := decodeBody(t, w)
+	assert.Equal(t, float64(2), resp["would_import"])
+	errs := resp["errors"].([]any)
+	require.Len(t, errs, 2)
+	// номера строк учитывают перенос внутри поля в кавычках
+	assert.Equal(t, float64(4), errs[0].(map[string]any)["line"])
+	assert.Equal(t, float64(5), errs[1].(map[string]any)["line"])
+	assert.NotContains(t, errs[0], "index")
+}
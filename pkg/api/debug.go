@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+// debugPath - префикс профилировщика net/http/pprof. Профили CPU и trace
+// снимаются дольше TODO_REQUEST_TIMEOUT, поэтому на этот префикс
+// ограничение времени запроса не действует.
+const debugPath = "/debug/pprof/"
+
+// processStart - время запуска процесса для поля uptime в /api/admin/runtime.
+var processStart = time.Now()
+
+// RuntimeResp - ответ GET /api/admin/runtime.
+type RuntimeResp struct {
+	GoVersion  string      `json:"go_version"`
+	Goroutines int         `json:"goroutines"`
+	Uptime     string      `json:"uptime"`         // в формате time.Duration, например "26h3m12s"
+	UptimeSec  int64       `json:"uptime_seconds"` // то же в секундах
+	Heap       RuntimeHeap `json:"heap"`
+}
+
+// RuntimeHeap - состояние кучи и сборщика мусора из runtime.MemStats.
+type RuntimeHeap struct {
+	Alloc      uint64 `json:"alloc"`       // байт в живых объектах
+	Sys        uint64 `json:"sys"`         // байт получено от ОС под кучу
+	Idle       uint64 `json:"idle"`        // байт в свободных спанах
+	Released   uint64 `json:"released"`    // байт возвращено ОС
+	Objects    uint64 `json:"objects"`     // живых объектов
+	TotalAlloc uint64 `json:"total_alloc"` // байт выделено с запуска
+	NextGC     uint64 `json:"next_gc"`     // размер кучи, при котором начнется сборка
+	NumGC      uint32 `json:"num_gc"`      // завершенных сборок мусора
+	PauseTotal string `json:"pause_total"` // суммарная пауза сборок
+}
+
+// registerDebug регистрирует отладочные эндпоинты: профилировщик pprof
+// в root и GET /api/admin/runtime в mux. Вызывается из routes только
+// при TODO_DEBUG=1, иначе эти пути отвечают 404.
+//
+// Эндпоинты доступны только с адресов loopback (см. loopbackOnly)
+// и требуют аутентификации администратора: профили раскрывают код и данные
+// процесса. Ограничение попыток входа их не касается.
+func registerDebug(root, mux *http.ServeMux) {
+	debug := func(h http.HandlerFunc) http.Handler {
+		return loopbackOnly(auth(adminOnly(h)))
+	}
+	handle(root, "", debugPath, debug(pprof.Index))
+	handle(root, "", debugPath+"cmdline", debug(pprof.Cmdline))
+	handle(root, "", debugPath+"profile", debug(pprof.Profile))
+	handle(root, "", debugPath+"symbol", debug(pprof.Symbol))
+	handle(root, "", debugPath+"trace", debug(pprof.Trace))
+	handle(mux, http.MethodGet, "/api/admin/runtime", debug(runtimeHandler))
+}
+
+// loopbackOnly пропускает запросы, пришедшие на сервер с адреса loopback
+// (127.0.0.1, ::1), остальным отвечает 403. Адрес берется из соединения,
+// а не из TODO_CLIENT_IP_HEADER: заголовок подделывается. За прокси на том же
+// хосте все запросы приходят с loopback, и защищает только аутентификация.
+func loopbackOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			sendAPIError(w, r, CodeForbidden, "Отладочные эндпоинты доступны только с локального адреса", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// runtimeHandler обрабатывает GET-запрос /api/admin/runtime - состояние
+// процесса для диагностики без профилировщика:
+//
+//	{"go_version":"go1.24.2","goroutines":12,"uptime":"26h3m12s","uptime_seconds":93792,
+//	 "heap":{"alloc":4194304,"sys":12582912,...,"num_gc":41,"pause_total":"3.2ms"}}
+//
+// runtime.ReadMemStats ненадолго останавливает программу, поэтому
+// эндпоинт не предназначен для частого опроса: для этого есть /metrics.
+func runtimeHandler(w http.ResponseWriter, r *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	uptime := time.Since(processStart).Round(time.Second)
+
+	sendJSON(w, r, RuntimeResp{
+		GoVersion:  runtime.Version(),
+		Goroutines: runtime.NumGoroutine(),
+		Uptime:     uptime.String(),
+		UptimeSec:  int64(uptime.Seconds()),
+		Heap: RuntimeHeap{
+			Alloc:      m.HeapAlloc,
+			Sys:        m.HeapSys,
+			Idle:       m.HeapIdle,
+			Released:   m.HeapReleased,
+			Objects:    m.HeapObjects,
+			TotalAlloc: m.TotalAlloc,
+			NextGC:     m.NextGC,
+			NumGC:      m.NumGC,
+			PauseTotal: time.Duration(m.PauseTotalNs).String(),
+		},
+	}, http.StatusOK)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useDebug задает TODO_DEBUG до конца теста.
+func useDebug(t *testing.T, enabled bool) {
+	t.Helper()
+	useConf(t).Debug = enabled
+}
+
+// serveDebug выполняет GET-запрос target к h с адреса remote.
+func serveDebug(h http.Handler, target, remote string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.RemoteAddr = remote
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestDebugDisabled(t *testing.T) {
+	setupDB(t)
+	useDebug(t, false)
+	h := routes()
+	for _, target := range []string{debugPath, debugPath + "heap", debugPath + "profile?seconds=1", "/api/admin/runtime"} {
+		assert.Equal(t, http.StatusNotFound, serveDebug(h, target, "127.0.0.1:5000").Code, target)
+	}
+}
+
+func TestDebugEndpoints(t *testing.T) {
+	setupDB(t)
+	useDebug(t, true)
+	h := routes()
+
+	w := serveDebug(h, debugPath, "127.0.0.1:5000")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine")
+
+	w = serveDebug(h, debugPath+"goroutine?debug=1", "[::1]:5000")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine profile")
+
+	w = serveDebug(h, "/api/admin/runtime", "127.0.0.1:5000")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp RuntimeResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, runtime.Version(), resp.GoVersion)
+	assert.Positive(t, resp.Goroutines)
+	assert.Positive(t, resp.Heap.Alloc)
+	assert.GreaterOrEqual(t, resp.UptimeSec, int64(0))
+
+	// только с локального адреса
+	for _, target := range []string{debugPath, "/api/admin/runtime"} {
+		w = serveDebug(h, target, "192.0.2.1:5000")
+		assert.Equal(t, http.StatusForbidden, w.Code, target)
+		assertAPIError(t, w, CodeForbidden)
+	}
+}
+
+func TestDebugRequiresAuth(t *testing.T) {
+	setupDB(t)
+	useDebug(t, true)
+	usePassword(t, "secret", time.Hour)
+	h := routes()
+
+	for _, target := range []string{debugPath + "heap", "/api/admin/runtime"} {
+		assert.Equal(t, http.StatusUnauthorized, serveDebug(h, target, "127.0.0.1:5000").Code, target)
+	}
+
+	token, err := getToken(admin, time.Now())
+	require.NoError(t, err)
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/runtime", nil)
+	r.RemoteAddr = "127.0.0.1:5000"
+	r.AddCookie(&http.Cookie{Name: "token", Value: token})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestDebugNoTimeout(t *testing.T) {
+	// профиль снимается дольше TODO_REQUEST_TIMEOUT
+	setupDB(t)
+	useDebug(t, true)
+	h := requestTimeout(routes(), 50*time.Millisecond)
+	w := serveDebug(h, debugPath+"profile?seconds=1", "127.0.0.1:5000")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotZero(t, w.Body.Len())
+}
@@ -0,0 +1,124 @@
+// Package api предоставляет функционал для работы API сервиса.
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"go1f/pkg/config"
+)
+
+// ErrorResponse представляет структуру для возврата ошибок в API.
+// Code не меняется вместе с текстом ошибки, поэтому клиенты различают
+// ошибки по нему, а Error показывают пользователю. RequestID совпадает
+// с заголовком X-Request-ID и атрибутом request_id записей журнала.
+type ErrorResponse struct {
+	Error     string    `json:"error"`
+	Code      ErrorCode `json:"code"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// ErrorCode - машиночитаемый код ошибки API.
+type ErrorCode string
+
+// Коды ошибок API. HTTP-статус каждого кода задан в errorStatus.
+const (
+	CodeInvalidJSON      ErrorCode = "invalid_json"       // тело запроса не разбирается как JSON
+	CodeUnknownField     ErrorCode = "unknown_field"      // в теле запроса неизвестное поле
+	CodeTitleRequired    ErrorCode = "title_required"     // пустой заголовок задачи
+	CodeBadField         ErrorCode = "bad_field"          // поле задачи слишком длинное или вне диапазона
+	CodeBadDate          ErrorCode = "bad_date"           // неверная дата
+	CodeBadRepeat        ErrorCode = "bad_repeat"         // неверное или исчерпанное правило повторения
+	CodeBadPhrase        ErrorCode = "bad_phrase"         // фраза не переводится в правило повторения
+	CodeBadID            ErrorCode = "bad_id"             // id задачи не задан или не положительное целое число
+	CodeBadRequest       ErrorCode = "bad_request"        // остальные ошибки параметров запроса
+	CodeUnauthorized     ErrorCode = "unauthorized"       // нет или неверны пароль, токен или ключ
+	CodeForbidden        ErrorCode = "forbidden"          // операция недоступна пользователю
+	CodeNotFound         ErrorCode = "not_found"          // задача или другой объект не найден
+	CodeMethodNotAllowed ErrorCode = "method_not_allowed" // метод не поддерживается
+	CodeConflict         ErrorCode = "conflict"           // объект уже существует или занят
+	CodeBodyTooLarge     ErrorCode = "body_too_large"     // тело запроса больше ограничения
+	CodeRateLimited      ErrorCode = "rate_limited"       // слишком много запросов
+	CodeDBError          ErrorCode = "db_error"           // ошибка БД
+	CodeInternal         ErrorCode = "internal_error"     // прочие ошибки сервера
+	CodeNotImplemented   ErrorCode = "not_implemented"    // не поддерживается для этой СУБД
+	CodeUnavailable      ErrorCode = "unavailable"        // сервис на обслуживании или БД недоступна
+	CodeTimeout          ErrorCode = "timeout"            // запрос не уложился в срок
+)
+
+// errorStatus - HTTP-статус для каждого кода ошибки.
+// Таблица повторена в README, тесты проверяют по ней ответы обработчиков.
+var errorStatus = map[ErrorCode]int{
+	CodeInvalidJSON:      http.StatusBadRequest,
+	CodeUnknownField:     http.StatusBadRequest,
+	CodeTitleRequired:    http.StatusBadRequest,
+	CodeBadField:         http.StatusBadRequest,
+	CodeBadDate:          http.StatusBadRequest,
+	CodeBadRepeat:        http.StatusBadRequest,
+	CodeBadPhrase:        http.StatusUnprocessableEntity,
+	CodeBadID:            http.StatusBadRequest,
+	CodeBadRequest:       http.StatusBadRequest,
+	CodeUnauthorized:     http.StatusUnauthorized,
+	CodeForbidden:        http.StatusForbidden,
+	CodeNotFound:         http.StatusNotFound,
+	CodeMethodNotAllowed: http.StatusMethodNotAllowed,
+	CodeConflict:         http.StatusConflict,
+	CodeBodyTooLarge:     http.StatusRequestEntityTooLarge,
+	CodeRateLimited:      http.StatusTooManyRequests,
+	CodeDBError:          http.StatusInternalServerError,
+	CodeInternal:         http.StatusInternalServerError,
+	CodeNotImplemented:   http.StatusNotImplemented,
+	CodeUnavailable:      http.StatusServiceUnavailable,
+	CodeTimeout:          http.StatusServiceUnavailable,
+}
+
+// apiError - ошибка с кодом и текстом для ответа клиенту.
+// Ее возвращают проверки вроде checkTask, а отправляет sendErr.
+type apiError struct {
+	code    ErrorCode
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+// newError создает ошибку API с кодом code и текстом по format.
+func newError(code ErrorCode, format string, args ...any) error {
+	return &apiError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// errorCode возвращает код ошибки API или CodeInternal для остальных ошибок.
+func errorCode(err error) ErrorCode {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.code
+	}
+	return CodeInternal
+}
+
+// sendErr отправляет ошибку API со статусом ее кода из errorStatus.
+// Текст остальных ошибок клиенту не отправляется, а пишется в журнал.
+func sendErr(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		slog.ErrorContext(r.Context(), "Ошибка без кода API", "error", err)
+		sendAPIError(w, r, CodeInternal, "внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+	sendAPIError(w, r, apiErr.code, apiErr.message, errorStatus[apiErr.code])
+}
+
+// sendAPIError отправляет ошибку в формате JSON с указанным HTTP-статусом
+// через sendJSON, поэтому у нее тот же Content-Type и та же обработка ошибок записи.
+// Принимает:
+//   - w - ResponseWriter для записи ответа
+//   - r - запрос, его ID попадает в поле request_id
+//   - code - код ошибки, статус должен совпадать с errorStatus[code]
+//   - message - текст сообщения об ошибке
+//   - statusCode - HTTP-статус ошибки
+func sendAPIError(w http.ResponseWriter, r *http.Request, code ErrorCode, message string, statusCode int) {
+	sendJSON(w, r, ErrorResponse{Error: message, Code: code, RequestID: config.RequestID(r.Context())}, statusCode)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertAPIError проверяет, что ответ - ошибка с кодом code
+// и статусом этого кода из errorStatus.
+func assertAPIError(t *testing.T, w *httptest.ResponseRecorder, code ErrorCode) {
+	t.Helper()
+	status, ok := errorStatus[code]
+	require.True(t, ok, "у кода %s нет статуса", code)
+	assert.Equal(t, status, w.Code, w.Body.String())
+	resp := decodeBody(t, w)
+	assert.Equal(t, string(code), resp["code"])
+	assert.NotEmpty(t, resp["error"])
+}
+
+func TestErrorCodes(t *testing.T) {
+	setupDB(t)
+	future := "20990101"
+
+	for _, tc := range []struct {
+		name, method, target string
+		body                 any
+		code                 ErrorCode
+	}{
+		{"unknown_field", http.MethodPost, "/api/task", map[string]any{"titel": "x"}, CodeUnknownField},
+		{"title_required", http.MethodPost, "/api/task", map[string]any{"date": future, "title": " "}, CodeTitleRequired},
+		{"bad_field", http.MethodPost, "/api/task", map[string]any{"title": "x", "priority": 9}, CodeBadField},
+		{"bad_date", http.MethodPost, "/api/task", map[string]any{"title": "x", "date": "28.01.2024"}, CodeBadDate},
+		{"bad_exclude", http.MethodPost, "/api/task", map[string]any{"title": "x", "repeat": "d 1", "exclude": []string{"2099"}}, CodeBadDate},
+		{"bad_repeat", http.MethodPost, "/api/task", map[string]any{"title": "x", "repeat": "d 500"}, CodeBadRepeat},
+		{"bad_id", http.MethodGet, "/api/task?id=abc", nil, CodeBadID},
+		{"no_id", http.MethodDelete, "/api/task", nil, CodeBadID},
+		{"not_found", http.MethodGet, "/api/task?id=100500", nil, CodeNotFound},
+		{"bad_request", http.MethodGet, "/api/tasks?sort=color", nil, CodeBadRequest},
+		{"method_not_allowed", http.MethodPost, "/api/nextdate", nil, CodeMethodNotAllowed},
+		{"nextdate_bad_date", http.MethodGet, "/api/nextdate?now=20240126&date=2024&repeat=d+1", nil, CodeBadDate},
+		{"nextdate_bad_repeat", http.MethodGet, "/api/nextdate?now=20240126&date=20240101&repeat=d+500", nil, CodeBadRepeat},
+		{"nextdates_bad_now", http.MethodGet, "/api/nextdates?now=x&date=20240101&repeat=d+1", nil, CodeBadDate},
+		{"forecast_bad_date", http.MethodGet, "/api/tasks/forecast?from=x&to=20240101", nil, CodeBadDate},
+		{"bad_phrase", http.MethodPost, "/api/repeat/parse", map[string]any{"text": "иногда"}, CodeBadPhrase},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := doRequest(t, apiHandler, tc.method, tc.target, tc.body)
+			assertAPIError(t, w, tc.code)
+		})
+	}
+
+	t.Run("invalid_json", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		apiHandler(w, httptest.NewRequest(http.MethodPost, "/api/task", strings.NewReader("{")))
+		assertAPIError(t, w, CodeInvalidJSON)
+	})
+
+	t.Run("body_too_large", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPost, "/api/task",
+			map[string]any{"title": "x", "comment": strings.Repeat("a", 100<<10)})
+		assertAPIError(t, w, CodeBodyTooLarge)
+	})
+
+	t.Run("db_error", func(t *testing.T) {
+		useStore(t, &fakeStore{err: errors.New("database is locked")})
+		w := doRequest(t, apiHandler, http.MethodGet, "/api/task?id=1", nil)
+		assertAPIError(t, w, CodeDBError)
+		assert.NotContains(t, w.Body.String(), "locked")
+	})
+}
+
+func TestCheckTaskErrorCodes(t *testing.T) {
+	for _, tc := range []struct {
+		task db.Task
+		code ErrorCode
+	}{
+		{db.Task{Title: ""}, CodeTitleRequired},
+		{db.Task{Title: "x", Comment: strings.Repeat("a", maxCommentLen+1)}, CodeBadField},
+		{db.Task{Title: "x", Exclude: make([]string, maxExclude+1)}, CodeBadField},
+		{db.Task{Title: "x", Date: "2099-01-01"}, ""},
+		{db.Task{Title: "x", Date: "2024-13-01"}, CodeBadDate},
+		{db.Task{Title: "x", Repeat: "q"}, CodeBadRepeat},
+	} {
+		err := checkTask(&tc.task)
+		if tc.code == "" {
+			assert.NoError(t, err)
+			continue
+		}
+		assert.Equal(t, tc.code, errorCode(err), tc.task)
+	}
+}
+
+func TestSendErr(t *testing.T) {
+	// текст ошибки без кода не уходит клиенту
+	r := httptest.NewRequest(http.MethodGet, "/api/task?id=5", nil)
+	w := httptest.NewRecorder()
+	sendErr(w, r, errors.New("секрет"))
+	assertAPIError(t, w, CodeInternal)
+	assert.NotContains(t, w.Body.String(), "секрет")
+
+	w = httptest.NewRecorder()
+	sendErr(w, r, newError(CodeNotFound, "задача с id =%v не найдена", 5))
+	assertAPIError(t, w, CodeNotFound)
+	assert.Equal(t, "задача с id =5 не найдена", decodeBody(t, w)["error"])
+}
+
+func TestSendAPIErrorHeaders(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/calendar.ics", nil)
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", "text/calendar; charset=UTF-8")
+	sendAPIError(w, r, CodeNotFound, "не найдено", http.StatusNotFound)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error":"не найдено","code":"not_found"}`, w.Body.String())
+}
+
+func TestSendJSONEncodeError(t *testing.T) {
+	// ошибка сериализации заменяет ответ, а не дописывается после него
+	r := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+	w := httptest.NewRecorder()
+	sendJSON(w, r, map[string]any{"ch": make(chan int)}, http.StatusOK)
+
+	assertAPIError(t, w, CodeInternal)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	dec := json.NewDecoder(w.Body)
+	var resp ErrorResponse
+	require.NoError(t, dec.Decode(&resp))
+	assert.False(t, dec.More())
+}
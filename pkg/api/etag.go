@@ -0,0 +1,59 @@
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+)
+
+// taskETag возвращает слабый ETag задачи id с версией version: версия
+// растет при каждом изменении задачи (см. db.Task.Version).
+func taskETag(id string, version int64) string {
+	return fmt.Sprintf(`W/"%s-%d"`, id, version)
+}
+
+// listETag возвращает слабый ETag списка задач из номера последнего изменения
+// seq (см. db.Store.ChangeSeq) и количества задач count. Номер изменения общий
+// для всех пользователей, поэтому чужое изменение тоже меняет ETag - клиент
+// лишний раз получит тот же список, но не пропустит изменение.
+//
+// Список зависит и от того, что не видно в URL: пользователя, сегодняшней
+// даты для filter=today и filter=overdue, лимита TODO_LIMIT_TASKS. Они
+// учитываются хешем variant, чтобы после полуночи или смены пользователя
+// ответ не считался прежним.
+func listETag(seq int64, count int, variant ...any) string {
+	h := fnv.New32a()
+	fmt.Fprintln(h, variant...)
+	return fmt.Sprintf(`W/"%d-%d-%08x"`, seq, count, h.Sum32())
+}
+
+// notModified устанавливает заголовок ETag ответа и, если клиент уже получил
+// ответ с этим ETag (заголовок If-None-Match), отвечает 304 без тела.
+// Возвращает true, если ответ отправлен и обработчику больше нечего делать.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	if !etagMatch(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatch сообщает, есть ли etag в значении заголовка If-None-Match header.
+// ETag сравниваются без учета признака W/ (слабое сравнение, RFC 9110).
+func etagMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
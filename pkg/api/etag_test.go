@@ -0,0 +1,114 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// conditionalGet выполняет GET-запрос target к h с заголовком If-None-Match etag.
+func conditionalGet(h http.HandlerFunc, target, etag string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if etag != "" {
+		r.Header.Set("If-None-Match", etag)
+	}
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func TestTasksETag(t *testing.T) {
+	setupDB(t)
+	useConf(t).LimitTask = 50
+	id, err := store.AddTask(t.Context(), &db.Task{Date: "20240101", Title: "Опрос списка"})
+	require.NoError(t, err)
+
+	w := conditionalGet(tasksHandler, "/api/tasks", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.Regexp(t, `^W/".+"$`, etag)
+
+	w = conditionalGet(tasksHandler, "/api/tasks", etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Zero(t, w.Body.Len())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	task, err := store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	task.Title = "Опрос списка (изменена)"
+	require.NoError(t, store.PutTaskID(t.Context(), &task))
+
+	w = conditionalGet(tasksHandler, "/api/tasks", etag)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, "Опрос списка (изменена)", decodeBody(t, w)["tasks"].([]any)[0].(map[string]any)["title"])
+
+	// другой лимит - другой список при том же URL
+	etag = w.Header().Get("ETag")
+	useConf(t).LimitTask = 10
+	assert.Equal(t, http.StatusOK, conditionalGet(tasksHandler, "/api/tasks", etag).Code)
+}
+
+func TestTasksETagToday(t *testing.T) {
+	setupDB(t)
+	useConf(t).LimitTask = 50
+	now := time.Date(2025, 3, 9, 12, 0, 0, 0, time.UTC)
+	useClock(t, &now)
+	_, err := store.AddTask(t.Context(), &db.Task{Date: "20250309", Title: "Сегодня"})
+	require.NoError(t, err)
+
+	w := conditionalGet(tasksHandler, "/api/tasks?filter=overdue", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, http.StatusNotModified, conditionalGet(tasksHandler, "/api/tasks?filter=overdue", etag).Code)
+
+	// после полуночи задача просрочена, хотя задачи не менялись
+	now = now.Add(24 * time.Hour)
+	w = conditionalGet(tasksHandler, "/api/tasks?filter=overdue", etag)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeBody(t, w)["tasks"], 1)
+}
+
+func TestTaskETag(t *testing.T) {
+	setupDB(t)
+	id, err := store.AddTask(t.Context(), &db.Task{Date: "20240101", Title: "Задача"})
+	require.NoError(t, err)
+	target := "/api/task?id=" + strconv.FormatInt(id, 10)
+
+	w := conditionalGet(handleGetTask, target, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, taskETag(strconv.FormatInt(id, 10), 1), etag)
+
+	w = conditionalGet(handleGetTask, target, `"другой", `+etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Zero(t, w.Body.Len())
+
+	require.NoError(t, store.SetCompleted(t.Context(), id, true))
+	w = conditionalGet(handleGetTask, target, etag)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, taskETag(strconv.FormatInt(id, 10), 2), w.Header().Get("ETag"))
+}
+
+func TestETagMatch(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{`W/"1-2"`, true},
+		{`"1-2"`, true}, // слабое сравнение
+		{`"0-1", W/"1-2"`, true},
+		{"*", true},
+		{`W/"1-3"`, false},
+	} {
+		assert.Equal(t, tc.want, etagMatch(tc.header, `W/"1-2"`), tc.header)
+	}
+}
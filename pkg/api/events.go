@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go1f/pkg/db"
+)
+
+// Параметры потока событий /api/events.
+const (
+	eventsPath  = "/api/events" // поток не ограничивается TODO_REQUEST_TIMEOUT
+	eventBuffer = 16            // событий в очереди одного клиента
+)
+
+// eventKeepAlive - период комментариев, которые не дают прокси закрыть
+// молчащее соединение. В тестах уменьшается.
+var eventKeepAlive = 15 * time.Second
+
+// TaskEvent - событие задачи в потоке /api/events.
+// Для удаленной задачи Task не передается.
+type TaskEvent struct {
+	Type   string   `json:"type"` // одно из Event*
+	TaskID string   `json:"task_id"`
+	Task   *db.Task `json:"task,omitempty"`
+}
+
+// eventClient - подключенный к потоку клиент.
+type eventClient struct {
+	userID int64          // клиент получает только события своих задач
+	ch     chan TaskEvent // закрывается, когда брокер отключает клиента
+}
+
+// eventBroker раздает события задач подключенным клиентам.
+//
+// У каждого клиента своя ограниченная очередь: публикация никогда не ждет,
+// а клиент, который не успевает читать события, отключается и при
+// переподключении перечитывает список задач.
+type eventBroker struct {
+	mu      sync.Mutex
+	clients map[*eventClient]struct{}
+	closed  bool
+}
+
+// events - брокер событий сервера, nil - поток событий недоступен.
+var events *eventBroker
+
+// newEventBroker создает брокер без клиентов.
+func newEventBroker() *eventBroker {
+	return &eventBroker{clients: make(map[*eventClient]struct{})}
+}
+
+// StartEvents запускает брокер событий для GET /api/events.
+// При отмене ctx брокер закрывается и отключает клиентов, чтобы открытые
+// потоки не мешали плавной остановке сервера.
+func StartEvents(ctx context.Context) {
+	b := newEventBroker()
+	events = b
+	context.AfterFunc(ctx, b.close)
+}
+
+// subscribe подключает клиента пользователя userID.
+// Возвращает nil, если брокер закрыт.
+func (b *eventBroker) subscribe(userID int64) *eventClient {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	c := &eventClient{userID: userID, ch: make(chan TaskEvent, eventBuffer)}
+	b.clients[c] = struct{}{}
+	return c
+}
+
+// unsubscribe отключает клиента, если брокер еще не отключил его сам.
+func (b *eventBroker) unsubscribe(c *eventClient) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop(c)
+}
+
+// drop удаляет клиента и закрывает его очередь. Вызывается под b.mu.
+func (b *eventBroker) drop(c *eventClient) {
+	if _, ok := b.clients[c]; ok {
+		delete(b.clients, c)
+		close(c.ch)
+	}
+}
+
+// publish отправляет событие клиентам пользователя userID без ожидания.
+func (b *eventBroker) publish(userID int64, ev TaskEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		if c.userID != userID {
+			continue
+		}
+		select {
+		case c.ch <- ev:
+		default:
+			slog.Warn("Клиент потока событий не успевает читать события и отключен", "user", userID)
+			b.drop(c)
+		}
+	}
+}
+
+// close отключает всех клиентов; новые подключения не принимаются.
+func (b *eventBroker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for c := range b.clients {
+		b.drop(c)
+	}
+}
+
+// notify сообщает о событии event задачи id пользователя из ctx вебхукам
+// (см. webhookDispatcher) и клиентам потока /api/events.
+// task - задача после изменения или nil, если обработчик её не читал: тогда
+// задача читается здесь же, чтобы событие несло её состояние сразу после
+// изменения. Вызывается после успешной записи в БД и никогда не влияет
+// на ответ клиенту.
+func notify(ctx context.Context, event string, id int64, task *db.Task) {
+	d, b := webhooks, events
+	if d == nil && b == nil {
+		return
+	}
+	if task == nil && event != EventDeleted {
+		if found, err := store.GetTaskID(ctx, id); err == nil {
+			task = &found
+		}
+	}
+	if task != nil {
+		copied := *task // обработчик может изменить задачу после вызова
+		task = &copied
+	}
+
+	if d != nil {
+		d.enqueue(webhookEvent{userID: db.UserID(ctx), event: event, taskID: id, task: task, at: clock()})
+	}
+	if b != nil {
+		b.publish(db.UserID(ctx), TaskEvent{Type: event, TaskID: strconv.FormatInt(id, 10), Task: task})
+	}
+}
+
+// eventsHandler обрабатывает GET-запрос /api/events - поток Server-Sent Events
+// с изменениями задач пользователя:
+//
+//	event: done
+//	data: {"type":"done","task_id":"1","task":{"id":"1",...}}
+//
+// Типы событий те же, что у вебхуков: created, updated, deleted, done.
+// Пока событий нет, раз в 15 секунд приходит комментарий ": keep-alive".
+// Поток закрывается, если клиент не успевает читать события, и при остановке
+// сервера; клиенту нужно переподключиться и заново прочитать список задач.
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	b := events
+	var c *eventClient
+	if b != nil {
+		c = b.subscribe(db.UserID(r.Context()))
+	}
+	if c == nil {
+		sendAPIError(w, r, CodeUnavailable, "Поток событий недоступен", http.StatusServiceUnavailable)
+		return
+	}
+	defer b.unsubscribe(c)
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx не буферизует поток
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	if err := rc.Flush(); err != nil {
+		slog.Error("Поток событий не поддерживается соединением", "err", err)
+		return
+	}
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case ev, ok := <-c.ch:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				slog.Error("Ошибка при сериализации события", "err", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
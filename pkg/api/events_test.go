@@ -0,0 +1,164 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useEvents включает брокер событий до конца теста.
+func useEvents(t *testing.T) *eventBroker {
+	t.Helper()
+	b := newEventBroker()
+	events = b
+	t.Cleanup(func() {
+		b.close()
+		events = nil
+	})
+	return b
+}
+
+// sseFrame - одно событие потока.
+type sseFrame struct {
+	event string
+	data  string
+}
+
+// connectEvents подключается к потоку событий srv и возвращает канал
+// событий, который закрывается вместе с потоком. Возвращается после
+// первого комментария, когда клиент уже подписан.
+func connectEvents(t *testing.T, srv *httptest.Server) <-chan sseFrame {
+	t.Helper()
+	resp, err := http.Get(srv.URL + eventsPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(resp.Body)
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, ": connected\n", line)
+
+	frames := make(chan sseFrame, 16)
+	go func() {
+		defer close(frames)
+		var frame sseFrame
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				if frame.event != "" {
+					frames <- frame
+				}
+				frame = sseFrame{}
+			case strings.HasPrefix(line, "event: "):
+				frame.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				frame.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return frames
+}
+
+// nextFrame ждет очередное событие потока.
+func nextFrame(t *testing.T, frames <-chan sseFrame) TaskEvent {
+	t.Helper()
+	select {
+	case frame, ok := <-frames:
+		require.True(t, ok, "поток закрыт")
+		var ev TaskEvent
+		require.NoError(t, json.Unmarshal([]byte(frame.data), &ev))
+		assert.Equal(t, frame.event, ev.Type)
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("событие не получено")
+		return TaskEvent{}
+	}
+}
+
+func TestEventsStream(t *testing.T) {
+	setupDB(t)
+	b := useEvents(t)
+	prev := eventKeepAlive
+	eventKeepAlive = 10 * time.Millisecond
+	t.Cleanup(func() { eventKeepAlive = prev })
+	// поток живет дольше TODO_REQUEST_TIMEOUT
+	srv := httptest.NewServer(requestTimeout(routes(), 50*time.Millisecond))
+	t.Cleanup(srv.Close) // после закрытия потоков клиентов
+
+	first, second := connectEvents(t, srv), connectEvents(t, srv)
+	time.Sleep(100 * time.Millisecond)
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task", db.Task{Date: "20990101", Title: "Живое обновление"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	id := strconv.FormatInt(mustID(t, decodeBody(t, w)["id"]), 10)
+
+	for _, frames := range []<-chan sseFrame{first, second} {
+		ev := nextFrame(t, frames)
+		assert.Equal(t, EventCreated, ev.Type)
+		assert.Equal(t, id, ev.TaskID)
+		require.NotNil(t, ev.Task)
+		assert.Equal(t, "Живое обновление", ev.Task.Title)
+	}
+
+	w = doRequest(t, apiHandler, http.MethodDelete, "/api/task?id="+id, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	for _, frames := range []<-chan sseFrame{first, second} {
+		ev := nextFrame(t, frames)
+		assert.Equal(t, TaskEvent{Type: EventDeleted, TaskID: id}, ev)
+	}
+
+	// при закрытии брокера потоки завершаются
+	b.close()
+	for _, frames := range []<-chan sseFrame{first, second} {
+		select {
+		case _, ok := <-frames:
+			assert.False(t, ok)
+		case <-time.After(5 * time.Second):
+			t.Fatal("поток не закрыт")
+		}
+	}
+	resp, err := http.Get(srv.URL + eventsPath)
+	require.NoError(t, err)
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestEventBrokerDropsSlowClient(t *testing.T) {
+	b := newEventBroker()
+	slow, other := b.subscribe(1), b.subscribe(2)
+	for i := range eventBuffer + 1 {
+		b.publish(1, TaskEvent{Type: EventUpdated, TaskID: strconv.Itoa(i)})
+	}
+	// очередь медленного клиента вычитывается до конца и закрыта
+	received := 0
+	for range slow.ch {
+		received++
+	}
+	assert.Equal(t, eventBuffer, received)
+	// события чужих задач другой клиент не получает
+	assert.Empty(t, other.ch)
+	b.unsubscribe(slow) // повторное отключение безопасно
+	b.close()
+	_, ok := <-other.ch
+	assert.False(t, ok)
+	assert.Nil(t, b.subscribe(1))
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/taskdate"
+)
+
+// exportVersion - версия формата выгрузки задач (ExportFile.Version).
+// Увеличивается при несовместимых изменениях формата.
+const exportVersion = 1
+
+// exportFormat - формат файла выгрузки: как записать его начало,
+// очередную задачу и конец.
+type exportFormat struct {
+	ext         string // расширение имени файла
+	contentType string
+	begin       func() error
+	encode      func(task *db.Task) error
+	end         func() error
+}
+
+// jsonExport - выгрузка в формате ExportFile.
+func jsonExport(w io.Writer, now time.Time) exportFormat {
+	enc := json.NewEncoder(w)
+	sep := ""
+	return exportFormat{
+		ext:         "json",
+		contentType: "application/json; charset=UTF-8",
+		begin: func() error {
+			_, err := fmt.Fprintf(w, `{"version":%d,"exported_at":%q,"tasks":[`, exportVersion, now.Format(time.RFC3339))
+			return err
+		},
+		encode: func(task *db.Task) error {
+			if _, err := io.WriteString(w, sep); err != nil {
+				return err
+			}
+			sep = ","
+			return enc.Encode(task)
+		},
+		end: func() error {
+			_, err := io.WriteString(w, "]}\n")
+			return err
+		},
+	}
+}
+
+// exportHandler обрабатывает GET-запрос /api/export.
+//
+// Отдает все задачи пользователя, включая выполненные, файлом для скачивания
+// tasks-YYYYMMDD.json в формате ExportFile:
+//
+//	{"version":1,"exported_at":"2025-07-01T10:00:00+03:00","tasks":[{...}]}
+//
+// С параметром format=csv отдает файл tasks-YYYYMMDD.csv (см. csvExport).
+//
+// Задачи пишутся в ответ по мере чтения из БД, поэтому память не растет
+// с количеством задач. Если чтение прервалось на середине, соединение
+// разрывается, чтобы клиент не принял обрезанный файл за целый.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	now := localNow()
+	var format exportFormat
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		format = jsonExport(w, now)
+	case "csv":
+		format = csvExport(w)
+	default:
+		sendAPIError(w, r, CodeBadRequest, "формат выгрузки должен быть json или csv", http.StatusBadRequest)
+		return
+	}
+	started := false
+
+	// start отправляет заголовки и начало файла перед первой задачей:
+	// до этого об ошибке чтения еще можно ответить кодом 500.
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", format.contentType)
+		w.Header().Set("Content-Disposition",
+			"attachment; filename=tasks-"+now.Format(taskdate.DateFormat)+"."+format.ext)
+		return format.begin()
+	}
+
+	err := store.StreamTasks(r.Context(), func(task *db.Task) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return format.encode(task)
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка выгрузки задач", "err", err)
+		if !started {
+			sendAPIError(w, r, CodeDBError, "ошибка выгрузки задач", http.StatusInternalServerError)
+			return
+		}
+		panic(http.ErrAbortHandler)
+	}
+	if err := format.end(); err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка выгрузки задач", "err", err)
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportHandler(t *testing.T) {
+	setupDB(t)
+	now := time.Date(2025, 7, 1, 23, 30, 0, 0, time.UTC)
+	useClock(t, &now)
+	useConf(t).Location = time.FixedZone("MSK", 3*60*60)
+
+	var ids []string
+	for _, task := range []db.Task{
+		{Date: "20250701", Title: "Первая", Comment: "с комментарием", Tags: []string{"дом"}},
+		{Date: "20250702", Title: "Выполненная"},
+		{Date: "20250703", Title: "Удаленная"},
+	} {
+		_, err := store.AddTask(t.Context(), &task)
+		require.NoError(t, err)
+		ids = append(ids, task.ID)
+	}
+	require.NoError(t, store.SetCompleted(t.Context(), mustID(t, ids[1]), true))
+	require.NoError(t, store.DeleteTaskID(t.Context(), mustID(t, ids[2])))
+
+	w := doRequest(t, exportHandler, http.MethodGet, "/api/export", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	// дата в имени файла - по часовому поясу сервера
+	assert.Equal(t, "attachment; filename=tasks-20250702.json", w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+	var file ExportFile
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &file))
+	assert.Equal(t, exportVersion, file.Version)
+	assert.True(t, now.Equal(file.ExportedAt))
+	require.Len(t, file.Tasks, 2)
+	assert.Equal(t, "Первая", file.Tasks[0].Title)
+	assert.Equal(t, []string{"дом"}, file.Tasks[0].Tags)
+	assert.True(t, file.Tasks[1].Completed)
+
+	// выгрузка загружается в пустую БД
+	setupDB(t)
+	n, err := ImportTasks(t.Context(), store, bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	tasks, err := store.GetTasksPage(t.Context(), 10, 0, db.TaskFilter{})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1) // выполненные задачи список не показывает
+	assert.Equal(t, "с комментарием", tasks[0].Comment)
+}
+
+func TestExportEmpty(t *testing.T) {
+	setupDB(t)
+	w := doRequest(t, exportHandler, http.MethodGet, "/api/export", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp := decodeBody(t, w)
+	assert.Equal(t, []any{}, resp["tasks"])
+}
+
+func TestImportNewerVersion(t *testing.T) {
+	setupDB(t)
+	_, err := ImportTasks(t.Context(), store, strings.NewReader(`{"version":99,"tasks":[]}`))
+	assert.ErrorContains(t, err, "версия")
+}
+
+// streamStore отдает задачи из tasks, а затем возвращает err.
+type streamStore struct {
+	TaskStore
+	tasks []*db.Task
+	err   error
+}
+
+func (s streamStore) StreamTasks(ctx context.Context, fn func(*db.Task) error) error {
+	for _, task := range s.tasks {
+		if err := fn(task); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func TestExportStreamError(t *testing.T) {
+	broken := errors.New("диск отвалился")
+
+	// до первой задачи еще можно ответить ошибкой
+	useStore(t, streamStore{err: broken})
+	w := doRequest(t, exportHandler, http.MethodGet, "/api/export", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+
+	// после начала выгрузки соединение разрывается
+	useStore(t, streamStore{tasks: []*db.Task{{Title: "Первая"}}, err: broken})
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		doRequest(t, exportHandler, http.MethodGet, "/api/export", nil)
+	})
+}
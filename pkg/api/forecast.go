@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/taskdate"
+)
+
+// Ограничения прогноза выполнения задач.
+const (
+	maxForecastDays   = 93    // максимальная длина интервала прогноза в днях
+	forecastIterLimit = 20000 // общий бюджет вычисленных дат на один запрос
+)
+
+// Occurrence описывает одно выполнение задачи в прогнозе.
+type Occurrence struct {
+	Date   string `json:"date"`
+	TaskID int64  `json:"task_id"`
+	Title  string `json:"title"`
+}
+
+// forecastHandler обрабатывает GET-запрос /api/tasks/forecast.
+//
+// Параметры запроса:
+//   - from, to: границы интервала в формате YYYYMMDD, не более 93 дней
+//
+// Возвращает все выполнения повторяющихся задач и разовые задачи внутри интервала:
+//
+//	[{"date":"20250702","task_id":5,"title":"..."},...]
+//
+// Список отсортирован по дате, затем по id задачи.
+// Если повторений слишком много, возвращает 400 с предложением сократить интервал.
+func forecastHandler(w http.ResponseWriter, r *http.Request) {
+
+	from, to, err := parseInterval(r, maxForecastDays)
+	if err != nil {
+		sendErr(w, r, err)
+		return
+	}
+
+	tasks, err := store.GetScheduledTasks(r.Context(), from.Format(taskdate.DateFormat), to.Format(taskdate.DateFormat))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении задач для прогноза", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения задач", http.StatusInternalServerError)
+		return
+	}
+
+	occurrences, err := expandTasks(tasks, from, to, forecastIterLimit)
+	if errors.Is(err, taskdate.ErrBudgetExceeded) {
+		sendAPIError(w, r, CodeBadRequest, "Слишком много повторений, сократите интервал", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при расчете прогноза", "err", err)
+		sendAPIError(w, r, CodeInternal, "ошибка расчета прогноза", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, r, occurrences, http.StatusOK)
+}
+
+// parseInterval разбирает параметры from и to в формате YYYYMMDD.
+// Проверяет, что to не раньше from и интервал не длиннее maxDays дней.
+func parseInterval(r *http.Request, maxDays int) (time.Time, time.Time, error) {
+	from, err := parseDate(r.URL.Query().Get("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, newError(CodeBadDate, "параметр from указан неверно")
+	}
+	to, err := parseDate(r.URL.Query().Get("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, newError(CodeBadDate, "параметр to указан неверно")
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, newError(CodeBadRequest, "параметр to не может быть раньше from")
+	}
+	if to.Sub(from) > time.Duration(maxDays)*24*time.Hour {
+		return time.Time{}, time.Time{}, newError(CodeBadRequest, "интервал не может быть длиннее %d дней", maxDays)
+	}
+	return from, to, nil
+}
+
+// expandTasks разворачивает задачи в список выполнений внутри интервала [from, to].
+// budget ограничивает общее количество вычисленных дат для всех задач.
+// Задачи с некорректным правилом повторения пропускаются.
+// Результат отсортирован по дате, затем по id задачи.
+func expandTasks(tasks []*db.Task, from, to time.Time, budget int) ([]Occurrence, error) {
+	occurrences := []Occurrence{}
+	for _, task := range tasks {
+		dates, err := taskdate.Occurrences(task.Date, task.Repeat, from, to, &budget)
+		if errors.Is(err, taskdate.ErrBudgetExceeded) {
+			return nil, err
+		}
+		if err != nil {
+			slog.Warn("Пропущена задача с неверным правилом повторения", "id", task.ID, "repeat", task.Repeat)
+			continue
+		}
+
+		id, err := strconv.ParseInt(task.ID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		for _, date := range dates {
+			occurrences = append(occurrences, Occurrence{Date: date, TaskID: id, Title: task.Title})
+		}
+	}
+
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		if occurrences[i].Date != occurrences[j].Date {
+			return occurrences[i].Date < occurrences[j].Date
+		}
+		return occurrences[i].TaskID < occurrences[j].TaskID
+	})
+	return occurrences, nil
+}
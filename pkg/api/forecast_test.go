@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForecast(t *testing.T) {
+	setupDB(t)
+	fixtures := []db.Task{
+		{Date: "20250131", Title: "Оплатить аренду", Repeat: "m -1"},
+		{Date: "20250206", Title: "Планерка", Repeat: "w 4"},
+		{Date: "20250215", Title: "Разовая в окне"},
+		{Date: "20250401", Title: "Разовая вне окна"},
+		{Date: "20250101", Title: "Повтор после окна", Repeat: "y"},
+	}
+	for i := range fixtures {
+		_, err := store.AddTask(t.Context(), &fixtures[i])
+		require.NoError(t, err)
+	}
+
+	w := doRequest(t, forecastHandler, http.MethodGet, "/api/tasks/forecast?from=20250125&to=20250305", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var got []Occurrence
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	want := []Occurrence{
+		{Date: "20250131", TaskID: 1, Title: "Оплатить аренду"},
+		{Date: "20250206", TaskID: 2, Title: "Планерка"},
+		{Date: "20250213", TaskID: 2, Title: "Планерка"},
+		{Date: "20250215", TaskID: 3, Title: "Разовая в окне"},
+		{Date: "20250220", TaskID: 2, Title: "Планерка"},
+		{Date: "20250227", TaskID: 2, Title: "Планерка"},
+		{Date: "20250228", TaskID: 1, Title: "Оплатить аренду"},
+	}
+	assert.Equal(t, want, got)
+
+	for _, target := range []string{
+		"/api/tasks/forecast?from=20250125",
+		"/api/tasks/forecast?from=20250305&to=20250125",
+		"/api/tasks/forecast?from=20250101&to=20250601",
+	} {
+		w := doRequest(t, forecastHandler, http.MethodGet, target, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
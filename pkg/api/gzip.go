@@ -0,0 +1,186 @@
+package api
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gzipMinSize - ответы короче не сжимаются: заголовок gzip и время на сжатие
+// не окупаются.
+const gzipMinSize = 1024
+
+// gzipWriters переиспользует компрессоры между запросами.
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// compress - middleware сжатия ответов gzip для клиентов, приславших
+// Accept-Encoding: gzip.
+//
+// Ответ копится в буфере, пока не наберется gzipMinSize байт: короткие
+// ответы отправляются как есть. Не сжимаются ответы, уже сжатые обработчиком
+// (есть Content-Encoding), частичные (206), уже сжатые форматы (изображения,
+// архивы) и поток /api/events, который должен уходить клиенту сразу.
+// Для остальных клиентов тело ответа не меняется, добавляется только Vary.
+func compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == eventsPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipWriter{ResponseWriter: w, status: http.StatusOK}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip сообщает, принимает ли клиент ответ в gzip.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		weight, err := strconv.ParseFloat(q, 64)
+		return err == nil && weight > 0
+	}
+	return false
+}
+
+// gzipWriter откладывает код ответа и начало тела, пока не станет ясно,
+// сжимать ли ответ, а затем пишет тело через gzip или как есть.
+type gzipWriter struct {
+	http.ResponseWriter
+	status  int          // отложенный код ответа
+	buf     []byte       // начало тела до решения о сжатии
+	decided bool         // код и заголовки отправлены
+	gz      *gzip.Writer // nil - ответ не сжимается
+}
+
+// WriteHeader запоминает код ответа до решения о сжатии.
+func (gw *gzipWriter) WriteHeader(code int) {
+	if gw.decided {
+		gw.ResponseWriter.WriteHeader(code) // повторный вызов обработает net/http
+		return
+	}
+	gw.status = code
+}
+
+// Write копит начало тела и решает, сжимать ли ответ, когда его набралось
+// gzipMinSize байт.
+func (gw *gzipWriter) Write(b []byte) (int, error) {
+	if !gw.decided {
+		gw.buf = append(gw.buf, b...)
+		if len(gw.buf) < gzipMinSize {
+			return len(b), nil
+		}
+		if err := gw.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if gw.gz != nil {
+		return gw.gz.Write(b)
+	}
+	return gw.ResponseWriter.Write(b)
+}
+
+// Flush отправляет уже записанную часть ответа клиенту.
+func (gw *gzipWriter) Flush() {
+	if !gw.decided {
+		if err := gw.decide(len(gw.buf) > 0); err != nil {
+			return
+		}
+	}
+	if gw.gz != nil {
+		gw.gz.Flush()
+	}
+	http.NewResponseController(gw.ResponseWriter).Flush()
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (gw *gzipWriter) Unwrap() http.ResponseWriter { return gw.ResponseWriter }
+
+// decide отправляет код и заголовки ответа, сжатого, если allow и ответ
+// подходит для сжатия, и записывает накопленное начало тела.
+func (gw *gzipWriter) decide(allow bool) error {
+	gw.decided = true
+	h := gw.Header()
+	if allow && gw.compressible() {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		gw.gz = gzipWriters.Get().(*gzip.Writer)
+		gw.gz.Reset(gw.ResponseWriter)
+	}
+	gw.ResponseWriter.WriteHeader(gw.status)
+
+	buf := gw.buf
+	gw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if gw.gz != nil {
+		_, err = gw.gz.Write(buf)
+	} else {
+		_, err = gw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// compressible сообщает, стоит ли сжимать ответ с такими кодом и заголовками.
+func (gw *gzipWriter) compressible() bool {
+	h := gw.Header()
+	if gw.status < http.StatusOK || gw.status == http.StatusNoContent ||
+		gw.status == http.StatusPartialContent || gw.status == http.StatusNotModified {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(gw.buf)
+		h.Set("Content-Type", contentType) // как сделал бы net/http для несжатого ответа
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	switch {
+	case mediaType == "image/svg+xml":
+		return true
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "font/woff"):
+		return false
+	}
+	switch mediaType {
+	case "application/zip", "application/gzip", "application/x-gzip", "application/octet-stream", "application/pdf":
+		return false
+	}
+	return true
+}
+
+// close завершает ответ: отправляет короткий ответ как есть или дописывает
+// окончание потока gzip.
+func (gw *gzipWriter) close() {
+	if !gw.decided {
+		gw.decide(false)
+	}
+	if gw.gz != nil {
+		gw.gz.Close()
+		gw.gz.Reset(nil)
+		gzipWriters.Put(gw.gz)
+		gw.gz = nil
+	}
+}
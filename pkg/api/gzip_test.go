@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveGzip выполняет GET-запрос target к h с заголовком Accept-Encoding,
+// если он не пустой.
+func serveGzip(h http.Handler, target, acceptEncoding string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if acceptEncoding != "" {
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// gunzip распаковывает тело ответа.
+func gunzip(t *testing.T, body []byte) []byte {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return data
+}
+
+func TestCompressTasks(t *testing.T) {
+	setupDB(t)
+	for i := range 50 {
+		_, err := store.AddTask(t.Context(), &db.Task{Date: "20990101", Title: fmt.Sprintf("Задача номер %d", i), Comment: "Комментарий к задаче"})
+		require.NoError(t, err)
+	}
+	plain := serveGzip(routes(), "/api/tasks?limit=50", "")
+	require.Equal(t, http.StatusOK, plain.Code)
+
+	h := compress(routes())
+	// без Accept-Encoding ответ тот же байт в байт
+	identity := serveGzip(h, "/api/tasks?limit=50", "")
+	assert.Equal(t, plain.Code, identity.Code)
+	assert.Equal(t, plain.Body.Bytes(), identity.Body.Bytes())
+	assert.Empty(t, identity.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", identity.Header().Get("Vary"))
+
+	zipped := serveGzip(h, "/api/tasks?limit=50", "deflate, gzip;q=0.8")
+	require.Equal(t, http.StatusOK, zipped.Code)
+	assert.Equal(t, "gzip", zipped.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", zipped.Header().Get("Vary"))
+	assert.Equal(t, plain.Header().Get("Content-Type"), zipped.Header().Get("Content-Type"))
+	assert.Less(t, zipped.Body.Len(), plain.Body.Len()/2)
+	assert.Equal(t, plain.Body.Bytes(), gunzip(t, zipped.Body.Bytes()))
+
+	// клиент отказался от gzip
+	assert.Empty(t, serveGzip(h, "/api/tasks?limit=50", "gzip;q=0").Header().Get("Content-Encoding"))
+}
+
+func TestCompressSkips(t *testing.T) {
+	large := strings.Repeat("задача ", gzipMinSize)
+	for _, tc := range []struct {
+		name string
+		path string
+		h    http.HandlerFunc
+	}{
+		{"short body", "/api/nextdate", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "20250701")
+		}},
+		{"compressed type", "/favicon.png", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, large)
+		}},
+		{"already encoded", "/metrics", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, large)
+		}},
+		{"partial content", "/js/scripts.min.js", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/99999", len(large)-1))
+			w.WriteHeader(http.StatusPartialContent)
+			io.WriteString(w, large)
+		}},
+		{"events", eventsPath, func(w http.ResponseWriter, r *http.Request) {
+			_, ok := w.(*gzipWriter)
+			assert.False(t, ok)
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, large)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			plain := serveGzip(tc.h, tc.path, "")
+			w := serveGzip(compress(tc.h), tc.path, "gzip")
+			assert.Equal(t, plain.Code, w.Code)
+			assert.Equal(t, plain.Header().Get("Content-Encoding"), w.Header().Get("Content-Encoding"))
+			assert.Equal(t, plain.Body.String(), w.Body.String())
+		})
+	}
+}
+
+func TestCompressStatusAndLogging(t *testing.T) {
+	large := strings.Repeat("не найдено ", gzipMinSize)
+	h := compress(logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, large[:10])
+		io.WriteString(w, large[10:])
+		w.WriteHeader(http.StatusOK) // отбрасывается журналом запросов
+	})))
+
+	w := serveGzip(h, "/missing", "gzip")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, large, string(gunzip(t, w.Body.Bytes())))
+}
+
+func TestCompressFlush(t *testing.T) {
+	h := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"tasks":[`)
+		require.NoError(t, http.NewResponseController(w).Flush())
+		io.WriteString(w, `]}`)
+	}))
+	w := serveGzip(h, "/api/export", "gzip")
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, `{"tasks":[]}`, string(gunzip(t, w.Body.Bytes())))
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	for value, want := range map[string]bool{
+		"":                  false,
+		"gzip":              true,
+		"GZIP":              true,
+		"deflate, gzip":     true,
+		"gzip;q=0.5":        true,
+		"gzip; q=0":         false,
+		"br, deflate":       false,
+		"x-gzip-compressed": false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", value)
+		assert.Equal(t, want, acceptsGzip(r), value)
+	}
+}
+
+func BenchmarkCompressTasks(b *testing.B) {
+	body := bytes.Repeat([]byte(`{"id":"1","date":"20990101","title":"Задача","comment":"","repeat":""},`), 500)
+	h := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	var size int
+	for b.Loop() {
+		size = serveGzip(h, "/api/tasks", "gzip").Body.Len()
+	}
+	b.ReportMetric(float64(size)/float64(len(body)), "ratio")
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// HealthResp - ответ /api/health.
+type HealthResp struct {
+	Status      string      `json:"status"`
+	Maintenance Maintenance `json:"maintenance"`
+	Attention   []string    `json:"needs_attention,omitempty"` // ID задач с неверной датой, только в подробном ответе
+}
+
+// healthHandler обрабатывает GET-запрос /api/health (проверка живости).
+// Не требует аутентификации, не обращается к БД без verbose=1
+// и сообщает состояние режима обслуживания:
+//
+//	{"status":"ok","maintenance":{"enabled":false}}
+//
+// С параметром verbose=1 в ответ добавляются ID задач, дату которых
+// не удалось восстановить при запуске (поле needs_attention).
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+
+	resp := HealthResp{Status: "ok", Maintenance: getMaintenance()}
+	if r.URL.Query().Get("verbose") == "1" {
+		ids, err := store.TasksNeedingAttention(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Ошибка при получении задач с неверной датой", "err", err)
+			sendAPIError(w, r, CodeDBError, "ошибка проверки задач", http.StatusInternalServerError)
+			return
+		}
+		resp.Attention = ids
+	}
+
+	sendJSON(w, r, resp, http.StatusOK)
+}
+
+// readyHandler обрабатывает GET-запрос /api/ready (проверка готовности).
+// Не требует аутентификации. Проверяет доступность БД и возвращает
+// {"status":"ready"} или 503 с текстом ошибки, если БД недоступна
+// (файл удален, заблокирован или соединение закрыто).
+func readyHandler(w http.ResponseWriter, r *http.Request) {
+
+	if err := store.Ping(r.Context()); err != nil {
+		slog.WarnContext(r.Context(), "Проверка готовности не пройдена", "err", err)
+		sendAPIError(w, r, CodeUnavailable, "БД недоступна: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	sendJSON(w, r, HealthResp{Status: "ready", Maintenance: getMaintenance()}, http.StatusOK)
+}
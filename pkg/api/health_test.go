@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthAndReady(t *testing.T) {
+	s := setupDB(t)
+
+	w := doRequest(t, healthHandler, http.MethodGet, "/api/health", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ok", decodeBody(t, w)["status"])
+
+	w = doRequest(t, readyHandler, http.MethodGet, "/api/ready", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ready", decodeBody(t, w)["status"])
+
+	// без БД сервис жив, но не готов
+	require.NoError(t, s.Close())
+	w = doRequest(t, healthHandler, http.MethodGet, "/api/health", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = doRequest(t, readyHandler, http.MethodGet, "/api/ready", nil)
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, decodeBody(t, w)["error"], "database is closed")
+
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/ready", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"time"
+
+	"go1f/pkg/db"
+)
+
+// maxImportSize - наибольший размер файла выгрузки для POST /api/import.
+const maxImportSize = 10 << 20
+
+// ExportFile описывает формат файла с выгрузкой задач (см. exportHandler).
+type ExportFile struct {
+	Version    int        `json:"version"`     // версия формата; 0 у выгрузок без версии
+	ExportedAt time.Time  `json:"exported_at"` // время выгрузки
+	Tasks      []*db.Task `json:"tasks"`
+}
+
+// ImportError - задача из выгрузки, не прошедшая проверку.
+type ImportError struct {
+	Index int       `json:"index,omitempty"` // номер задачи в выгрузке JSON, начиная с 1
+	Line  int       `json:"line,omitempty"`  // номер строки файла CSV
+	Error string    `json:"error"`
+	Code  ErrorCode `json:"code"` // код ошибки, как в ответах API
+}
+
+// ImportResp - ответ на POST /api/import.
+type ImportResp struct {
+	Imported int           `json:"imported"`
+	Errors   []ImportError `json:"errors"`
+}
+
+// ImportDryRunResp - ответ на POST /api/import?dry_run=1.
+type ImportDryRunResp struct {
+	WouldImport int           `json:"would_import"`
+	Errors      []ImportError `json:"errors"`
+}
+
+// ImportTasks читает задачи из JSON и добавляет их в хранилище s.
+//
+// Принимает как файл выгрузки вида {"tasks":[...]}, так и просто массив задач.
+// Каждая задача проходит ту же проверку, что и при создании через POST /api/task.
+// Если хотя бы одна задача не прошла проверку, ничего не импортируется.
+//
+// Возвращает количество импортированных задач.
+func ImportTasks(ctx context.Context, s TaskStore, r io.Reader) (int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения файла: %w", err)
+	}
+
+	file, err := parseExport(data)
+	if err != nil {
+		return 0, err
+	}
+	if _, errs := checkImport(file.Tasks, nil); len(errs) > 0 {
+		return 0, fmt.Errorf("задача #%d: %s", errs[0].Index, errs[0].Error)
+	}
+
+	return s.ImportTasks(ctx, file.Tasks)
+}
+
+// importHandler обрабатывает POST-запрос /api/import.
+//
+// Принимает файл выгрузки (см. ImportTasks) в теле запроса или в поле file
+// формы multipart/form-data. Задачи, не прошедшие проверку POST /api/task,
+// пропускаются и перечисляются в ответе, остальные добавляются в одной транзакции:
+//
+//	{"imported":2,"errors":[{"index":3,"error":"Поле Title не должно быть пустым"}]}
+//
+// Параметры запроса:
+//   - format=csv: файл в CSV (см. parseCSV), ошибки указывают номер строки (line)
+//   - dry_run=1: только проверить файл и ответить {"would_import":2,"errors":[...]}
+//   - replace=1: удалить все задачи пользователя перед импортом в той же транзакции
+//
+// ID задач из файла игнорируются; задачи сопоставляются по UID, как в db.ImportTasks.
+func importHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := readImport(w, r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		sendAPIError(w, r, CodeBodyTooLarge, fmt.Sprintf("файл выгрузки больше %d МБ", maxImportSize>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		sendAPIError(w, r, CodeBadRequest, "не удалось прочитать файл выгрузки", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	var (
+		tasks []*db.Task
+		lines []int
+	)
+	switch query.Get("format") {
+	case "", "json":
+		var file ExportFile
+		file, err = parseExport(data)
+		tasks = file.Tasks
+	case "csv":
+		tasks, lines, err = parseCSV(data)
+	default:
+		sendAPIError(w, r, CodeBadRequest, "формат файла должен быть json или csv", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		sendAPIError(w, r, CodeBadRequest, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tasks, errs := checkImport(tasks, lines)
+	if errs == nil {
+		errs = []ImportError{}
+	}
+
+	if query.Get("dry_run") == "1" {
+		sendJSON(w, r, ImportDryRunResp{WouldImport: len(tasks), Errors: errs}, http.StatusOK)
+		return
+	}
+
+	var n int
+	if query.Get("replace") == "1" {
+		n, err = store.ReplaceTasks(r.Context(), tasks)
+	} else {
+		n, err = store.ImportTasks(r.Context(), tasks)
+	}
+	if errors.Is(err, db.ErrUIDTaken) {
+		sendAPIError(w, r, CodeConflict, "UID задачи из выгрузки занят задачей другого пользователя", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка импорта задач", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка импорта задач", http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, r, ImportResp{Imported: n, Errors: errs}, http.StatusOK)
+}
+
+// readImport читает файл выгрузки из запроса: тело целиком или поле file
+// формы multipart/form-data. Размер ограничен maxImportSize.
+func readImport(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "multipart/form-data" {
+		return io.ReadAll(r.Body)
+	}
+
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// parseExport разбирает файл выгрузки или массив задач.
+func parseExport(data []byte) (ExportFile, error) {
+	var (
+		file ExportFile
+		err  error
+	)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &file.Tasks)
+	} else {
+		err = json.Unmarshal(trimmed, &file)
+	}
+	if err != nil {
+		return ExportFile{}, fmt.Errorf("неверный формат JSON: %w", err)
+	}
+	if file.Version > exportVersion {
+		return ExportFile{}, fmt.Errorf("неподдерживаемая версия выгрузки: %d", file.Version)
+	}
+	return file, nil
+}
+
+// checkImport проверяет задачи выгрузки так же, как checkTask при создании
+// задачи. Возвращает прошедшие проверку задачи и ошибки остальных.
+// Если переданы lines (номера строк задач в CSV), ошибки указывают строку,
+// иначе - номер задачи.
+func checkImport(tasks []*db.Task, lines []int) ([]*db.Task, []ImportError) {
+	valid := make([]*db.Task, 0, len(tasks))
+	var errs []ImportError
+	fail := func(i int, err error) {
+		e := ImportError{Index: i + 1, Error: err.Error(), Code: errorCode(err)}
+		if lines != nil {
+			e.Index, e.Line = 0, lines[i]
+		}
+		errs = append(errs, e)
+	}
+	for i, task := range tasks {
+		if task == nil {
+			fail(i, newError(CodeBadRequest, "пустая запись"))
+			continue
+		}
+		if err := checkTask(task); err != nil {
+			fail(i, err)
+			continue
+		}
+		valid = append(valid, task)
+	}
+	return valid, errs
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportTasksTwice(t *testing.T) {
+	setupDB(t)
+	for _, title := range []string{"Первая", "Вторая"} {
+		_, err := store.AddTask(t.Context(), &db.Task{Date: "20990101", Title: title})
+		require.NoError(t, err)
+	}
+	tasks, err := store.GetTasksPage(t.Context(), 10, 0, db.TaskFilter{})
+	require.NoError(t, err)
+	data, err := json.Marshal(ExportFile{Tasks: tasks})
+	require.NoError(t, err)
+
+	// повторный импорт той же выгрузки не создает дубликатов
+	for i := 0; i < 2; i++ {
+		n, err := ImportTasks(t.Context(), store, bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+	}
+
+	after, err := store.GetTasksPage(t.Context(), 10, 0, db.TaskFilter{})
+	require.NoError(t, err)
+	assert.Len(t, after, 2)
+}
+
+func TestImportHandler(t *testing.T) {
+	setupDB(t)
+	_, err := store.AddTask(t.Context(), &db.Task{Date: "20990101", Title: "Старая"})
+	require.NoError(t, err)
+
+	file := ExportFile{Tasks: []*db.Task{
+		{ID: "999", Date: "20990102", Title: "Новая", Tags: []string{"дом"}},
+		{Date: "20990103"},
+		nil,
+		{Date: "20990104", Title: "Еще одна"},
+	}}
+	wantErrors := []any{
+		map[string]any{"index": float64(2), "error": "Поле Title не должно быть пустым", "code": "title_required"},
+		map[string]any{"index": float64(3), "error": "пустая запись", "code": "bad_request"},
+	}
+
+	// dry_run только проверяет файл
+	w := doRequest(t, importHandler, http.MethodPost, "/api/import?dry_run=1", file)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp := decodeBody(t, w)
+	assert.Equal(t, float64(2), resp["would_import"])
+	assert.Equal(t, wantErrors, resp["errors"])
+	total, err := store.CountTasks(t.Context(), db.TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	// неверные задачи пропускаются, остальные добавляются с новыми ID
+	w = doRequest(t, importHandler, http.MethodPost, "/api/import", file)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = decodeBody(t, w)
+	assert.Equal(t, float64(2), resp["imported"])
+	assert.Equal(t, wantErrors, resp["errors"])
+	tasks, err := store.GetTasksPage(t.Context(), 10, 0, db.TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Старая", "Новая", "Еще одна"}, []string{tasks[0].Title, tasks[1].Title, tasks[2].Title})
+	assert.NotEqual(t, "999", tasks[1].ID)
+	assert.Equal(t, []string{"дом"}, tasks[1].Tags)
+
+	// replace заменяет все задачи пользователя
+	w = doRequest(t, importHandler, http.MethodPost, "/api/import?replace=1",
+		[]*db.Task{{Date: "20990105", Title: "Единственная"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(1), decodeBody(t, w)["imported"])
+	tasks, err = store.GetTasksPage(t.Context(), 10, 0, db.TaskFilter{})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "Единственная", tasks[0].Title)
+}
+
+func TestImportHandlerMultipart(t *testing.T) {
+	setupDB(t)
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "tasks-20250701.json")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(`{"version":1,"tasks":[{"date":"20990101","title":"Из файла"}]}`))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/api/import", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	importHandler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(1), decodeBody(t, w)["imported"])
+
+	// форма без поля file
+	r = httptest.NewRequest(http.MethodPost, "/api/import", strings.NewReader("--x--\r\n"))
+	r.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	w = httptest.NewRecorder()
+	importHandler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportHandlerBadFile(t *testing.T) {
+	setupDB(t)
+	for body, code := range map[string]int{
+		`{"tasks":`:                          http.StatusBadRequest,
+		`{"version":99,"tasks":[]}`:          http.StatusBadRequest,
+		strings.Repeat(" ", maxImportSize+1): http.StatusRequestEntityTooLarge,
+	} {
+		w := httptest.NewRecorder()
+		importHandler(w, httptest.NewRequest(http.MethodPost, "/api/import", strings.NewReader(body)))
+		assert.Equal(t, code, w.Code, body[:min(len(body), 30)])
+	}
+}
+
+func TestImportTasksInvalid(t *testing.T) {
+	setupDB(t)
+	// импорт из командной строки не добавляет ничего, если есть неверная задача
+	_, err := ImportTasks(t.Context(), store, strings.NewReader(`[{"date":"20990101","title":"Верная"},{"date":"20990101"}]`))
+	assert.ErrorContains(t, err, "задача #2")
+	total, err := store.CountTasks(t.Context(), db.TaskFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// logRequests — middleware журнала запросов.
+//
+// На каждый запрос пишет одну запись slog с методом, путем, кодом ответа,
+// длительностью, адресом клиента и размером ответа.
+// Ответы 5xx пишутся с уровнем ERROR, остальные - INFO.
+//
+// Ответ отправляется не больше одного раза: если обработчик после начала ответа
+// снова отправляет код (например, ошибку после 200), код и тело второго ответа
+// отбрасываются с предупреждением в журнале.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(r.Context(), level, "HTTP-запрос",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote", r.RemoteAddr),
+			slog.Int("size", rec.size),
+		)
+	})
+}
+
+// responseRecorder запоминает код и размер ответа обработчика
+// и не дает отправить второй ответ после первого.
+type responseRecorder struct {
+	http.ResponseWriter
+	status  int  // код ответа, 200 если обработчик не вызвал WriteHeader
+	size    int  // количество записанных байт тела
+	started bool // код ответа уже отправлен явно или первой записью тела
+	discard bool // обработчик начал второй ответ, его тело отбрасывается
+}
+
+// WriteHeader запоминает и отправляет код ответа.
+// Повторный вызов не отправляет код и отбрасывает дальнейшее тело.
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.started {
+		slog.Warn("Повторная отправка ответа отброшена", "status", rec.status, "dropped", code)
+		rec.discard = true
+		return
+	}
+	rec.started = true
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// Write пишет тело ответа и учитывает его размер.
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.discard {
+		return len(b), nil
+	}
+	rec.started = true
+	n, err := rec.ResponseWriter.Write(b)
+	rec.size += n
+	return n, err
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter { return rec.ResponseWriter }
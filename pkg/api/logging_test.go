@@ -0,0 +1,58 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogRequests(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	h := logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sendAPIError(w, r, CodeNotFound, "нет такой задачи", http.StatusNotFound)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/task?id=1", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "GET", record["method"])
+	assert.Equal(t, "/api/task", record["path"])
+	assert.EqualValues(t, http.StatusNotFound, record["status"])
+	assert.Equal(t, "192.0.2.1:1234", record["remote"])
+	assert.EqualValues(t, w.Body.Len(), record["size"])
+	assert.Contains(t, record, "duration")
+}
+
+func TestLogRequestsSingleResponse(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	// обработчик с ошибкой отправляет ошибку после успешного ответа
+	h := logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sendJSON(w, r, struct{}{}, http.StatusOK)
+		sendAPIError(w, r, CodeDBError, "ошибка сохранения", http.StatusInternalServerError)
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/task/done?id=1", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "{}\n", w.Body.String())
+	assert.Contains(t, buf.String(), "Повторная отправка ответа отброшена")
+	assert.Contains(t, buf.String(), `"status":200`)
+}
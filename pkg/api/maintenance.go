@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// Параметры режима обслуживания.
+const (
+	maintenanceRetryAfter = 120 // значение заголовка Retry-After, секунды
+	maintenanceDefaultMsg = "Сервис в режиме обслуживания, изменения временно недоступны"
+)
+
+// Maintenance описывает состояние режима обслуживания.
+// Используется как тело запроса и ответа /api/admin/maintenance_mode.
+type Maintenance struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+}
+
+// maintenanceState хранит текущее состояние режима обслуживания.
+// Состояние живет только в памяти процесса и сбрасывается при перезапуске.
+var maintenanceState atomic.Pointer[Maintenance]
+
+// setMaintenance включает или выключает режим обслуживания.
+// Если сообщение не задано, используется maintenanceDefaultMsg.
+func setMaintenance(enabled bool, message string) Maintenance {
+	state := Maintenance{Enabled: enabled}
+	if enabled {
+		state.Message = message
+		if state.Message == "" {
+			state.Message = maintenanceDefaultMsg
+		}
+	}
+	maintenanceState.Store(&state)
+	return state
+}
+
+// getMaintenance возвращает текущее состояние режима обслуживания.
+func getMaintenance() Maintenance {
+	if state := maintenanceState.Load(); state != nil {
+		return *state
+	}
+	return Maintenance{}
+}
+
+// maintenanceMode — middleware режима обслуживания (только чтение).
+//
+// Пока режим включен, все изменяющие запросы (кроме входа, управления самим
+// режимом, резервного копирования и разбора фраз повторения) получают 503
+// с сообщением и заголовком Retry-After.
+// GET, HEAD и OPTIONS обрабатываются как обычно.
+// Оборачивает весь маршрутизатор, поэтому новые изменяющие обработчики
+// попадают под ограничение автоматически.
+func maintenanceMode(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := getMaintenance()
+		if !state.Enabled || !isMutating(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(maintenanceRetryAfter))
+		sendAPIError(w, r, CodeUnavailable, state.Message, http.StatusServiceUnavailable)
+	})
+}
+
+// isMutating сообщает, может ли запрос изменить данные.
+func isMutating(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	switch r.URL.Path {
+	case "/api/signin", "/api/refresh", "/api/logout", "/api/admin/maintenance_mode":
+		return false
+	case "/api/admin/backup":
+		// копия снимается VACUUM INTO и не меняет рабочую БД,
+		// а нужна как раз перед восстановлением или миграцией
+		return false
+	case "/api/repeat/parse":
+		// только разбирает фразу, задачи не меняются
+		return false
+	}
+	return true
+}
+
+// maintenanceHandler обрабатывает POST-запрос /api/admin/maintenance_mode.
+//
+// Принимает JSON вида {"enabled":true,"message":"Идет восстановление из копии"}
+// и возвращает новое состояние режима в том же формате.
+// Режим общий для всех пользователей, поэтому маршрут доступен только
+// администратору (см. adminOnly).
+func maintenanceHandler(w http.ResponseWriter, r *http.Request) {
+
+	var req Maintenance
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendDecodeError(w, r, err)
+		return
+	}
+
+	state := setMaintenance(req.Enabled, req.Message)
+	if state.Enabled {
+		slog.WarnContext(r.Context(), "Включен режим обслуживания", "message", state.Message)
+	} else {
+		slog.InfoContext(r.Context(), "Режим обслуживания выключен")
+	}
+
+	sendJSON(w, r, state, http.StatusOK)
+}
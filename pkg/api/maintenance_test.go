@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/taskdate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	setupDB(t)
+	t.Cleanup(func() { setMaintenance(false, "") })
+	today := time.Now().Format(taskdate.DateFormat)
+	id, err := store.AddTask(t.Context(), &db.Task{Date: today, Title: "Задача"})
+	require.NoError(t, err)
+	taskID := fmt.Sprint(id)
+
+	useBackupDir(t)
+	h := maintenanceMode(routes())
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	w := serve(http.MethodPost, "/api/admin/maintenance_mode", `{"enabled":true,"message":"Восстановление"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, true, decodeBody(t, w)["enabled"])
+
+	t.Run("writes rejected", func(t *testing.T) {
+		for _, req := range []struct{ method, target, body string }{
+			{http.MethodPost, "/api/task", `{"date":"` + today + `","title":"Новая"}`},
+			{http.MethodPut, "/api/task", `{"id":"` + taskID + `","date":"` + today + `","title":"Изменена"}`},
+			{http.MethodDelete, "/api/task?id=" + taskID, ""},
+			{http.MethodPost, "/api/task/done?id=" + taskID, ""},
+		} {
+			w := serve(req.method, req.target, req.body)
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code, req.method+" "+req.target)
+			assert.Equal(t, "120", w.Header().Get("Retry-After"))
+			assert.Equal(t, "Восстановление", decodeBody(t, w)["error"])
+		}
+
+		task, err := store.GetTaskID(t.Context(), mustID(t, taskID))
+		require.NoError(t, err)
+		assert.Equal(t, "Задача", task.Title)
+	})
+
+	t.Run("reads allowed", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/task?id="+taskID, "").Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/api/tasks", "").Code)
+
+		w := serve(http.MethodGet, "/api/health", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]any{"enabled": true, "message": "Восстановление"}, decodeBody(t, w)["maintenance"])
+	})
+
+	t.Run("backup allowed", func(t *testing.T) {
+		w := serve(http.MethodPost, "/api/admin/backup", "")
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	})
+
+	t.Run("repeat parse allowed", func(t *testing.T) {
+		w := serve(http.MethodPost, "/api/repeat/parse", `{"text":"каждый день"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "d 1", decodeBody(t, w)["repeat"])
+	})
+
+	w = serve(http.MethodPost, "/api/admin/maintenance_mode", `{"enabled":false}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	t.Run("writes allowed after disable", func(t *testing.T) {
+		w := serve(http.MethodPut, "/api/task", `{"id":"`+taskID+`","date":"`+today+`","title":"Изменена"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = serve(http.MethodGet, "/api/health", "")
+		assert.Equal(t, map[string]any{"enabled": false}, decodeBody(t, w)["maintenance"])
+	})
+}
+
+func TestMaintenanceAdminOnly(t *testing.T) {
+	setupDB(t)
+	usePassword(t, "secret", time.Hour)
+	t.Cleanup(func() { setMaintenance(false, "") })
+	adminToken := signIn(t, "", "secret")
+	w := doAuthRequest(t, adminToken, http.MethodPost, "/api/users", UserReq{Login: "partner", Password: "partner-pass"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	enable := Maintenance{Enabled: true}
+
+	partnerToken := signIn(t, "partner", "partner-pass")
+	w = doAuthRequest(t, partnerToken, http.MethodPost, "/api/admin/maintenance_mode", enable)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// ключ API администратора без can_admin не включает режим
+	key := createKey(t, adminToken, APIKeyReq{Name: "cron"})
+	w = doKeyRequest(t, key.Key, http.MethodPost, "/api/admin/maintenance_mode", enable)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, getMaintenance().Enabled)
+
+	key = createKey(t, adminToken, APIKeyReq{Name: "ops", CanAdmin: true})
+	w = doKeyRequest(t, key.Key, http.MethodPost, "/api/admin/maintenance_mode", enable)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, getMaintenance().Enabled)
+}
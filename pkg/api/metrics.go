@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/metrics"
+	"go1f/pkg/taskdate"
+)
+
+// metricsRefreshInterval - период обновления метрик количества задач из БД.
+const metricsRefreshInterval = 30 * time.Second
+
+// runTaskMetrics периодически обновляет метрики количества задач.
+// Работает до завершения процесса.
+func runTaskMetrics(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := refreshTaskMetrics(context.Background()); err != nil {
+			slog.Error("Ошибка при обновлении метрик задач", "err", err)
+		}
+		<-ticker.C
+	}
+}
+
+// refreshTaskMetrics считает невыполненные и просроченные задачи всех пользователей
+// и обновляет метрики.
+func refreshTaskMetrics(ctx context.Context) error {
+	ctx = db.WithAllUsers(ctx)
+	total, err := store.CountTasks(ctx, db.TaskFilter{})
+	if err != nil {
+		return err
+	}
+	overdue, err := store.CountOverdue(ctx, localNow().Format(taskdate.DateFormat))
+	if err != nil {
+		return err
+	}
+	metrics.SetTasks(total, overdue)
+	return nil
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/metrics"
+	"go1f/pkg/taskdate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskMetrics(t *testing.T) {
+	setupDB(t)
+	today := time.Now().Format(taskdate.DateFormat)
+	for _, task := range []db.Task{
+		{Date: "20200101", Title: "Просрочена"},
+		{Date: "20200102", Title: "Просрочена повторяющаяся", Repeat: "d 1"},
+		{Date: today, Title: "Сегодня"},
+	} {
+		_, err := store.AddTask(t.Context(), &task)
+		require.NoError(t, err)
+	}
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task", map[string]any{"title": "Через API"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NoError(t, refreshTaskMetrics(context.Background()))
+
+	var buf bytes.Buffer
+	metrics.Write(&buf)
+	assert.Contains(t, buf.String(), "todo_tasks 4\n")
+	assert.Contains(t, buf.String(), "todo_tasks_overdue 2\n")
+	assert.Regexp(t, `todo_tasks_created_total [1-9]\d*\n`, buf.String())
+}
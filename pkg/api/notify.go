@@ -0,0 +1,36 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go1f/pkg/telegram"
+)
+
+// telegramAPIURL - адрес Bot API для проверочного сообщения, в тестах подменяется.
+var telegramAPIURL = telegram.DefaultAPIURL
+
+// notifyTestHandler обрабатывает POST-запрос /api/admin/notify/test.
+//
+// Отправляет проверочное сообщение в чат TODO_TELEGRAM_CHAT_ID ботом
+// TODO_TELEGRAM_TOKEN и возвращает пустой ответ со статусом 200 OK.
+//
+// Возможные ошибки:
+//   - 503: уведомления не настроены или Telegram не принял сообщение
+//     (в тексте ошибки - ответ Bot API)
+func notifyTestHandler(w http.ResponseWriter, r *http.Request) {
+	c := conf()
+	if c.TelegramToken == "" {
+		sendAPIError(w, r, CodeUnavailable, "Уведомления Telegram не настроены: задайте TODO_TELEGRAM_TOKEN и TODO_TELEGRAM_CHAT_ID", http.StatusServiceUnavailable)
+		return
+	}
+
+	client := telegram.New(c.TelegramToken, c.TelegramChatID)
+	client.APIURL = telegramAPIURL
+	if err := client.Send(r.Context(), "Проверка уведомлений планировщика задач"); err != nil {
+		slog.Warn("Проверочное сообщение Telegram не отправлено", "err", err)
+		sendAPIError(w, r, CodeUnavailable, "Сообщение не отправлено: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	sendJSON(w, r, struct{}{}, http.StatusOK)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTelegram задает токен и чат бота до конца теста и направляет запросы
+// к Bot API на url.
+func useTelegram(t *testing.T, token, chatID, url string) {
+	t.Helper()
+	c := useConf(t)
+	prevURL := telegramAPIURL
+	c.TelegramToken, c.TelegramChatID, telegramAPIURL = token, chatID, url
+	t.Cleanup(func() { telegramAPIURL = prevURL })
+}
+
+func TestNotifyTest(t *testing.T) {
+	setupDB(t)
+	usePassword(t, "secret", time.Hour)
+	token := signIn(t, "", "secret")
+
+	useTelegram(t, "", "", "")
+	w := doAuthRequest(t, token, http.MethodPost, "/api/admin/notify/test", nil)
+	assertAPIError(t, w, CodeUnavailable)
+
+	var calls atomic.Int32
+	bot := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path != "/bot123:abc/sendMessage" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"ok":false,"description":"Unauthorized"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer bot.Close()
+
+	useTelegram(t, "123:abc", "42", bot.URL)
+	w = doAuthRequest(t, token, http.MethodPost, "/api/admin/notify/test", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.EqualValues(t, 1, calls.Load())
+
+	// ответ Bot API передается администратору
+	useTelegram(t, "123:wrong", "42", bot.URL)
+	w = doAuthRequest(t, token, http.MethodPost, "/api/admin/notify/test", nil)
+	assertAPIError(t, w, CodeUnavailable)
+	assert.Contains(t, w.Body.String(), "Unauthorized")
+	assert.NotContains(t, w.Body.String(), "123:wrong")
+
+	// только администратор
+	w = doAuthRequest(t, token, http.MethodPost, "/api/users", UserReq{Login: "partner", Password: "partner-pass"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = doAuthRequest(t, signIn(t, "partner", "partner-pass"), http.MethodPost, "/api/admin/notify/test", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.EqualValues(t, 2, calls.Load())
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"go1f/pkg/db"
+)
+
+// TaskPatch - тело PATCH-запроса /api/task.
+// Поля-указатели отличают отсутствующее поле (nil) от пустой строки.
+type TaskPatch struct {
+	Date     *string   `json:"date"`
+	Title    *string   `json:"title"`
+	Comment  *string   `json:"comment"`
+	Repeat   *string   `json:"repeat"`
+	Priority *int      `json:"priority"`
+	Tags     *[]string `json:"tags"`
+	Exclude  *[]string `json:"exclude"`
+	Version  *int64    `json:"version"` // версия, которую видел клиент; nil - версия прочитанной задачи
+}
+
+// apply переносит заданные поля патча в задачу.
+func (p TaskPatch) apply(task *db.Task) {
+	if p.Date != nil {
+		task.Date = *p.Date
+	}
+	if p.Title != nil {
+		task.Title = *p.Title
+	}
+	if p.Comment != nil {
+		task.Comment = *p.Comment
+	}
+	if p.Repeat != nil {
+		task.Repeat = *p.Repeat
+	}
+	if p.Priority != nil {
+		task.Priority = *p.Priority
+	}
+	if p.Tags != nil {
+		task.Tags = *p.Tags
+	}
+	if p.Exclude != nil {
+		task.Exclude = *p.Exclude
+	}
+	if p.Version != nil {
+		task.Version = *p.Version
+	}
+}
+
+// handlePatchTask обрабатывает PATCH-запрос для частичного обновления задачи.
+// ID задачи передается в параметре запроса "id" или UID в параметре "uid".
+// Принимает JSON только с изменяемыми полями, например {"comment":"новый"}.
+// Остальные поля берутся из сохраненной задачи, итоговая задача проверяется checkTask.
+//
+// Возвращает обновленную задачу или ошибку:
+//   - 400: неверный JSON, неверное правило повторения или итоговая задача не прошла проверку
+//   - 404: задача не найдена
+//   - 409: задачу изменили после того, как клиент прочитал версию из поля version,
+//     или параллельно с этим запросом; в ответе текущая задача (ConflictResp)
+func handlePatchTask(w http.ResponseWriter, r *http.Request) {
+
+	id, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var patch TaskPatch
+	if !decodeTask(w, r, &patch) {
+		return
+	}
+
+	task, err := store.GetTaskID(r.Context(), id)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении задачи из БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения задачи", http.StatusInternalServerError)
+		return
+	}
+
+	patch.apply(&task)
+
+	if err := checkTask(&task); err != nil {
+		sendErr(w, r, err)
+		return
+	}
+
+	// без version в теле задача сохраняется с прочитанной выше версией,
+	// чтобы не затереть изменение, сделанное между чтением и сохранением
+	if err := store.PutTaskID(r.Context(), &task); err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) {
+			sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, db.ErrVersionConflict) {
+			sendVersionConflict(w, r, id)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Ошибка при сохранении задачи в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка сохранения: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	notify(r.Context(), EventUpdated, id, &task)
+	sendJSON(w, r, task, http.StatusOK)
+}
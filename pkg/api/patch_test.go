@@ -0,0 +1,74 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/taskdate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchTask(t *testing.T) {
+	setupDB(t)
+	tomorrow := time.Now().AddDate(0, 0, 1).Format(taskdate.DateFormat)
+	id, err := store.AddTask(t.Context(), &db.Task{Date: tomorrow, Title: "Исходная", Comment: "старый", Repeat: "d 7"})
+	require.NoError(t, err)
+	target := fmt.Sprintf("/api/task?id=%d", id)
+
+	t.Run("comment only", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPatch, target, map[string]any{"comment": "новый"})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "новый", decodeBody(t, w)["comment"])
+
+		task, err := store.GetTaskID(t.Context(), id)
+		require.NoError(t, err)
+		assert.Equal(t, db.Task{ID: task.ID, Date: tomorrow, Title: "Исходная", Comment: "новый", Repeat: "d 7", UID: task.UID, Tags: []string{}, Exclude: []string{}, Version: 2}, task)
+	})
+
+	t.Run("empty string clears field", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPatch, target, map[string]any{"repeat": ""})
+		require.Equal(t, http.StatusOK, w.Code)
+
+		task, err := store.GetTaskID(t.Context(), id)
+		require.NoError(t, err)
+		assert.Empty(t, task.Repeat)
+		assert.Equal(t, "новый", task.Comment)
+	})
+
+	t.Run("invalid repeat", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPatch, target, map[string]any{"repeat": "x 5"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		task, err := store.GetTaskID(t.Context(), id)
+		require.NoError(t, err)
+		assert.Empty(t, task.Repeat)
+	})
+
+	t.Run("empty title", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPatch, target, map[string]any{"title": ""})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("priority", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPatch, target, map[string]any{"priority": 2})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.EqualValues(t, 2, decodeBody(t, w)["priority"])
+
+		w = doRequest(t, apiHandler, http.MethodPatch, target, map[string]any{"priority": 5})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		task, err := store.GetTaskID(t.Context(), id)
+		require.NoError(t, err)
+		assert.Equal(t, 2, task.Priority)
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPatch, "/api/task?id=100500", map[string]any{"comment": "x"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"go1f/pkg/db"
+	"go1f/pkg/metrics"
+	"go1f/pkg/quickparse"
+)
+
+// QuickTaskReq - тело запроса POST /api/task/quick.
+type QuickTaskReq struct {
+	Text string `json:"text"` // строка вида "Заплатить за свет 25.06 каждый месяц"
+}
+
+// QuickTaskResp - ответ POST /api/task/quick: созданная задача и фрагменты
+// строки, которые распознаны как дата и правило повторения.
+type QuickTaskResp struct {
+	CreatedTaskResp
+	Tokens []quickparse.Token `json:"tokens"`
+}
+
+// handleQuickTask обрабатывает POST-запрос /api/task/quick - создание задачи
+// из одной строки текста (см. quickparse.Parse):
+//
+//	{"text":"Заплатить за свет 25.06 каждый месяц"}
+//
+// Дата и правило повторения берутся из строки, остальное становится
+// заголовком. Задача проверяется и создается так же, как в POST /api/task,
+// а в ответе 201 кроме неё есть распознанные фрагменты, чтобы интерфейс
+// показал, что было понято:
+//
+//	{"id":186,"date":"20250625","title":"Заплатить за свет","repeat":"m 25",...,
+//	 "tokens":[{"kind":"date","text":"25.06","value":"20250625"},
+//	           {"kind":"repeat","text":"каждый месяц","value":"m 25"}]}
+//
+// Непонятная строка не отклоняется: задача создается на сегодня с этой строкой
+// в заголовке. Пустая строка - ошибка, как пустой заголовок.
+func handleQuickTask(w http.ResponseWriter, r *http.Request) {
+	var req QuickTaskReq
+	if !decodeTask(w, r, &req) {
+		return
+	}
+
+	parsed := quickparse.Parse(req.Text, localNow())
+	task := db.Task{
+		Date:   parsed.Date,
+		Title:  parsed.Title,
+		Repeat: parsed.Repeat,
+	}
+	if err := checkTask(&task); err != nil {
+		sendErr(w, r, err)
+		return
+	}
+
+	id, err := store.AddTask(r.Context(), &task)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при добавлении задачи в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка при добавлении задачи в БД", http.StatusInternalServerError)
+		return
+	}
+
+	metrics.TaskCreated()
+	notify(r.Context(), EventCreated, id, &task)
+	tokens := parsed.Tokens
+	if tokens == nil {
+		tokens = []quickparse.Token{}
+	}
+	sendJSON(w, r, QuickTaskResp{CreatedTaskResp: CreatedTaskResp{Task: &task, ID: id}, Tokens: tokens}, http.StatusCreated)
+}
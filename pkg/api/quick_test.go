@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickTask(t *testing.T) {
+	setupDB(t)
+	now := time.Date(2025, 6, 18, 12, 0, 0, 0, time.UTC)
+	useClock(t, &now)
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task/quick",
+		map[string]any{"text": "Заплатить за свет 25.06 каждый месяц"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	resp := decodeBody(t, w)
+	assert.Equal(t, "Заплатить за свет", resp["title"])
+	assert.Equal(t, "20250625", resp["date"])
+	assert.Equal(t, "m 25", resp["repeat"])
+	assert.Equal(t, []any{
+		map[string]any{"kind": "date", "text": "25.06", "value": "20250625"},
+		map[string]any{"kind": "repeat", "text": "каждый месяц", "value": "m 25"},
+	}, resp["tokens"])
+
+	task, err := store.GetTaskID(t.Context(), mustID(t, resp["id"]))
+	require.NoError(t, err)
+	assert.Equal(t, "Заплатить за свет", task.Title)
+	assert.Equal(t, "20250625", task.Date)
+	assert.Equal(t, "m 25", task.Repeat)
+
+	// непонятая строка - задача на сегодня с этой строкой в заголовке
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/quick",
+		map[string]any{"text": "Отпуск 01.07 15.07"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	resp = decodeBody(t, w)
+	assert.Equal(t, "Отпуск 01.07 15.07", resp["title"])
+	assert.Equal(t, "20250618", resp["date"])
+	assert.Equal(t, []any{}, resp["tokens"])
+
+	// прошедшая дата с годом переносится на сегодня, как в POST /api/task
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/quick",
+		map[string]any{"text": "Отчет 05.01.2024"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "20250618", decodeBody(t, w)["date"])
+
+	for _, body := range []map[string]any{
+		{"text": "  "},
+		{"text": "Задача", "date": "20250101"},
+	} {
+		w = doRequest(t, apiHandler, http.MethodPost, "/api/task/quick", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, decodeBody(t, w), "error", body)
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go1f/pkg/config"
+)
+
+// signinLimiter ограничивает подбор пароля в /api/signin.
+// Создается заново в Init, параметры меняет SetSigninLimit.
+var signinLimiter = newLoginLimiter(config.DefaultSigninFails, config.DefaultSigninWindow, time.Now)
+
+// SetSigninLimit задает ограничение попыток входа: limit неудач за window
+// (TODO_SIGNIN_MAX_FAILURES и TODO_SIGNIN_WINDOW), например после перечитывания
+// конфигурации по SIGHUP. Нулевые значения заменяются значениями по умолчанию.
+// Уже учтенные неудачи сохраняются и проверяются по новым параметрам.
+func SetSigninLimit(limit int, window time.Duration) {
+	signinLimiter.Configure(limit, window)
+}
+
+// loginLimiter считает неудачные попытки входа по IP клиента.
+// Неудачи считаются в окне, которое начинается с первой неудачи;
+// после limit неудач вход с этого IP запрещен до конца окна.
+// Безопасен для одновременного использования.
+type loginLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	now       func() time.Time
+	failures  map[string]*loginFailures
+	lastSweep time.Time
+}
+
+// loginFailures - неудачные попытки одного IP в текущем окне.
+type loginFailures struct {
+	count int
+	start time.Time
+}
+
+// newLoginLimiter создает ограничитель на limit неудач за window.
+// now возвращает текущее время, тесты передают свои часы.
+func newLoginLimiter(limit int, window time.Duration, now func() time.Time) *loginLimiter {
+	return &loginLimiter{
+		limit:    limit,
+		window:   window,
+		now:      now,
+		failures: make(map[string]*loginFailures),
+	}
+}
+
+// Allow сообщает, можно ли принять попытку входа с ip.
+// Если нельзя, возвращает время до снятия блокировки.
+func (l *loginLimiter) Allow(ip string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	f, ok := l.failures[ip]
+	if !ok || now.Sub(f.start) >= l.window {
+		return 0, true
+	}
+	if f.count < l.limit {
+		return 0, true
+	}
+	return f.start.Add(l.window).Sub(now), false
+}
+
+// Fail учитывает неудачную попытку входа с ip.
+// Заодно не чаще раза за окно удаляет записи с истекшим окном.
+func (l *loginLimiter) Fail(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= l.window {
+		for key, f := range l.failures {
+			if now.Sub(f.start) >= l.window {
+				delete(l.failures, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	f, ok := l.failures[ip]
+	if !ok || now.Sub(f.start) >= l.window {
+		l.failures[ip] = &loginFailures{count: 1, start: now}
+		return
+	}
+	f.count++
+}
+
+// Configure меняет число неудач limit и окно window; нулевые значения
+// заменяются config.DefaultSigninFails и config.DefaultSigninWindow.
+func (l *loginLimiter) Configure(limit int, window time.Duration) {
+	if limit <= 0 {
+		limit = config.DefaultSigninFails
+	}
+	if window <= 0 {
+		window = config.DefaultSigninWindow
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit, l.window = limit, window
+}
+
+// Reset забывает неудачные попытки ip после успешного входа.
+func (l *loginLimiter) Reset(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, ip)
+}
+
+// clientIP возвращает IP клиента.
+// Если задан TODO_CLIENT_IP_HEADER, IP берется из этого заголовка, который
+// выставляет доверенный прокси; из списка через запятую (X-Forwarded-For)
+// берется последний адрес - его добавил сам прокси.
+// Иначе используется адрес соединения.
+func clientIP(r *http.Request) string {
+	if header := conf().ClientIPHeader; header != "" {
+		if value := r.Header.Get(header); value != "" {
+			parts := strings.Split(value, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
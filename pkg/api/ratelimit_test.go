@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go1f/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginLimiter(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	l := newLoginLimiter(3, time.Minute, func() time.Time { return now })
+
+	for range 3 {
+		_, ok := l.Allow("10.0.0.1")
+		require.True(t, ok)
+		l.Fail("10.0.0.1")
+	}
+	now = now.Add(20 * time.Second)
+	retry, ok := l.Allow("10.0.0.1")
+	assert.False(t, ok)
+	assert.Equal(t, 40*time.Second, retry)
+
+	_, ok = l.Allow("10.0.0.2")
+	assert.True(t, ok, "другой IP не блокируется")
+
+	now = now.Add(40 * time.Second)
+	_, ok = l.Allow("10.0.0.1")
+	assert.True(t, ok, "окно истекло")
+
+	l.Fail("10.0.0.1")
+	l.Fail("10.0.0.1")
+	l.Fail("10.0.0.1")
+	l.Reset("10.0.0.1")
+	_, ok = l.Allow("10.0.0.1")
+	assert.True(t, ok, "успешный вход сбрасывает счетчик")
+
+	// записи с истекшим окном удаляются при следующей неудаче
+	l.Fail("10.0.0.3")
+	now = now.Add(2 * time.Minute)
+	l.Fail("10.0.0.4")
+	assert.NotContains(t, l.failures, "10.0.0.3")
+	assert.Contains(t, l.failures, "10.0.0.4")
+}
+
+func TestLoginLimiterConcurrent(t *testing.T) {
+	l := newLoginLimiter(1000, time.Minute, time.Now)
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				l.Allow("10.0.0.1")
+				l.Fail("10.0.0.1")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 500, l.failures["10.0.0.1"].count)
+}
+
+func TestLoginLimiterConfigure(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	l := newLoginLimiter(3, time.Minute, func() time.Time { return now })
+	l.Fail("10.0.0.1")
+	l.Fail("10.0.0.1")
+
+	// учтенные неудачи проверяются по новому лимиту
+	l.Configure(2, time.Minute)
+	retry, ok := l.Allow("10.0.0.1")
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute, retry)
+
+	l.Configure(2, 10*time.Minute)
+	retry, _ = l.Allow("10.0.0.1")
+	assert.Equal(t, 10*time.Minute, retry)
+
+	l.Configure(0, 0)
+	assert.Equal(t, config.DefaultSigninFails, l.limit)
+	assert.Equal(t, config.DefaultSigninWindow, l.window)
+}
+
+func TestSignInRateLimit(t *testing.T) {
+	setupDB(t)
+	usePassword(t, "secret", time.Hour)
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	old := signinLimiter
+	signinLimiter = newLoginLimiter(config.DefaultSigninFails, config.DefaultSigninWindow, func() time.Time { return now })
+	t.Cleanup(func() { signinLimiter = old })
+
+	signin := func(password string) *httptest.ResponseRecorder {
+		return doRequest(t, apiHandler, http.MethodPost, "/api/signin", Pass{Password: password})
+	}
+
+	for range config.DefaultSigninFails - 1 {
+		require.Equal(t, http.StatusUnauthorized, signin("1234").Code)
+	}
+	require.Equal(t, http.StatusOK, signin("secret").Code)
+
+	for range config.DefaultSigninFails {
+		require.Equal(t, http.StatusUnauthorized, signin("1234").Code)
+	}
+	now = now.Add(15 * time.Second)
+	w := signin("secret")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "45", w.Header().Get("Retry-After"))
+
+	now = now.Add(45 * time.Second)
+	assert.Equal(t, http.StatusOK, signin("secret").Code)
+}
+
+func TestClientIP(t *testing.T) {
+	c := useConf(t)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/signin", nil)
+	r.RemoteAddr = "192.0.2.1:5000"
+	r.Header.Set("X-Forwarded-For", "203.0.113.9, 198.51.100.7")
+
+	c.ClientIPHeader = ""
+	assert.Equal(t, "192.0.2.1", clientIP(r))
+
+	c.ClientIPHeader = "X-Forwarded-For"
+	assert.Equal(t, "198.51.100.7", clientIP(r))
+
+	r.Header.Del("X-Forwarded-For")
+	assert.Equal(t, "192.0.2.1", clientIP(r))
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// recoverPanic — middleware, перехватывающий панику обработчика.
+//
+// Паника пишется в журнал вместе со стеком, а клиент получает
+// обычный JSON с ошибкой и статус 500 вместо оборванного соединения.
+// http.ErrAbortHandler пробрасывается дальше: им обработчик сам
+// прерывает ответ, и net/http обрабатывает его без записи в журнал.
+func recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			slog.ErrorContext(r.Context(), "Паника при обработке запроса",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", err,
+				"stack", string(debug.Stack()))
+			sendAPIError(w, r, CodeInternal, "внутренняя ошибка сервера", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
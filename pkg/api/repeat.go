@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"go1f/pkg/config"
+	"go1f/pkg/taskdate"
+)
+
+// repeatSuggestions - сколько примеров фраз возвращается с ошибкой bad_phrase.
+const repeatSuggestions = 3
+
+// RepeatParseReq - тело запроса POST /api/repeat/parse.
+type RepeatParseReq struct {
+	Text string `json:"text"` // фраза вида "по понедельникам и пятницам"
+}
+
+// RepeatParseResp - правило повторения, в которое переведена фраза.
+type RepeatParseResp struct {
+	Repeat string `json:"repeat"`
+}
+
+// RepeatParseError - ответ 422 POST /api/repeat/parse: ошибка и примеры
+// фраз, похожих на непонятую, с их правилами.
+type RepeatParseError struct {
+	ErrorResponse
+	Suggestions []taskdate.RepeatExample `json:"suggestions"`
+}
+
+// repeatParseHandler обрабатывает POST-запрос /api/repeat/parse - перевод
+// фразы на русском или английском в правило повторения (см. taskdate.ParseRepeatPhrase):
+//
+//	{"text":"по понедельникам и пятницам"} -> {"repeat":"w 1,5"}
+//
+// Задачи не меняются, поэтому, как и /api/nextdate, запрос не требует
+// аутентификации. Непонятую фразу отклоняет с кодом bad_phrase
+// и подсказками, которые интерфейс может показать при вводе:
+//
+//	{"error":"...","code":"bad_phrase",
+//	 "suggestions":[{"phrase":"каждые 3 дня","repeat":"d 3"},...]}
+//
+// Пустая фраза - ошибка bad_request.
+func repeatParseHandler(w http.ResponseWriter, r *http.Request) {
+	var req RepeatParseReq
+	if !decodeTask(w, r, &req) {
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		sendAPIError(w, r, CodeBadRequest, "не задана фраза правила повторения", http.StatusBadRequest)
+		return
+	}
+
+	repeat, err := taskdate.ParseRepeatPhrase(req.Text)
+	if err != nil {
+		sendJSON(w, r, RepeatParseError{
+			ErrorResponse: ErrorResponse{
+				Error:     "не удалось понять правило повторения: " + err.Error(),
+				Code:      CodeBadPhrase,
+				RequestID: config.RequestID(r.Context()),
+			},
+			Suggestions: taskdate.SuggestRepeat(req.Text, repeatSuggestions),
+		}, errorStatus[CodeBadPhrase])
+		return
+	}
+	sendJSON(w, r, RepeatParseResp{Repeat: repeat}, http.StatusOK)
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepeatParse(t *testing.T) {
+	for text, want := range map[string]string{
+		"по понедельникам и пятницам":       "w 1,5",
+		"каждые 3 дня":                      "d 3",
+		"последний день месяца":             "m -1",
+		"every 2 weeks on monday":           "w 2 1",
+		"первое воскресенье каждого месяца": "mw 1 7",
+	} {
+		w := doRequest(t, apiHandler, http.MethodPost, "/api/repeat/parse", map[string]any{"text": text})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, map[string]any{"repeat": want}, decodeBody(t, w), text)
+	}
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/repeat/parse", map[string]any{"text": "каждые 2 нед"})
+	assertAPIError(t, w, CodeBadPhrase)
+	suggestions := decodeBody(t, w)["suggestions"].([]any)
+	require.NotEmpty(t, suggestions)
+	assert.Equal(t, map[string]any{"phrase": "каждые 2 недели по средам", "repeat": "w 2 3"}, suggestions[0])
+
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/repeat/parse", map[string]any{"text": " "})
+	assertAPIError(t, w, CodeBadRequest)
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/repeat/parse", map[string]any{"phrase": "каждый день"})
+	assertAPIError(t, w, CodeUnknownField)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"crypto/rand"
+	"net/http"
+
+	"go1f/pkg/config"
+)
+
+// Заголовок с ID запроса и наибольшая длина ID, принятого от клиента.
+const (
+	requestIDHeader = "X-Request-ID"
+	maxRequestIDLen = 128
+)
+
+// requestID - middleware, присваивающее запросу ID: из заголовка X-Request-ID
+// (его ставит прокси или клиент) или случайный, если заголовка нет или
+// значение не подходит. ID сохраняется в контексте запроса (config.WithRequestID),
+// возвращается в заголовке X-Request-ID ответа, в поле request_id ошибок API
+// и попадает во все записи журнала с контекстом запроса.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(config.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID сообщает, можно ли принять id от клиента: непустая строка
+// до maxRequestIDLen символов из латинских букв, цифр и знаков "-_.:".
+// Остальное не пишется в журнал и заголовки как есть.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range []byte(id) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
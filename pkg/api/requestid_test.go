@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go1f/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLog направляет журнал в буфер до конца теста.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(config.NewLogger(&buf, slog.LevelInfo, config.LogFormatJSON))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+// requestLogID возвращает request_id записи журнала о запросе к path.
+func requestLogID(t *testing.T, buf *bytes.Buffer, path string) string {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		if record["msg"] == "HTTP-запрос" && record["path"] == path {
+			id, _ := record["request_id"].(string)
+			return id
+		}
+	}
+	t.Fatalf("нет записи о запросе %s", path)
+	return ""
+}
+
+func TestRequestID(t *testing.T) {
+	setupDB(t)
+	h := requestID(routes())
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		want   string // пусто - ID создает сервер
+	}{
+		{"from client", "edge-7f3a:1", "edge-7f3a:1"},
+		{"generated", "", ""},
+		{"invalid", "id с пробелами\r\n", ""},
+		{"too long", strings.Repeat("a", maxRequestIDLen+1), ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := captureLog(t)
+			r := httptest.NewRequest(http.MethodGet, "/api/task?id=999", nil)
+			if tc.header != "" {
+				r.Header.Set(requestIDHeader, tc.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			require.Equal(t, http.StatusNotFound, w.Code)
+
+			id := w.Header().Get(requestIDHeader)
+			if tc.want != "" {
+				assert.Equal(t, tc.want, id)
+			} else {
+				assert.True(t, validRequestID(id), id)
+				assert.NotEqual(t, tc.header, id)
+			}
+			assert.Equal(t, id, decodeBody(t, w)["request_id"])
+			assert.Equal(t, id, requestLogID(t, buf, "/api/task"))
+		})
+	}
+
+	// у разных запросов разные ID
+	first, second := httptest.NewRecorder(), httptest.NewRecorder()
+	h.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/api/nextdate", nil))
+	h.ServeHTTP(second, httptest.NewRequest(http.MethodGet, "/api/nextdate", nil))
+	assert.NotEqual(t, first.Header().Get(requestIDHeader), second.Header().Get(requestIDHeader))
+}
+
+func TestRequestIDTimeout(t *testing.T) {
+	// ID есть и в ошибке, которую отправляет middleware, а не обработчик
+	h := requestID(requestTimeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		sendAPIError(w, r, CodeDBError, "ошибка БД", http.StatusInternalServerError)
+	}), 10*time.Millisecond))
+	r := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+	r.Header.Set(requestIDHeader, "slow-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "slow-1", decodeBody(t, w)["request_id"])
+}
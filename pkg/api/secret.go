@@ -0,0 +1,92 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go1f/pkg/config"
+	"go1f/pkg/db"
+)
+
+// Параметры ключа подписи токенов.
+const (
+	signingKeySize = 32        // байт случайного ключа
+	signingKeyFile = "jwt.key" // файл ключа рядом с БД, если не задан TODO_JWT_KEY_FILE
+)
+
+// signingKey - ключ подписи JWT-токенов (HS256).
+// Init загружает постоянный ключ, до этого используется случайный.
+var signingKey = newSigningKey()
+
+// newSigningKey возвращает случайный ключ подписи.
+func newSigningKey() []byte {
+	key := make([]byte, signingKeySize)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// loadSigningKey возвращает ключ подписи токенов.
+//
+// Ключ берется из TODO_JWT_SECRET, а если он не задан - из файла
+// TODO_JWT_KEY_FILE, по умолчанию jwt.key в каталоге файла SQLite.
+// Если файла нет, ключ генерируется и сохраняется в него, чтобы токены
+// переживали перезапуск сервера. С БД в памяти каталога нет, и ключ,
+// как и данные, живет до остановки сервера. У PostgreSQL каталога БД тоже нет,
+// и без TODO_JWT_SECRET или TODO_JWT_KEY_FILE возвращается ошибка.
+func loadSigningKey() ([]byte, error) {
+	if secret := conf().JWTSecret; secret != "" {
+		if len(secret) < signingKeySize {
+			slog.Warn("TODO_JWT_SECRET короче рекомендуемого", "min_length", signingKeySize)
+		}
+		return []byte(secret), nil
+	}
+	path := conf().JWTKeyFile
+	if path == "" {
+		switch {
+		case conf().DBDriver == config.DBDriverPostgres:
+			return nil, errors.New("ключ подписи токенов не задан: при TODO_DB_DRIVER=postgres нужен TODO_JWT_SECRET или TODO_JWT_KEY_FILE")
+		case conf().PathToDB == db.MemoryPath:
+			return newSigningKey(), nil
+		}
+		path = filepath.Join(filepath.Dir(conf().PathToDB), signingKeyFile)
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) < signingKeySize {
+			return nil, fmt.Errorf("поврежден файл ключа %s", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	key := newSigningKey()
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0o600); err != nil {
+		return nil, err
+	}
+	slog.Info("Создан ключ подписи токенов", "path", path)
+	return key, nil
+}
+
+// pwdVersion возвращает версию пароля для claim "pwd_version".
+// Смена TODO_PASSWORD меняет версию и делает выданные токены недействительными.
+// Версия вычисляется с ключом подписи, поэтому по токену нельзя подобрать пароль.
+func pwdVersion(password string) string {
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte("pwd_version:" + password))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
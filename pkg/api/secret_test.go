@@ -0,0 +1,79 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go1f/pkg/config"
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSigningKey(t *testing.T) {
+	c := useConf(t)
+	dir := t.TempDir()
+	c.PathToDB = filepath.Join(dir, "scheduler.db")
+	c.JWTSecret = ""
+
+	key, err := loadSigningKey()
+	require.NoError(t, err)
+	assert.Len(t, key, signingKeySize)
+
+	info, err := os.Stat(filepath.Join(dir, signingKeyFile))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	again, err := loadSigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, key, again, "ключ переживает перезапуск")
+
+	c.JWTSecret = "секрет из окружения"
+	fromEnv, err := loadSigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("секрет из окружения"), fromEnv)
+
+	c.JWTSecret = ""
+	require.NoError(t, os.WriteFile(filepath.Join(dir, signingKeyFile), []byte("не hex"), 0o600))
+	_, err = loadSigningKey()
+	assert.Error(t, err)
+
+	c.PathToDB = db.MemoryPath
+	inMemory, err := loadSigningKey()
+	require.NoError(t, err)
+	assert.Len(t, inMemory, signingKeySize)
+	assert.NoFileExists(t, signingKeyFile, "ключ БД в памяти не сохраняется на диск")
+}
+
+func TestLoadSigningKeyFile(t *testing.T) {
+	c := useConf(t)
+	c.JWTSecret = ""
+	c.PathToDB = db.MemoryPath
+	c.DBDriver = config.DBDriverPostgres
+
+	_, err := loadSigningKey()
+	assert.ErrorContains(t, err, "TODO_JWT_KEY_FILE")
+
+	c.JWTKeyFile = filepath.Join(t.TempDir(), "keys", "todo.key")
+	require.NoError(t, os.MkdirAll(filepath.Dir(c.JWTKeyFile), 0o700))
+	key, err := loadSigningKey()
+	require.NoError(t, err)
+	assert.FileExists(t, c.JWTKeyFile)
+
+	again, err := loadSigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, key, again, "ключ читается из TODO_JWT_KEY_FILE")
+}
+
+func TestPwdVersion(t *testing.T) {
+	assert.Equal(t, pwdVersion("secret"), pwdVersion("secret"))
+	assert.NotEqual(t, pwdVersion("secret"), pwdVersion("1234"))
+
+	old := signingKey
+	t.Cleanup(func() { signingKey = old })
+	before := pwdVersion("secret")
+	signingKey = newSigningKey()
+	assert.NotEqual(t, before, pwdVersion("secret"), "версия зависит от ключа")
+}
@@ -2,20 +2,26 @@ package api
 
 import (
 	"crypto/sha256"
-	"encoding/hex"
+	"crypto/subtle"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"go1f/pkg/config"
+	"go1f/pkg/db"
 
 	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
 )
 
-// Pass представляет структуру для парсинга входящего JSON-запроса с паролем.
-// Используется в обработчике /api/signin.
+// Pass представляет структуру для парсинга входящего JSON-запроса с логином и паролем.
+// Используется в обработчике /api/signin. Пустой логин означает пользователя по умолчанию.
 type Pass struct {
+	Login    string `json:"login,omitempty"`
 	Password string `json:"password"`
 }
 
@@ -27,22 +33,28 @@ type RespSign struct {
 
 // handleSignIn обрабатывает POST-запрос на аутентификацию (/api/signin).
 //
-// Принимает JSON вида {"password":"string"}.
-// Сравнивает пароль с значением из переменной окружения TODO_PASSWORD.
+// Принимает JSON вида {"login":"string","password":"string"}.
+// Без логина входит пользователь по умолчанию (db.DefaultUserLogin), его пароль
+// проверяется функцией checkPassword, пароли остальных - по bcrypt-хешу из БД.
 //
-// В случае успеха возвращает JWT-токен в формате:
+// В случае успеха устанавливает куку "token" (HttpOnly, SameSite=Lax)
+// и возвращает тот же JWT-токен в формате:
 //
 //	{"token":"eyJhbGciOiJ..."}
 //
+// Токен передается в куке "token" или в заголовке "Authorization: Bearer <токен>".
+//
 // Возможные ошибки:
-//   - 405: метод не POST
 //   - 400: неверный формат JSON или аутентификация не настроена
-//   - 401: неверный пароль или ошибка генерации токена
+//   - 401: неверный логин или пароль, ошибка генерации токена
+//   - 500: ошибка чтения пользователя из БД
+//   - 429: с этого IP слишком много неудачных попыток, см. заголовок Retry-After
 func handleSignIn(w http.ResponseWriter, r *http.Request) {
 
-	if r.Method != http.MethodPost {
-		log.Println("Ошибка метода запроса при аутентификации")
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	ip := clientIP(r)
+	if retry, ok := signinLimiter.Allow(ip); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+		sendAPIError(w, r, CodeRateLimited, "Слишком много попыток входа, повторите позже", http.StatusTooManyRequests)
 		return
 	}
 
@@ -50,110 +62,298 @@ func handleSignIn(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&password)
 	if err != nil {
-		sendError(w, "Неверный формат JSON: "+err.Error(), http.StatusBadRequest)
+		sendDecodeError(w, r, err)
+		return
+	}
+
+	if credential() == "" {
+		sendAPIError(w, r, CodeBadRequest, "Аутентификация не настроена", http.StatusBadRequest)
 		return
 	}
 
-	secretPassword := config.App.PasswordTest
-	if secretPassword == "" {
-		sendError(w, "Аутентификация не настроена", http.StatusBadRequest)
+	login := strings.TrimSpace(password.Login)
+	if login == "" {
+		login = db.DefaultUserLogin
+	}
+	user, err := store.UserByLogin(r.Context(), login)
+	if err != nil && !errors.Is(err, db.ErrUserNotFound) {
+		sendAPIError(w, r, CodeDBError, "Ошибка чтения пользователя", http.StatusInternalServerError)
 		return
 	}
 
-	if password.Password != secretPassword {
-		log.Printf("Введен невенрный пароль %v", password.Password)
-		sendError(w, "Неверный пароль", http.StatusUnauthorized)
+	// Неизвестный логин не отличается от неверного пароля
+	if err != nil || !checkUserPassword(user, password.Password) {
+		signinLimiter.Fail(ip)
+		slog.WarnContext(r.Context(), "Введен неверный логин или пароль", "ip", ip, "login", login)
+		sendAPIError(w, r, CodeUnauthorized, "Неверный пароль", http.StatusUnauthorized)
 		return
 	}
 
-	resp, err := getToken(secretPassword)
+	signinLimiter.Reset(ip)
+
+	resp, err := getToken(user, clock())
 	if err != nil {
-		sendError(w, "Ошибка получения токена", http.StatusUnauthorized)
+		sendAPIError(w, r, CodeUnauthorized, "Ошибка получения токена", http.StatusUnauthorized)
 		return
 	}
 
-	sendJSON(w, RespSign{resp}, http.StatusOK)
+	setTokenCookie(w, r, resp)
+	sendJSON(w, r, RespSign{resp}, http.StatusOK)
 }
 
-// getToken генерирует JWT-токен на основе пароля.
+// handleLogout обрабатывает POST-запрос на выход (/api/logout).
+// Удаляет куку "token" и возвращает пустой JSON {}.
+// Токены, переданные в заголовке Authorization, продолжают действовать до истечения срока.
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, tokenCookie(r, "", -1))
+	sendJSON(w, r, struct{}{}, http.StatusOK)
+}
+
+// setTokenCookie сохраняет токен в куке "token" на срок его жизни.
+// Кука недоступна скриптам страницы (HttpOnly).
+func setTokenCookie(w http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(w, tokenCookie(r, token, int(conf().TokenTTL.Seconds())))
+}
+
+// tokenCookie собирает куку "token" с общими атрибутами.
+// Secure выставляется, если запрос пришел по TLS, сервер работает по HTTPS
+// (TODO_TLS_CERT) или, при TODO_TRUST_PROXY, прокси сообщил о TLS заголовком
+// X-Forwarded-Proto.
+func tokenCookie(r *http.Request, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     "token",
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// isHTTPS сообщает, пришел ли запрос клиента по HTTPS.
+func isHTTPS(r *http.Request) bool {
+	if r.TLS != nil || conf().TLS.Enabled() {
+		return true
+	}
+	return conf().TrustProxy && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// credential возвращает учетные данные, от которых зависит версия пароля в токенах:
+// bcrypt-хеш из TODO_PASSWORD_HASH, если он задан, иначе пароль из TODO_PASSWORD.
+// Пустая строка означает, что аутентификация выключена.
+func credential() string {
+	c := conf()
+	if c.PasswordHash != "" {
+		return c.PasswordHash
+	}
+	return c.PasswordTest
+}
+
+// checkPassword сообщает, совпадает ли password с настроенным паролем.
 //
-// Пароль хешируется с помощью SHA-256, результат используется как:
-//   - Секрет для подписи токена (алгоритм HS256)
-//   - Полезная нагрузка (claim "pwd_hash")
+// TODO_PASSWORD_HASH имеет приоритет над TODO_PASSWORD: если хеш задан,
+// пароль проверяется только bcrypt.CompareHashAndPassword.
+// Иначе пароль сравнивается с TODO_PASSWORD за постоянное время:
+// сравниваются SHA-256 обоих значений, чтобы не выдавать и длину пароля.
+func checkPassword(password string) bool {
+	c := conf()
+	if c.PasswordHash != "" {
+		return bcrypt.CompareHashAndPassword([]byte(c.PasswordHash), []byte(password)) == nil
+	}
+	if c.PasswordTest == "" {
+		return false
+	}
+	got := sha256.Sum256([]byte(password))
+	want := sha256.Sum256([]byte(c.PasswordTest))
+	return subtle.ConstantTimeCompare(got[:], want[:]) == 1
+}
+
+// userCredential возвращает учетные данные пользователя для версии пароля в токенах:
+// bcrypt-хеш из БД или, у пользователя по умолчанию, credential().
+func userCredential(user db.User) string {
+	if user.PasswordHash != "" {
+		return user.PasswordHash
+	}
+	return credential()
+}
+
+// checkUserPassword сообщает, совпадает ли password с паролем пользователя.
+// Пароль пользователя по умолчанию проверяется checkPassword.
+func checkUserPassword(user db.User, password string) bool {
+	if user.PasswordHash != "" {
+		return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) == nil
+	}
+	return checkPassword(password)
+}
+
+// clock возвращает текущее время для выдачи и проверки токенов.
+// Тесты подменяют его, чтобы проверять истечение срока без ожидания.
+var clock = time.Now
+
+// handleRefresh обрабатывает POST-запрос на продление токена (/api/refresh).
+//
+// Принимает действующий токен из заголовка Authorization или куки "token" и возвращает новый токен
+// с отсчетом срока жизни от текущего момента, обновляя куку, в том же формате, что и /api/signin:
 //
-// Токен имеет срок жизни 8 часов (claim "exp").
+//	{"token":"eyJhbGciOiJ..."}
+//
+// Возможные ошибки:
+//   - 400: аутентификация не настроена
+//   - 401: токен не передан, истек, подпись неверна или пароль изменен
+func handleRefresh(w http.ResponseWriter, r *http.Request) {
+
+	if credential() == "" {
+		sendAPIError(w, r, CodeBadRequest, "Аутентификация не настроена", http.StatusBadRequest)
+		return
+	}
+
+	now := clock()
+	user, msg, ok := checkToken(r, now)
+	if !ok {
+		sendAPIError(w, r, CodeUnauthorized, msg, http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := getToken(user, now)
+	if err != nil {
+		sendAPIError(w, r, CodeUnauthorized, "Ошибка получения токена", http.StatusUnauthorized)
+		return
+	}
+
+	setTokenCookie(w, r, resp)
+	sendJSON(w, r, RespSign{resp}, http.StatusOK)
+}
+
+// tokenSubject - значение claim "sub" токенов сервиса.
+const tokenSubject = "todo"
+
+// getToken генерирует JWT-токен для пользователя user.
+//
+// Токен подписывается ключом signingKey (алгоритм HS256) и содержит:
+//   - "sub": tokenSubject
+//   - "user_id": ID пользователя
+//   - "pwd_version": версия пароля пользователя (см. pwdVersion)
+//   - "exp": момент now плюс TODO_TOKEN_TTL
 //
 // Возвращает:
 //   - string: подписанный токен в формате JWT
 //   - error: ошибка при подписании
-func getToken(s string) (string, error) {
+func getToken(user db.User, now time.Time) (string, error) {
 
-	// Создаём хэш пароля для использования в качестве секрета
-	hash := sha256.Sum256([]byte(s))
-	secret := hex.EncodeToString(hash[:])
-
-	// Создаём полезную нагрузку claims с хэшем пароля
 	claims := jwt.MapClaims{
-		"pwd_hash": secret,
-		"exp":      time.Now().Add(8 * time.Hour).Unix(),
+		"sub":         tokenSubject,
+		"user_id":     user.ID,
+		"pwd_version": pwdVersion(userCredential(user)),
+		"exp":         now.Add(conf().TokenTTL).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	result, err := token.SignedString([]byte(secret))
+	result, err := token.SignedString(signingKey)
 	return result, err
 }
 
-// auth — middleware для проверки JWT-токена из куки.
+// tokenFromRequest возвращает токен из заголовка "Authorization: Bearer <jwt>",
+// а если заголовка нет — из куки "token".
+// Заголовок другого вида считается ошибкой, к куке в этом случае не переходим.
+func tokenFromRequest(r *http.Request) (string, string, bool) {
+
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		token = strings.TrimSpace(token)
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return "", "Неверный заголовок Authorization", false
+		}
+		return token, "", true
+	}
+
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		return "", "Требуется аутентификация", false
+	}
+	return cookie.Value, "", true
+}
+
+// checkToken проверяет токен запроса (см. tokenFromRequest) на момент now:
+//  1. Наличие заголовка Authorization или куки "token"
+//  2. Алгоритм подписи (должен быть HS256) и подпись ключом signingKey
+//  3. Срок действия токена
+//  4. Пользователя из claim "user_id": он должен существовать
+//  5. Версию пароля: токены, выданные до смены пароля пользователя, отклоняются
+//
+// Токены старого формата (подписанные хешем пароля или без user_id) не проходят
+// проверку, и клиенту нужно войти заново.
 //
-// Если TODO_PASSWORD не задан, аутентификация пропускается.
+// Возвращает пользователя токена, а если токен не прошел проверку - текст ошибки и false.
+func checkToken(r *http.Request, now time.Time) (db.User, string, bool) {
+
+	value, msg, ok := tokenFromRequest(r)
+	if !ok {
+		return db.User{}, msg, false
+	}
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(value, claims, func(token *jwt.Token) (interface{}, error) {
+		return signingKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithTimeFunc(func() time.Time { return now }), jwt.WithExpirationRequired(),
+		jwt.WithSubject(tokenSubject))
+
+	if err != nil || !token.Valid {
+		return db.User{}, "Неверный токен", false
+	}
+
+	// JSON-числа в MapClaims разбираются как float64
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return db.User{}, "Неверный токен", false
+	}
+	user, err := store.UserByID(r.Context(), int64(userID))
+	if err != nil {
+		return db.User{}, "Неверный токен", false
+	}
+
+	// Токен выдан до смены пароля
+	if claims["pwd_version"] != pwdVersion(userCredential(user)) {
+		return db.User{}, "Пароль изменен", false
+	}
+	return user, "", true
+}
+
+// auth — middleware для проверки JWT-токена из заголовка Authorization или куки.
 //
-// Проверяет:
-//  1. Наличие куки "token"
-//  2. Алгоритм подписи (должен быть HS256)
-//  3. Соответствие секрета (хеш пароля из токена и env)
-//  4. Срок действия токена
+// Если не заданы ни TODO_PASSWORD, ни TODO_PASSWORD_HASH, аутентификация пропускается,
+// и запрос выполняется от пользователя по умолчанию.
+// Токен проверяется функцией checkToken, пользователь токена кладется в контекст
+// запроса (db.WithUser), и хранилище работает только с его задачами.
 //
 // В случае ошибки возвращает:
-//   - 401: кука отсутствует/токен невалиден/пароль изменён
+//   - 401: токен не передан/токен невалиден/пароль изменён
 func auth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		secretPassword := config.App.PasswordTest
-		if secretPassword == "" {
+		if credential() == "" {
 			next(w, r)
 			return
 		}
 
-		cookie, err := r.Cookie("token")
-		if err != nil {
-			sendError(w, "Требуется аутентификация", http.StatusUnauthorized)
-			return
-		}
-
-		// Проверка токена
-		hash := sha256.Sum256([]byte(secretPassword))
-		secret := hex.EncodeToString(hash[:])
-
-		token, err := jwt.Parse(cookie.Value, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
+		// ключ API - альтернатива токену для скриптов
+		if plain := r.Header.Get(apiKeyHeader); plain != "" {
+			key, msg, ok := checkAPIKey(r, plain)
+			if !ok {
+				sendAPIError(w, r, CodeUnauthorized, msg, http.StatusUnauthorized)
+				return
 			}
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
-			sendError(w, "Неверный токен", http.StatusUnauthorized)
+			next(w, r.WithContext(withAPIKey(r.Context(), key)))
 			return
 		}
 
-		// Дополнительная проверка хэша пароля
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			if claims["pwd_hash"] != secret {
-				sendError(w, "Пароль изменен", http.StatusUnauthorized)
-				return
-			}
+		user, msg, ok := checkToken(r, clock())
+		if !ok {
+			sendAPIError(w, r, CodeUnauthorized, msg, http.StatusUnauthorized)
+			return
 		}
-		// вызов следующего обработчика
-		next(w, r)
+		// вызов следующего обработчика от имени пользователя токена
+		next(w, r.WithContext(db.WithUser(r.Context(), user.ID)))
 	}
 }
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go1f/pkg/config"
+	"go1f/pkg/db"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// useClock подменяет clock на время, возвращаемое *at, до конца теста.
+func useClock(t *testing.T, at *time.Time) {
+	t.Helper()
+	old := clock
+	clock = func() time.Time { return *at }
+	t.Cleanup(func() { clock = old })
+}
+
+// usePassword включает аутентификацию с паролем password и сроком жизни токена ttl
+// и возвращает конфигурацию теста (см. useConf).
+func usePassword(t *testing.T, password string, ttl time.Duration) *config.Config {
+	t.Helper()
+	c := useConf(t)
+	c.PasswordTest = password
+	c.TokenTTL = ttl
+	return c
+}
+
+// admin - пользователь по умолчанию, его пароль задает usePassword.
+var admin = db.User{ID: db.DefaultUserID, Login: db.DefaultUserLogin}
+
+// forgedToken возвращает токен пользователя по умолчанию, подписанный чужим ключом.
+func forgedToken(t *testing.T, now time.Time) string {
+	t.Helper()
+	key := signingKey
+	signingKey = newSigningKey()
+	defer func() { signingKey = key }()
+	token, err := getToken(admin, now)
+	require.NoError(t, err)
+	return token
+}
+
+// refresh выполняет POST /api/refresh с токеном token в куке.
+func refresh(t *testing.T, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/api/refresh", nil)
+	if token != "" {
+		r.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	w := httptest.NewRecorder()
+	apiHandler(w, r)
+	return w
+}
+
+func TestRefreshToken(t *testing.T) {
+	setupDB(t)
+	c := usePassword(t, "secret", time.Hour)
+	issued := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	now := issued
+	useClock(t, &now)
+
+	token, err := getToken(admin, issued)
+	require.NoError(t, err)
+
+	t.Run("before expiry", func(t *testing.T) {
+		now = issued.Add(time.Hour - time.Second)
+		w := refresh(t, token)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp RespSign
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		claims := jwt.MapClaims{}
+		_, _, err := jwt.NewParser().ParseUnverified(resp.Token, claims)
+		require.NoError(t, err)
+		exp, err := claims.GetExpirationTime()
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(time.Hour).Unix(), exp.Unix())
+	})
+
+	t.Run("at expiry", func(t *testing.T) {
+		now = issued.Add(time.Hour)
+		assert.Equal(t, http.StatusUnauthorized, refresh(t, token).Code)
+	})
+
+	t.Run("expired token rejected by auth", func(t *testing.T) {
+		now = issued.Add(2 * time.Hour)
+		r := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+		r.AddCookie(&http.Cookie{Name: "token", Value: token})
+		w := httptest.NewRecorder()
+		apiHandler(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		now = issued
+		assert.Equal(t, http.StatusUnauthorized, refresh(t, forgedToken(t, issued)).Code)
+	})
+
+	t.Run("stale password version", func(t *testing.T) {
+		now = issued
+		// подпись верна, но токен выдан до смены пароля
+		c.PasswordTest = "old"
+		stale, err := getToken(admin, issued)
+		c.PasswordTest = "secret"
+		require.NoError(t, err)
+		w := refresh(t, stale)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "Пароль изменен", decodeBody(t, w)["error"])
+	})
+
+	t.Run("legacy token format", func(t *testing.T) {
+		now = issued
+		// прежний формат: подпись и claim pwd_hash от sha256(пароля)
+		hash := sha256.Sum256([]byte("secret"))
+		secret := hex.EncodeToString(hash[:])
+		claims := jwt.MapClaims{"pwd_hash": secret, "exp": issued.Add(time.Hour).Unix()}
+		legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, refresh(t, legacy).Code)
+	})
+
+	t.Run("no password hash in claims", func(t *testing.T) {
+		claims := jwt.MapClaims{}
+		_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+		require.NoError(t, err)
+		assert.NotContains(t, claims, "pwd_hash")
+		assert.Equal(t, tokenSubject, claims["sub"])
+	})
+
+	t.Run("no cookie", func(t *testing.T) {
+		now = issued
+		assert.Equal(t, http.StatusUnauthorized, refresh(t, "").Code)
+	})
+}
+
+func TestAuthTokenSources(t *testing.T) {
+	setupDB(t)
+	usePassword(t, "secret", time.Hour)
+	issued := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	useClock(t, &issued)
+
+	valid, err := getToken(admin, issued)
+	require.NoError(t, err)
+	forged := forgedToken(t, issued)
+
+	tests := []struct {
+		name   string
+		header string
+		cookie string
+		want   int
+	}{
+		{"header only", "Bearer " + valid, "", http.StatusOK},
+		{"lowercase scheme", "bearer " + valid, "", http.StatusOK},
+		{"cookie only", "", valid, http.StatusOK},
+		{"header wins over cookie", "Bearer " + forged, valid, http.StatusUnauthorized},
+		{"valid header, bad cookie", "Bearer " + valid, forged, http.StatusOK},
+		{"no token", "", "", http.StatusUnauthorized},
+		{"no scheme", valid, "", http.StatusUnauthorized},
+		{"basic scheme", "Basic " + valid, valid, http.StatusUnauthorized},
+		{"empty bearer", "Bearer ", valid, http.StatusUnauthorized},
+		{"garbage bearer", "Bearer not.a.jwt", "", http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/refresh", nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			if tc.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "token", Value: tc.cookie})
+			}
+			w := httptest.NewRecorder()
+			apiHandler(w, r)
+			assert.Equal(t, tc.want, w.Code)
+		})
+	}
+}
+
+func TestTokenCookie(t *testing.T) {
+	setupDB(t)
+	usePassword(t, "secret", 2*time.Hour)
+	issued := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	useClock(t, &issued)
+
+	// cookie возвращает единственную куку ответа w.
+	cookie := func(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+		t.Helper()
+		cookies := w.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "token", cookies[0].Name)
+		assert.Equal(t, "/", cookies[0].Path)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+		return cookies[0]
+	}
+
+	t.Run("signin", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPost, "/api/signin", Pass{Password: "secret"})
+		require.Equal(t, http.StatusOK, w.Code)
+		c := cookie(t, w)
+		assert.Equal(t, 7200, c.MaxAge)
+		assert.False(t, c.Secure)
+
+		var resp RespSign
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		assert.Equal(t, resp.Token, c.Value)
+	})
+
+	t.Run("refresh over tls", func(t *testing.T) {
+		token, err := getToken(admin, issued)
+		require.NoError(t, err)
+		r := httptest.NewRequest(http.MethodPost, "https://localhost/api/refresh", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		apiHandler(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, cookie(t, w).Secure)
+	})
+
+	t.Run("forwarded proto", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/api/logout", nil)
+		r.Header.Set("X-Forwarded-Proto", "https")
+		assert.False(t, isHTTPS(r))
+		useConf(t).TrustProxy = true
+		assert.True(t, isHTTPS(r))
+	})
+
+	t.Run("tls mode", func(t *testing.T) {
+		useConf(t).TLS = config.TLSConfig{Cert: "cert.pem", Key: "key.pem"}
+		assert.True(t, isHTTPS(httptest.NewRequest(http.MethodPost, "/api/logout", nil)))
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPost, "/api/logout", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		c := cookie(t, w)
+		assert.Empty(t, c.Value)
+		assert.Negative(t, c.MaxAge)
+		assert.Contains(t, w.Header().Get("Set-Cookie"), "Max-Age=0")
+	})
+}
+
+func TestCheckPassword(t *testing.T) {
+	setupDB(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("из хеша"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	t.Run("plaintext", func(t *testing.T) {
+		usePassword(t, "secret", time.Hour)
+		assert.True(t, checkPassword("secret"))
+		// неверные пароли разной длины, в том числе с общим префиксом
+		for _, wrong := range []string{"", "s", "secre", "secret ", "secrets", "SECRET", "другой пароль подлиннее"} {
+			assert.False(t, checkPassword(wrong), wrong)
+		}
+	})
+
+	t.Run("hash takes precedence", func(t *testing.T) {
+		usePassword(t, "secret", time.Hour).PasswordHash = string(hash)
+		assert.True(t, checkPassword("из хеша"))
+		assert.False(t, checkPassword("secret"))
+		assert.False(t, checkPassword(""))
+		assert.Equal(t, string(hash), credential())
+
+		w := doRequest(t, apiHandler, http.MethodPost, "/api/signin", Pass{Password: "из хеша"})
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp RespSign
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+
+		r := httptest.NewRequest(http.MethodPost, "/api/refresh", nil)
+		r.Header.Set("Authorization", "Bearer "+resp.Token)
+		w = httptest.NewRecorder()
+		apiHandler(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("auth disabled", func(t *testing.T) {
+		usePassword(t, "", time.Hour)
+		assert.False(t, checkPassword(""))
+	})
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/taskdate"
+)
+
+// StatsResp - статистика выполнения задач.
+type StatsResp struct {
+	Total          int    `json:"total"`            // невыполненных задач в списке
+	CompletedWeek  int    `json:"completed_week"`   // выполнений с понедельника текущей недели
+	CompletedMonth int    `json:"completed_month"`  // выполнений с 1-го числа текущего месяца
+	ByWeekday      [7]int `json:"by_weekday"`       // выполнений по дням недели за все время, с понедельника
+	Streak         *int   `json:"streak,omitempty"` // текущая серия задачи id, только с параметром id
+}
+
+// statsHandler обрабатывает GET-запрос /api/stats.
+//
+// Считает выполнения по истории отметок /api/task/done в часовом поясе
+// TODO_TIMEZONE. С параметром id счетчики выполнений относятся только к этой
+// задаче (в том числе удаленной) и добавляется её текущая серия - сколько дней
+// подряд, до сегодняшнего или вчерашнего, задача отмечалась выполненной:
+//
+//	{"total":12,"completed_week":3,"completed_month":9,"by_weekday":[2,1,0,3,1,1,1],"streak":4}
+//
+// Задача без истории, которой нет в списке, - 404.
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	var id int64
+	if param := r.URL.Query().Get("id"); param != "" {
+		var err error
+		if id, err = parseID(param); err != nil {
+			sendErr(w, r, err)
+			return
+		}
+	}
+
+	total, err := store.CountTasks(r.Context(), db.TaskFilter{})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при подсчете задач", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения статистики", http.StatusInternalServerError)
+		return
+	}
+	history, err := store.GetHistory(r.Context(), id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении истории выполнений", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения статистики", http.StatusInternalServerError)
+		return
+	}
+	if id != 0 && len(history) == 0 {
+		_, err := store.GetTaskID(r.Context(), id)
+		if errors.Is(err, db.ErrTaskNotFound) {
+			sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Ошибка при получении задачи из БД", "err", err)
+			sendAPIError(w, r, CodeDBError, "ошибка получения задачи", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	stats := completionStats(history, localNow())
+	stats.Total = total
+	if id != 0 {
+		streak := completionStreak(history, localNow())
+		stats.Streak = &streak
+	}
+	sendJSON(w, r, stats, http.StatusOK)
+}
+
+// completionStats считает выполнения из history за текущую неделю и месяц
+// и по дням недели. Дни определяются в часовом поясе now.
+func completionStats(history []db.Completion, now time.Time) StatsResp {
+	today := startOfDay(now)
+	week := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	month := today.AddDate(0, 0, 1-today.Day())
+
+	var stats StatsResp
+	for _, c := range history {
+		at := c.CompletedAt.In(now.Location())
+		if !at.Before(week) {
+			stats.CompletedWeek++
+		}
+		if !at.Before(month) {
+			stats.CompletedMonth++
+		}
+		stats.ByWeekday[(int(at.Weekday())+6)%7]++
+	}
+	return stats
+}
+
+// completionStreak возвращает число дней подряд с выполнениями в history,
+// заканчивающихся сегодня или, если сегодня выполнений еще нет, вчера.
+// Пропущенный день обрывает серию.
+func completionStreak(history []db.Completion, now time.Time) int {
+	days := make(map[string]bool, len(history))
+	for _, c := range history {
+		days[c.CompletedAt.In(now.Location()).Format(taskdate.DateFormat)] = true
+	}
+
+	day := startOfDay(now)
+	if !days[day.Format(taskdate.DateFormat)] {
+		day = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for days[day.Format(taskdate.DateFormat)] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
+// startOfDay возвращает полночь дня t в его часовом поясе.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
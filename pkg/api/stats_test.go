@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/taskdate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// completionsAt возвращает выполнения задачи 1 в полдень дней days (YYYYMMDD).
+func completionsAt(t *testing.T, days ...string) []db.Completion {
+	t.Helper()
+	var history []db.Completion
+	for _, day := range days {
+		date, err := time.Parse("20060102", day)
+		require.NoError(t, err)
+		history = append(history, db.Completion{TaskID: 1, CompletedAt: date.Add(12 * time.Hour), Date: day})
+	}
+	return history
+}
+
+func TestCompletionStats(t *testing.T) {
+	// среда, 16 июля 2025
+	now := time.Date(2025, 7, 16, 20, 0, 0, 0, time.UTC)
+	history := completionsAt(t,
+		"20250610",             // вторник, прошлый месяц
+		"20250711", "20250713", // пятница и воскресенье прошлой недели
+		"20250714", "20250714", "20250716", // понедельник дважды и среда
+	)
+
+	stats := completionStats(history, now)
+	assert.Equal(t, 3, stats.CompletedWeek)
+	assert.Equal(t, 5, stats.CompletedMonth)
+	assert.Equal(t, [7]int{2, 1, 1, 0, 1, 0, 1}, stats.ByWeekday)
+
+	// неделя начинается в понедельник и в воскресенье
+	stats = completionStats(history[:3], time.Date(2025, 7, 13, 23, 0, 0, 0, time.UTC))
+	assert.Equal(t, 2, stats.CompletedWeek)
+}
+
+func TestCompletionStreak(t *testing.T) {
+	now := time.Date(2025, 7, 16, 20, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name string
+		days []string
+		want int
+	}{
+		{"нет выполнений", nil, 0},
+		{"до сегодня", []string{"20250714", "20250715", "20250716"}, 3},
+		{"до вчера", []string{"20250713", "20250714", "20250715"}, 3},
+		{"пропущенный день", []string{"20250712", "20250713", "20250715", "20250716"}, 2},
+		{"пропущено вчера", []string{"20250713", "20250714"}, 0},
+		{"два раза за день", []string{"20250715", "20250716", "20250716"}, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, completionStreak(completionsAt(t, tc.days...), now))
+		})
+	}
+
+	// день выполнения определяется в часовом поясе now
+	msk := time.FixedZone("MSK", 3*60*60)
+	history := []db.Completion{{CompletedAt: time.Date(2025, 7, 15, 22, 0, 0, 0, time.UTC)}} // 16 июля по Москве
+	assert.Equal(t, 1, completionStreak(history, now.In(msk)))
+	assert.Equal(t, 0, completionStreak(history, time.Date(2025, 7, 17, 22, 0, 0, 0, time.UTC).In(msk)))
+}
+
+func TestStatsHandler(t *testing.T) {
+	// время отметки ставит БД, поэтому здесь все выполнения - сегодня
+	setupDB(t)
+	today := localNow().Format(taskdate.DateFormat)
+	id, err := store.AddTask(t.Context(), &db.Task{Date: today, Title: "Зарядка", Repeat: "d 1"})
+	require.NoError(t, err)
+	other, err := store.AddTask(t.Context(), &db.Task{Date: today, Title: "Отчет"})
+	require.NoError(t, err)
+	task, err := store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+
+	for _, target := range []int64{id, id, other} {
+		w := doRequest(t, apiHandler, http.MethodPost, fmt.Sprintf("/api/task/done?id=%d", target), nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// в истории дата выполнения по расписанию, а не дата отметки
+	history, err := store.GetHistory(t.Context(), id)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, task.Date, history[0].Date)
+	assert.Greater(t, history[1].Date, history[0].Date)
+
+	var weekday [7]int
+	weekday[(int(localNow().Weekday())+6)%7] = 3
+	w := doRequest(t, apiHandler, http.MethodGet, "/api/stats", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var stats StatsResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, StatsResp{Total: 1, CompletedWeek: 3, CompletedMonth: 3, ByWeekday: weekday}, stats)
+
+	w = doRequest(t, apiHandler, http.MethodGet, fmt.Sprintf("/api/stats?id=%d", id), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	resp := decodeBody(t, w)
+	assert.Equal(t, float64(2), resp["completed_week"])
+	assert.Equal(t, float64(1), resp["streak"])
+
+	// история удаленной задачи сохраняется
+	require.NoError(t, store.DeleteTaskID(t.Context(), id))
+	w = doRequest(t, apiHandler, http.MethodGet, fmt.Sprintf("/api/stats?id=%d", id), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, float64(2), decodeBody(t, w)["completed_week"])
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/stats?id=100500", nil)
+	assertAPIError(t, w, CodeNotFound)
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/stats?id=abc", nil)
+	assertAPIError(t, w, CodeBadID)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"go1f/pkg/db"
+)
+
+// TaskStore - хранилище задач, с которым работают обработчики API.
+// Реализуется *db.Store, в тестах может быть заменено подделкой.
+// Все методы принимают контекст запроса и прерываются при его отмене.
+// Методы задач работают с задачами пользователя из контекста (см. db.WithUser).
+type TaskStore interface {
+	AddTask(ctx context.Context, task *db.Task) (int64, error)
+	GetTasksPage(ctx context.Context, limit, offset int, filter db.TaskFilter) ([]*db.Task, error)
+	CountTasks(ctx context.Context, filter db.TaskFilter) (int, error)
+	CountSearchTasks(ctx context.Context, search string, filter db.TaskFilter) (int, error)
+	CountOverdue(ctx context.Context, today string) (int, error)
+	SearchTasks(ctx context.Context, search string, limit int, filter db.TaskFilter) ([]*db.Task, error)
+	SearchTasksRegex(ctx context.Context, re *regexp.Regexp, limit int, filter db.TaskFilter) ([]*db.Task, error)
+	SearchTasksFuzzy(ctx context.Context, search string, limit int, filter db.TaskFilter) ([]*db.Task, error)
+	GetTaskID(ctx context.Context, id int64) (db.Task, error)
+	TaskIDByUID(ctx context.Context, uid string) (int64, error)
+	PutTaskID(ctx context.Context, task *db.Task) error
+	DeleteTaskID(ctx context.Context, id int64) error
+	DeleteTasks(ctx context.Context, ids []string) (int, []string, error)
+	SetCompleted(ctx context.Context, id int64, completed bool) error
+	CompleteTask(ctx context.Context, id int64, nextDate func(db.Task) (string, error)) error
+	RescheduleTasks(ctx context.Context, today string, nextDate func(db.Task) (string, error)) ([]string, error)
+	GetScheduledTasks(ctx context.Context, from, to string) ([]*db.Task, error)
+	StreamTasks(ctx context.Context, fn func(*db.Task) error) error
+	ImportTasks(ctx context.Context, tasks []*db.Task) (int, error)
+	ReplaceTasks(ctx context.Context, tasks []*db.Task) (int, error)
+	GetFacets(ctx context.Context) (db.Facets, error)
+	GetChanges(ctx context.Context, since time.Time) (*db.Changes, error)
+	GetChangesSince(ctx context.Context, since int64) (*db.SeqChanges, error)
+	ChangeSeq(ctx context.Context) (int64, error)
+	TasksNeedingAttention(ctx context.Context) ([]string, error)
+	Ping(ctx context.Context) error
+	BackupToDir(ctx context.Context, dir string, now time.Time) (string, error)
+	CreateUser(ctx context.Context, login, hash string) (db.User, error)
+	UserByLogin(ctx context.Context, login string) (db.User, error)
+	UserByID(ctx context.Context, id int64) (db.User, error)
+	CreateAPIKey(ctx context.Context, hash, name string, canAdmin bool) (db.APIKey, error)
+	APIKeyByHash(ctx context.Context, hash string) (db.APIKey, error)
+	DeleteAPIKey(ctx context.Context, id string) error
+	SetCalendarToken(ctx context.Context, hash string) error
+	DeleteCalendarToken(ctx context.Context) error
+	CalendarTokenUser(ctx context.Context, hash string) (int64, error)
+	AddTemplate(ctx context.Context, tmpl *db.Template) (int64, error)
+	GetTemplates(ctx context.Context) ([]*db.Template, error)
+	GetTemplate(ctx context.Context, id int64) (db.Template, error)
+	PutTemplate(ctx context.Context, tmpl *db.Template) error
+	DeleteTemplate(ctx context.Context, id int64) error
+	GetHistory(ctx context.Context, taskID int64) ([]db.Completion, error)
+	AddWebhook(ctx context.Context, hook *db.Webhook) (int64, error)
+	GetWebhooks(ctx context.Context) ([]*db.Webhook, error)
+	PutWebhook(ctx context.Context, hook *db.Webhook) error
+	DeleteWebhook(ctx context.Context, id int64) error
+	TasksToNotify(ctx context.Context, today string) ([]*db.Task, error)
+	MarkNotified(ctx context.Context, today string, ids []string) error
+	LastRun(ctx context.Context, name string) (string, error)
+	SetLastRun(ctx context.Context, name, day string) error
+}
+
+// store - хранилище задач, переданное в Init.
+var store TaskStore
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore - хранилище задач в памяти для тестов обработчиков без SQLite.
+// Неиспользуемые методы TaskStore не реализованы и паникуют при вызове.
+type fakeStore struct {
+	TaskStore
+	tasks map[int64]db.Task
+	err   error // если задана, возвращается всеми методами
+	// beforeSave, если задана, вызывается в CompleteTask между расчетом
+	// новой даты и сохранением - имитирует параллельный запрос
+	beforeSave func()
+}
+
+func (f *fakeStore) GetTaskID(ctx context.Context, id int64) (db.Task, error) {
+	if f.err != nil {
+		return db.Task{}, f.err
+	}
+	task, ok := f.tasks[id]
+	if !ok {
+		return db.Task{}, db.ErrTaskNotFound
+	}
+	return task, nil
+}
+
+func (f *fakeStore) DeleteTaskID(ctx context.Context, id int64) error {
+	if _, err := f.GetTaskID(ctx, id); err != nil {
+		return err
+	}
+	delete(f.tasks, id)
+	return nil
+}
+
+// CompleteTask повторяет поведение db.Store.CompleteTask: сохранение
+// не выполняется, если задача пропала после чтения.
+func (f *fakeStore) CompleteTask(ctx context.Context, id int64, nextDate func(db.Task) (string, error)) error {
+	task, err := f.GetTaskID(ctx, id)
+	if err != nil {
+		return err
+	}
+	date, err := nextDate(task)
+	if err != nil {
+		return err
+	}
+	if f.beforeSave != nil {
+		f.beforeSave()
+	}
+	if _, ok := f.tasks[id]; !ok {
+		return db.ErrTaskNotFound
+	}
+	if date == "" {
+		task.Completed = true
+	} else {
+		task.Date = date
+	}
+	f.tasks[id] = task
+	return nil
+}
+
+// useStore подменяет хранилище обработчиков на время теста.
+func useStore(t *testing.T, s TaskStore) {
+	t.Helper()
+	prev := store
+	store = s
+	t.Cleanup(func() { store = prev })
+}
+
+func TestHandlersWithFakeStore(t *testing.T) {
+	fake := &fakeStore{tasks: map[int64]db.Task{
+		7: {ID: "7", Date: "20240101", Title: "Из памяти", Tags: []string{}},
+	}}
+	useStore(t, fake)
+
+	w := doRequest(t, apiHandler, http.MethodGet, "/api/task?id=7", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Из памяти", decodeBody(t, w)["title"])
+
+	w = doRequest(t, apiHandler, http.MethodDelete, "/api/task?id=7", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, fake.tasks)
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/task?id=7", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	fake.err = errors.New("диск недоступен")
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/task?id=7", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestDoneTaskStoreErrors(t *testing.T) {
+	fake := &fakeStore{tasks: map[int64]db.Task{
+		7: {ID: "7", Date: "20240101", Title: "Ежедневная", Repeat: "d 1"},
+	}}
+	useStore(t, fake)
+
+	// удаление задачи, пришедшее между чтением и сохранением
+	fake.beforeSave = func() { delete(fake.tasks, 7) }
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task/done?id=7", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, fake.tasks, "удаленная задача не должна появиться снова")
+
+	fake.beforeSave = nil
+	fake.tasks[7] = db.Task{ID: "7", Date: "20240101", Title: "Ежедневная", Repeat: "d 1"}
+	fake.err = errors.New("database is locked")
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/done?id=7", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "ошибка сохранения", decodeBody(t, w)["error"])
+
+	fake.err = nil
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/done?id=7", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotEqual(t, "20240101", fake.tasks[7].Date)
+}
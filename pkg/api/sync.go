@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go1f/pkg/db"
+)
+
+// minUnixMillis - значения since не меньше этого считаются миллисекундами,
+// меньшие - секундами (1e11 секунд - это примерно 5138 год).
+const minUnixMillis = 100_000_000_000
+
+// SyncResp - ответ разностной синхронизации.
+type SyncResp struct {
+	Changed    []*db.Task `json:"changed"`
+	Deleted    []string   `json:"deleted"`
+	ServerTime string     `json:"server_time"`
+}
+
+// syncHandler обрабатывает GET-запрос /api/sync.
+//
+// Параметры запроса:
+//   - since: момент предыдущей синхронизации, unix-время (секунды или миллисекунды)
+//     или RFC3339; без параметра возвращаются все задачи
+//
+// Возвращает задачи, созданные или измененные начиная с since, и UID удаленных задач:
+//
+//	{"changed":[{...}],"deleted":["uid"],"server_time":"2025-07-01T10:00:00.123Z"}
+//
+// Значение server_time клиент передает как since при следующей синхронизации.
+func syncHandler(w http.ResponseWriter, r *http.Request) {
+
+	since, err := parseSince(r.URL.Query().Get("since"))
+	if err != nil {
+		sendAPIError(w, r, CodeBadRequest, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changes, err := store.GetChanges(r.Context(), since)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении изменений задач", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения изменений", http.StatusInternalServerError)
+		return
+	}
+
+	resp := SyncResp{
+		Changed:    changes.Changed,
+		Deleted:    changes.Deleted,
+		ServerTime: changes.ServerTime.UTC().Format(time.RFC3339Nano),
+	}
+	if resp.Changed == nil {
+		resp.Changed = []*db.Task{}
+	}
+	if resp.Deleted == nil {
+		resp.Deleted = []string{}
+	}
+	sendJSON(w, r, resp, http.StatusOK)
+}
+
+// ChangesResp - ответ синхронизации по номерам изменений.
+type ChangesResp struct {
+	Tasks   []*db.Task `json:"tasks"`
+	Deleted []string   `json:"deleted"`
+	Cursor  string     `json:"cursor"`
+}
+
+// changesHandler обрабатывает GET-запрос /api/changes.
+//
+// Параметры запроса:
+//   - since: курсор из предыдущего ответа; без параметра или 0 возвращаются все задачи
+//
+// Возвращает задачи, созданные или измененные после since, и ID удаленных задач:
+//
+//	{"tasks":[{...}],"deleted":["12"],"cursor":"57"}
+//
+// В отличие от /api/sync курсор не зависит от часов сервера: каждое изменение
+// получает следующий номер, поэтому изменения не теряются и не повторяются.
+func changesHandler(w http.ResponseWriter, r *http.Request) {
+
+	var since int64
+	if s := r.URL.Query().Get("since"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			sendAPIError(w, r, CodeBadRequest, "параметр since указан неверно", http.StatusBadRequest)
+			return
+		}
+		since = n
+	}
+
+	changes, err := store.GetChangesSince(r.Context(), since)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении изменений задач", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения изменений", http.StatusInternalServerError)
+		return
+	}
+
+	resp := ChangesResp{
+		Tasks:   changes.Tasks,
+		Deleted: changes.Deleted,
+		Cursor:  strconv.FormatInt(changes.Cursor, 10),
+	}
+	if resp.Tasks == nil {
+		resp.Tasks = []*db.Task{}
+	}
+	if resp.Deleted == nil {
+		resp.Deleted = []string{}
+	}
+	sendJSON(w, r, resp, http.StatusOK)
+}
+
+// parseSince разбирает параметр since: unix-время в секундах или миллисекундах
+// либо время в формате RFC3339. Пустая строка означает начало времен.
+func parseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.UnixMilli(0), nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n >= minUnixMillis {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("параметр since указан неверно")
+	}
+	return t, nil
+}
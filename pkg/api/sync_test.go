@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSince(t *testing.T) {
+	want := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	for _, s := range []string{"1751364000", "1751364000000", "2025-07-01T10:00:00Z", "2025-07-01T13:00:00+03:00"} {
+		got, err := parseSince(s)
+		require.NoError(t, err, s)
+		assert.True(t, want.Equal(got), s)
+	}
+
+	_, err := parseSince("вчера")
+	assert.Error(t, err)
+}
+
+func TestSyncHandler(t *testing.T) {
+	setupDB(t)
+	first := db.Task{Date: "20250701", Title: "Первая"}
+	_, err := store.AddTask(t.Context(), &first)
+	require.NoError(t, err)
+
+	w := doRequest(t, syncHandler, http.MethodGet, "/api/sync", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp := decodeBody(t, w)
+	assert.Len(t, resp["changed"], 1)
+	assert.Equal(t, []any{}, resp["deleted"])
+	since := resp["server_time"].(string)
+
+	// Изменения видны клиенту при следующей синхронизации
+	time.Sleep(2 * time.Millisecond)
+	second := db.Task{Date: "20250702", Title: "Вторая"}
+	_, err = store.AddTask(t.Context(), &second)
+	require.NoError(t, err)
+	id, err := store.TaskIDByUID(t.Context(), first.UID)
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteTaskID(t.Context(), id))
+
+	w = doRequest(t, syncHandler, http.MethodGet, "/api/sync?since="+url.QueryEscape(since), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = decodeBody(t, w)
+	require.Len(t, resp["changed"], 1)
+	assert.Equal(t, second.UID, resp["changed"].([]any)[0].(map[string]any)["uid"])
+	assert.Equal(t, []any{first.UID}, resp["deleted"])
+
+	w = doRequest(t, syncHandler, http.MethodGet, "/api/sync?since=завтра", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestChangesHandler(t *testing.T) {
+	setupDB(t)
+	first := db.Task{Date: "20250701", Title: "Первая"}
+	firstID, err := store.AddTask(t.Context(), &first)
+	require.NoError(t, err)
+
+	w := doRequest(t, changesHandler, http.MethodGet, "/api/changes", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp := decodeBody(t, w)
+	assert.Len(t, resp["tasks"], 1)
+	assert.Equal(t, []any{}, resp["deleted"])
+	cursor := resp["cursor"].(string)
+
+	second := db.Task{Date: "20250702", Title: "Вторая"}
+	_, err = store.AddTask(t.Context(), &second)
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteTaskID(t.Context(), firstID))
+
+	w = doRequest(t, changesHandler, http.MethodGet, "/api/changes?since="+cursor, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	resp = decodeBody(t, w)
+	require.Len(t, resp["tasks"], 1)
+	assert.Equal(t, "Вторая", resp["tasks"].([]any)[0].(map[string]any)["title"])
+	assert.Equal(t, []any{strconv.FormatInt(firstID, 10)}, resp["deleted"])
+	assert.NotEqual(t, cursor, resp["cursor"])
+
+	for _, since := range []string{"вчера", "-1"} {
+		w = doRequest(t, changesHandler, http.MethodGet, "/api/changes?since="+url.QueryEscape(since), nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, since)
+	}
+}
@@ -2,309 +2,765 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go1f/pkg/config"
 	"go1f/pkg/db"
+	"go1f/pkg/metrics"
 	"go1f/pkg/taskdate"
-	"log"
+	"log/slog"
 	"net/http"
-	"sync"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
-// ErrorResponse представляет структуру для возврата ошибок в API.
-type ErrorResponse struct {
-	Error string `json:"error"`
+// CreatedTaskResp - ответ на создание задачи: задача целиком после нормализации
+// даты и числовой id, который возвращался и раньше.
+type CreatedTaskResp struct {
+	*db.Task
+	ID int64 `json:"id"`
 }
 
 // TasksResp представляет структуру для возврата списка задач в API.
 type TasksResp struct {
-	Tasks []*db.Task `json:"tasks"`
+	Tasks   []*db.Task `json:"tasks"`
+	Total   *int       `json:"total,omitempty"` // общее количество задач без учета limit, нет для regex и fuzzy
+	Count   *int       `json:"count,omitempty"` // количество задач под параметром filter без учета search и limit
+	HasMore bool       `json:"has_more"`        // есть задачи после возвращенных
 }
 
-var taskMutex sync.Mutex
-var errTask error = fmt.Errorf("ошибка Task")
-
-// taskHandler обрабатывает HTTP-запросы для работы с задачами.
-// В зависимости от метода запроса (GET, POST, PUT, DELETE) вызывает соответствующий обработчик.
-// Если метод не поддерживается, возвращает ошибку 405 Method Not Allowed.
-func taskHandler(w http.ResponseWriter, r *http.Request) {
-
-	switch r.Method {
-	case http.MethodGet:
-		handleGetTask(w, r)
-	case http.MethodPost:
-		handlePostTask(w, r)
-	case http.MethodPut:
-		handlePutTask(w, r)
-	case http.MethodDelete:
-		handleDeleteTask(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
+// Наибольшая длина полей задачи в символах.
+const (
+	maxTitleLen   = 256
+	maxCommentLen = 4096
+	maxRepeatLen  = 128 // как repeat VARCHAR(128) в схеме БД
+)
 
 // handlePostTask обрабатывает POST-запрос для создания новой задачи.
 // Принимает JSON с данными задачи в теле запроса.
-// Проверяет валидность данных, добавляет задачу в БД и возвращает ID созданной задачи.
+// Проверяет валидность данных, добавляет задачу в БД и возвращает 201 с созданной задачей,
+// в том числе датой, которую могла изменить checkTask:
+//
+//	{"id":5,"date":"20250701","title":"...","comment":"","repeat":"","uid":"..."}
+//
+// Поле id остается числом, как и раньше.
 // В случае ошибки возвращает соответствующий HTTP-статус и описание ошибки.
 func handlePostTask(w http.ResponseWriter, r *http.Request) {
 	var newTask db.Task
-
-	err := json.NewDecoder(r.Body).Decode(&newTask)
-	if err != nil {
-		log.Println("Ошибка при разборе JSON")
-		sendError(w, "Неверный формат JSON: "+err.Error(), http.StatusBadRequest)
+	if !decodeTask(w, r, &newTask) {
 		return
 	}
 
-	text, err := checkTask(&newTask)
-	if err != nil {
-		sendError(w, text, http.StatusBadRequest)
+	if err := checkTask(&newTask); err != nil {
+		sendErr(w, r, err)
 		return
 	}
 
-	taskMutex.Lock()
-	defer taskMutex.Unlock()
-	id, err := db.AddTask(&newTask)
+	id, err := store.AddTask(r.Context(), &newTask)
 	if err != nil {
-		log.Println("Ошибка при добавлении задачи в БД")
-		sendError(w, "Ошибка при добавлении задачи в БД", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Ошибка при добавлении задачи в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка при добавлении задачи в БД", http.StatusInternalServerError)
 		return
 	}
 
-	sendJSON(w, map[string]int64{"id": id}, http.StatusCreated)
+	metrics.TaskCreated()
+	notify(r.Context(), EventCreated, id, &newTask)
+	sendJSON(w, r, CreatedTaskResp{Task: &newTask, ID: id}, http.StatusCreated)
 
 }
 
 // handleGetTask обрабатывает GET-запрос для получения задачи по ID.
-// ID задачи передается в параметре запроса "id".
-// Возвращает JSON с данными задачи или ошибку, если задача не найдена.
+// ID задачи передается в параметре запроса "id" или UID в параметре "uid".
+// Возвращает JSON с данными задачи, 404 если задача не найдена или 500 при ошибке БД.
+// Ответ содержит слабый ETag из ID и версии задачи; с совпадающим
+// If-None-Match возвращается 304 без тела.
 func handleGetTask(w http.ResponseWriter, r *http.Request) {
 
-	id := r.URL.Query().Get("id")
-	if id == "" {
-		sendError(w, "id задачи не задан", http.StatusBadRequest)
+	id, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	iso, ok := isoDates(w, r)
+	if !ok {
 		return
 	}
 
-	resp, err := db.GetTaskID(id)
+	resp, err := store.GetTaskID(r.Context(), id)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		sendError(w, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusBadRequest)
+		slog.ErrorContext(r.Context(), "Ошибка при получении задачи из БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения задачи", http.StatusInternalServerError)
 		return
 	}
+	if notModified(w, r, taskETag(resp.ID, resp.Version)) {
+		return
+	}
+	if iso {
+		resp.Date = taskdate.ISODate(resp.Date)
+	}
 
-	sendJSON(w, resp, http.StatusOK)
+	sendJSON(w, r, resp, http.StatusOK)
 
 }
 
 // handlePutTask обрабатывает PUT-запрос для обновления существующей задачи.
 // Принимает JSON с обновленными данными задачи в теле запроса.
 // Проверяет валидность данных и обновляет задачу в БД.
-// Возвращает пустой ответ со статусом 200 OK или описание ошибки.
+// Вместо id в теле задачу можно указать параметром запроса "uid".
+// Поле uid в теле игнорируется: UID назначается сервером и не меняется.
+//
+// Поведение в зависимости от id:
+//   - id задан и задача найдена - задача обновляется, ответ 200 OK с новой версией
+//     задачи: {"version":3}
+//   - id задан, но задача не найдена - 404 Not Found
+//   - id не задан - 400 Bad Request с предложением использовать POST,
+//     либо при параметре upsert=1 задача создается и возвращается 201, как в POST
+//
+// Если в теле передана версия задачи (поле version из GET), задача сохраняется,
+// только если с тех пор её никто не изменил, иначе возвращается 409 с текущей
+// задачей (см. ConflictResp). Без версии задача сохраняется без проверки.
 func handlePutTask(w http.ResponseWriter, r *http.Request) {
 
 	var task db.Task
-	err := json.NewDecoder(r.Body).Decode(&task)
-	if err != nil {
-		sendError(w, "Неверный формат JSON: "+err.Error(), http.StatusBadRequest)
+	if !decodeTask(w, r, &task) {
 		return
 	}
-	mess, err := checkTask(&task)
-	if err != nil {
-		sendError(w, mess, http.StatusBadRequest)
+
+	if uid := r.URL.Query().Get("uid"); task.ID == "" && uid != "" {
+		id, err := store.TaskIDByUID(r.Context(), uid)
+		if errors.Is(err, db.ErrTaskNotFound) {
+			sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с uid =%v не найдена", uid), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			sendAPIError(w, r, CodeDBError, "ошибка поиска задачи", http.StatusInternalServerError)
+			return
+		}
+		task.ID = strconv.FormatInt(id, 10)
+	}
+
+	upsert := r.URL.Query().Get("upsert") == "1"
+	if task.ID == "" && !upsert {
+		sendAPIError(w, r, CodeBadID, "id задачи не задан, для создания задачи используйте POST", http.StatusBadRequest)
+		return
+	}
+	if task.ID != "" {
+		id, err := parseID(task.ID)
+		if err != nil {
+			sendErr(w, r, err)
+			return
+		}
+		task.ID = strconv.FormatInt(id, 10)
+	}
+
+	if err := checkTask(&task); err != nil {
+		sendErr(w, r, err)
 		return
 	}
 
-	taskMutex.Lock()
-	defer taskMutex.Unlock()
+	if task.ID == "" {
+		id, err := store.AddTask(r.Context(), &task)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Ошибка при добавлении задачи в БД", "err", err)
+			sendAPIError(w, r, CodeDBError, "Ошибка при добавлении задачи в БД", http.StatusInternalServerError)
+			return
+		}
+		metrics.TaskCreated()
+		notify(r.Context(), EventCreated, id, &task)
+		sendJSON(w, r, CreatedTaskResp{Task: &task, ID: id}, http.StatusCreated)
+		return
+	}
 
-	if err := db.PutTaskID(&task); err != nil {
-		log.Println("Ошибка при сохранении задачи в БД")
-		sendError(w, "Ошибка сохранения: "+err.Error(), http.StatusInternalServerError)
+	id, _ := strconv.ParseInt(task.ID, 10, 64) // проверен parseID выше
+	if err := store.PutTaskID(r.Context(), &task); err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) {
+			sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", task.ID), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, db.ErrVersionConflict) {
+			sendVersionConflict(w, r, id)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Ошибка при сохранении задачи в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка сохранения: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	sendJSON(w, struct{}{}, http.StatusOK)
+	notify(r.Context(), EventUpdated, id, &task)
+	sendJSON(w, r, VersionResp{Version: task.Version}, http.StatusOK)
+
+}
+
+// VersionResp - ответ на сохранение задачи: её новая версия.
+type VersionResp struct {
+	Version int64 `json:"version"`
+}
 
+// ConflictResp - ответ 409 на сохранение задачи, которую после чтения клиентом
+// изменил другой запрос: ошибка и текущая задача на сервере, чтобы клиент
+// мог объединить изменения и повторить сохранение с её версией.
+//
+//	{"error":"...","code":"conflict","task":{"id":"5","version":4,...}}
+type ConflictResp struct {
+	ErrorResponse
+	Task *db.Task `json:"task,omitempty"`
+}
+
+// sendVersionConflict отправляет 409 с текущей задачей id.
+// Если задачу успели удалить, отправляет 404.
+func sendVersionConflict(w http.ResponseWriter, r *http.Request, id int64) {
+	resp := ConflictResp{ErrorResponse: ErrorResponse{
+		Error:     "Задача изменена другим запросом, обновите её и повторите сохранение",
+		Code:      CodeConflict,
+		RequestID: config.RequestID(r.Context()),
+	}}
+	task, err := store.GetTaskID(r.Context(), id)
+	switch {
+	case errors.Is(err, db.ErrTaskNotFound):
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusNotFound)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Ошибка при получении задачи из БД после конфликта версий", "err", err)
+	default:
+		resp.Task = &task
+	}
+	sendJSON(w, r, resp, http.StatusConflict)
 }
 
 // handleDeleteTask обрабатывает DELETE-запрос для удаления задачи по ID.
-// ID задачи передается в параметре запроса "id".
-// Возвращает пустой ответ со статусом 200 OK или описание ошибки.
+// ID задачи передается в параметре запроса "id" или UID в параметре "uid".
+// Возвращает пустой ответ со статусом 200 OK, 404 если задача не найдена
+// или описание ошибки.
 func handleDeleteTask(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Query().Get("id")
-	if id == "" {
-		sendError(w, "id задачи не задан", http.StatusBadRequest)
+	id, ok := taskIDParam(w, r)
+	if !ok {
 		return
 	}
 
-	taskMutex.Lock()
-	defer taskMutex.Unlock()
-
-	err := db.DeleteTaskID(id)
+	err := store.DeleteTaskID(r.Context(), id)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		log.Println("Ошибка при удалении задачи из БД")
-		sendError(w, "ошибка удаления", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Ошибка при удалении задачи из БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка удаления", http.StatusInternalServerError)
 		return
 	}
 
-	sendJSON(w, struct{}{}, http.StatusOK)
+	notify(r.Context(), EventDeleted, id, nil)
+	sendJSON(w, r, struct{}{}, http.StatusOK)
 }
 
 // handleDoneTask обрабатывает POST-запрос для завершения задачи.
-// Для одноразовых задач - удаляет их, для повторяющихся - вычисляет следующую дату выполнения.
-// ID задачи передается в параметре запроса "id".
+// Одноразовые задачи и задачи с исчерпанным правилом (until=, count=) отмечаются
+// выполненными (completed) и пропадают из списка задач, для повторяющихся -
+// вычисляется следующая дата выполнения.
+// ID задачи передается в параметре запроса "id" или UID в параметре "uid".
 // Возвращает пустой ответ со статусом 200 OK или описание ошибки.
 func handleDoneTask(w http.ResponseWriter, r *http.Request) {
 
-	if r.Method != http.MethodPost {
-		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	id, ok := taskIDParam(w, r)
+	if !ok {
+		return
 	}
-
-	id := r.URL.Query().Get("id")
-	if id == "" {
-		sendError(w, "id задачи не задан", http.StatusBadRequest)
+	// Чтение задачи, расчет новой даты и сохранение выполняются в одной транзакции,
+	// чтобы параллельное удаление не оставило обновление "призрачной" строки
+	err := store.CompleteTask(r.Context(), id, nextDoneDate)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, taskdate.ErrAllExcluded) {
+		sendAPIError(w, r, CodeBadRepeat, msgAllExcluded, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, errNextDate) {
+		slog.ErrorContext(r.Context(), "Ошибка при пересчете даты задачи из БД", "err", err)
+		sendAPIError(w, r, CodeInternal, "ошибка при расчете новой даты", http.StatusInternalServerError)
 		return
 	}
-	task, err := db.GetTaskID(id)
 	if err != nil {
-		sendError(w, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusBadRequest)
+		slog.ErrorContext(r.Context(), "Ошибка при сохранении выполнения задачи в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка сохранения", http.StatusInternalServerError)
+		return
+	}
+	metrics.TaskCompleted()
+	notify(r.Context(), EventDone, id, nil)
+
+	sendJSON(w, r, struct{}{}, http.StatusOK)
+}
+
+// errNextDate - ошибка пересчета даты выполненной задачи по сохраненному правилу.
+var errNextDate = errors.New("ошибка при расчете новой даты")
+
+// nextDoneDate возвращает дату следующего выполнения задачи после отметки о выполнении.
+// Для разовой задачи и задачи с исчерпанным правилом (until=, count=)
+// возвращает пустую строку - задача отмечается выполненной.
+func nextDoneDate(task db.Task) (string, error) {
+	if task.Repeat == "" {
+		return "", nil
+	}
+	date, err := taskdate.NextDateExcluding(localNow(), task.Date, task.Repeat, task.Exclude)
+	if errors.Is(err, taskdate.ErrRepeatFinished) {
+		return "", nil
+	}
+	if err != nil && !errors.Is(err, taskdate.ErrAllExcluded) {
+		return "", fmt.Errorf("%w: %w", errNextDate, err)
+	}
+	return date, err
+}
+
+// handleSkipTask обрабатывает POST-запрос /api/task/skip:
+// сдвигает дату повторяющейся задачи на одно выполнение вперед, ничего не отмечая
+// выполненным. Исключенные даты пропускаются, ограничение count= уменьшается.
+// ID задачи передается в параметре запроса "id" или UID в параметре "uid".
+// Возвращает задачу с новой датой или описание ошибки:
+//   - 400: задача не повторяется, правило исчерпано или все даты исключены
+//   - 404: задача не найдена
+//   - 409: задачу изменили параллельно, в ответе текущая задача
+func handleSkipTask(w http.ResponseWriter, r *http.Request) {
+
+	id, ok := taskIDParam(w, r)
+	if !ok {
 		return
 	}
 
+	task, err := store.GetTaskID(r.Context(), id)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении задачи из БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения задачи", http.StatusInternalServerError)
+		return
+	}
 	if task.Repeat == "" {
-		// Удаляем одноразовую задачу
-		err = db.DeleteTaskID(id)
-		if err != nil {
-			log.Println("Ошибка при удалении задачи из БД")
-			sendError(w, "ошибка удаления", http.StatusInternalServerError)
+		sendAPIError(w, r, CodeBadRepeat, "Пропустить выполнение можно только у повторяющейся задачи", http.StatusBadRequest)
+		return
+	}
+
+	// следующее выполнение после текущей даты задачи
+	date, err := parseDate(task.Date)
+	if err != nil {
+		sendAPIError(w, r, CodeBadDate, "Поле Date указано неверно", http.StatusBadRequest)
+		return
+	}
+	next, err := taskdate.NextDateExcluding(date, task.Date, task.Repeat, task.Exclude)
+	switch {
+	case errors.Is(err, taskdate.ErrRepeatFinished):
+		sendAPIError(w, r, CodeBadRepeat, "Повторение задачи завершено, пропустить выполнение нельзя", http.StatusBadRequest)
+		return
+	case errors.Is(err, taskdate.ErrAllExcluded):
+		sendAPIError(w, r, CodeBadRepeat, msgAllExcluded, http.StatusBadRequest)
+		return
+	case err != nil:
+		sendAPIError(w, r, CodeBadRepeat, "Неверное правило повторения: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	task.Date = next
+	task.Repeat = taskdate.NextRepeat(task.Repeat)
+	// сохраняется с прочитанной версией, чтобы не затереть параллельное изменение
+	if err := store.PutTaskID(r.Context(), &task); err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) || errors.Is(err, db.ErrVersionConflict) {
+			sendVersionConflict(w, r, id)
 			return
 		}
-	} else {
-		// Персчитываем дату для задачи
-		newDate, err := taskdate.NextDate(time.Now(), task.Date, task.Repeat)
-		if err != nil {
-			log.Println("Ошибка при пересчете даты задачи из БД")
-			sendError(w, "ошибка при расчете новой даты", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Ошибка при сохранении задачи в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка сохранения", http.StatusInternalServerError)
+		return
+	}
+
+	notify(r.Context(), EventUpdated, id, &task)
+	sendJSON(w, r, task, http.StatusOK)
+}
+
+// Количество дней, на которое /api/task/postpone переносит задачу.
+const (
+	defaultPostponeDays = 1   // по умолчанию
+	maxPostponeDays     = 365 // не больше
+)
+
+// handlePostponeTask обрабатывает POST-запрос /api/task/postpone:
+// переносит дату задачи на days дней вперед (по умолчанию 1, не больше 365).
+// Правило повторения не меняется, поэтому /api/task/done считает следующую
+// дату уже от перенесенной. Дата переносится от даты задачи, а не от сегодня.
+// ID задачи передается в параметре запроса "id" или UID в параметре "uid".
+// Возвращает задачу с новой датой или описание ошибки:
+//   - 400: неверный параметр days
+//   - 404: задача не найдена
+//   - 409: задачу изменили параллельно, в ответе текущая задача
+func handlePostponeTask(w http.ResponseWriter, r *http.Request) {
+
+	id, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	days := defaultPostponeDays
+	if param := r.URL.Query().Get("days"); param != "" {
+		var err error
+		if days, err = strconv.Atoi(param); err != nil || days < 1 || days > maxPostponeDays {
+			sendAPIError(w, r, CodeBadRequest, fmt.Sprintf("Параметр days должен быть числом от 1 до %d", maxPostponeDays), http.StatusBadRequest)
 			return
 		}
-		// Обновляем задачу в БД
-		task.Date = newDate
-		db.PutTaskID(&task)
 	}
 
-	sendJSON(w, struct{}{}, http.StatusOK)
+	task, err := store.GetTaskID(r.Context(), id)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении задачи из БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения задачи", http.StatusInternalServerError)
+		return
+	}
+
+	date, err := parseDate(task.Date)
+	if err != nil {
+		sendAPIError(w, r, CodeBadDate, "Поле Date указано неверно", http.StatusBadRequest)
+		return
+	}
+	task.Date = date.AddDate(0, 0, days).Format(taskdate.DateFormat)
+
+	// сохраняется с прочитанной версией, чтобы не затереть параллельное изменение
+	if err := store.PutTaskID(r.Context(), &task); err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) || errors.Is(err, db.ErrVersionConflict) {
+			sendVersionConflict(w, r, id)
+			return
+		}
+		slog.ErrorContext(r.Context(), "Ошибка при сохранении задачи в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка сохранения", http.StatusInternalServerError)
+		return
+	}
+
+	notify(r.Context(), EventUpdated, id, &task)
+	sendJSON(w, r, task, http.StatusOK)
+}
+
+// TaskClone - необязательное тело запроса /api/task/clone: поля, которые
+// у копии отличаются от исходной задачи.
+type TaskClone struct {
+	Date  *string `json:"date"`
+	Title *string `json:"title"`
+}
+
+// handleCloneTask обрабатывает POST-запрос /api/task/clone: создает копию задачи
+// с заголовком, комментарием, правилом повторения, датой, приоритетом, тегами
+// и исключенными датами исходной. Дату и заголовок копии можно заменить в теле
+// запроса: {"date":"20250801","title":"..."}. Копия проверяется checkTask, как
+// новая задача, и получает свой id и UID; отметка о выполнении не копируется.
+// ID задачи передается в параметре запроса "id" или UID в параметре "uid".
+// Возвращает 201 с копией, как POST /api/task, 404 если исходная задача
+// не найдена или описание ошибки.
+func handleCloneTask(w http.ResponseWriter, r *http.Request) {
+
+	id, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+	var clone TaskClone
+	if r.ContentLength != 0 && !decodeTask(w, r, &clone) {
+		return
+	}
+
+	task, err := store.GetTaskID(r.Context(), id)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении задачи из БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения задачи", http.StatusInternalServerError)
+		return
+	}
+
+	newTask := db.Task{
+		Date:     task.Date,
+		Title:    task.Title,
+		Comment:  task.Comment,
+		Repeat:   task.Repeat,
+		Priority: task.Priority,
+		Tags:     task.Tags,
+		Exclude:  task.Exclude,
+	}
+	if clone.Date != nil {
+		newTask.Date = *clone.Date
+	}
+	if clone.Title != nil {
+		newTask.Title = *clone.Title
+	}
+	if err := checkTask(&newTask); err != nil {
+		sendErr(w, r, err)
+		return
+	}
+
+	newID, err := store.AddTask(r.Context(), &newTask)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при добавлении задачи в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка при добавлении задачи в БД", http.StatusInternalServerError)
+		return
+	}
+
+	metrics.TaskCreated()
+	notify(r.Context(), EventCreated, newID, &newTask)
+	sendJSON(w, r, CreatedTaskResp{Task: &newTask, ID: newID}, http.StatusCreated)
+}
+
+// handleUndoneTask обрабатывает POST-запрос /api/task/undone:
+// снимает с задачи отметку о выполнении, и она возвращается в список задач.
+// ID задачи передается в параметре запроса "id" или UID в параметре "uid".
+// Возвращает пустой ответ со статусом 200 OK, 404 если задача не найдена
+// или описание ошибки.
+func handleUndoneTask(w http.ResponseWriter, r *http.Request) {
+
+	id, ok := taskIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	err := store.SetCompleted(r.Context(), id, false)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с id =%v не найдена", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при снятии отметки о выполнении", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка сохранения", http.StatusInternalServerError)
+		return
+	}
+
+	notify(r.Context(), EventUpdated, id, nil)
+	sendJSON(w, r, struct{}{}, http.StatusOK)
 }
 
 // nextDayHandler обрабатывает запрос для вычисления следующей даты выполнения задачи.
 // Принимает параметры:
-//   - now (опционально) - текущая дата в формате YYYYMMDD
+//   - now (опционально) - текущая дата
 //   - date - исходная дата задачи
 //   - repeat - правило повторения
+//   - date_format (опционально) - "iso", чтобы вернуть дату в формате YYYY-MM-DD
 //
-// Возвращает новую дату в формате YYYYMMDD или описание ошибки.
+// Даты принимаются в форматах YYYYMMDD и YYYY-MM-DD.
+// Возвращает новую дату в формате YYYYMMDD или описание ошибки в JSON:
+// bad_date для неверной даты, bad_repeat для неверного правила.
 func nextDayHandler(w http.ResponseWriter, r *http.Request) {
 
-	if r.Method != http.MethodGet {
-		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-
 	var now time.Time
 	var err error
 	nowParam := r.FormValue("now")
 
 	// Если параметр не пустой парсим его
 	if nowParam != "" {
-		now, err = time.Parse(taskdate.DateFormat, nowParam)
+		now, err = parseDate(nowParam)
 		if err != nil {
-			log.Println("Ошибка с получением текущей даты")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			slog.DebugContext(r.Context(), "Неверная текущая дата в запросе", "now", nowParam, "err", err)
+			sendAPIError(w, r, CodeBadDate, err.Error(), http.StatusBadRequest)
 			return
 		}
 	} else {
 		// Если параметр пустой используем текущую дату
-		now = time.Now()
+		now = localNow()
 	}
 
-	date := r.FormValue("date")
+	date := inputDate(r.FormValue("date"))
 	repeat := r.FormValue("repeat")
 
-	date, err = taskdate.NextDate(now, date, repeat)
+	next, err := taskdate.NextDate(now, date, repeat)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		sendErr(w, r, ruleError(date, err))
+		return
+	}
+	if r.FormValue("date_format") == dateFormatISO {
+		next = taskdate.ISODate(next)
+	}
+
+	if _, err := w.Write([]byte(next)); err != nil {
+		slog.WarnContext(r.Context(), "Ошибка при записи ответа по дате", "err", err)
+	}
+}
+
+// Количество дат в ответе /api/nextdates.
+const (
+	defaultNextDates = 5  // по умолчанию
+	maxNextDates     = 50 // не больше
+)
+
+// NextDatesResp - ответ /api/nextdates.
+type NextDatesResp struct {
+	Dates []string `json:"dates"`
+}
+
+// nextDatesHandler обрабатывает запрос ближайших дат выполнения задачи,
+// например для предпросмотра правила повторения при редактировании.
+// Принимает параметры:
+//   - now (опционально) - текущая дата
+//   - date - исходная дата задачи
+//   - repeat - правило повторения
+//   - count (опционально) - количество дат, по умолчанию 5, не больше 50
+//   - date_format (опционально) - "iso", чтобы вернуть даты в формате YYYY-MM-DD
+//
+// Даты принимаются в форматах YYYYMMDD и YYYY-MM-DD.
+// Возвращает JSON вида {"dates":["20240101",...]}; для разовой задачи список пуст.
+// При неверном правиле возвращает 400 с описанием ошибки.
+func nextDatesHandler(w http.ResponseWriter, r *http.Request) {
+
+	now := localNow()
+	if nowParam := r.FormValue("now"); nowParam != "" {
+		var err error
+		if now, err = parseDate(nowParam); err != nil {
+			sendAPIError(w, r, CodeBadDate, "Неверный формат параметра now", http.StatusBadRequest)
+			return
+		}
+	}
+
+	count := defaultNextDates
+	if countParam := r.FormValue("count"); countParam != "" {
+		var err error
+		if count, err = strconv.Atoi(countParam); err != nil || count < 1 {
+			sendAPIError(w, r, CodeBadRequest, "Параметр count должен быть положительным числом", http.StatusBadRequest)
+			return
+		}
+		count = min(count, maxNextDates)
+	}
+
+	iso, ok := isoDates(w, r)
+	if !ok {
 		return
 	}
 
-	if _, err := w.Write([]byte(date)); err != nil {
-		log.Printf("Ошибка при записи ответа по дате: %v \n", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	date := inputDate(r.FormValue("date"))
+	dates, err := taskdate.NextDates(now, date, r.FormValue("repeat"), count)
+	if err != nil {
+		sendErr(w, r, ruleError(date, err))
+		return
+	}
+	if iso {
+		for i, date := range dates {
+			dates[i] = taskdate.ISODate(date)
+		}
 	}
+
+	sendJSON(w, r, NextDatesResp{Dates: dates}, http.StatusOK)
 }
 
 // sendJSON отправляет ответ в формате JSON с указанным HTTP-статусом.
 // Принимает:
 //   - w - ResponseWriter для записи ответа
+//   - r - запрос, на который отправляется ответ
 //   - resp - данные для сериализации в JSON
 //   - status - HTTP-статус ответа
 //
-// В случае ошибки сериализации отправляет ошибку 500 Internal Server Error.
-func sendJSON(w http.ResponseWriter, resp any, status int) {
+// Ответ сериализуется до отправки статуса, поэтому в случае ошибки сериализации
+// вместо него уходит ошибка 500 (internal_error), а не второй ответ после первого.
+// Ошибки сериализации и записи пишутся в журнал с ID запроса.
+func sendJSON(w http.ResponseWriter, r *http.Request, resp any, status int) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(resp); err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при формировании JSON", "err", err)
+		body.Reset()
+		json.NewEncoder(&body).Encode(ErrorResponse{
+			Error:     "ошибка формирования ответа",
+			Code:      CodeInternal,
+			RequestID: config.RequestID(r.Context()),
+		})
+		status = http.StatusInternalServerError
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Println("Ошибка при формировании JSON")
-		sendError(w, fmt.Sprintf("Error encoding JSON: %v", err), http.StatusInternalServerError)
+	if _, err := w.Write(body.Bytes()); err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при отправке ответа", "err", err)
 	}
 }
 
-// sendError отправляет ошибку в формате JSON с указанным HTTP-статусом.
-// Принимает:
-//   - w - ResponseWriter для записи ответа
-//   - message - текст сообщения об ошибке
-//   - statusCode - HTTP-статус ошибки
-func sendError(w http.ResponseWriter, message string, statusCode int) {
-	response := ErrorResponse{
-		Error: message,
-	}
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(response)
-}
-
 // checkTask проверяет валидность данных задачи.
 // Проверяет:
 //   - наличие заголовка (Title)
 //   - корректность формата даты
 //   - актуальность даты (при необходимости вычисляет следующую дату по правилу повторения)
 //
-// Возвращает текст ошибки и nil, если проверка прошла успешно,
-// или текст ошибки и errTask, если найдены ошибки.
+// Возвращает nil, если проверка прошла успешно, или ошибку API с кодом
+// (title_required, bad_field, bad_date, bad_repeat), если найдены ошибки.
 // Может модифицировать дату задачи для приведения к корректному значению.
-func checkTask(t *db.Task) (string, error) {
+func checkTask(t *db.Task) error {
 
 	// Проверка на пустоту заголовка
+	t.Title = strings.TrimSpace(t.Title)
 	if t.Title == "" {
-		return "Поле Title не должно быть пустым", errTask
+		return newError(CodeTitleRequired, "Поле Title не должно быть пустым")
+	}
+
+	// Проверка длины текстовых полей
+	for _, field := range []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"Title", t.Title, maxTitleLen},
+		{"Comment", t.Comment, maxCommentLen},
+		{"Repeat", t.Repeat, maxRepeatLen},
+	} {
+		if utf8.RuneCountInString(field.value) > field.max {
+			return newError(CodeBadField, "Поле %s должно быть не длиннее %d символов", field.name, field.max)
+		}
+	}
+
+	// Проверка диапазона приоритета
+	if t.Priority < minPriority || t.Priority > maxPriority {
+		return newError(CodeBadField, "Поле Priority должно быть от %d до %d", minPriority, maxPriority)
+	}
+
+	// Нормализация тегов
+	t.Tags = normalizeTags(t.Tags)
+	if len(t.Tags) > maxTags {
+		return newError(CodeBadField, "У задачи может быть не больше %d тегов", maxTags)
 	}
 
-	now := time.Now()
+	// Нормализация исключенных дат
+	exclude, err := normalizeExclude(t.Exclude)
+	if err != nil {
+		return err
+	}
+	t.Exclude = exclude
+
+	// Правило проверяется всегда, а не только когда нужно вычислить дату
+	if err := taskdate.ValidateRepeat(t.Repeat); err != nil {
+		return newError(CodeBadRepeat, "Неверное правило повторения: %v", err)
+	}
+
+	now := localNow()
 	today := now.Format(taskdate.DateFormat)
 
 	// Обработка пустой даты
 	if t.Date == "" {
 		t.Date = today
-		return "", nil
 	}
 
-	// Парсинг даты
-	_, err := time.Parse(taskdate.DateFormat, t.Date)
+	// Парсинг даты: кроме YYYYMMDD принимается YYYY-MM-DD
+	t.Date, err = taskdate.NormalizeDate(t.Date)
 	if err != nil {
-		return "Поле Date указано неверно", errTask
+		return newError(CodeBadDate, "Поле Date указано неверно")
+	}
+
+	if taskdate.RepeatEnded(t.Date, t.Repeat) {
+		return newError(CodeBadRepeat, "Повторение задачи уже завершено: дата until раньше даты задачи")
 	}
 
 	// Если дата в будущем или сегодняшнаяя - оставляем без изменений
 	if t.Date >= today {
-		return "", nil
+		return nil
 	}
 
 	if t.Repeat == "" {
@@ -312,11 +768,185 @@ func checkTask(t *db.Task) (string, error) {
 		t.Date = today
 	} else {
 		// С правилом - вычисляем следующую доступную дату
-		next, err := taskdate.NextDate(now, t.Date, t.Repeat)
+		next, err := taskdate.NextDateExcluding(now, t.Date, t.Repeat, t.Exclude)
+		if errors.Is(err, taskdate.ErrRepeatFinished) {
+			return newError(CodeBadRepeat, "Повторение задачи уже завершено")
+		}
+		if errors.Is(err, taskdate.ErrAllExcluded) {
+			return newError(CodeBadRepeat, msgAllExcluded)
+		}
 		if err != nil {
-			return "Неверное правило повторения: " + err.Error(), errTask
+			return newError(CodeBadRepeat, "Неверное правило повторения: %v", err)
 		}
 		t.Date = next
 	}
-	return "", nil
+	return nil
+}
+
+// msgAllExcluded - ошибка правила, все даты которого исключены.
+const msgAllExcluded = "Все даты повторения задачи исключены"
+
+// normalizeExclude проверяет исключенные даты задачи, убирает пробелы
+// и дубликаты и сортирует даты по возрастанию.
+func normalizeExclude(exclude []string) ([]string, error) {
+	if len(exclude) > maxExclude {
+		return nil, newError(CodeBadField, "У задачи может быть не больше %d исключенных дат", maxExclude)
+	}
+	result := make([]string, 0, len(exclude))
+	for _, date := range exclude {
+		date = strings.TrimSpace(date)
+		if _, err := time.Parse(taskdate.DateFormat, date); err != nil {
+			return nil, newError(CodeBadDate, "Поле Exclude: неверная дата %q", date)
+		}
+		result = append(result, date)
+	}
+	slices.Sort(result)
+	return slices.Compact(result), nil
+}
+
+// normalizeTags обрезает пробелы, приводит теги к нижнему регистру
+// и убирает пустые значения и дубликаты, сохраняя порядок.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// decodeTask читает задачу или её изменения из JSON-тела запроса в v.
+// Тело ограничено TODO_MAX_BODY_KB, неизвестные поля не допускаются, чтобы
+// опечатка в имени поля не терялась молча.
+// При ошибке сам отправляет ответ и возвращает false:
+//   - 400: неверный JSON или неизвестное поле (с его именем)
+//   - 413: тело больше ограничения (или TODO_MAX_BODY_BYTES, если оно меньше)
+func decodeTask(w http.ResponseWriter, r *http.Request, v any) bool {
+	limit := conf().MaxBodySize
+	if limit <= 0 {
+		limit = config.DefaultMaxBodyKB << 10
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		return true
+	}
+
+	// encoding/json не экспортирует ошибку неизвестного поля
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		sendAPIError(w, r, CodeUnknownField, "Неизвестное поле "+field, http.StatusBadRequest)
+		return false
+	}
+	sendDecodeError(w, r, err)
+	return false
+}
+
+// parseID разбирает ID задачи из строки параметра запроса или тела.
+// ID задачи - положительное целое число; для остальных значений возвращает
+// ошибку API с кодом bad_id.
+func parseID(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, newError(CodeBadID, "неверный id задачи %q: ожидается целое число", s)
+	}
+	if id <= 0 {
+		return 0, newError(CodeBadID, "неверный id задачи %v: ожидается положительное число", id)
+	}
+	return id, nil
+}
+
+// taskIDParam возвращает ID задачи из параметра запроса "id" или,
+// если он не задан, находит ID по параметру "uid".
+// При ошибке сам отправляет ответ и возвращает false:
+//   - 400: не задан ни id, ни uid, или id не положительное целое число (см. parseID)
+//   - 404: задача с таким uid не найдена
+//   - 500: ошибка БД
+func taskIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	if param := r.URL.Query().Get("id"); param != "" {
+		id, err := parseID(param)
+		if err != nil {
+			sendErr(w, r, err)
+			return 0, false
+		}
+		return id, true
+	}
+
+	uid := r.URL.Query().Get("uid")
+	if uid == "" {
+		sendAPIError(w, r, CodeBadID, "id задачи не задан", http.StatusBadRequest)
+		return 0, false
+	}
+
+	id, err := store.TaskIDByUID(r.Context(), uid)
+	if errors.Is(err, db.ErrTaskNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("задача с uid =%v не найдена", uid), http.StatusNotFound)
+		return 0, false
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при поиске задачи по uid", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка поиска задачи", http.StatusInternalServerError)
+		return 0, false
+	}
+	return id, true
+}
+
+// localNow возвращает текущее время в часовом поясе из TODO_TIMEZONE,
+// чтобы "сегодня" совпадало с календарем пользователя, а не сервера.
+func localNow() time.Time {
+	return clock().In(conf().TimeZone())
+}
+
+// parseDate разбирает дату в одном из форматов taskdate.NormalizeDate
+// в часовом поясе из TODO_TIMEZONE.
+func parseDate(s string) (time.Time, error) {
+	date, err := taskdate.NormalizeDate(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(taskdate.DateFormat, date, conf().TimeZone())
+}
+
+// Значения параметра date_format.
+const (
+	dateFormatDefault = ""    // даты YYYYMMDD, как хранятся
+	dateFormatISO     = "iso" // даты YYYY-MM-DD
+)
+
+// isoDates разбирает параметр запроса date_format и сообщает, нужно ли
+// вернуть даты в формате ISO (YYYY-MM-DD). При неизвестном значении сам
+// отправляет 400 и возвращает false вторым результатом.
+func isoDates(w http.ResponseWriter, r *http.Request) (iso bool, ok bool) {
+	switch format := r.URL.Query().Get("date_format"); format {
+	case dateFormatDefault:
+		return false, true
+	case dateFormatISO:
+		return true, true
+	default:
+		sendAPIError(w, r, CodeBadRequest, fmt.Sprintf("неизвестный формат дат %q, ожидается iso", format), http.StatusBadRequest)
+		return false, false
+	}
+}
+
+// ruleError возвращает ошибку API для ошибки расчета дат по правилу:
+// bad_date, если дата задачи не разбирается, иначе bad_repeat.
+func ruleError(date string, err error) error {
+	if _, dateErr := taskdate.NormalizeDate(date); dateErr != nil {
+		return newError(CodeBadDate, "%v", dateErr)
+	}
+	return newError(CodeBadRepeat, "%v", err)
+}
+
+// inputDate приводит дату из параметра запроса к формату YYYYMMDD.
+// Неразобранная строка возвращается как есть, чтобы ошибку сообщила проверка правила.
+func inputDate(s string) string {
+	if date, err := taskdate.NormalizeDate(s); err == nil {
+		return date
+	}
+	return s
 }
@@ -0,0 +1,833 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go1f/pkg/config"
+	"go1f/pkg/db"
+	"go1f/pkg/taskdate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupDB создает БД в памяти для теста, передает её обработчикам,
+// возвращает её и закрывает по завершении.
+func setupDB(t *testing.T) *db.Store {
+	t.Helper()
+	s := db.OpenForTest(t)
+	store = s
+	return s
+}
+
+// useConf подменяет конфигурацию приложения копией действующей до конца
+// теста и возвращает копию, чтобы тест изменил нужные настройки.
+func useConf(t *testing.T) *config.Config {
+	t.Helper()
+	prev := conf()
+	c := *prev
+	current.Store(&c)
+	t.Cleanup(func() { current.Store(prev) })
+	return &c
+}
+
+// setupDBFile открывает для теста БД в файле path, например чтобы проверить
+// работу нескольких соединений.
+func setupDBFile(t *testing.T, path string) {
+	t.Helper()
+	s, err := db.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	store = s
+}
+
+// mustID возвращает ID задачи числом из строки или числа JSON.
+func mustID(t *testing.T, v any) int64 {
+	t.Helper()
+	id, err := parseID(fmt.Sprint(v))
+	require.NoError(t, err)
+	return id
+}
+
+// doRequest выполняет запрос к обработчику и возвращает записанный ответ.
+func doRequest(t *testing.T, h http.HandlerFunc, method, target string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(method, target, &buf))
+	return w
+}
+
+// apiHandler передает запрос маршрутизатору API.
+func apiHandler(w http.ResponseWriter, r *http.Request) {
+	routes().ServeHTTP(w, r)
+}
+
+// decodeBody разбирает JSON-ответ в map.
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &m))
+	return m
+}
+
+func TestPutTask(t *testing.T) {
+	setupDB(t)
+	today := time.Now().Format(taskdate.DateFormat)
+	id, err := store.AddTask(t.Context(), &db.Task{Date: today, Title: "Исходная"})
+	require.NoError(t, err)
+
+	t.Run("empty id", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPut, "/api/task", map[string]any{"title": "Новая"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, decodeBody(t, w), "error")
+	})
+
+	t.Run("empty id with upsert", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPut, "/api/task?upsert=1", map[string]any{"title": "Новая"})
+		require.Equal(t, http.StatusCreated, w.Code)
+		newID := decodeBody(t, w)["id"]
+		require.NotNil(t, newID)
+
+		task, err := store.GetTaskID(t.Context(), mustID(t, newID))
+		require.NoError(t, err)
+		assert.Equal(t, "Новая", task.Title)
+		assert.Equal(t, today, task.Date)
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		for _, target := range []string{"/api/task", "/api/task?upsert=1"} {
+			w := doRequest(t, apiHandler, http.MethodPut, target, map[string]any{"id": "100500", "title": "Новая"})
+			assert.Equal(t, http.StatusNotFound, w.Code, target)
+		}
+	})
+
+	t.Run("valid id", func(t *testing.T) {
+		w := doRequest(t, apiHandler, http.MethodPut, "/api/task",
+			map[string]any{"id": fmt.Sprint(id), "title": "Обновленная", "date": today})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		task, err := store.GetTaskID(t.Context(), id)
+		require.NoError(t, err)
+		assert.Equal(t, "Обновленная", task.Title)
+	})
+}
+
+func TestTaskByUID(t *testing.T) {
+	setupDB(t)
+	task := db.Task{Date: time.Now().Format(taskdate.DateFormat), Title: "С uid"}
+	_, err := store.AddTask(t.Context(), &task)
+	require.NoError(t, err)
+	require.NotEmpty(t, task.UID)
+
+	w := doRequest(t, apiHandler, http.MethodGet, "/api/task?uid="+task.UID, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, task.UID, decodeBody(t, w)["uid"])
+
+	// uid из тела игнорируется, задача ищется по параметру запроса
+	w = doRequest(t, apiHandler, http.MethodPut, "/api/task?uid="+task.UID,
+		map[string]any{"title": "Обновлена", "date": task.Date, "uid": "подмена"})
+	require.Equal(t, http.StatusOK, w.Code)
+	id, err := store.TaskIDByUID(t.Context(), task.UID)
+	require.NoError(t, err)
+	updated, err := store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "Обновлена", updated.Title)
+	assert.Equal(t, task.UID, updated.UID)
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/task?uid=unknown", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(t, apiHandler, http.MethodDelete, "/api/task?uid="+task.UID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, err = store.TaskIDByUID(t.Context(), task.UID)
+	assert.ErrorIs(t, err, db.ErrTaskNotFound)
+}
+
+func TestPostTaskReturnsTask(t *testing.T) {
+	setupDB(t)
+	today := time.Now().Format(taskdate.DateFormat)
+
+	// дата в прошлом без правила заменяется на сегодняшнюю
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task",
+		map[string]any{"date": "20200101", "title": "Созданная", "comment": "текст"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	resp := decodeBody(t, w)
+
+	id, ok := resp["id"].(float64)
+	require.True(t, ok, "id должен остаться числом")
+	assert.Equal(t, today, resp["date"])
+	assert.Equal(t, "Созданная", resp["title"])
+	assert.Equal(t, "текст", resp["comment"])
+	assert.NotEmpty(t, resp["uid"])
+
+	task, err := store.GetTaskID(t.Context(), mustID(t, id))
+	require.NoError(t, err)
+	assert.Equal(t, resp["uid"], task.UID)
+}
+
+func TestTaskDatesInTimeZone(t *testing.T) {
+	setupDB(t)
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	useConf(t).Location = ny
+	// 23:30 9 марта в Нью-Йорке, в UTC уже 10 марта
+	now := time.Date(2025, 3, 10, 3, 30, 0, 0, time.UTC)
+	useClock(t, &now)
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task",
+		map[string]any{"title": "Сегодня", "repeat": "d 1"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	resp := decodeBody(t, w)
+	assert.Equal(t, "20250309", resp["date"])
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/nextdate?date=20250308&repeat=d+1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "20250310", w.Body.String())
+
+	w = doRequest(t, handleDoneTask, http.MethodPost, fmt.Sprintf("/api/task/done?id=%v", resp["id"]), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	task, err := store.GetTaskID(t.Context(), mustID(t, resp["id"]))
+	require.NoError(t, err)
+	assert.Equal(t, "20250310", task.Date)
+}
+
+func TestInvalidRepeat(t *testing.T) {
+	setupDB(t)
+	future := time.Now().AddDate(1, 0, 0).Format(taskdate.DateFormat)
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task",
+		map[string]any{"date": future, "title": "Задача", "repeat": "d 7"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	id := fmt.Sprint(decodeBody(t, w)["id"])
+
+	// правило проверяется и для дат в будущем, и для пустой даты
+	for _, tc := range []struct {
+		method, target string
+		body           map[string]any
+	}{
+		{http.MethodPost, "/api/task", map[string]any{"date": future, "title": "Задача", "repeat": "q 5"}},
+		{http.MethodPost, "/api/task", map[string]any{"title": "Задача", "repeat": "d 9999"}},
+		{http.MethodPut, "/api/task", map[string]any{"id": id, "date": future, "title": "Задача", "repeat": "w 0"}},
+		{http.MethodPatch, "/api/task?id=" + id, map[string]any{"repeat": "m 0"}},
+	} {
+		w := doRequest(t, apiHandler, tc.method, tc.target, tc.body)
+		require.Equal(t, http.StatusBadRequest, w.Code, tc.body["repeat"])
+		assert.Contains(t, decodeBody(t, w)["error"], "Неверное правило повторения", tc.body["repeat"])
+	}
+
+	task, err := store.GetTaskID(t.Context(), mustID(t, id))
+	require.NoError(t, err)
+	assert.Equal(t, "d 7", task.Repeat)
+}
+
+func TestTaskNotFound(t *testing.T) {
+	s := setupDB(t)
+	today := time.Now().Format(taskdate.DateFormat)
+	id, err := store.AddTask(t.Context(), &db.Task{Date: today, Title: "Есть"})
+	require.NoError(t, err)
+	found := fmt.Sprintf("/api/task?id=%d", id)
+	missing := "/api/task?id=100500"
+	body := map[string]any{"id": "100500", "date": today, "title": "Нет"}
+
+	tbl := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		body    any
+		want    int
+	}{
+		{"get found", apiHandler, http.MethodGet, found, nil, http.StatusOK},
+		{"get missing", apiHandler, http.MethodGet, missing, nil, http.StatusNotFound},
+		{"put missing", apiHandler, http.MethodPut, "/api/task", body, http.StatusNotFound},
+		{"done missing", handleDoneTask, http.MethodPost, "/api/task/done?id=100500", nil, http.StatusNotFound},
+		{"delete missing", apiHandler, http.MethodDelete, missing, nil, http.StatusNotFound},
+		{"delete found", apiHandler, http.MethodDelete, found, nil, http.StatusOK},
+		{"delete twice", apiHandler, http.MethodDelete, found, nil, http.StatusNotFound},
+	}
+	for _, v := range tbl {
+		w := doRequest(t, v.handler, v.method, v.target, v.body)
+		assert.Equal(t, v.want, w.Code, v.name)
+		if v.want == http.StatusNotFound {
+			assert.Contains(t, decodeBody(t, w), "error", v.name)
+		}
+	}
+
+	// Ошибка БД остается ошибкой сервера, а не 404
+	require.NoError(t, s.Close())
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		w := doRequest(t, apiHandler, method, missing, nil)
+		assert.Equal(t, http.StatusInternalServerError, w.Code, method)
+	}
+	w := doRequest(t, apiHandler, http.MethodPut, "/api/task", body)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestTaskBodyValidation(t *testing.T) {
+	setupDB(t)
+	today := time.Now().Format(taskdate.DateFormat)
+	id, err := store.AddTask(t.Context(), &db.Task{Date: today, Title: "Есть"})
+	require.NoError(t, err)
+
+	// опечатка в имени поля - ошибка с именем поля, а не пустой заголовок
+	for _, req := range []struct {
+		method, target string
+		body           map[string]any
+	}{
+		{http.MethodPost, "/api/task", map[string]any{"date": today, "titel": "Опечатка"}},
+		{http.MethodPut, "/api/task", map[string]any{"id": fmt.Sprint(id), "date": today, "titel": "Опечатка"}},
+		{http.MethodPatch, fmt.Sprintf("/api/task?id=%d", id), map[string]any{"titel": "Опечатка"}},
+	} {
+		w := doRequest(t, apiHandler, req.method, req.target, req.body)
+		require.Equal(t, http.StatusBadRequest, w.Code, req.method)
+		assert.Equal(t, `Неизвестное поле "titel"`, decodeBody(t, w)["error"], req.method)
+	}
+
+	// тело больше TODO_MAX_BODY_KB
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task",
+		map[string]any{"date": today, "title": "Большая", "comment": strings.Repeat("x", config.DefaultMaxBodyKB<<10)})
+	require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, decodeBody(t, w)["error"], "64 КБ")
+
+	// длина считается в символах, заголовок обрезается от пробелов
+	title := strings.Repeat("я", maxTitleLen)
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "  " + title + "\n"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, title, decodeBody(t, w)["title"])
+
+	for _, body := range []map[string]any{
+		{"title": title + "я"},
+		{"title": "Задача", "comment": strings.Repeat("ж", maxCommentLen+1)},
+		{"title": "Задача", "repeat": "d 1" + strings.Repeat(" ", maxRepeatLen)},
+	} {
+		body["date"] = today
+		w := doRequest(t, apiHandler, http.MethodPost, "/api/task", body)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, decodeBody(t, w)["error"], "должно быть не длиннее")
+	}
+
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "   "})
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Поле Title не должно быть пустым", decodeBody(t, w)["error"])
+}
+
+func TestISODates(t *testing.T) {
+	setupDB(t)
+
+	// даты принимаются в двух форматах и хранятся как YYYYMMDD
+	for _, in := range []string{"20990601", "2099-06-01"} {
+		w := doRequest(t, apiHandler, http.MethodPost, "/api/task", map[string]any{"date": in, "title": "Задача " + in})
+		require.Equal(t, http.StatusCreated, w.Code, in)
+		resp := decodeBody(t, w)
+		assert.Equal(t, "20990601", resp["date"], in)
+		task, err := store.GetTaskID(t.Context(), mustID(t, resp["id"]))
+		require.NoError(t, err)
+		assert.Equal(t, "20990601", task.Date, in)
+	}
+	for _, in := range []string{"2099-6-1", "2099/06/01", "2099-02-30", "01.06.2099"} {
+		w := doRequest(t, apiHandler, http.MethodPost, "/api/task", map[string]any{"date": in, "title": "Плохая"})
+		require.Equal(t, http.StatusBadRequest, w.Code, in)
+		assert.Equal(t, "Поле Date указано неверно", decodeBody(t, w)["error"], in)
+	}
+
+	// по умолчанию ответы в YYYYMMDD, с date_format=iso - в YYYY-MM-DD
+	w := doRequest(t, apiHandler, http.MethodGet, "/api/tasks?limit=1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list TasksResp
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	require.Len(t, list.Tasks, 1)
+	assert.Equal(t, "20990601", list.Tasks[0].Date)
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/tasks?limit=1&date_format=iso", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	assert.Equal(t, "2099-06-01", list.Tasks[0].Date)
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/task?id="+list.Tasks[0].ID+"&date_format=iso", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2099-06-01", decodeBody(t, w)["date"])
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/tasks?date_format=unix", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// следующая дата
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/nextdate?now=2024-01-26&date=2024-01-20&repeat=d+7", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "20240127", w.Body.String())
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/nextdate?now=20240126&date=20240120&repeat=d+7&date_format=iso", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "2024-01-27", w.Body.String())
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/nextdates?now=2024-01-26&date=2024-01-20&repeat=d+7&count=2&date_format=iso", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []any{"2024-01-27", "2024-02-03"}, decodeBody(t, w)["dates"])
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/nextdate?now=2024-01-26&date=2024-01-40&repeat=d+7", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMalformedTaskID(t *testing.T) {
+	// до БД неверный id не доходит: хранилище ответило бы 500
+	useStore(t, &fakeStore{err: errors.New("хранилище не должно вызываться")})
+	today := time.Now().Format(taskdate.DateFormat)
+
+	for _, tc := range []struct {
+		id   string
+		want string
+	}{
+		{"abc", "ожидается целое число"},
+		{"1.5", "ожидается целое число"},
+		{"99999999999999999999", "ожидается целое число"},
+		{"0", "ожидается положительное число"},
+		{"-5", "ожидается положительное число"},
+	} {
+		query := "?id=" + url.QueryEscape(tc.id)
+		for _, req := range []struct {
+			method, target string
+			body           any
+		}{
+			{http.MethodGet, "/api/task" + query, nil},
+			{http.MethodDelete, "/api/task" + query, nil},
+			{http.MethodPatch, "/api/task" + query, map[string]any{"title": "Новая"}},
+			{http.MethodPost, "/api/task/done" + query, nil},
+			{http.MethodPost, "/api/task/undone" + query, nil},
+			{http.MethodPost, "/api/task/skip" + query, nil},
+			{http.MethodPost, "/api/task/postpone" + query, nil},
+			{http.MethodPost, "/api/task/clone" + query, nil},
+			{http.MethodPut, "/api/task", map[string]any{"id": tc.id, "date": today, "title": "Новая"}},
+			{http.MethodPost, "/api/tasks/delete", map[string]any{"ids": []string{tc.id}}},
+		} {
+			w := doRequest(t, apiHandler, req.method, req.target, req.body)
+			name := req.method + " " + req.target + " " + tc.id
+			require.Equal(t, http.StatusBadRequest, w.Code, name)
+			assert.Contains(t, decodeBody(t, w)["error"], tc.want, name)
+		}
+	}
+}
+
+func TestDoneUndone(t *testing.T) {
+	setupDB(t)
+	useConf(t).LimitTask = 50
+	today := time.Now().Format(taskdate.DateFormat)
+	id, err := store.AddTask(t.Context(), &db.Task{Date: today, Title: "Разовая"})
+	require.NoError(t, err)
+	_, err = store.AddTask(t.Context(), &db.Task{Date: today, Title: "Повторяющаяся", Repeat: "d 1"})
+	require.NoError(t, err)
+	target := fmt.Sprintf("?id=%d", id)
+
+	listTitles := func(query string) []string {
+		w := doRequest(t, tasksHandler, http.MethodGet, "/api/tasks"+query, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		titles := []string{}
+		for _, task := range decodeBody(t, w)["tasks"].([]any) {
+			titles = append(titles, task.(map[string]any)["title"].(string))
+		}
+		return titles
+	}
+
+	w := doRequest(t, handleDoneTask, http.MethodPost, "/api/task/done"+target, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// выполненная задача не удаляется, но пропадает из списка
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/task"+target, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, true, decodeBody(t, w)["completed"])
+	assert.Equal(t, []string{"Повторяющаяся"}, listTitles(""))
+	assert.Equal(t, []string{"Разовая"}, listTitles("?completed=true"))
+	assert.Equal(t, []string{"Разовая"}, listTitles("?completed=true&search=Разовая"))
+	assert.Empty(t, listTitles("?search=Разовая"))
+
+	w = doRequest(t, handleUndoneTask, http.MethodPost, "/api/task/undone"+target, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.ElementsMatch(t, []string{"Разовая", "Повторяющаяся"}, listTitles(""))
+	assert.Empty(t, listTitles("?completed=true"))
+
+	w = doRequest(t, handleUndoneTask, http.MethodPost, "/api/task/undone?id=100500", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(t, tasksHandler, http.MethodGet, "/api/tasks?completed=может", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestNextDates(t *testing.T) {
+	w := doRequest(t, apiHandler, http.MethodGet, "/api/nextdates?now=20250115&date=20250110&repeat=d+7&count=3", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp NextDatesResp
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Equal(t, []string{"20250117", "20250124", "20250131"}, resp.Dates)
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/nextdates?now=20250115&date=20250110&repeat=d+1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.Dates, defaultNextDates)
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/nextdates?now=20250115&date=20250110&repeat=d+1&count=1000", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	assert.Len(t, resp.Dates, maxNextDates)
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/nextdate?now=20240101&date=20240101&repeat=mw+-1+5+2", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "20240223", w.Body.String())
+
+	for _, query := range []string{"date=20250110&repeat=m+31+2", "date=20250110&repeat=q", "date=20250110&repeat=d+1&count=0", "date=bad&repeat=y"} {
+		w := doRequest(t, apiHandler, http.MethodGet, "/api/nextdates?"+query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.NotEmpty(t, decodeBody(t, w)["error"], query)
+	}
+}
+
+func TestDoneLimitedRepeat(t *testing.T) {
+	setupDB(t)
+	today := time.Now()
+
+	id, err := store.AddTask(t.Context(), &db.Task{Date: today.Format(taskdate.DateFormat), Title: "Таблетки", Repeat: "d 1 count=2"})
+	require.NoError(t, err)
+	target := fmt.Sprintf("/api/task/done?id=%d", id)
+
+	// первое выполнение: дата сдвигается, счетчик уменьшается
+	w := doRequest(t, apiHandler, http.MethodPost, target, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	task, err := store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, today.AddDate(0, 0, 1).Format(taskdate.DateFormat), task.Date)
+	assert.Equal(t, "d 1 count=1", task.Repeat)
+	assert.False(t, task.Completed)
+
+	// последнее выполнение: задача выполнена, как разовая
+	w = doRequest(t, apiHandler, http.MethodPost, target, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	task, err = store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	assert.True(t, task.Completed)
+
+	// until= на сегодня: выполнение сегодня последнее
+	until := "d 1 until=" + today.Format(taskdate.DateFormat)
+	id, err = store.AddTask(t.Context(), &db.Task{Date: today.Format(taskdate.DateFormat), Title: "До сегодня", Repeat: until})
+	require.NoError(t, err)
+	w = doRequest(t, apiHandler, http.MethodPost, fmt.Sprintf("/api/task/done?id=%d", id), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	task, err = store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	assert.True(t, task.Completed)
+}
+
+func TestCreateExpiredRepeat(t *testing.T) {
+	setupDB(t)
+	future := time.Now().AddDate(0, 1, 0).Format(taskdate.DateFormat)
+
+	for _, body := range []map[string]any{
+		{"date": "20200101", "title": "Истекла", "repeat": "d 1 until=20200201"},
+		{"date": future, "title": "До начала", "repeat": "d 1 until=20200201"},
+		{"title": "Сегодня", "repeat": "w 1 until=20200201"},
+		{"date": "20200101", "title": "Одна", "repeat": "d 1 count=1"},
+	} {
+		w := doRequest(t, apiHandler, http.MethodPost, "/api/task", body)
+		require.Equal(t, http.StatusBadRequest, w.Code, body["repeat"])
+		assert.Contains(t, decodeBody(t, w)["error"], "завершено", body["repeat"])
+	}
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task",
+		map[string]any{"date": future, "title": "Две недели", "repeat": "d 1 count=14"})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}
+
+func TestExcludeAndSkip(t *testing.T) {
+	setupDB(t)
+	monday := weekStartAfter(time.Now())
+	next := monday.AddDate(0, 0, 7).Format(taskdate.DateFormat)
+	holiday := monday.AddDate(0, 0, 14).Format(taskdate.DateFormat)
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task", map[string]any{
+		"date": monday.Format(taskdate.DateFormat), "title": "Планерка", "repeat": "w 1",
+		"exclude": []string{" " + holiday, holiday},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	id := fmt.Sprint(decodeBody(t, w)["id"])
+	task, err := store.GetTaskID(t.Context(), mustID(t, id))
+	require.NoError(t, err)
+	assert.Equal(t, []string{holiday}, task.Exclude)
+
+	// пропуск сдвигает дату на одно выполнение, праздник пропускается
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/skip?id="+id, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, next, decodeBody(t, w)["date"])
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/skip?id="+id, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, monday.AddDate(0, 0, 21).Format(taskdate.DateFormat), decodeBody(t, w)["date"])
+	task, err = store.GetTaskID(t.Context(), mustID(t, id))
+	require.NoError(t, err)
+	assert.False(t, task.Completed)
+
+	// выполнение тоже пропускает исключенные даты
+	w = doRequest(t, apiHandler, http.MethodPatch, "/api/task?id="+id, map[string]any{
+		"date": monday.Format(taskdate.DateFormat), "exclude": []string{next},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/done?id="+id, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	task, err = store.GetTaskID(t.Context(), mustID(t, id))
+	require.NoError(t, err)
+	assert.Equal(t, holiday, task.Date)
+
+	once, err := store.AddTask(t.Context(), &db.Task{Date: next, Title: "Разовая"})
+	require.NoError(t, err)
+	w = doRequest(t, apiHandler, http.MethodPost, fmt.Sprintf("/api/task/skip?id=%d", once), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/skip?id=100500", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task", map[string]any{
+		"title": "Плохая", "repeat": "d 1", "exclude": []string{"2025-01-01"},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// weekStartAfter возвращает ближайший понедельник после t.
+func weekStartAfter(t time.Time) time.Time {
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1)
+	for date.Weekday() != time.Monday {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// writeLoad выполняет workers горутин, каждая из которых perWorker раз
+// создает, изменяет и удаляет задачу через API. Если lock задан, каждый запрос
+// выполняется под ним, как при прежней глобальной блокировке taskMutex.
+// Возвращает описания неуспешных ответов.
+func writeLoad(tb testing.TB, workers, perWorker int, lock sync.Locker) []string {
+	tb.Helper()
+	send := func(method, target string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		json.NewEncoder(&buf).Encode(body)
+		w := httptest.NewRecorder()
+		if lock != nil {
+			lock.Lock()
+			defer lock.Unlock()
+		}
+		apiHandler(w, httptest.NewRequest(method, target, &buf))
+		return w
+	}
+
+	var mu sync.Mutex
+	var failures []string
+	fail := func(step string, w *httptest.ResponseRecorder) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, fmt.Sprintf("%s: %d %s", step, w.Code, w.Body.String()))
+	}
+
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				title := fmt.Sprintf("Задача %d-%d", worker, i)
+				w := send(http.MethodPost, "/api/task", map[string]any{"date": "20300101", "title": title})
+				if w.Code != http.StatusCreated {
+					fail("POST", w)
+					continue
+				}
+				var created CreatedTaskResp
+				json.NewDecoder(w.Body).Decode(&created)
+				id := strconv.FormatInt(created.ID, 10)
+
+				w = send(http.MethodPut, "/api/task", map[string]any{"id": id, "date": "20300102", "title": title + " (изменена)"})
+				if w.Code != http.StatusOK {
+					fail("PUT", w)
+				}
+				w = send(http.MethodDelete, "/api/task?id="+id, nil)
+				if w.Code != http.StatusOK {
+					fail("DELETE", w)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return failures
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	setupDBFile(t, filepath.Join(t.TempDir(), "scheduler.db"))
+	const workers, perWorker = 8, 20
+
+	start := time.Now()
+	assert.Empty(t, writeLoad(t, workers, perWorker, &sync.Mutex{}))
+	serial := time.Since(start)
+
+	start = time.Now()
+	failures := writeLoad(t, workers, perWorker, nil)
+	parallel := time.Since(start)
+	assert.Empty(t, failures, "параллельная запись без блокировки не должна получать database is locked")
+
+	// Время зависит от диска и планировщика, поэтому сравнение только в журнале
+	// теста; устойчивое сравнение - BenchmarkWrites.
+	t.Logf("%d запросов: под мьютексом %v, параллельно %v", 3*workers*perWorker, serial, parallel)
+}
+
+func BenchmarkWrites(b *testing.B) {
+	s, err := db.Open(filepath.Join(b.TempDir(), "scheduler.db"))
+	require.NoError(b, err)
+	b.Cleanup(func() { s.Close() })
+	store = s
+
+	b.Run("mutex", func(b *testing.B) {
+		lock := &sync.Mutex{}
+		for b.Loop() {
+			writeLoad(b, 8, 5, lock)
+		}
+	})
+	b.Run("concurrent", func(b *testing.B) {
+		for b.Loop() {
+			writeLoad(b, 8, 5, nil)
+		}
+	})
+}
+
+func TestPutTaskVersionConflict(t *testing.T) {
+	setupDB(t)
+	id, err := store.AddTask(t.Context(), &db.Task{Date: "20990101", Title: "Исходная"})
+	require.NoError(t, err)
+	target := fmt.Sprintf("/api/task?id=%d", id)
+	put := func(title string, version int64) *httptest.ResponseRecorder {
+		return doRequest(t, apiHandler, http.MethodPut, "/api/task",
+			map[string]any{"id": fmt.Sprint(id), "date": "20990101", "title": title, "version": version})
+	}
+
+	// обе вкладки прочитали задачу версии 1
+	w := doRequest(t, apiHandler, http.MethodGet, target, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(1), decodeBody(t, w)["version"])
+
+	w = put("Из первой вкладки", 1)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, map[string]any{"version": float64(2)}, decodeBody(t, w))
+
+	// вторая вкладка получает 409 и текущую задачу
+	w = put("Из второй вкладки", 1)
+	assertAPIError(t, w, CodeConflict)
+	current := decodeBody(t, w)["task"].(map[string]any)
+	assert.Equal(t, "Из первой вкладки", current["title"])
+	assert.Equal(t, float64(2), current["version"])
+
+	// после объединения сохраняется с новой версией
+	w = put("Из второй вкладки", 2)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// без версии - как раньше, без проверки
+	w = doRequest(t, apiHandler, http.MethodPut, "/api/task",
+		map[string]any{"id": fmt.Sprint(id), "date": "20990101", "title": "Без версии"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// PATCH с устаревшей версией тоже отклоняется
+	w = doRequest(t, apiHandler, http.MethodPatch, target, map[string]any{"comment": "x", "version": 1})
+	assertAPIError(t, w, CodeConflict)
+	task, err := store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "Без версии", task.Title)
+	assert.Empty(t, task.Comment)
+}
+
+func TestPostponeTask(t *testing.T) {
+	setupDB(t)
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	useClock(t, &now)
+	id, err := store.AddTask(t.Context(), &db.Task{Date: "20250701", Title: "Полить цветы", Repeat: "d 7"})
+	require.NoError(t, err)
+	target := fmt.Sprintf("/api/task/postpone?id=%d", id)
+
+	// по умолчанию на один день, правило не меняется
+	w := doRequest(t, apiHandler, http.MethodPost, target, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	resp := decodeBody(t, w)
+	assert.Equal(t, "20250702", resp["date"])
+	assert.Equal(t, "d 7", resp["repeat"])
+
+	w = doRequest(t, apiHandler, http.MethodPost, target+"&days=3", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "20250705", decodeBody(t, w)["date"])
+
+	// выполнение считает следующую дату от перенесенной
+	w = doRequest(t, apiHandler, http.MethodPost, fmt.Sprintf("/api/task/done?id=%d", id), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	task, err := store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "20250712", task.Date)
+	assert.Equal(t, "d 7", task.Repeat)
+
+	for _, days := range []string{"0", "-1", "366", "три"} {
+		w = doRequest(t, apiHandler, http.MethodPost, target+"&days="+days, nil)
+		assertAPIError(t, w, CodeBadRequest)
+	}
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/postpone?id=100500", nil)
+	assertAPIError(t, w, CodeNotFound)
+	task, err = store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "20250712", task.Date)
+}
+
+func TestCloneTask(t *testing.T) {
+	setupDB(t)
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	useClock(t, &now)
+	original := db.Task{Date: "20250705", Title: "Оплатить интернет", Comment: "кв. 12",
+		Repeat: "m 5", Priority: 2, Tags: []string{"дом"}}
+	id, err := store.AddTask(t.Context(), &original)
+	require.NoError(t, err)
+	require.NoError(t, store.SetCompleted(t.Context(), id, true))
+	before, err := store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	target := fmt.Sprintf("/api/task/clone?id=%d", id)
+
+	w := doRequest(t, apiHandler, http.MethodPost, target, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	resp := decodeBody(t, w)
+	cloneID := int64(resp["id"].(float64))
+	assert.NotEqual(t, id, cloneID)
+	clone, err := store.GetTaskID(t.Context(), cloneID)
+	require.NoError(t, err)
+	assert.Equal(t, "20250705", clone.Date)
+	assert.Equal(t, "Оплатить интернет", clone.Title)
+	assert.Equal(t, "кв. 12", clone.Comment)
+	assert.Equal(t, "m 5", clone.Repeat)
+	assert.Equal(t, 2, clone.Priority)
+	assert.Equal(t, []string{"дом"}, clone.Tags)
+	assert.False(t, clone.Completed)
+	assert.NotEqual(t, before.UID, clone.UID)
+
+	// поля из тела заменяют поля копии и проверяются как при создании
+	w = doRequest(t, apiHandler, http.MethodPost, target, map[string]any{"title": "Оплатить интернет, кв. 7", "date": "2025-08-05"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	resp = decodeBody(t, w)
+	assert.Equal(t, "Оплатить интернет, кв. 7", resp["title"])
+	assert.Equal(t, "20250805", resp["date"])
+
+	w = doRequest(t, apiHandler, http.MethodPost, target, map[string]any{"title": " "})
+	assertAPIError(t, w, CodeTitleRequired)
+	w = doRequest(t, apiHandler, http.MethodPost, target, map[string]any{"date": "2025"})
+	assertAPIError(t, w, CodeBadDate)
+	w = doRequest(t, apiHandler, http.MethodPost, target, map[string]any{"comment": "x"})
+	assertAPIError(t, w, CodeUnknownField)
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/clone?id=100500", nil)
+	assertAPIError(t, w, CodeNotFound)
+
+	// исходная задача не меняется
+	after, err := store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
@@ -2,62 +2,332 @@
 package api
 
 import (
+	"fmt"
 	"go1f/pkg/db"
-	"log"
+	"log/slog"
 	"net/http"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"go1f/pkg/config"
+	"go1f/pkg/taskdate"
 )
 
+// Параметры поиска по регулярному выражению.
+const (
+	searchModeRegex = "regex" // значение параметра mode для поиска по регулярному выражению
+	maxRegexLen     = 256     // максимальная длина регулярного выражения в символах
+	maxRegexInst    = 5000    // максимальный размер скомпилированного выражения
+)
+
+// Допустимый диапазон приоритета задачи.
+const (
+	minPriority = 0
+	maxPriority = 3
+)
+
+// maxTags - максимальное количество тегов у одной задачи.
+const maxTags = 16
+
+// maxExclude - максимальное количество исключенных дат у одной задачи.
+const maxExclude = 366
+
 // tasksHandler обрабатывает HTTP-запросы для работы с задачами.
 // Поддерживает только GET-запросы.
 // Параметры запроса:
 //   - search: строка для поиска задач по контексту или дате (необязательный)
+//   - mode: режим поиска (необязательный), "regex" - поиск по регулярному выражению
+//   - fuzzy: "1" - поиск с учетом опечаток (необязательный, несовместим с mode)
+//   - limit: максимальное количество задач (необязательный), по умолчанию TODO_LIMIT_TASKS,
+//     не больше TODO_MAX_LIMIT
+//   - offset: сколько задач пропустить (необязательный, только без search)
+//   - completed: "true" - вернуть выполненные задачи вместо невыполненных (необязательный)
+//   - priority: вернуть только задачи с этим приоритетом от 0 до 3 (необязательный)
+//   - tag: вернуть только задачи с этим тегом (необязательный)
+//   - sort: порядок задач (необязательный, несовместим с fuzzy): "date" (по умолчанию),
+//     "-date", "title", "-title", "id", "-id", "priority" - по приоритету, затем по дате,
+//     "relevance" - сначала лучше подходящие под search (только для поиска по словам).
+//     Без sort и список, и поиск упорядочены по дате от ранних к поздним
+//   - filter: "overdue" - только просроченные задачи (дата раньше сегодняшней),
+//     "today" - только задачи на сегодня (необязательный). "Сегодня" считается
+//     в часовом поясе TODO_TIMEZONE. Совместим с search
+//   - date_format: "iso" - вернуть даты задач в формате YYYY-MM-DD вместо YYYYMMDD (необязательный)
+//
+// В поле total возвращается общее количество задач в списке или найденных
+// поиском по словам или дате (для regex и fuzzy не считается), в поле has_more -
+// есть ли задачи после возвращенных. С параметром filter в поле count
+// возвращается количество всех задач под этим фильтром, без учета search и limit.
+//
+// В режиме regex строка search компилируется как регулярное выражение и проверяется
+// по title и comment. Этот режим не использует быстрый поиск через LIKE и может быть медленнее.
+//
+// В нечетком режиме находятся задачи, слова которых отличаются от слов запроса
+// на одну-две опечатки. Результаты упорядочены по близости к запросу, затем по дате.
 //
-// Если параметр search не указан, возвращает список задач с ограничением по количеству,
-// которое задается переменной окружения TODO_LIMIT_TASKS (по умолчанию 50).
+// Ответ содержит слабый ETag (см. listETag). Если задачи не менялись, на запрос
+// с этим ETag в If-None-Match возвращается 304 без тела - клиенту, который
+// опрашивает список, не нужно скачивать его заново.
 //
 // В случае ошибки возвращает соответствующий HTTP-статус и сообщение об ошибке.
 func tasksHandler(w http.ResponseWriter, r *http.Request) {
 
-	if r.Method != http.MethodGet {
-		log.Println("Ошибка метода запроса")
-		sendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	searchQuery := r.URL.Query().Get("search")
+	mode := r.URL.Query().Get("mode")
+	fuzzy := r.URL.Query().Get("fuzzy") == "1"
+
+	limit, err := parseLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		sendAPIError(w, r, CodeBadRequest, err.Error(), http.StatusBadRequest)
+		return
+	}
+	offset, err := parseOffset(r.URL.Query().Get("offset"))
+	if err != nil {
+		sendAPIError(w, r, CodeBadRequest, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var filter db.TaskFilter
+	if completed := r.URL.Query().Get("completed"); completed != "" {
+		if filter.Completed, err = strconv.ParseBool(completed); err != nil {
+			sendAPIError(w, r, CodeBadRequest, "параметр completed должен быть true или false", http.StatusBadRequest)
+			return
+		}
+	}
+	if priority := r.URL.Query().Get("priority"); priority != "" {
+		if filter.Priority, err = parsePriority(priority); err != nil {
+			sendAPIError(w, r, CodeBadRequest, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	filter.Tag = strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag")))
+	if filter.Sort = r.URL.Query().Get("sort"); !db.ValidSort(filter.Sort) {
+		sendAPIError(w, r, CodeBadRequest, fmt.Sprintf("неизвестный порядок сортировки %q", filter.Sort), http.StatusBadRequest)
+		return
+	}
+	switch filter.Due = r.URL.Query().Get("filter"); filter.Due {
+	case db.DueAny:
+	case db.DueOverdue, db.DueToday:
+		filter.Today = localNow().Format(taskdate.DateFormat)
+	default:
+		sendAPIError(w, r, CodeBadRequest, fmt.Sprintf("неизвестный фильтр %q", filter.Due), http.StatusBadRequest)
+		return
+	}
+	if filter.Due == db.DueOverdue && filter.Completed {
+		sendAPIError(w, r, CodeBadRequest, "выполненные задачи не бывают просроченными", http.StatusBadRequest)
+		return
+	}
+	iso, ok := isoDates(w, r)
+	if !ok {
+		return
+	}
+	// номер изменения читается до задач: если задачи изменятся между
+	// запросами к БД, следующий запрос получит новый ETag и новый список
+	seq, err := store.ChangeSeq(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении номера изменения задач", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения задач", http.StatusInternalServerError)
+		return
 	}
 
-	searchQuery := r.URL.Query().Get("search")
+	// send отправляет найденные задачи, а с параметром filter - и количество
+	// всех задач под фильтром. Если список не изменился с ответа, ETag
+	// которого клиент прислал в If-None-Match, отправляется 304 без тела.
+	send := func(tasks []*db.Task, total *int, hasMore bool) {
+		count := len(tasks)
+		if total != nil {
+			count = *total
+		}
+		if notModified(w, r, listETag(seq, count, db.UserID(r.Context()), filter.Today, limit)) {
+			return
+		}
+		if iso {
+			for _, task := range tasks {
+				task.Date = taskdate.ISODate(task.Date)
+			}
+		}
+		resp := TasksResp{Tasks: tasks, Total: total, HasMore: hasMore}
+		if filter.Due != db.DueAny && searchQuery == "" {
+			resp.Count = total // в списке без поиска total уже посчитан по фильтру
+		} else if filter.Due != db.DueAny {
+			n, err := store.CountTasks(r.Context(), filter)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "Ошибка при подсчете задач в БД", "err", err)
+				sendAPIError(w, r, CodeDBError, "ошибка получения задач", http.StatusInternalServerError)
+				return
+			}
+			resp.Count = &n
+		}
+		sendResponse(w, r, resp)
+	}
 
-	if searchQuery == "" {
-		// просто n задач
-		tasks, err := db.GetTasks(config.App.LimitTask)
+	switch {
+	case mode != "" && mode != searchModeRegex:
+		sendAPIError(w, r, CodeBadRequest, fmt.Sprintf("неизвестный режим поиска %q", mode), http.StatusBadRequest)
+	case fuzzy && mode != "":
+		sendAPIError(w, r, CodeBadRequest, "нечеткий поиск нельзя совмещать с параметром mode", http.StatusBadRequest)
+	case fuzzy && filter.Sort != db.SortDefault:
+		sendAPIError(w, r, CodeBadRequest, "результаты нечеткого поиска упорядочены по близости, параметр sort не поддерживается", http.StatusBadRequest)
+	case filter.Sort == db.SortRelevance && (searchQuery == "" || mode != ""):
+		sendAPIError(w, r, CodeBadRequest, "сортировка по релевантности есть только у поиска по словам", http.StatusBadRequest)
+	case searchQuery == "":
+		// страница из n задач
+		tasks, err := store.GetTasksPage(r.Context(), limit, offset, filter)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Ошибка при получении задачи из БД", "err", err)
+			sendAPIError(w, r, CodeDBError, "ошибка получения задач", http.StatusInternalServerError)
+			return
+		}
+		total, err := store.CountTasks(r.Context(), filter)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Ошибка при подсчете задач в БД", "err", err)
+			sendAPIError(w, r, CodeDBError, "ошибка получения задач", http.StatusInternalServerError)
+			return
+		}
+		send(tasks, &total, offset+len(tasks) < total)
+	case offset > 0:
+		sendAPIError(w, r, CodeBadRequest, "параметр offset нельзя совмещать с поиском", http.StatusBadRequest)
+	case mode == searchModeRegex:
+		// n задач, подходящих под регулярное выражение
+		re, err := compileSearchRegex(searchQuery)
 		if err != nil {
-			log.Println("Ошибка при получении задачи из БД")
-			sendError(w, "ошибка получения задач", http.StatusInternalServerError)
+			sendAPIError(w, r, CodeBadRequest, "Неверное регулярное выражение: "+err.Error(), http.StatusBadRequest)
 			return
 		}
-		sendResponse(w, tasks)
-	} else {
+		// общее количество потребовало бы проверить все задачи, поэтому
+		// о следующих задачах узнаем по одной лишней
+		tasks, err := store.SearchTasksRegex(r.Context(), re, limit+1, filter)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Ошибка с поиском по регулярному выражению", "err", err)
+			sendAPIError(w, r, CodeDBError, "ошибка поиска задач", http.StatusInternalServerError)
+			return
+		}
+		tasks, hasMore := cutExtra(tasks, limit)
+		send(tasks, nil, hasMore)
+	case fuzzy:
+		// n задач, похожих на запрос с учетом опечаток
+		tasks, err := store.SearchTasksFuzzy(r.Context(), searchQuery, limit+1, filter)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Ошибка с нечетким поиском задач", "err", err)
+			sendAPIError(w, r, CodeDBError, "ошибка поиска задач", http.StatusInternalServerError)
+			return
+		}
+		tasks, hasMore := cutExtra(tasks, limit)
+		send(tasks, nil, hasMore)
+	default:
 		// n задач в которых есть определенные слова или даты
-		tasks, err := db.SearchTasks(searchQuery, config.App.LimitTask)
+		tasks, err := store.SearchTasks(r.Context(), searchQuery, limit, filter)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Ошибка с поиском контекста в задачах", "err", err)
+			sendAPIError(w, r, CodeDBError, "ошибка поиска задач", http.StatusInternalServerError)
+			return
+		}
+		total, err := store.CountSearchTasks(r.Context(), searchQuery, filter)
 		if err != nil {
-			log.Println("Ошибка с поиском контекста в задачах")
-			sendError(w, "ошибка поиска задач", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Ошибка при подсчете найденных задач", "err", err)
+			sendAPIError(w, r, CodeDBError, "ошибка поиска задач", http.StatusInternalServerError)
 			return
 		}
-		sendResponse(w, tasks)
+		send(tasks, &total, len(tasks) < total)
+	}
+}
+
+// compileSearchRegex компилирует регулярное выражение для поиска задач.
+// Ограничивает длину выражения maxRegexLen символами и размер
+// скомпилированной программы maxRegexInst инструкциями.
+func compileSearchRegex(pattern string) (*regexp.Regexp, error) {
+	if utf8.RuneCountInString(pattern) > maxRegexLen {
+		return nil, fmt.Errorf("длина выражения больше %d символов", maxRegexLen)
+	}
+
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	if len(prog.Inst) > maxRegexInst {
+		return nil, fmt.Errorf("выражение слишком сложное")
+	}
+
+	return regexp.Compile(pattern)
+}
+
+// parseLimit разбирает параметр limit.
+// Пустое значение означает TODO_LIMIT_TASKS, значение больше TODO_MAX_LIMIT
+// уменьшается до него.
+func parseLimit(s string) (int, error) {
+	c := conf()
+	maxLimit := c.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = config.DefaultMaxLimit
+	}
+	if s == "" {
+		return min(c.LimitTask, maxLimit), nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("параметр limit должен быть неотрицательным числом")
+	}
+	return min(limit, maxLimit), nil
+}
+
+// parsePriority разбирает параметр priority - число от minPriority до maxPriority.
+func parsePriority(s string) (*int, error) {
+	priority, err := strconv.Atoi(s)
+	if err != nil || priority < minPriority || priority > maxPriority {
+		return nil, fmt.Errorf("параметр priority должен быть числом от %d до %d", minPriority, maxPriority)
+	}
+	return &priority, nil
+}
+
+// parseOffset разбирает параметр offset. Пустое значение означает 0.
+func parseOffset(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(s)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("параметр offset должен быть неотрицательным числом")
+	}
+	return offset, nil
+}
+
+// cutExtra отбрасывает задачи сверх limit. Если их запросить на одну больше,
+// второй результат сообщает, есть ли задачи после возвращенных.
+func cutExtra(tasks []*db.Task, limit int) ([]*db.Task, bool) {
+	if len(tasks) > limit {
+		return tasks[:limit], true
 	}
+	return tasks, false
 }
 
-// sendResponse формирует и отправляет JSON-ответ со списком задач.
-// Если tasks равен nil, возвращает пустой массив задач.
-func sendResponse(w http.ResponseWriter, tasks []*db.Task) {
-	if tasks == nil {
-		tasks = []*db.Task{}
+// sendResponse отправляет JSON-ответ со списком задач.
+// Если resp.Tasks равен nil, возвращает пустой массив задач.
+func sendResponse(w http.ResponseWriter, r *http.Request, resp TasksResp) {
+	if resp.Tasks == nil {
+		resp.Tasks = []*db.Task{}
 	}
+	sendJSON(w, r, resp, http.StatusOK)
+}
+
+// facetsHandler обрабатывает GET-запрос /api/tasks/facets.
+// Возвращает количество задач по каждому фильтруемому измерению в формате:
+//
+//	{"repeat":{"d":3,"none":120},"priority":{"0":100,"3":23},"tag":{"work":5},...}
+//
+// Используется веб-интерфейсом для построения выпадающих списков фильтров.
+func facetsHandler(w http.ResponseWriter, r *http.Request) {
 
-	resp := TasksResp{
-		Tasks: tasks,
+	facets, err := store.GetFacets(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при подсчете фильтров задач", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения фильтров", http.StatusInternalServerError)
+		return
 	}
 
-	sendJSON(w, resp, http.StatusOK)
+	sendJSON(w, r, facets, http.StatusOK)
 }
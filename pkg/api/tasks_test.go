@@ -0,0 +1,361 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTasksRegexSearch(t *testing.T) {
+	setupDB(t)
+	useConf(t).LimitTask = 50
+	for _, title := range []string{"PROJ-1 сборка", "Ревью PROJ-2"} {
+		_, err := store.AddTask(t.Context(), &db.Task{Date: "20240101", Title: title})
+		require.NoError(t, err)
+	}
+
+	w := doRequest(t, tasksHandler, http.MethodGet,
+		"/api/tasks?mode=regex&search="+url.QueryEscape(`^PROJ-\d+`), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	tasks := decodeBody(t, w)["tasks"].([]any)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "PROJ-1 сборка", tasks[0].(map[string]any)["title"])
+
+	w = doRequest(t, tasksHandler, http.MethodGet,
+		"/api/tasks?mode=regex&search="+url.QueryEscape(`PROJ-(\d+`), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, decodeBody(t, w)["error"], "missing closing )")
+
+	w = doRequest(t, tasksHandler, http.MethodGet, "/api/tasks?mode=glob&search=x", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTasksFuzzySearch(t *testing.T) {
+	setupDB(t)
+	useConf(t).LimitTask = 50
+	_, err := store.AddTask(t.Context(), &db.Task{Date: "20240101", Title: "Купить пылесос"})
+	require.NoError(t, err)
+
+	w := doRequest(t, tasksHandler, http.MethodGet, "/api/tasks?fuzzy=1&search="+url.QueryEscape("пылсос"), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeBody(t, w)["tasks"], 1)
+
+	w = doRequest(t, tasksHandler, http.MethodGet, "/api/tasks?search="+url.QueryEscape("пылсос"), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, decodeBody(t, w)["tasks"])
+
+	w = doRequest(t, tasksHandler, http.MethodGet, "/api/tasks?fuzzy=1&mode=regex&search=x", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTasksSearchLiteralPercent(t *testing.T) {
+	setupDB(t)
+	useConf(t).LimitTask = 50
+	for _, title := range []string{"Скидка 100%", "Скидка 1000 рублей", "Без скидки"} {
+		_, err := store.AddTask(t.Context(), &db.Task{Date: "20240101", Title: title})
+		require.NoError(t, err)
+	}
+
+	for search, want := range map[string]int{"100%": 1, "%": 1, "_": 0} {
+		w := doRequest(t, tasksHandler, http.MethodGet, "/api/tasks?search="+url.QueryEscape(search), nil)
+		require.Equal(t, http.StatusOK, w.Code, search)
+		assert.Len(t, decodeBody(t, w)["tasks"], want, search)
+	}
+}
+
+func TestTasksDueFilter(t *testing.T) {
+	setupDB(t)
+	c := useConf(t)
+	c.LimitTask = 2
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	c.Location = ny
+	// 23:30 9 марта в Нью-Йорке, в UTC уже 10 марта
+	now := time.Date(2025, 3, 10, 3, 30, 0, 0, time.UTC)
+	useClock(t, &now)
+
+	for _, task := range []db.Task{
+		{Date: "20250301", Title: "Давно просрочена"},
+		{Date: "20250305", Title: "Ежедневная отчетность", Repeat: "d 1"},
+		{Date: "20250308", Title: "Вчерашний отчет"},
+		{Date: "20250309", Title: "Сегодняшний отчет"},
+		{Date: "20250309", Title: "Сегодня погулять"},
+		{Date: "20250310", Title: "Завтра"},
+	} {
+		_, err := store.AddTask(t.Context(), &task)
+		require.NoError(t, err)
+	}
+	done, err := store.AddTask(t.Context(), &db.Task{Date: "20250302", Title: "Выполненный отчет"})
+	require.NoError(t, err)
+	require.NoError(t, store.SetCompleted(t.Context(), done, true))
+
+	tbl := []struct {
+		target string
+		want   []string
+		count  float64
+	}{
+		// limit применяется к отфильтрованным задачам, count - число всех под фильтром
+		{"/api/tasks?filter=overdue", []string{"Давно просрочена", "Ежедневная отчетность"}, 3},
+		{"/api/tasks?filter=overdue&limit=5", []string{"Давно просрочена", "Ежедневная отчетность", "Вчерашний отчет"}, 3},
+		{"/api/tasks?filter=today", []string{"Сегодняшний отчет", "Сегодня погулять"}, 2},
+		{"/api/tasks?filter=today&completed=true", []string{}, 0},
+		{"/api/tasks?filter=overdue&search=" + url.QueryEscape("отчет"), []string{"Вчерашний отчет", "Ежедневная отчетность"}, 3},
+		{"/api/tasks?filter=today&search=" + url.QueryEscape("отчет"), []string{"Сегодняшний отчет"}, 2},
+		{"/api/tasks?filter=today&search=09.03.2025", []string{"Сегодняшний отчет", "Сегодня погулять"}, 2},
+		{"/api/tasks?filter=overdue&fuzzy=1&search=" + url.QueryEscape("отчот"), []string{"Вчерашний отчет"}, 3},
+	}
+	for _, v := range tbl {
+		w := doRequest(t, tasksHandler, http.MethodGet, v.target, nil)
+		require.Equal(t, http.StatusOK, w.Code, v.target)
+		resp := decodeBody(t, w)
+		got := []string{}
+		for _, task := range resp["tasks"].([]any) {
+			got = append(got, task.(map[string]any)["title"].(string))
+		}
+		assert.ElementsMatch(t, v.want, got, v.target)
+		assert.Equal(t, v.count, resp["count"], v.target)
+	}
+
+	w := doRequest(t, tasksHandler, http.MethodGet, "/api/tasks", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, decodeBody(t, w), "count", "без filter поля count нет")
+
+	for _, target := range []string{"/api/tasks?filter=week", "/api/tasks?filter=overdue&completed=true"} {
+		w := doRequest(t, tasksHandler, http.MethodGet, target, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func TestTasksPagination(t *testing.T) {
+	setupDB(t)
+	c := useConf(t)
+	c.LimitTask = 2
+	c.MaxLimit = 3
+	for _, date := range []string{"20240101", "20240102", "20240103", "20240104", "20240105"} {
+		_, err := store.AddTask(t.Context(), &db.Task{Date: date, Title: "Задача " + date})
+		require.NoError(t, err)
+	}
+
+	tbl := []struct {
+		target string
+		want   []string
+	}{
+		{"/api/tasks", []string{"20240101", "20240102"}},
+		{"/api/tasks?limit=1&offset=2", []string{"20240103"}},
+		{"/api/tasks?offset=4", []string{"20240105"}},
+		{"/api/tasks?offset=10", []string{}},
+		// limit больше максимального уменьшается до TODO_MAX_LIMIT
+		{"/api/tasks?limit=3", []string{"20240101", "20240102", "20240103"}},
+		{"/api/tasks?limit=4", []string{"20240101", "20240102", "20240103"}},
+	}
+	for _, v := range tbl {
+		w := doRequest(t, tasksHandler, http.MethodGet, v.target, nil)
+		require.Equal(t, http.StatusOK, w.Code, v.target)
+		resp := decodeBody(t, w)
+		dates := []string{}
+		for _, task := range resp["tasks"].([]any) {
+			dates = append(dates, task.(map[string]any)["date"].(string))
+		}
+		assert.Equal(t, v.want, dates, v.target)
+		assert.EqualValues(t, 5, resp["total"], v.target)
+	}
+
+	for _, target := range []string{
+		"/api/tasks?limit=abc",
+		"/api/tasks?limit=-1",
+		"/api/tasks?offset=-1",
+		"/api/tasks?offset=x",
+		"/api/tasks?search=Задача&offset=1",
+	} {
+		w := doRequest(t, tasksHandler, http.MethodGet, target, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+		assert.Contains(t, decodeBody(t, w), "error", target)
+	}
+}
+
+func TestTasksSort(t *testing.T) {
+	setupDB(t)
+	useConf(t).LimitTask = 50
+	for _, task := range []db.Task{
+		{Date: "20240103", Title: "Отчёт по проекту"},
+		{Date: "20240101", Title: "арбуз купить"},
+		{Date: "20240102", Title: "Ёлка", Comment: "отчет не забыть"},
+		{Date: "20240102", Title: "Баня"},
+	} {
+		_, err := store.AddTask(t.Context(), &task)
+		require.NoError(t, err)
+	}
+
+	search := "&search=" + url.QueryEscape("о")
+	tbl := []struct {
+		target string
+		want   []string
+	}{
+		{"/api/tasks", []string{"арбуз купить", "Ёлка", "Баня", "Отчёт по проекту"}},
+		{"/api/tasks?sort=date", []string{"арбуз купить", "Ёлка", "Баня", "Отчёт по проекту"}},
+		{"/api/tasks?sort=-date", []string{"Отчёт по проекту", "Баня", "Ёлка", "арбуз купить"}},
+		{"/api/tasks?sort=title", []string{"арбуз купить", "Баня", "Ёлка", "Отчёт по проекту"}},
+		{"/api/tasks?sort=-title", []string{"Отчёт по проекту", "Ёлка", "Баня", "арбуз купить"}},
+		{"/api/tasks?sort=id", []string{"Отчёт по проекту", "арбуз купить", "Ёлка", "Баня"}},
+		{"/api/tasks?sort=-id", []string{"Баня", "Ёлка", "арбуз купить", "Отчёт по проекту"}},
+		// поиск раньше возвращал задачи от поздних к ранним, теперь по умолчанию
+		// он упорядочен по дате так же, как список
+		{"/api/tasks?" + search[1:], []string{"Ёлка", "Отчёт по проекту"}},
+		{"/api/tasks?sort=-date" + search, []string{"Отчёт по проекту", "Ёлка"}},
+		{"/api/tasks?sort=title" + search, []string{"Ёлка", "Отчёт по проекту"}},
+		{"/api/tasks?sort=-id&search=02.01.2024", []string{"Баня", "Ёлка"}},
+		{"/api/tasks?sort=title&mode=regex&search=" + url.QueryEscape("^[А-ЯЁ]"), []string{"Баня", "Ёлка", "Отчёт по проекту"}},
+		// по релевантности выше задача со словом в заголовке, хотя она позже
+		{"/api/tasks?sort=relevance&search=" + url.QueryEscape("отчет"), []string{"Отчёт по проекту", "Ёлка"}},
+		{"/api/tasks?search=" + url.QueryEscape("отчет"), []string{"Ёлка", "Отчёт по проекту"}},
+	}
+	for _, v := range tbl {
+		w := doRequest(t, tasksHandler, http.MethodGet, v.target, nil)
+		require.Equal(t, http.StatusOK, w.Code, v.target)
+		got := []string{}
+		for _, task := range decodeBody(t, w)["tasks"].([]any) {
+			got = append(got, task.(map[string]any)["title"].(string))
+		}
+		assert.Equal(t, v.want, got, v.target)
+	}
+
+	for _, target := range []string{
+		"/api/tasks?sort=DATE",
+		"/api/tasks?sort=" + url.QueryEscape("date; DROP TABLE scheduler"),
+		"/api/tasks?sort=relevance",
+		"/api/tasks?sort=relevance&mode=regex&search=x",
+	} {
+		w := doRequest(t, tasksHandler, http.MethodGet, target, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func TestTasksTotalHasMore(t *testing.T) {
+	setupDB(t)
+	useConf(t).LimitTask = 2
+	for i := 1; i <= 5; i++ {
+		title := fmt.Sprint("Задача ", i)
+		if i%2 == 0 {
+			title = fmt.Sprint("Отчет ", i)
+		}
+		_, err := store.AddTask(t.Context(), &db.Task{Date: fmt.Sprintf("2024010%d", i), Title: title})
+		require.NoError(t, err)
+	}
+
+	tbl := []struct {
+		target  string
+		tasks   int
+		total   any // nil - поля нет в ответе
+		hasMore bool
+	}{
+		{"/api/tasks", 2, 5.0, true},
+		{"/api/tasks?offset=2", 2, 5.0, true},
+		{"/api/tasks?offset=3", 2, 5.0, false},
+		{"/api/tasks?limit=5", 5, 5.0, false},
+		{"/api/tasks?search=" + url.QueryEscape("задача"), 2, 3.0, true},
+		{"/api/tasks?search=" + url.QueryEscape("отчет"), 2, 2.0, false},
+		{"/api/tasks?search=" + url.QueryEscape("отчет") + "&limit=1", 1, 2.0, true},
+		{"/api/tasks?search=03.01.2024", 1, 1.0, false},
+		{"/api/tasks?search=" + url.QueryEscape("нет такой"), 0, 0.0, false},
+		{"/api/tasks?mode=regex&search=" + url.QueryEscape(`\d$`), 2, nil, true},
+		{"/api/tasks?mode=regex&search=" + url.QueryEscape(`^Отчет`), 2, nil, false},
+		{"/api/tasks?fuzzy=1&search=" + url.QueryEscape("задaча"), 2, nil, true},
+	}
+	for _, v := range tbl {
+		w := doRequest(t, tasksHandler, http.MethodGet, v.target, nil)
+		require.Equal(t, http.StatusOK, w.Code, v.target)
+		resp := decodeBody(t, w)
+		assert.Len(t, resp["tasks"], v.tasks, v.target)
+		assert.Equal(t, v.total, resp["total"], v.target)
+		assert.Equal(t, v.hasMore, resp["has_more"], v.target)
+	}
+}
+
+func TestTasksPriority(t *testing.T) {
+	setupDB(t)
+	useConf(t).LimitTask = 50
+	for i, priority := range []int{0, 3, 1, 3} {
+		date := fmt.Sprintf("2024010%d", i+1)
+		_, err := store.AddTask(t.Context(), &db.Task{Date: date, Title: "Задача " + date, Priority: priority})
+		require.NoError(t, err)
+	}
+
+	tbl := []struct {
+		target string
+		want   []string
+	}{
+		{"/api/tasks", []string{"20240101", "20240102", "20240103", "20240104"}},
+		{"/api/tasks?sort=priority", []string{"20240102", "20240104", "20240103", "20240101"}},
+		{"/api/tasks?priority=3", []string{"20240102", "20240104"}},
+		{"/api/tasks?priority=0", []string{"20240101"}},
+		{"/api/tasks?priority=1&search=Задача", []string{"20240103"}},
+	}
+	for _, v := range tbl {
+		w := doRequest(t, tasksHandler, http.MethodGet, v.target, nil)
+		require.Equal(t, http.StatusOK, w.Code, v.target)
+		dates := []string{}
+		for _, task := range decodeBody(t, w)["tasks"].([]any) {
+			task := task.(map[string]any)
+			assert.Contains(t, task, "priority", v.target)
+			dates = append(dates, task["date"].(string))
+		}
+		assert.Equal(t, v.want, dates, v.target)
+	}
+
+	for _, target := range []string{
+		"/api/tasks?priority=4",
+		"/api/tasks?priority=-1",
+		"/api/tasks?priority=высокий",
+		"/api/tasks?sort=name",
+		"/api/tasks?fuzzy=1&search=Задача&sort=priority",
+	} {
+		w := doRequest(t, tasksHandler, http.MethodGet, target, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+
+	for _, priority := range []int{-1, 4} {
+		w := doRequest(t, apiHandler, http.MethodPost, "/api/task",
+			map[string]any{"title": "Неверный приоритет", "priority": priority})
+		assert.Equal(t, http.StatusBadRequest, w.Code, priority)
+	}
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task", map[string]any{"title": "Срочная", "priority": 2})
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.EqualValues(t, 2, decodeBody(t, w)["priority"])
+}
+
+func TestTasksTags(t *testing.T) {
+	setupDB(t)
+	useConf(t).LimitTask = 50
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task",
+		map[string]any{"title": "Отчет", "tags": []string{" Work ", "work", "", "Срочно"}})
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, []any{"work", "срочно"}, decodeBody(t, w)["tags"])
+	_, err := store.AddTask(t.Context(), &db.Task{Date: "20240101", Title: "Без тегов"})
+	require.NoError(t, err)
+
+	for target, want := range map[string]int{
+		"/api/tasks?tag=WORK":        1,
+		"/api/tasks?tag=home":        0,
+		"/api/tasks?search=срочно":   1,
+		"/api/tasks?tag=work&search": 1,
+	} {
+		w := doRequest(t, tasksHandler, http.MethodGet, target, nil)
+		require.Equal(t, http.StatusOK, w.Code, target)
+		assert.Len(t, decodeBody(t, w)["tasks"], want, target)
+	}
+
+	tags := make([]string, maxTags+1)
+	for i := range tags {
+		tags[i] = fmt.Sprint("tag", i)
+	}
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task", map[string]any{"title": "Много тегов", "tags": tags})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
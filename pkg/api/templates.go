@@ -0,0 +1,177 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"go1f/pkg/db"
+	"go1f/pkg/metrics"
+)
+
+// TemplatesResp - список шаблонов задач.
+type TemplatesResp struct {
+	Templates []*db.Template `json:"templates"`
+}
+
+// checkTemplate проверяет шаблон по тем же правилам, что и задачу
+// (см. checkTask): заголовок обязателен, длина полей ограничена,
+// правило повторения должно разбираться. Заголовок обрезается по краям.
+func checkTemplate(tmpl *db.Template) error {
+	task := db.Task{Title: tmpl.Title, Comment: tmpl.Comment, Repeat: tmpl.Repeat}
+	if err := checkTask(&task); err != nil {
+		return err
+	}
+	tmpl.Title = task.Title
+	return nil
+}
+
+// templateIDParam возвращает ID шаблона из строки s - параметра запроса
+// или поля id тела. При ошибке сам отправляет ответ 400 и возвращает false.
+func templateIDParam(w http.ResponseWriter, r *http.Request, s string) (int64, bool) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		sendAPIError(w, r, CodeBadID, fmt.Sprintf("неверный id шаблона %q: ожидается положительное целое число", s), http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// handleGetTemplates обрабатывает GET-запрос /api/templates.
+// Возвращает шаблоны пользователя в порядке заголовков:
+//
+//	{"templates":[{"id":"1","title":"Оплатить интернет","comment":"","repeat":""}]}
+func handleGetTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := store.GetTemplates(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении шаблонов из БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения шаблонов", http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, r, TemplatesResp{Templates: templates}, http.StatusOK)
+}
+
+// handlePostTemplate обрабатывает POST-запрос /api/templates - создание шаблона.
+// Принимает JSON вида {"title":"...","comment":"...","repeat":"..."} и возвращает
+// 201 с созданным шаблоном или описание ошибки, как при создании задачи.
+func handlePostTemplate(w http.ResponseWriter, r *http.Request) {
+	var tmpl db.Template
+	if !decodeTask(w, r, &tmpl) {
+		return
+	}
+	if err := checkTemplate(&tmpl); err != nil {
+		sendErr(w, r, err)
+		return
+	}
+
+	if _, err := store.AddTemplate(r.Context(), &tmpl); err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при добавлении шаблона в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка при добавлении шаблона в БД", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, r, tmpl, http.StatusCreated)
+}
+
+// handlePutTemplate обрабатывает PUT-запрос /api/templates - изменение шаблона.
+// Принимает шаблон целиком с полем id. Возвращает пустой ответ со статусом
+// 200 OK, 404 если шаблон не найден или описание ошибки.
+func handlePutTemplate(w http.ResponseWriter, r *http.Request) {
+	var tmpl db.Template
+	if !decodeTask(w, r, &tmpl) {
+		return
+	}
+	id, ok := templateIDParam(w, r, tmpl.ID)
+	if !ok {
+		return
+	}
+	tmpl.ID = strconv.FormatInt(id, 10)
+	if err := checkTemplate(&tmpl); err != nil {
+		sendErr(w, r, err)
+		return
+	}
+
+	err := store.PutTemplate(r.Context(), &tmpl)
+	if errors.Is(err, db.ErrTemplateNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("шаблон с id =%v не найден", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при сохранении шаблона в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка сохранения", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, r, struct{}{}, http.StatusOK)
+}
+
+// handleDeleteTemplate обрабатывает DELETE-запрос /api/templates?id=N.
+// Задачи, созданные из шаблона, не удаляются.
+// Возвращает пустой ответ со статусом 200 OK, 404 если шаблон не найден
+// или описание ошибки.
+func handleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := templateIDParam(w, r, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+
+	err := store.DeleteTemplate(r.Context(), id)
+	if errors.Is(err, db.ErrTemplateNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("шаблон с id =%v не найден", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при удалении шаблона из БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка удаления", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, r, struct{}{}, http.StatusOK)
+}
+
+// handleTaskFromTemplate обрабатывает POST-запрос /api/task/from-template?id=N&date=YYYYMMDD:
+// создает задачу с заголовком, комментарием и правилом повторения шаблона N.
+// Дата по умолчанию сегодняшняя; задача проверяется checkTask, как при создании.
+// Возвращает 201 с созданной задачей, как POST /api/task, 404 если шаблон
+// не найден или описание ошибки.
+func handleTaskFromTemplate(w http.ResponseWriter, r *http.Request) {
+	id, ok := templateIDParam(w, r, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+
+	tmpl, err := store.GetTemplate(r.Context(), id)
+	if errors.Is(err, db.ErrTemplateNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("шаблон с id =%v не найден", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении шаблона из БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения шаблона", http.StatusInternalServerError)
+		return
+	}
+
+	task := db.Task{
+		Date:    r.URL.Query().Get("date"),
+		Title:   tmpl.Title,
+		Comment: tmpl.Comment,
+		Repeat:  tmpl.Repeat,
+	}
+	if err := checkTask(&task); err != nil {
+		sendErr(w, r, err)
+		return
+	}
+
+	taskID, err := store.AddTask(r.Context(), &task)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при добавлении задачи в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка при добавлении задачи в БД", http.StatusInternalServerError)
+		return
+	}
+
+	metrics.TaskCreated()
+	notify(r.Context(), EventCreated, taskID, &task)
+	sendJSON(w, r, CreatedTaskResp{Task: &task, ID: taskID}, http.StatusCreated)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplates(t *testing.T) {
+	setupDB(t)
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/templates",
+		map[string]any{"title": " Оплатить интернет ", "comment": "кв. 12", "repeat": "m 5"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	resp := decodeBody(t, w)
+	id := resp["id"].(string)
+	assert.Equal(t, "Оплатить интернет", resp["title"])
+
+	_, err := store.AddTemplate(t.Context(), &db.Template{Title: "Вынести мусор"})
+	require.NoError(t, err)
+
+	w = doRequest(t, apiHandler, http.MethodPut, "/api/templates",
+		map[string]any{"id": id, "title": "Оплатить интернет", "comment": "кв. 7", "repeat": ""})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/templates", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list TemplatesResp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Templates, 2)
+	assert.Equal(t, db.Template{ID: id, Title: "Оплатить интернет", Comment: "кв. 7"}, *list.Templates[1])
+	assert.Equal(t, "Вынести мусор", list.Templates[0].Title)
+
+	for _, tc := range []struct {
+		method, target string
+		body           any
+		code           ErrorCode
+	}{
+		{http.MethodPost, "/api/templates", map[string]any{"title": " "}, CodeTitleRequired},
+		{http.MethodPost, "/api/templates", map[string]any{"title": "x", "repeat": "d 500"}, CodeBadRepeat},
+		{http.MethodPost, "/api/templates", map[string]any{"title": "x", "date": "20250101"}, CodeUnknownField},
+		{http.MethodPut, "/api/templates", map[string]any{"title": "x"}, CodeBadID},
+		{http.MethodPut, "/api/templates", map[string]any{"id": "100500", "title": "x"}, CodeNotFound},
+		{http.MethodDelete, "/api/templates?id=abc", nil, CodeBadID},
+		{http.MethodDelete, "/api/templates?id=100500", nil, CodeNotFound},
+	} {
+		w := doRequest(t, apiHandler, tc.method, tc.target, tc.body)
+		assertAPIError(t, w, tc.code)
+	}
+
+	w = doRequest(t, apiHandler, http.MethodDelete, "/api/templates?id="+id, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	_, err = store.GetTemplate(t.Context(), mustID(t, id))
+	assert.ErrorIs(t, err, db.ErrTemplateNotFound)
+}
+
+func TestTaskFromTemplate(t *testing.T) {
+	setupDB(t)
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	useClock(t, &now)
+	tmpl := db.Template{Title: "Полить цветы", Comment: "на балконе", Repeat: "d 3"}
+	id, err := store.AddTemplate(t.Context(), &tmpl)
+	require.NoError(t, err)
+	target := fmt.Sprintf("/api/task/from-template?id=%d", id)
+
+	// дата по умолчанию - сегодня
+	w := doRequest(t, apiHandler, http.MethodPost, target, nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	task, err := store.GetTaskID(t.Context(), int64(decodeBody(t, w)["id"].(float64)))
+	require.NoError(t, err)
+	assert.Equal(t, "20250701", task.Date)
+	assert.Equal(t, "Полить цветы", task.Title)
+	assert.Equal(t, "на балконе", task.Comment)
+	assert.Equal(t, "d 3", task.Repeat)
+
+	w = doRequest(t, apiHandler, http.MethodPost, target+"&date=20250815", nil)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, "20250815", decodeBody(t, w)["date"])
+
+	w = doRequest(t, apiHandler, http.MethodPost, target+"&date=15.08.2025", nil)
+	assertAPIError(t, w, CodeBadDate)
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/from-template?id=100500", nil)
+	assertAPIError(t, w, CodeNotFound)
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/task/from-template", nil)
+	assertAPIError(t, w, CodeBadID)
+
+	// шаблон не меняется
+	got, err := store.GetTemplate(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, tmpl, got)
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// timeoutMsg - сообщение об ошибке, если запрос не уложился в отведенное время.
+const timeoutMsg = "превышено время обработки запроса"
+
+// requestTimeout — middleware, ограничивающий время обработки запроса.
+//
+// Контекст запроса получает срок timeout, поэтому запросы к БД
+// прерываются по его истечении. Если обработчик после этого отвечает
+// ошибкой сервера, клиент вместо неё получает 503.
+// Нулевой timeout отключает ограничение. Поток событий /api/events
+// не ограничивается: он открыт, пока подключен клиент; профилировщик
+// /debug/pprof/ - тоже, профиль снимается столько секунд, сколько запрошено.
+func requestTimeout(next http.Handler, timeout time.Duration) http.Handler {
+	if timeout <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == eventsPath || strings.HasPrefix(r.URL.Path, debugPath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		r = r.WithContext(ctx)
+		next.ServeHTTP(&timeoutWriter{ResponseWriter: w, r: r}, r)
+	})
+}
+
+// timeoutWriter заменяет ответ 5xx на 503, если срок контекста запроса истек.
+type timeoutWriter struct {
+	http.ResponseWriter
+	r        *http.Request // запрос со сроком обработки
+	timedOut bool          // ответ уже заменен, дальнейший вывод обработчика отбрасывается
+}
+
+// WriteHeader отправляет код ответа или 503, если обработчик не уложился в срок.
+func (tw *timeoutWriter) WriteHeader(code int) {
+	if code >= http.StatusInternalServerError && errors.Is(tw.r.Context().Err(), context.DeadlineExceeded) {
+		tw.timedOut = true
+		sendAPIError(tw.ResponseWriter, tw.r, CodeTimeout, timeoutMsg, http.StatusServiceUnavailable)
+		return
+	}
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+// Write пишет тело ответа, если оно не было заменено.
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	if tw.timedOut {
+		return len(b), nil
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter { return tw.ResponseWriter }
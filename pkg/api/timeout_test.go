@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowStore ждет отмены контекста запроса, как долгий запрос к БД.
+type slowStore struct {
+	TaskStore
+}
+
+func (slowStore) GetTaskID(ctx context.Context, id int64) (db.Task, error) {
+	<-ctx.Done()
+	return db.Task{}, ctx.Err()
+}
+
+func TestRequestTimeout(t *testing.T) {
+	useStore(t, slowStore{})
+	h := requestTimeout(http.HandlerFunc(apiHandler), 20*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/task?id=1", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, timeoutMsg, decodeBody(t, w)["error"])
+
+	// ответы, уложившиеся в срок, не меняются
+	useStore(t, &fakeStore{tasks: map[int64]db.Task{}})
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/task?id=1", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
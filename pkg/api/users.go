@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"go1f/pkg/db"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Ограничения учетных данных новых пользователей.
+const (
+	maxLoginLength    = 64 // символов в логине
+	minPasswordLength = 8  // символов в пароле
+	maxPasswordLength = 72 // байт в пароле, больше bcrypt не учитывает
+)
+
+// UserReq - тело запроса /api/users.
+type UserReq struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// UserResp - созданный пользователь.
+type UserResp struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+}
+
+// usersHandler обрабатывает POST-запрос /api/users - регистрацию пользователя.
+//
+// Создавать пользователей может только пользователь по умолчанию (администратор).
+// Принимает JSON вида {"login":"partner","password":"..."}, сохраняет bcrypt-хеш
+// пароля и возвращает 201 с созданным пользователем:
+//
+//	{"id":2,"login":"partner"}
+//
+// Возможные ошибки:
+//   - 400: неверный JSON, пустой или слишком длинный логин, слишком короткий или длинный пароль
+//   - 403: запрос не от администратора
+//   - 409: логин занят
+func usersHandler(w http.ResponseWriter, r *http.Request) {
+
+	if db.UserID(r.Context()) != db.DefaultUserID {
+		sendAPIError(w, r, CodeForbidden, "Создавать пользователей может только администратор", http.StatusForbidden)
+		return
+	}
+
+	var req UserReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendDecodeError(w, r, err)
+		return
+	}
+
+	login := strings.TrimSpace(req.Login)
+	switch {
+	case login == "" || strings.ContainsAny(login, " \t"):
+		sendAPIError(w, r, CodeBadRequest, "Логин не должен быть пустым или содержать пробелы", http.StatusBadRequest)
+		return
+	case utf8.RuneCountInString(login) > maxLoginLength:
+		sendAPIError(w, r, CodeBadRequest, "Слишком длинный логин", http.StatusBadRequest)
+		return
+	case utf8.RuneCountInString(req.Password) < minPasswordLength:
+		sendAPIError(w, r, CodeBadRequest, "Пароль короче 8 символов", http.StatusBadRequest)
+		return
+	case len(req.Password) > maxPasswordLength:
+		sendAPIError(w, r, CodeBadRequest, "Пароль длиннее 72 байт", http.StatusBadRequest)
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		sendAPIError(w, r, CodeInternal, "Ошибка при сохранении пароля", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := store.CreateUser(r.Context(), login, string(hash))
+	if errors.Is(err, db.ErrUserExists) {
+		sendAPIError(w, r, CodeConflict, "Логин уже занят", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		sendAPIError(w, r, CodeDBError, "Ошибка при создании пользователя", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, r, UserResp{ID: user.ID, Login: user.Login}, http.StatusCreated)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// doAuthRequest выполняет запрос к API с токеном token в заголовке Authorization.
+func doAuthRequest(t *testing.T, token, method, target string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	r := httptest.NewRequest(method, target, &buf)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	apiHandler(w, r)
+	return w
+}
+
+// signIn входит с логином и паролем и возвращает токен.
+func signIn(t *testing.T, login, password string) string {
+	t.Helper()
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/signin", Pass{Login: login, Password: password})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp RespSign
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+	return resp.Token
+}
+
+func TestUsersOwnTasks(t *testing.T) {
+	setupDB(t)
+	usePassword(t, "secret", time.Hour)
+
+	adminToken := signIn(t, "", "secret")
+
+	w := doAuthRequest(t, adminToken, http.MethodPost, "/api/users", UserReq{Login: "partner", Password: "partner-pass"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "partner", decodeBody(t, w)["login"])
+
+	for _, req := range []UserReq{
+		{Login: "partner", Password: "partner-pass"},
+		{Login: "", Password: "partner-pass"},
+		{Login: "short", Password: "1234"},
+	} {
+		w := doAuthRequest(t, adminToken, http.MethodPost, "/api/users", req)
+		assert.Contains(t, []int{http.StatusBadRequest, http.StatusConflict}, w.Code, req.Login)
+	}
+
+	partnerToken := signIn(t, "partner", "partner-pass")
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/signin", Pass{Login: "partner", Password: "secret"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = doRequest(t, apiHandler, http.MethodPost, "/api/signin", Pass{Login: "nobody", Password: "secret"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doAuthRequest(t, partnerToken, http.MethodPost, "/api/users", UserReq{Login: "third", Password: "third-pass"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	today := time.Now().Format("20060102")
+	w = doAuthRequest(t, adminToken, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "Задача админа"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	adminTask := fmt.Sprint(decodeBody(t, w)["id"])
+
+	t.Run("foreign task is not found", func(t *testing.T) {
+		target := "/api/task?id=" + adminTask
+		for _, tc := range []struct {
+			method, target string
+			body           any
+		}{
+			{http.MethodGet, target, nil},
+			{http.MethodPut, "/api/task", map[string]any{"id": adminTask, "date": today, "title": "Моя"}},
+			{http.MethodPatch, target, map[string]any{"title": "Моя"}},
+			{http.MethodPost, "/api/task/done?id=" + adminTask, nil},
+			{http.MethodDelete, target, nil},
+		} {
+			w := doAuthRequest(t, partnerToken, tc.method, tc.target, tc.body)
+			assert.Equal(t, http.StatusNotFound, w.Code, tc.method)
+		}
+
+		w := doAuthRequest(t, adminToken, http.MethodGet, target, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Задача админа", decodeBody(t, w)["title"])
+	})
+
+	t.Run("lists are separate", func(t *testing.T) {
+		w := doAuthRequest(t, partnerToken, http.MethodPost, "/api/task", map[string]any{"date": today, "title": "Задача партнера"})
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		for token, want := range map[string]string{adminToken: "Задача админа", partnerToken: "Задача партнера"} {
+			w := doAuthRequest(t, token, http.MethodGet, "/api/tasks?limit=10", nil)
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp TasksResp
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			require.Len(t, resp.Tasks, 1)
+			assert.Equal(t, want, resp.Tasks[0].Title)
+		}
+	})
+
+	t.Run("token without user", func(t *testing.T) {
+		claims := jwt.MapClaims{"sub": tokenSubject, "pwd_version": pwdVersion("secret"), "exp": time.Now().Add(time.Hour).Unix()}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(signingKey)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, doAuthRequest(t, token, http.MethodGet, "/api/tasks", nil).Code)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		token, err := getToken(db.User{ID: 100500}, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, doAuthRequest(t, token, http.MethodGet, "/api/tasks", nil).Code)
+	})
+}
+
+func TestUserCannotBlockOthers(t *testing.T) {
+	setupDB(t)
+	usePassword(t, "secret", time.Hour)
+	t.Cleanup(func() { setMaintenance(false, "") })
+	adminToken := signIn(t, "", "secret")
+	w := doAuthRequest(t, adminToken, http.MethodPost, "/api/users", UserReq{Login: "partner", Password: "partner-pass"})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// пользователь не может заморозить запись для администратора
+	partnerToken := signIn(t, "partner", "partner-pass")
+	w = doAuthRequest(t, partnerToken, http.MethodPost, "/api/admin/maintenance_mode", Maintenance{Enabled: true})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doAuthRequest(t, adminToken, http.MethodPost, "/api/task", db.Task{Date: "20990101", Title: "Задача"})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}
@@ -0,0 +1,390 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go1f/pkg/db"
+)
+
+// События задач, на которые подписываются вебхуки.
+const (
+	EventCreated = "created" // задача создана
+	EventUpdated = "updated" // задача изменена
+	EventDeleted = "deleted" // задача удалена
+	EventDone    = "done"    // задача отмечена выполненной
+)
+
+// webhookEvents - допустимые события вебхуков.
+var webhookEvents = []string{EventCreated, EventUpdated, EventDeleted, EventDone}
+
+// Параметры доставки вебхуков.
+const (
+	webhookSignatureHeader = "X-Webhook-Signature" // заголовок с подписью тела: sha256=<hex>
+	webhookEventHeader     = "X-Webhook-Event"     // заголовок с именем события
+	webhookTimeout         = 10 * time.Second      // время ожидания ответа на одну попытку
+	webhookBackoff         = time.Second           // пауза перед второй попыткой, дальше удваивается
+	maxWebhookURLLen       = 2048                  // символов в адресе вебхука
+	maxWebhookSecretLen    = 256                   // символов в ключе подписи
+)
+
+// WebhooksResp - список вебхуков пользователя.
+type WebhooksResp struct {
+	Webhooks []*db.Webhook `json:"webhooks"`
+}
+
+// WebhookPayload - тело запроса, которое получает адрес вебхука.
+// Для удаленной задачи Task содержит только id.
+type WebhookPayload struct {
+	Event     string    `json:"event"`
+	Task      *db.Task  `json:"task"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// checkWebhook проверяет адрес, ключ и события вебхука.
+// События приводятся к порядку webhookEvents без повторов.
+func checkWebhook(hook *db.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return newError(CodeBadField, "Поле url должно быть адресом http или https")
+	}
+	if len([]rune(hook.URL)) > maxWebhookURLLen {
+		return newError(CodeBadField, "Поле url должно быть не длиннее %d символов", maxWebhookURLLen)
+	}
+	if len([]rune(hook.Secret)) > maxWebhookSecretLen {
+		return newError(CodeBadField, "Поле secret должно быть не длиннее %d символов", maxWebhookSecretLen)
+	}
+	if len(hook.Events) == 0 {
+		return newError(CodeBadField, "Список events не должен быть пустым")
+	}
+	for _, event := range hook.Events {
+		if !slices.Contains(webhookEvents, event) {
+			return newError(CodeBadField, "Неизвестное событие %q, допустимы: created, updated, deleted, done", event)
+		}
+	}
+	var events []string
+	for _, event := range webhookEvents {
+		if slices.Contains(hook.Events, event) {
+			events = append(events, event)
+		}
+	}
+	hook.Events = events
+	return nil
+}
+
+// webhookIDParam возвращает ID вебхука из строки s - параметра запроса
+// или поля id тела. При ошибке сам отправляет ответ 400 и возвращает false.
+func webhookIDParam(w http.ResponseWriter, r *http.Request, s string) (int64, bool) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		sendAPIError(w, r, CodeBadID, fmt.Sprintf("неверный id вебхука %q: ожидается положительное целое число", s), http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// handleGetWebhooks обрабатывает GET-запрос /api/webhooks.
+// Возвращает вебхуки пользователя без ключей подписи:
+//
+//	{"webhooks":[{"id":"1","url":"https://...","events":["created","done"]}]}
+func handleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := store.GetWebhooks(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при получении вебхуков из БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка получения вебхуков", http.StatusInternalServerError)
+		return
+	}
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+	sendJSON(w, r, WebhooksResp{Webhooks: hooks}, http.StatusOK)
+}
+
+// handlePostWebhook обрабатывает POST-запрос /api/webhooks - создание вебхука.
+// Принимает JSON вида {"url":"https://...","secret":"...","events":["created","done"]}
+// и возвращает 201 с созданным вебхуком без ключа подписи.
+func handlePostWebhook(w http.ResponseWriter, r *http.Request) {
+	var hook db.Webhook
+	if !decodeTask(w, r, &hook) {
+		return
+	}
+	if err := checkWebhook(&hook); err != nil {
+		sendErr(w, r, err)
+		return
+	}
+
+	if _, err := store.AddWebhook(r.Context(), &hook); err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при добавлении вебхука в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "Ошибка при добавлении вебхука в БД", http.StatusInternalServerError)
+		return
+	}
+
+	hook.Secret = ""
+	sendJSON(w, r, hook, http.StatusCreated)
+}
+
+// handlePutWebhook обрабатывает PUT-запрос /api/webhooks - изменение вебхука.
+// Принимает вебхук целиком с полем id; без secret ключ подписи не меняется.
+// Возвращает пустой ответ со статусом 200 OK, 404 если вебхук не найден
+// или описание ошибки.
+func handlePutWebhook(w http.ResponseWriter, r *http.Request) {
+	var hook db.Webhook
+	if !decodeTask(w, r, &hook) {
+		return
+	}
+	id, ok := webhookIDParam(w, r, hook.ID)
+	if !ok {
+		return
+	}
+	hook.ID = strconv.FormatInt(id, 10)
+	if err := checkWebhook(&hook); err != nil {
+		sendErr(w, r, err)
+		return
+	}
+
+	err := store.PutWebhook(r.Context(), &hook)
+	if errors.Is(err, db.ErrWebhookNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("вебхук с id =%v не найден", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при сохранении вебхука в БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка сохранения", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, r, struct{}{}, http.StatusOK)
+}
+
+// handleDeleteWebhook обрабатывает DELETE-запрос /api/webhooks?id=N.
+// Возвращает пустой ответ со статусом 200 OK, 404 если вебхук не найден
+// или описание ошибки.
+func handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDParam(w, r, r.URL.Query().Get("id"))
+	if !ok {
+		return
+	}
+
+	err := store.DeleteWebhook(r.Context(), id)
+	if errors.Is(err, db.ErrWebhookNotFound) {
+		sendAPIError(w, r, CodeNotFound, fmt.Sprintf("вебхук с id =%v не найден", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Ошибка при удалении вебхука из БД", "err", err)
+		sendAPIError(w, r, CodeDBError, "ошибка удаления", http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, r, struct{}{}, http.StatusOK)
+}
+
+// webhookEvent - событие задачи в очереди диспетчера.
+type webhookEvent struct {
+	userID int64    // владелец задачи, его вебхуки получают событие
+	event  string   // одно из Event*
+	taskID int64    // задача события
+	task   *db.Task // задача после изменения, nil - только id
+	at     time.Time
+}
+
+// webhookDispatcher доставляет события задач на адреса вебхуков.
+//
+// Обработчики ставят события в ограниченную очередь и не ждут доставки:
+// при переполненной очереди событие отбрасывается с записью в журнал.
+// Один рабочий goroutine отправляет события по порядку, повторяя неудачную
+// отправку на адрес до attempts раз с паузой backoff, 2*backoff, 4*backoff...
+type webhookDispatcher struct {
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+
+	mu     sync.Mutex // защищает closed и отправку в queue
+	closed bool
+	queue  chan webhookEvent
+
+	ctx    context.Context // отменяется, если очередь не успела разойтись при остановке
+	cancel context.CancelFunc
+	done   chan struct{} // закрывается, когда рабочий goroutine завершился
+}
+
+// webhooks - диспетчер вебхуков сервера, nil - события не отправляются.
+var webhooks *webhookDispatcher
+
+// newWebhookDispatcher создает и запускает диспетчер с очередью size событий.
+func newWebhookDispatcher(size, attempts int, backoff time.Duration) *webhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &webhookDispatcher{
+		client:   &http.Client{Timeout: webhookTimeout},
+		attempts: attempts,
+		backoff:  backoff,
+		queue:    make(chan webhookEvent, size),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// StartWebhooks запускает отправку событий задач на вебхуки с размером очереди
+// TODO_WEBHOOK_QUEUE и числом попыток TODO_WEBHOOK_ATTEMPTS.
+// Возвращает функцию остановки (см. webhookDispatcher.stop).
+func StartWebhooks() (stop func(ctx context.Context)) {
+	d := newWebhookDispatcher(conf().WebhookQueue, conf().WebhookAttempts, webhookBackoff)
+	webhooks = d
+	return func(ctx context.Context) {
+		d.stop(ctx)
+		webhooks = nil
+	}
+}
+
+// enqueue ставит событие в очередь без ожидания. После остановки диспетчера
+// и при переполненной очереди событие отбрасывается.
+func (d *webhookDispatcher) enqueue(ev webhookEvent) {
+	if ev.task != nil {
+		task := *ev.task // обработчик может изменить задачу после вызова
+		ev.task = &task
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	select {
+	case d.queue <- ev:
+	default:
+		slog.Warn("Очередь вебхуков переполнена, событие отброшено", "event", ev.event, "task", ev.taskID)
+	}
+}
+
+// stop перестает принимать события и ждет отправки уже поставленных в очередь.
+// Если ctx истекает раньше, текущие попытки прерываются, а оставшиеся
+// события отбрасываются. Возвращается, когда рабочий goroutine завершился.
+func (d *webhookDispatcher) stop(ctx context.Context) {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.done:
+	case <-ctx.Done():
+		slog.Warn("Не все события вебхуков отправлены до остановки сервера", "left", len(d.queue))
+		d.cancel()
+		<-d.done
+	}
+	d.cancel()
+}
+
+// run отправляет события из очереди, пока она не закрыта.
+func (d *webhookDispatcher) run() {
+	defer close(d.done)
+	for ev := range d.queue {
+		if d.ctx.Err() != nil {
+			continue // остановка по таймауту: очередь только вычитывается
+		}
+		d.dispatch(ev)
+	}
+}
+
+// dispatch отправляет событие на все вебхуки владельца задачи, подписанные на него.
+func (d *webhookDispatcher) dispatch(ev webhookEvent) {
+	ctx := db.WithUser(d.ctx, ev.userID)
+	hooks, err := store.GetWebhooks(ctx)
+	if err != nil {
+		slog.Error("Ошибка при получении вебхуков", "err", err)
+		return
+	}
+	hooks = slices.DeleteFunc(hooks, func(hook *db.Webhook) bool {
+		return !slices.Contains(hook.Events, ev.event)
+	})
+	if len(hooks) == 0 {
+		return
+	}
+
+	task := ev.task
+	if task == nil {
+		task = &db.Task{ID: strconv.FormatInt(ev.taskID, 10)}
+	}
+	body, err := json.Marshal(WebhookPayload{Event: ev.event, Task: task, Timestamp: ev.at})
+	if err != nil {
+		slog.Error("Ошибка при сериализации события вебхука", "err", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if err := d.deliver(hook, ev.event, body); err != nil {
+			slog.Warn("Событие не доставлено на вебхук", "webhook", hook.ID, "event", ev.event,
+				"attempts", d.attempts, "err", err)
+		}
+	}
+}
+
+// deliver отправляет body на адрес вебхука, повторяя попытку с растущей паузой,
+// пока адрес не ответит 2xx, не кончатся попытки или не остановится диспетчер.
+// Тело подписывается HMAC-SHA256 с ключом вебхука, если он задан.
+func (d *webhookDispatcher) deliver(hook *db.Webhook, event string, body []byte) error {
+	var signature string
+	if hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(hook.Secret))
+		mac.Write(body)
+		signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var err error
+	pause := d.backoff
+	for attempt := 1; attempt <= d.attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(pause):
+			case <-d.ctx.Done():
+				return d.ctx.Err()
+			}
+			pause *= 2
+		}
+		if err = d.post(hook.URL, event, signature, body); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// post выполняет одну попытку отправки события.
+func (d *webhookDispatcher) post(target, event, signature string, body []byte) error {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, event)
+	if signature != "" {
+		req.Header.Set(webhookSignatureHeader, signature)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("ответ %d", resp.StatusCode)
+	}
+	return nil
+}
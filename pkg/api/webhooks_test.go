@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookServer - адрес вебхука для тестов: первые failures запросов
+// получают 500, остальные 200. Принятые тела и заголовки сохраняются.
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failures int
+	calls    int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func newWebhookServer(t *testing.T, failures int) *webhookServer {
+	t.Helper()
+	s := &webhookServer{failures: failures}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.calls++
+		if s.calls <= s.failures {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.bodies = append(s.bodies, body)
+		s.headers = append(s.headers, r.Header.Clone())
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// useWebhooks запускает для теста диспетчер с короткими паузами
+// и возвращает функцию, которая ждет отправки поставленных событий.
+func useWebhooks(t *testing.T, attempts int) func() {
+	t.Helper()
+	d := newWebhookDispatcher(10, attempts, time.Millisecond)
+	webhooks = d
+	stop := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		d.stop(ctx)
+	}
+	t.Cleanup(func() {
+		stop()
+		webhooks = nil
+	})
+	return stop
+}
+
+func TestWebhooksCRUD(t *testing.T) {
+	setupDB(t)
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/webhooks",
+		map[string]any{"url": "https://relay.example/hook", "secret": "s3cret", "events": []string{"done", "created", "done"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	resp := decodeBody(t, w)
+	id := resp["id"].(string)
+	assert.Equal(t, []any{"created", "done"}, resp["events"])
+	assert.NotContains(t, resp, "secret")
+
+	// без secret ключ не меняется
+	w = doRequest(t, apiHandler, http.MethodPut, "/api/webhooks",
+		map[string]any{"id": id, "url": "https://relay.example/v2", "events": []string{"deleted"}})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	hooks, err := store.GetWebhooks(t.Context())
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+	assert.Equal(t, db.Webhook{ID: id, URL: "https://relay.example/v2", Secret: "s3cret", Events: []string{"deleted"}}, *hooks[0])
+
+	w = doRequest(t, apiHandler, http.MethodGet, "/api/webhooks", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "s3cret")
+
+	for _, tc := range []struct {
+		method, target string
+		body           any
+		code           ErrorCode
+	}{
+		{http.MethodPost, "/api/webhooks", map[string]any{"url": "ftp://relay.example", "events": []string{"done"}}, CodeBadField},
+		{http.MethodPost, "/api/webhooks", map[string]any{"url": "relay.example", "events": []string{"done"}}, CodeBadField},
+		{http.MethodPost, "/api/webhooks", map[string]any{"url": "https://relay.example"}, CodeBadField},
+		{http.MethodPost, "/api/webhooks", map[string]any{"url": "https://relay.example", "events": []string{"moved"}}, CodeBadField},
+		{http.MethodPut, "/api/webhooks", map[string]any{"url": "https://relay.example", "events": []string{"done"}}, CodeBadID},
+		{http.MethodPut, "/api/webhooks", map[string]any{"id": "100500", "url": "https://relay.example", "events": []string{"done"}}, CodeNotFound},
+		{http.MethodDelete, "/api/webhooks?id=100500", nil, CodeNotFound},
+	} {
+		w := doRequest(t, apiHandler, tc.method, tc.target, tc.body)
+		assertAPIError(t, w, tc.code)
+	}
+
+	w = doRequest(t, apiHandler, http.MethodDelete, "/api/webhooks?id="+id, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	hooks, err = store.GetWebhooks(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, hooks)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	setupDB(t)
+	wait := useWebhooks(t, 3)
+	// первые две попытки неудачны, третья доставляет событие
+	srv := newWebhookServer(t, 2)
+	_, err := store.AddWebhook(t.Context(), &db.Webhook{URL: srv.URL, Secret: "s3cret", Events: []string{EventCreated, EventDone}})
+	require.NoError(t, err)
+
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task", map[string]any{"title": "Оплатить интернет"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	id := int64(decodeBody(t, w)["id"].(float64))
+	// на удаление адрес не подписан
+	w = doRequest(t, apiHandler, http.MethodPost, fmt.Sprintf("/api/task/done?id=%d", id), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = doRequest(t, apiHandler, http.MethodDelete, fmt.Sprintf("/api/task?id=%d", id), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	wait()
+
+	assert.Equal(t, 4, srv.calls)
+	require.Len(t, srv.bodies, 2)
+	var created, done WebhookPayload
+	require.NoError(t, json.Unmarshal(srv.bodies[0], &created))
+	require.NoError(t, json.Unmarshal(srv.bodies[1], &done))
+	assert.Equal(t, EventCreated, created.Event)
+	assert.Equal(t, "Оплатить интернет", created.Task.Title)
+	assert.Equal(t, fmt.Sprint(id), created.Task.ID)
+	assert.False(t, created.Timestamp.IsZero())
+	assert.Equal(t, EventDone, done.Event)
+	assert.True(t, done.Task.Completed)
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(srv.bodies[0])
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), srv.headers[0].Get(webhookSignatureHeader))
+	assert.Equal(t, EventCreated, srv.headers[0].Get(webhookEventHeader))
+	assert.Equal(t, "application/json", srv.headers[0].Get("Content-Type"))
+}
+
+func TestWebhookGivesUp(t *testing.T) {
+	setupDB(t)
+	wait := useWebhooks(t, 3)
+	srv := newWebhookServer(t, 100)
+	_, err := store.AddWebhook(t.Context(), &db.Webhook{URL: srv.URL, Events: []string{EventCreated}})
+	require.NoError(t, err)
+
+	// ошибка доставки не влияет на ответ API
+	w := doRequest(t, apiHandler, http.MethodPost, "/api/task", map[string]any{"title": "Задача"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	wait()
+
+	assert.Equal(t, 3, srv.calls)
+	assert.Empty(t, srv.bodies)
+}
+
+func TestWebhookDispatcherStop(t *testing.T) {
+	setupDB(t)
+	release := make(chan struct{})
+	var calls int
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	_, err := store.AddWebhook(t.Context(), &db.Webhook{URL: srv.URL, Events: []string{EventDeleted}})
+	require.NoError(t, err)
+
+	d := newWebhookDispatcher(1, 5, time.Hour)
+	ctx := db.WithUser(context.Background(), db.DefaultUserID)
+	webhooks = d
+	defer func() { webhooks = nil }()
+	notify(ctx, EventDeleted, 1, nil)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls == 1
+	}, time.Second, time.Millisecond)
+	// первое событие отправляется, второе ждет в очереди, третье не помещается
+	notify(ctx, EventDeleted, 2, nil)
+	notify(ctx, EventDeleted, 3, nil)
+	assert.Len(t, d.queue, 1)
+
+	// истекший срок остановки прерывает отправку и отбрасывает очередь
+	stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	d.stop(stopCtx)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 1, calls)
+
+	// после остановки события не принимаются
+	notify(ctx, EventDeleted, 4, nil)
+	assert.Empty(t, d.queue)
+}
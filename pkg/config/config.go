@@ -1,83 +1,594 @@
 /*
 Package config предоставляет инструменты для загрузки конфигурации приложения через переменные окружения.
 
-Конфигурация читается из .env файла или системных переменных окружения с приоритетом:
-1. Переменные окружения ОС
-2. Значения из .env файла
-3. Встроенные значения по умолчанию
+Конфигурация читается из флагов командной строки, .env файла или системных
+переменных окружения с приоритетом:
+1. Флаги командной строки (см. BindFlags)
+2. Переменные окружения ОС
+3. Значения из .env файла
+4. Файл конфигурации YAML из TODO_CONFIG или флага --config
+5. Встроенные значения по умолчанию
 
 Основные настройки:
-- Ограничение количества задач
-- Порт веб-сервера
+- Ограничение количества задач и размера тела запроса
+- Порт или адрес веб-сервера, сертификат TLS
 - Путь к файлу базы данных
+- Каталог веб-интерфейса вместо встроенного
 - Тестовый пароль для доступа
+- Отладочное логирование SQL-запросов
+- Режим обслуживания (только чтение)
+- Метрики Prometheus и отладочные эндпоинты (pprof)
+- Уровень и формат журнала, ID запроса в записях журнала
+- Срок жизни и ключ подписи токенов аутентификации
+- Ограничение неудачных попыток входа
+- Работа за доверенным обратным прокси и заголовок с IP клиента
+- Источники (CORS), которым разрешены запросы к API из браузера
+- Часовой пояс, в котором считаются даты задач
+- Каталог резервных копий и копия для восстановления при запуске
+- Очередь и число попыток доставки вебхуков
+- Уведомления о задачах в Telegram
+- Ежедневная сводка задач по электронной почте (SMTP)
 */
 package config
 
 import (
-	"log"
+	"cmp"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // база часовых поясов для образов без /usr/share/zoneinfo
 
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Переменные из env импортируемые в другие пакеты
 type Config struct {
-	LimitTask    int
-	PathToDB     string
-	PortServ     string
-	PasswordTest string
+	LimitTask       int
+	MaxLimit        int
+	MaxBodySize     int64 // наибольший размер JSON-тела задачи в байтах
+	MaxRequestBody  int64 // наибольший размер тела любого запроса к API в байтах
+	PathToDB        string
+	DBDriver        string // СУБД: DBDriverSQLite (по умолчанию) или DBDriverPostgres
+	DSN             string // строка подключения к PostgreSQL
+	PortServ        string
+	ListenAddr      string // адрес HTTP-сервера host:port
+	TLS             TLSConfig
+	WebDir          string // каталог веб-интерфейса на диске, пусто - встроенный
+	PasswordTest    string
+	PasswordHash    string
+	SQLDebug        bool
+	SQLSlow         time.Duration
+	Maintenance     bool
+	Timeout         time.Duration
+	ShutdownTimeout time.Duration
+	Metrics         bool
+	Debug           bool // /debug/pprof/ и /api/admin/runtime
+	LogLevel        slog.Level
+	LogFormat       string
+	TokenTTL        time.Duration
+	SigninFailures  int           // неудачных попыток входа с одного IP до блокировки
+	SigninWindow    time.Duration // окно подсчета неудачных попыток входа
+	TrustProxy      bool
+	ClientIPHeader  string
+	CORS            CORSConfig // запросы к API со страниц других доменов
+	JWTSecret       string
+	JWTKeyFile      string         // файл ключа подписи токенов, пусто - jwt.key рядом с файлом SQLite
+	Location        *time.Location // часовой пояс дат задач, nil - системный
+	BackupDir       string         // каталог резервных копий БД
+	RestoreFrom     string         // резервная копия, из которой восстанавливается отсутствующая БД
+	BackupInterval  time.Duration  // период автоматических резервных копий, 0 - выключены
+	BackupKeep      int            // сколько последних копий хранить, 0 - все
+	WebhookQueue    int            // размер очереди событий вебхуков
+	WebhookAttempts int            // попыток доставки события на один адрес
+	TelegramToken   string         // токен бота Telegram, пусто - уведомления выключены
+	TelegramChatID  string         // чат, в который бот отправляет уведомления
+	TelegramEvery   time.Duration  // период проверки задач для уведомлений
+	SMTP            SMTPConfig     // почтовый сервер ежедневной сводки
 }
 
-var App Config
+// TLSConfig - настройки HTTPS. Сервер работает по HTTPS, если заданы Cert и Key.
+type TLSConfig struct {
+	Cert         string // файл сертификата PEM, с цепочкой промежуточных
+	Key          string // файл закрытого ключа PEM
+	RedirectPort string // порт, с которого HTTP перенаправляется на HTTPS, пусто - выключено
+}
+
+// Enabled сообщает, что сервер работает по HTTPS.
+func (c TLSConfig) Enabled() bool {
+	return c.Cert != "" && c.Key != ""
+}
+
+// CORSConfig - настройки CORS: с каких страниц браузер может обращаться к API.
+type CORSConfig struct {
+	Origins     []string // разрешенные источники scheme://host[:port] или "*" - любой
+	Credentials bool     // разрешить запросы с куками; несовместимо с "*"
+}
+
+// Enabled сообщает, что запросы с других источников разрешены.
+func (c CORSConfig) Enabled() bool {
+	return len(c.Origins) > 0
+}
+
+// SMTPConfig - настройки отправки ежедневной сводки задач по почте.
+// Сводка включена, если заданы Host, From и To.
+type SMTPConfig struct {
+	Host string
+	Port string
+	User string // пустой - без аутентификации
+	Pass string
+	From string
+	To   []string
+	At   time.Duration // время отправки от полуночи в часовом поясе дат задач
+}
+
+// Enabled сообщает, что ежедневная сводка по почте включена.
+func (c SMTPConfig) Enabled() bool {
+	return c.Host != "" && c.From != "" && len(c.To) > 0
+}
+
+// TimeZone возвращает часовой пояс, в котором считаются даты задач:
+// TODO_TIMEZONE или системный, если он не задан.
+func (c Config) TimeZone() *time.Location {
+	if c.Location == nil {
+		return time.Local
+	}
+	return c.Location
+}
 
 // Значения по умолчанию для ключевых параметров приложения.
 const (
 	DefaultLimitTasks   = 50                   // Значение по умолчанию кол-ва отображаемых задач
+	DefaultMaxLimit     = 500                  // Значение по умолчанию максимального limit в запросе
+	DefaultMaxBodyKB    = 64                   // Значение по умолчанию наибольшего тела запроса задачи, КБ
+	DefaultMaxRequest   = 1 << 20              // Значение по умолчанию наибольшего тела запроса к API, байт
 	DefaultPort         = `7540`               // Значение по умолчнию порта
 	DefaultPathDb       = `/data/scheduler.db` // Значение по умолчнию пути к БД
 	DefaultTestPassword = `1234`               // Значение по умолчнию тестового пароля
+	DefaultSQLSlowMs    = 200                  // Значение по умолчанию порога медленного запроса, мс
+	DefaultTimeout      = 30 * time.Second     // Значение по умолчанию времени обработки запроса
+	DefaultShutdown     = 10 * time.Second     // Значение по умолчанию времени на завершение запросов при остановке
+	DefaultLogFormat    = LogFormatText        // Значение по умолчанию формата журнала
+	DefaultTokenTTL     = 8 * time.Hour        // Значение по умолчанию срока жизни токена
+	MinTokenTTL         = time.Minute          // Минимальный допустимый срок жизни токена
+	MaxTokenTTL         = 365 * 24 * time.Hour // Максимальный допустимый срок жизни токена
+	DefaultSigninFails  = 5                    // Значение по умолчанию числа неудачных попыток входа до блокировки
+	DefaultSigninWindow = time.Minute          // Значение по умолчанию окна подсчета неудачных попыток входа
+	MinSigninWindow     = time.Second          // Минимальное допустимое окно подсчета неудачных попыток входа
+	DefaultBackupKeep   = 7                    // Значение по умолчанию числа хранимых резервных копий
+	DefaultWebhookQueue = 100                  // Значение по умолчанию размера очереди событий вебхуков
+	DefaultWebhookTries = 5                    // Значение по умолчанию числа попыток доставки вебхука
+	DefaultNotifyEvery  = time.Hour            // Значение по умолчанию периода уведомлений Telegram
+	DefaultSMTPPort     = `587`                // Значение по умолчанию порта SMTP (STARTTLS)
+	DefaultSMTPAt       = 8 * time.Hour        // Значение по умолчанию времени ежедневной сводки, 08:00
+)
+
+// Форматы журнала, значения TODO_LOG_FORMAT.
+const (
+	LogFormatText = "text" // key=value, удобно читать в консоли
+	LogFormatJSON = "json" // одна JSON-запись на строку, для сборщиков логов
 )
 
-// ConfigServer инициализирует систему конфигурации.
-// Загружает переменные окружения из .env файла в корне проекта.
-// Должен вызываться при старте приложения перед использованием других функций.
-func ConfigServer() {
-	// Загружаем файл .env
-	_ = godotenv.Load()
-	App = Config{
-		LimitTask:    getLimitTasks(),
-		PathToDB:     getPathDB(),
-		PortServ:     getPort(),
-		PasswordTest: getPassword()}
+// VarError - неверное значение переменной окружения.
+type VarError struct {
+	Name  string // имя переменной, например TODO_PORT
+	Value string // значение из окружения, пустое для незаданных переменных и секретов
+	Err   error  // что не так со значением
+}
+
+func (e *VarError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s: %v", e.Name, e.Err)
+	}
+	return fmt.Sprintf("%s=%q: %v", e.Name, e.Value, e.Err)
+}
+
+func (e *VarError) Unwrap() error {
+	return e.Err
+}
+
+// varError создает ошибку переменной name со значением value.
+func varError(name, value, format string, args ...any) *VarError {
+	return &VarError{Name: name, Value: value, Err: fmt.Errorf(format, args...)}
+}
+
+// ValidationError - все неверные переменные окружения, найденные Load:
+// так их можно исправить за один запуск, а не по одной.
+type ValidationError struct {
+	Vars []*VarError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("неверная конфигурация:")
+	for _, v := range e.Vars {
+		b.WriteString("\n  ")
+		b.WriteString(v.Error())
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Vars))
+	for i, v := range e.Vars {
+		errs[i] = v
+	}
+	return errs
+}
+
+// validator собирает ошибки переменных окружения при чтении конфигурации.
+type validator struct {
+	vars []*VarError
+}
+
+// add запоминает ошибку переменной, если она есть.
+func (v *validator) add(err error) {
+	if err == nil {
+		return
+	}
+	varErr, ok := err.(*VarError)
+	if !ok {
+		varErr = &VarError{Name: "?", Err: err}
+	}
+	v.vars = append(v.vars, varErr)
+}
+
+// err возвращает собранные ошибки как *ValidationError или nil.
+func (v *validator) err() error {
+	if len(v.vars) == 0 {
+		return nil
+	}
+	return &ValidationError{Vars: v.vars}
+}
+
+// logOutput - куда пишет журнал, настроенный Load; тесты подменяют его буфером.
+var logOutput io.Writer = os.Stderr
+
+// fromFile - переменные окружения и их значения, которые Load задал из .env
+// и файла конфигурации, а не окружение процесса или флаги командной строки.
+var fromFile = make(map[string]string)
+
+// setFromFile задает переменную окружения name из .env или файла конфигурации.
+func setFromFile(name, value string) error {
+	fromFile[name] = value
+	return os.Setenv(name, value)
+}
+
+// resetFromFile удаляет переменные, заданные setFromFile и с тех пор
+// не измененные, чтобы повторный Load взял значения из изменившихся файлов,
+// а удаленные из файлов параметры вернулись к значениям по умолчанию.
+func resetFromFile() {
+	for name, value := range fromFile {
+		if os.Getenv(name) == value {
+			os.Unsetenv(name)
+		}
+	}
+	clear(fromFile)
+}
+
+// check возвращает значение, прочитанное get, и запоминает его ошибку в v.
+func check[T any](v *validator, get func() (T, error)) T {
+	value, err := get()
+	v.add(err)
+	return value
+}
+
+// Load читает конфигурацию приложения из переменных окружения, .env файла
+// в корне проекта и файла YAML из TODO_CONFIG (см. loadFile) и настраивает
+// журнал по TODO_LOG_LEVEL и TODO_LOG_FORMAT.
+// Вызывается при старте приложения, результат передается пакетам db, server
+// и api явно. Повторный вызов, например по SIGHUP, перечитывает .env и файл
+// конфигурации заново (см. setFromFile).
+//
+// Неверные значения не заменяются значениями по умолчанию: Load
+// проверяет все переменные и возвращает *ValidationError со списком каждой
+// неверной переменной и её значения.
+func Load() (Config, error) {
+	var v validator
+
+	// Сбрасываем значения прошлой загрузки и загружаем файл .env
+	resetFromFile()
+	if env, err := godotenv.Read(); err == nil {
+		for name, value := range env {
+			if _, ok := os.LookupEnv(name); !ok {
+				v.add(setFromFile(name, value))
+			}
+		}
+	}
+
+	unknown := check(&v, loadFile)
+
+	// Настраиваем журнал до чтения остальных параметров, чтобы они логировались в нужном формате
+	level, format := check(&v, getLogLevel), check(&v, getLogFormat)
+	slog.SetDefault(NewLogger(logOutput, level, format))
+	if len(unknown) > 0 {
+		slog.Warn("Неизвестные параметры в файле конфигурации пропущены", "file", os.Getenv("TODO_CONFIG"), "keys", unknown)
+	}
+
+	driver, dsn, err := getDatabase()
+	v.add(err)
+	port := check(&v, getPort)
+	pathDB := getPathDB()
+	telegramToken, telegramChat := getTelegram()
+	jwtSecret := os.Getenv("TODO_JWT_SECRET")
+
+	c := Config{
+		LimitTask:      check(&v, getLimitTasks),
+		MaxLimit:       check(&v, getMaxLimit),
+		MaxBodySize:    check(&v, getMaxBodySize),
+		MaxRequestBody: check(&v, getMaxRequestBody),
+		PathToDB:       pathDB,
+		DBDriver:       driver,
+		DSN:            dsn,
+		PortServ:       port,
+		ListenAddr: check(&v, func() (string, error) {
+			return getListenAddr(port)
+		}),
+		TLS:             check(&v, getTLS),
+		WebDir:          check(&v, getWebDir),
+		PasswordTest:    getPassword(),
+		PasswordHash:    check(&v, getPasswordHash),
+		SQLDebug:        check(&v, getSQLDebug),
+		SQLSlow:         check(&v, getSQLSlow),
+		Maintenance:     check(&v, getMaintenance),
+		Timeout:         check(&v, getTimeout),
+		ShutdownTimeout: check(&v, getShutdownTimeout),
+		Metrics:         check(&v, getMetrics),
+		Debug:           check(&v, getDebug),
+		LogLevel:        level,
+		LogFormat:       format,
+		TokenTTL:        check(&v, getTokenTTL),
+		SigninFailures:  check(&v, getSigninFailures),
+		SigninWindow:    check(&v, getSigninWindow),
+		TrustProxy:      check(&v, getTrustProxy),
+		ClientIPHeader:  getClientIPHeader(),
+		CORS:            check(&v, getCORS),
+		JWTSecret:       jwtSecret,
+		JWTKeyFile: check(&v, func() (string, error) {
+			return getJWTKeyFile(driver, jwtSecret)
+		}),
+		Location:        check(&v, getLocation),
+		BackupDir:       getBackupDir(pathDB),
+		RestoreFrom:     os.Getenv("TODO_RESTORE_FROM"),
+		BackupInterval:  check(&v, getBackupInterval),
+		BackupKeep:      check(&v, getBackupKeep),
+		WebhookQueue:    check(&v, getWebhookQueue),
+		WebhookAttempts: check(&v, getWebhookAttempts),
+		TelegramToken:   telegramToken,
+		TelegramChatID:  telegramChat,
+		TelegramEvery:   check(&v, getTelegramInterval),
+		SMTP:            check(&v, getSMTP),
+	}
+	if err := v.err(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// intVar читает целое число из переменной окружения name.
+// Для незаданной переменной возвращает ok = false, для нечислового значения
+// или значения меньше min - ошибку.
+func intVar(name string, min int) (value int, ok bool, err error) {
+	str := os.Getenv(name)
+	if str == "" {
+		return 0, false, nil
+	}
+	value, err = strconv.Atoi(str)
+	if err == nil && value >= min {
+		return value, true, nil
+	}
+	switch min {
+	case 0:
+		return 0, false, varError(name, str, "ожидается неотрицательное целое число")
+	case 1:
+		return 0, false, varError(name, str, "ожидается положительное целое число")
+	}
+	return 0, false, varError(name, str, "ожидается целое число не меньше %d", min)
+}
+
+// durationVar читает длительность в формате time.ParseDuration ("30s", "1h")
+// из переменной окружения name.
+// Для незаданной переменной возвращает ok = false, для неверного значения
+// или значения меньше min - ошибку.
+func durationVar(name string, min time.Duration) (value time.Duration, ok bool, err error) {
+	str := os.Getenv(name)
+	if str == "" {
+		return 0, false, nil
+	}
+	value, err = time.ParseDuration(str)
+	if err != nil {
+		return 0, false, varError(name, str, "ожидается длительность вроде 30s, 15m или 24h")
+	}
+	if value < min {
+		if min == 0 {
+			return 0, false, varError(name, str, "длительность не может быть отрицательной")
+		}
+		return 0, false, varError(name, str, "ожидается длительность не меньше %v", min)
+	}
+	return value, true, nil
+}
+
+// boolVar читает признак из переменной окружения name: true/false, 1/0
+// и другие значения strconv.ParseBool. Незаданная переменная - false.
+func boolVar(name string) (bool, error) {
+	str := os.Getenv(name)
+	if str == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(str)
+	if err != nil {
+		return false, varError(name, str, "ожидается true или false")
+	}
+	return value, nil
+}
 
+// portVar читает TCP-порт из переменной окружения name: число от 1 до 65535.
+// Незаданная переменная - пустая строка.
+func portVar(name string) (string, error) {
+	port := os.Getenv(name)
+	if port == "" {
+		return "", nil
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", varError(name, port, "ожидается порт от 1 до 65535")
+	}
+	return port, nil
 }
 
 // getLimitTasks возвращает максимальное количество задач для отображения.
 // Читает значение из переменной окружения TODO_LIMIT_TASKS.
-// При ошибке парсинга или отсутствии или отрицательном значении возвращает DefaultLimitTasks = 50.
-func getLimitTasks() int {
-	if limitStr := os.Getenv("TODO_LIMIT_TASKS"); limitStr != "" {
-		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
-			log.Printf("Будет выведено до %v задач \n", limit)
-			return limit
-		}
+// При отсутствии значения возвращает DefaultLimitTasks = 50,
+// для нечислового или неположительного значения - ошибку.
+func getLimitTasks() (int, error) {
+	limit, ok, err := intVar("TODO_LIMIT_TASKS", 1)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		slog.Info("Будет выведено задач (по умолчанию)", "limit", DefaultLimitTasks)
+		return DefaultLimitTasks, nil
+	}
+	slog.Info("Будет выведено задач", "limit", limit)
+	return limit, nil
+}
+
+// getMaxLimit возвращает максимальное количество задач, которое клиент
+// может запросить параметром limit.
+// Читает значение из переменной окружения TODO_MAX_LIMIT.
+// При отсутствии значения возвращает DefaultMaxLimit = 500,
+// для нечислового или неположительного значения - ошибку.
+func getMaxLimit() (int, error) {
+	limit, ok, err := intVar("TODO_MAX_LIMIT", 1)
+	if !ok {
+		return DefaultMaxLimit, err
+	}
+	slog.Info("Клиент может запросить задач", "max_limit", limit)
+	return limit, nil
+}
+
+// getMaxBodySize возвращает наибольший размер JSON-тела запроса на создание
+// или изменение задачи в байтах.
+// Читает значение в килобайтах из переменной окружения TODO_MAX_BODY_KB.
+// При отсутствии значения возвращает DefaultMaxBodyKB = 64 КБ,
+// для нечислового или неположительного значения - ошибку.
+func getMaxBodySize() (int64, error) {
+	size, ok, err := intVar("TODO_MAX_BODY_KB", 1)
+	if !ok {
+		return DefaultMaxBodyKB << 10, err
 	}
-	log.Printf("Будет выведено до %v задач \n", DefaultLimitTasks)
-	return DefaultLimitTasks
+	slog.Info("Наибольший размер тела запроса задачи", "kb", size)
+	return int64(size) << 10, nil
+}
+
+// getMaxRequestBody возвращает наибольший размер тела запроса к API в байтах.
+// Читает значение из переменной окружения TODO_MAX_BODY_BYTES.
+// При отсутствии значения возвращает DefaultMaxRequest = 1 МБ,
+// для нечислового или неположительного значения - ошибку.
+func getMaxRequestBody() (int64, error) {
+	size, ok, err := intVar("TODO_MAX_BODY_BYTES", 1)
+	if !ok {
+		return DefaultMaxRequest, err
+	}
+	slog.Info("Наибольший размер тела запроса к API", "bytes", size)
+	return int64(size), nil
 }
 
 // getPort возвращает TCP-порт для HTTP сервера.
 // Читает значение из переменной окружения TODO_PORT.
-// Если значение не задано, возвращает DefaultPort = 7540.
-func getPort() string {
-	if port := os.Getenv("TODO_PORT"); port != "" {
-		log.Printf("Сервер запущен на порту %v \n", port)
-		return port
+// Если значение не задано, возвращает DefaultPort = 7540,
+// для значения не от 1 до 65535 - ошибку.
+func getPort() (string, error) {
+	port, err := portVar("TODO_PORT")
+	if err != nil {
+		return "", err
+	}
+	if port == "" {
+		slog.Info("Сервер запущен на порту (по умолчанию)", "port", DefaultPort)
+		return DefaultPort, nil
+	}
+	slog.Info("Сервер запущен на порту", "port", port)
+	return port, nil
+}
+
+// getListenAddr возвращает адрес, который слушает HTTP-сервер.
+// Читает адрес host:port (например, 127.0.0.1:7540 или [::1]:7540) из переменной
+// окружения TODO_LISTEN_ADDR, он заменяет TODO_PORT. При отсутствии сервер
+// слушает порт port на всех интерфейсах, как раньше.
+// Возвращает ошибку для адреса, который не разбирает net.ResolveTCPAddr.
+func getListenAddr(port string) (string, error) {
+	addr := os.Getenv("TODO_LISTEN_ADDR")
+	if addr == "" {
+		return ":" + port, nil
+	}
+	if _, err := net.ResolveTCPAddr("tcp", addr); err != nil {
+		return "", varError("TODO_LISTEN_ADDR", addr, "неверный адрес: %w", err)
 	}
-	log.Printf("Сервер запущен на порту  %v (по умолчанию) \n", DefaultPort)
-	return DefaultPort
+	return addr, nil
+}
+
+// getTLS возвращает настройки HTTPS из переменных окружения TODO_TLS_CERT,
+// TODO_TLS_KEY и TODO_HTTP_REDIRECT_PORT.
+// Возвращает ошибку, если задан только один из файлов, файлы не читаются
+// или не подходят друг к другу, или порт перенаправления указан неверно:
+// сервер с такими настройками не должен запускаться по HTTP молча.
+func getTLS() (TLSConfig, error) {
+	c := TLSConfig{
+		Cert: os.Getenv("TODO_TLS_CERT"),
+		Key:  os.Getenv("TODO_TLS_KEY"),
+	}
+	redirectPort, err := portVar("TODO_HTTP_REDIRECT_PORT")
+	switch {
+	case c.Cert == "" && c.Key == "":
+		if os.Getenv("TODO_HTTP_REDIRECT_PORT") != "" {
+			slog.Warn("TODO_HTTP_REDIRECT_PORT работает только вместе с TODO_TLS_CERT и TODO_TLS_KEY, перенаправление выключено")
+		}
+		return TLSConfig{}, nil
+	case c.Cert == "":
+		return TLSConfig{}, varError("TODO_TLS_CERT", "", "для HTTPS нужны и TODO_TLS_CERT, и TODO_TLS_KEY")
+	case c.Key == "":
+		return TLSConfig{}, varError("TODO_TLS_KEY", "", "для HTTPS нужны и TODO_TLS_CERT, и TODO_TLS_KEY")
+	case err != nil:
+		return TLSConfig{}, err
+	}
+	c.RedirectPort = redirectPort
+	if _, err := tls.LoadX509KeyPair(c.Cert, c.Key); err != nil {
+		return TLSConfig{}, varError("TODO_TLS_CERT", c.Cert, "не удалось загрузить сертификат с ключом TODO_TLS_KEY: %w", err)
+	}
+	slog.Info("Сервер работает по HTTPS", "cert", c.Cert, "redirect_port", c.RedirectPort)
+	return c, nil
+}
+
+// getWebDir возвращает каталог, из которого отдается веб-интерфейс вместо
+// встроенного в бинарный файл, например при разработке интерфейса.
+// Читает значение из переменной окружения TODO_WEB_DIR.
+// При отсутствии возвращает пустую строку: используется встроенная копия.
+// Возвращает ошибку, если каталога нет: иначе сайт молча отвечал бы 404.
+func getWebDir() (string, error) {
+	dir := os.Getenv("TODO_WEB_DIR")
+	if dir == "" {
+		return "", nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", varError("TODO_WEB_DIR", dir, "%w", err)
+	}
+	if !info.IsDir() {
+		return "", varError("TODO_WEB_DIR", dir, "не каталог")
+	}
+	slog.Info("Веб-интерфейс отдается из каталога", "dir", dir)
+	return dir, nil
 }
 
 // getPathDB возвращает путь к файлу базы данных SQLite.
@@ -85,22 +596,546 @@ func getPort() string {
 // При отсутствии значения возвращает DefaultPathDb = "/data/scheduler.db".
 func getPathDB() string {
 	if pathDB := os.Getenv("TODO_DBFILE"); pathDB != "" {
-		log.Printf("База данных будет открыта по пути: %v \n", pathDB)
+		slog.Info("База данных будет открыта по пути", "path", pathDB)
 		return pathDB
 	}
-	log.Printf("База данных будет октрыта по пути: %v \n", DefaultPathDb)
+	slog.Info("База данных будет открыта по пути (по умолчанию)", "path", DefaultPathDb)
 	return DefaultPathDb
 }
 
+// getBackupDir возвращает каталог для резервных копий БД.
+// Читает значение из переменной окружения TODO_BACKUP_DIR.
+// При отсутствии значения копии сохраняются рядом с файлом БД pathDB.
+func getBackupDir(pathDB string) string {
+	if dir := os.Getenv("TODO_BACKUP_DIR"); dir != "" {
+		slog.Info("Резервные копии сохраняются в каталог", "dir", dir)
+		return dir
+	}
+	return filepath.Dir(pathDB)
+}
+
+// getBackupInterval возвращает период автоматического резервного копирования БД.
+// Читает значение из переменной окружения TODO_BACKUP_INTERVAL в формате
+// time.ParseDuration ("24h", "6h"). При отсутствии значения или 0 автоматические
+// копии не создаются, для неверного или отрицательного значения - ошибка.
+func getBackupInterval() (time.Duration, error) {
+	interval, ok, err := durationVar("TODO_BACKUP_INTERVAL", 0)
+	if !ok || interval == 0 {
+		return 0, err
+	}
+	slog.Info("Автоматическое резервное копирование", "interval", interval)
+	return interval, nil
+}
+
+// getBackupKeep возвращает, сколько последних резервных копий хранить
+// при автоматическом копировании. Читает значение из переменной окружения TODO_BACKUP_KEEP,
+// 0 отключает удаление старых копий.
+// При отсутствии значения возвращает DefaultBackupKeep = 7,
+// для нечислового или отрицательного значения - ошибку.
+func getBackupKeep() (int, error) {
+	keep, ok, err := intVar("TODO_BACKUP_KEEP", 0)
+	if !ok {
+		return DefaultBackupKeep, err
+	}
+	return keep, nil
+}
+
+// getWebhookQueue возвращает размер очереди событий вебхуков.
+// Читает значение из переменной окружения TODO_WEBHOOK_QUEUE: события сверх
+// очереди отбрасываются.
+// При отсутствии значения возвращает DefaultWebhookQueue = 100,
+// для нечислового или неположительного значения - ошибку.
+func getWebhookQueue() (int, error) {
+	queue, ok, err := intVar("TODO_WEBHOOK_QUEUE", 1)
+	if !ok {
+		return DefaultWebhookQueue, err
+	}
+	return queue, nil
+}
+
+// getWebhookAttempts возвращает, сколько раз пытаться доставить событие
+// на адрес вебхука. Читает значение из переменной окружения TODO_WEBHOOK_ATTEMPTS.
+// При отсутствии значения возвращает DefaultWebhookTries = 5,
+// для нечислового или неположительного значения - ошибку.
+func getWebhookAttempts() (int, error) {
+	attempts, ok, err := intVar("TODO_WEBHOOK_ATTEMPTS", 1)
+	if !ok {
+		return DefaultWebhookTries, err
+	}
+	return attempts, nil
+}
+
+// getTelegram возвращает токен бота и чат для уведомлений о задачах.
+// Читает значения из переменных окружения TODO_TELEGRAM_TOKEN и TODO_TELEGRAM_CHAT_ID.
+// Уведомления включаются, только если заданы обе переменные: если задана
+// одна, пишется предупреждение и возвращаются пустые строки.
+// Сам токен в журнал не пишется.
+func getTelegram() (token, chatID string) {
+	token, chatID = os.Getenv("TODO_TELEGRAM_TOKEN"), os.Getenv("TODO_TELEGRAM_CHAT_ID")
+	switch {
+	case token != "" && chatID != "":
+		slog.Info("Уведомления Telegram включены", "chat", chatID)
+		return token, chatID
+	case token != "" || chatID != "":
+		slog.Warn("Для уведомлений Telegram нужны TODO_TELEGRAM_TOKEN и TODO_TELEGRAM_CHAT_ID, уведомления выключены")
+	}
+	return "", ""
+}
+
+// getTelegramInterval возвращает период проверки задач для уведомлений Telegram.
+// Читает значение из переменной окружения TODO_TELEGRAM_INTERVAL в формате
+// time.ParseDuration ("1h", "30m").
+// При отсутствии значения возвращает DefaultNotifyEvery = 1h,
+// для неверного или неположительного значения - ошибку.
+func getTelegramInterval() (time.Duration, error) {
+	interval, ok, err := durationVar("TODO_TELEGRAM_INTERVAL", time.Second)
+	if !ok {
+		return DefaultNotifyEvery, err
+	}
+	return interval, nil
+}
+
+// getSMTP возвращает настройки ежедневной сводки по почте из переменных
+// окружения TODO_SMTP_HOST, TODO_SMTP_PORT (по умолчанию 587), TODO_SMTP_USER,
+// TODO_SMTP_PASS, TODO_SMTP_FROM, TODO_SMTP_TO (адреса через запятую)
+// и TODO_SMTP_AT - время отправки ЧЧ:ММ (по умолчанию 08:00).
+// Если задана только часть из TODO_SMTP_HOST, TODO_SMTP_FROM и TODO_SMTP_TO,
+// пишется предупреждение и сводка выключается. Пароль в журнал не пишется.
+// Возвращает ошибку для неверного порта или времени отправки.
+func getSMTP() (SMTPConfig, error) {
+	c := SMTPConfig{
+		Host: os.Getenv("TODO_SMTP_HOST"),
+		User: os.Getenv("TODO_SMTP_USER"),
+		Pass: os.Getenv("TODO_SMTP_PASS"),
+		From: os.Getenv("TODO_SMTP_FROM"),
+		At:   DefaultSMTPAt,
+	}
+	for _, addr := range strings.Split(os.Getenv("TODO_SMTP_TO"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			c.To = append(c.To, addr)
+		}
+	}
+	port, err := portVar("TODO_SMTP_PORT")
+	if err != nil {
+		return SMTPConfig{}, err
+	}
+	c.Port = cmp.Or(port, DefaultSMTPPort)
+	if atStr := os.Getenv("TODO_SMTP_AT"); atStr != "" {
+		at, err := time.Parse("15:04", atStr)
+		if err != nil {
+			return SMTPConfig{}, varError("TODO_SMTP_AT", atStr, "ожидается время ЧЧ:ММ, например 08:00")
+		}
+		c.At = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	}
+
+	switch {
+	case c.Enabled():
+		slog.Info("Ежедневная сводка по почте включена", "host", c.Host, "to", c.To, "at", c.At)
+		return c, nil
+	case c.Host != "" || c.From != "" || len(c.To) > 0:
+		slog.Warn("Для сводки по почте нужны TODO_SMTP_HOST, TODO_SMTP_FROM и TODO_SMTP_TO, сводка выключена")
+	}
+	return SMTPConfig{}, nil
+}
+
 // getPassword возвращает тестовый пароль для авторизации.
 // Читает значение из переменной окружения TODO_PASSWORD.
-// При отсутствии значения пароль не требуется.
-// В случае ошибки выставляется пароль 1234.
+// При отсутствии значения используется пароль 1234.
+// Без пароля или с паролем 1234 сервер запускается, но пишет предупреждение.
+// Сам пароль в журнал не пишется.
 func getPassword() string {
-	if password := os.Getenv("TODO_PASSWORD"); password != "" {
-		log.Printf("Пароль для входа %v \n", password)
-		return password
+	password := os.Getenv("TODO_PASSWORD")
+	switch password {
+	case "":
+		slog.Warn("Используется пароль для входа по умолчанию, задайте TODO_PASSWORD или TODO_PASSWORD_HASH")
+		return DefaultTestPassword
+	case DefaultTestPassword:
+		slog.Warn("TODO_PASSWORD совпадает с паролем по умолчанию, задайте другой пароль")
+	default:
+		slog.Info("Пароль для входа задан")
+	}
+	return password
+}
+
+// getPasswordHash возвращает bcrypt-хеш пароля для входа.
+// Читает значение из переменной окружения TODO_PASSWORD_HASH.
+// Если хеш задан, он имеет приоритет над TODO_PASSWORD, и пароль проверяется по нему.
+// Возвращает ошибку, если значение не является bcrypt-хешем:
+// запускать сервер с неработающим паролем нельзя. Значение в ошибку не попадает.
+func getPasswordHash() (string, error) {
+	hash := os.Getenv("TODO_PASSWORD_HASH")
+	if hash == "" {
+		return "", nil
+	}
+	if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+		return "", varError("TODO_PASSWORD_HASH", "", "значение не является bcrypt-хешем: %w", err)
+	}
+	slog.Info("Пароль для входа проверяется по bcrypt-хешу из TODO_PASSWORD_HASH")
+	return hash, nil
+}
+
+// Значения TODO_DB_DRIVER.
+const (
+	DBDriverSQLite   = "sqlite"
+	DBDriverPostgres = "postgres"
+)
+
+// getDatabase возвращает СУБД и строку подключения к ней.
+// Читает TODO_DB_DRIVER (sqlite или postgres) и TODO_DSN.
+// По умолчанию используется SQLite с файлом из TODO_DBFILE, как раньше.
+// Для PostgreSQL строка подключения TODO_DSN обязательна.
+func getDatabase() (string, string, error) {
+	driver := os.Getenv("TODO_DB_DRIVER")
+	dsn := os.Getenv("TODO_DSN")
+	switch driver {
+	case "", DBDriverSQLite:
+		return DBDriverSQLite, "", nil
+	case DBDriverPostgres:
+		if dsn == "" {
+			return "", "", varError("TODO_DSN", "", "не задана строка подключения, обязательная при TODO_DB_DRIVER=postgres")
+		}
+		slog.Info("Задачи хранятся в PostgreSQL")
+		return driver, dsn, nil
+	}
+	return "", "", varError("TODO_DB_DRIVER", driver, "неизвестная СУБД, ожидается sqlite или postgres")
+}
+
+// getJWTKeyFile возвращает файл ключа подписи токенов из TODO_JWT_KEY_FILE.
+// Пустое значение означает файл jwt.key в каталоге файла SQLite. У PostgreSQL
+// такого каталога нет, поэтому без ключа secret (TODO_JWT_SECRET) файл
+// нужно задать явно, иначе - ошибка.
+func getJWTKeyFile(driver, secret string) (string, error) {
+	path := os.Getenv("TODO_JWT_KEY_FILE")
+	if path == "" && secret == "" && driver == DBDriverPostgres {
+		return "", varError("TODO_JWT_KEY_FILE", "",
+			"не задан файл ключа подписи токенов, обязательный при TODO_DB_DRIVER=postgres без TODO_JWT_SECRET")
+	}
+	return path, nil
+}
+
+// getLocation возвращает часовой пояс дат задач.
+// Читает IANA-имя пояса (например, Europe/Moscow) из переменной окружения TODO_TIMEZONE.
+// При отсутствии возвращает системный пояс time.Local, как раньше.
+// Возвращает ошибку для неизвестного пояса: иначе "сегодня" считалось бы не там.
+func getLocation() (*time.Location, error) {
+	name := os.Getenv("TODO_TIMEZONE")
+	if name == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, varError("TODO_TIMEZONE", name, "неизвестный часовой пояс: %w", err)
+	}
+	slog.Info("Даты задач считаются в часовом поясе", "timezone", location.String())
+	return location, nil
+}
+
+// getSQLDebug возвращает признак отладочного логирования SQL-запросов.
+// Читает значение из переменной окружения TODO_SQL_DEBUG.
+// При отсутствии логирование выключено, для неверного значения - ошибка.
+func getSQLDebug() (bool, error) {
+	debug, err := boolVar("TODO_SQL_DEBUG")
+	if debug {
+		slog.Info("Включено отладочное логирование SQL-запросов")
+	}
+	return debug, err
+}
+
+// getSQLSlow возвращает порог, после которого SQL-запрос считается медленным.
+// Читает значение в миллисекундах из переменной окружения TODO_SQL_SLOW_MS.
+// Значение 0 отключает журнал медленных запросов.
+// При отсутствии значения возвращает DefaultSQLSlowMs = 200,
+// для нечислового или отрицательного значения - ошибку.
+func getSQLSlow() (time.Duration, error) {
+	slow, ok, err := intVar("TODO_SQL_SLOW_MS", 0)
+	if !ok {
+		return DefaultSQLSlowMs * time.Millisecond, err
+	}
+	slog.Info("Порог медленных SQL-запросов", "ms", slow)
+	return time.Duration(slow) * time.Millisecond, nil
+}
+
+// getMaintenance возвращает признак запуска в режиме обслуживания.
+// Читает значение из переменной окружения TODO_MAINTENANCE.
+// При отсутствии режим выключен, для неверного значения - ошибка.
+func getMaintenance() (bool, error) {
+	mode, err := boolVar("TODO_MAINTENANCE")
+	if mode {
+		slog.Warn("Сервер запущен в режиме обслуживания, изменения задач запрещены")
+	}
+	return mode, err
+}
+
+// getTimeout возвращает максимальное время обработки одного HTTP-запроса.
+// Читает значение из переменной окружения TODO_REQUEST_TIMEOUT в формате
+// time.ParseDuration ("30s", "1m"), 0 отключает ограничение.
+// При отсутствии значения возвращает DefaultTimeout,
+// для неверного или отрицательного значения - ошибку.
+func getTimeout() (time.Duration, error) {
+	timeout, ok, err := durationVar("TODO_REQUEST_TIMEOUT", 0)
+	if !ok {
+		return DefaultTimeout, err
+	}
+	slog.Info("Время обработки запроса ограничено", "timeout", timeout)
+	return timeout, nil
+}
+
+// getShutdownTimeout возвращает время, которое сервер при остановке ждет
+// завершения начатых запросов.
+// Читает значение из переменной окружения TODO_SHUTDOWN_TIMEOUT в формате time.ParseDuration.
+// При отсутствии значения возвращает DefaultShutdown,
+// для неверного или отрицательного значения - ошибку.
+func getShutdownTimeout() (time.Duration, error) {
+	grace, ok, err := durationVar("TODO_SHUTDOWN_TIMEOUT", 0)
+	if !ok {
+		return DefaultShutdown, err
+	}
+	slog.Info("Время на завершение запросов при остановке", "timeout", grace)
+	return grace, nil
+}
+
+// getMetrics возвращает признак включения эндпоинта /metrics.
+// Читает значение из переменной окружения TODO_METRICS.
+// При отсутствии метрики выключены, для неверного значения - ошибка.
+func getMetrics() (bool, error) {
+	metrics, err := boolVar("TODO_METRICS")
+	if metrics {
+		slog.Info("Включены метрики Prometheus на /metrics")
+	}
+	return metrics, err
+}
+
+// getDebug возвращает признак включения отладочных эндпоинтов
+// /debug/pprof/ и /api/admin/runtime.
+// Читает значение из переменной окружения TODO_DEBUG.
+// При отсутствии эндпоинты выключены, для неверного значения - ошибка.
+func getDebug() (bool, error) {
+	debug, err := boolVar("TODO_DEBUG")
+	if debug {
+		slog.Warn("Включены отладочные эндпоинты /debug/pprof/ и /api/admin/runtime")
+	}
+	return debug, err
+}
+
+// getTokenTTL возвращает срок жизни JWT-токена.
+// Читает значение из переменной окружения TODO_TOKEN_TTL в формате time.ParseDuration ("8h", "720h").
+// Значение должно быть в пределах от MinTokenTTL до MaxTokenTTL.
+// При отсутствии значения возвращает DefaultTokenTTL = 8 часов,
+// для неверного значения или значения вне пределов - ошибку.
+func getTokenTTL() (time.Duration, error) {
+	ttl, ok, err := durationVar("TODO_TOKEN_TTL", MinTokenTTL)
+	if !ok {
+		return DefaultTokenTTL, err
+	}
+	if ttl > MaxTokenTTL {
+		return DefaultTokenTTL, varError("TODO_TOKEN_TTL", os.Getenv("TODO_TOKEN_TTL"), "срок жизни токена больше %v", MaxTokenTTL)
+	}
+	slog.Info("Срок жизни токена", "ttl", ttl)
+	return ttl, nil
+}
+
+// getSigninFailures возвращает, сколько неудачных попыток входа с одного IP
+// допускается за окно TODO_SIGNIN_WINDOW до блокировки.
+// Читает значение из переменной окружения TODO_SIGNIN_MAX_FAILURES.
+// При отсутствии значения возвращает DefaultSigninFails = 5,
+// для нечислового или неположительного значения - ошибку.
+func getSigninFailures() (int, error) {
+	failures, ok, err := intVar("TODO_SIGNIN_MAX_FAILURES", 1)
+	if !ok {
+		return DefaultSigninFails, err
+	}
+	return failures, nil
+}
+
+// getSigninWindow возвращает окно подсчета неудачных попыток входа.
+// Читает значение из переменной окружения TODO_SIGNIN_WINDOW в формате time.ParseDuration ("1m", "15m").
+// При отсутствии значения возвращает DefaultSigninWindow = 1 минута,
+// для неверного значения или значения меньше MinSigninWindow - ошибку.
+func getSigninWindow() (time.Duration, error) {
+	window, ok, err := durationVar("TODO_SIGNIN_WINDOW", MinSigninWindow)
+	if !ok {
+		return DefaultSigninWindow, err
+	}
+	return window, nil
+}
+
+// getTrustProxy возвращает признак работы за доверенным обратным прокси.
+// Читает значение из переменной окружения TODO_TRUST_PROXY. Если включено,
+// заголовок X-Forwarded-Proto учитывается при выставлении атрибута Secure у куки.
+// При отсутствии выключено, для неверного значения - ошибка.
+func getTrustProxy() (bool, error) {
+	trust, err := boolVar("TODO_TRUST_PROXY")
+	if trust {
+		slog.Info("Заголовки X-Forwarded-* от прокси считаются доверенными")
+	}
+	return trust, err
+}
+
+// getCORS возвращает настройки CORS из переменных окружения TODO_CORS_ORIGINS -
+// источники через запятую (https://todo.example.com) или "*" - и
+// TODO_CORS_CREDENTIALS - разрешить браузеру отправлять куку token.
+// Без TODO_CORS_ORIGINS запросы с других источников не разрешены, как раньше.
+// Возвращает ошибку для источника не в виде scheme://host[:port] и для "*"
+// вместе с TODO_CORS_CREDENTIALS: браузеры не принимают такой ответ.
+func getCORS() (CORSConfig, error) {
+	var c CORSConfig
+	for _, origin := range strings.Split(os.Getenv("TODO_CORS_ORIGINS"), ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		if origin != "*" {
+			u, err := url.Parse(origin)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+				u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+				return CORSConfig{}, varError("TODO_CORS_ORIGINS", origin, "источник должен быть вида https://example.com")
+			}
+			origin = strings.ToLower(origin)
+		}
+		c.Origins = append(c.Origins, origin)
 	}
-	log.Printf("Пароль для входа (по умолчанию) %v \n", DefaultTestPassword)
-	return DefaultTestPassword
+	cred, err := boolVar("TODO_CORS_CREDENTIALS")
+	if err != nil {
+		return CORSConfig{}, err
+	}
+	c.Credentials = cred
+	if c.Credentials && slices.Contains(c.Origins, "*") {
+		return CORSConfig{}, varError("TODO_CORS_CREDENTIALS", os.Getenv("TODO_CORS_CREDENTIALS"),
+			"несовместим с TODO_CORS_ORIGINS=*: перечислите источники явно")
+	}
+	if c.Enabled() {
+		slog.Info("Запросы к API с других источников разрешены", "origins", c.Origins, "credentials", c.Credentials)
+	}
+	return c, nil
+}
+
+// getClientIPHeader возвращает заголовок, из которого берется IP клиента
+// за доверенным прокси (например, X-Forwarded-For или X-Real-IP).
+// Читает значение из переменной окружения TODO_CLIENT_IP_HEADER.
+// При отсутствии значения IP берется из адреса соединения.
+func getClientIPHeader() string {
+	if header := os.Getenv("TODO_CLIENT_IP_HEADER"); header != "" {
+		slog.Info("IP клиента берется из заголовка", "header", header)
+		return http.CanonicalHeaderKey(header)
+	}
+	return ""
+}
+
+// getLogLevel возвращает минимальный уровень записей журнала.
+// Читает значение из переменной окружения TODO_LOG_LEVEL: debug, info, warn или error.
+// При отсутствии возвращает info, для неизвестного уровня - info и ошибку.
+func getLogLevel() (slog.Level, error) {
+	var level slog.Level
+	levelStr := os.Getenv("TODO_LOG_LEVEL")
+	if levelStr == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(levelStr)); err != nil {
+		return slog.LevelInfo, varError("TODO_LOG_LEVEL", levelStr, "ожидается debug, info, warn или error")
+	}
+	return level, nil
+}
+
+// getLogFormat возвращает формат журнала из переменной окружения TODO_LOG_FORMAT.
+// При отсутствии возвращает DefaultLogFormat, для неизвестного формата -
+// DefaultLogFormat и ошибку.
+func getLogFormat() (string, error) {
+	switch format := os.Getenv("TODO_LOG_FORMAT"); format {
+	case "":
+		return DefaultLogFormat, nil
+	case LogFormatText, LogFormatJSON:
+		return format, nil
+	default:
+		return DefaultLogFormat, varError("TODO_LOG_FORMAT", format, "ожидается text или json")
+	}
+}
+
+// NewLogger создает логгер, пишущий в w записи не ниже level
+// в формате format (LogFormatText или LogFormatJSON).
+// Записи с контекстом запроса API получают атрибут request_id (см. WithRequestID).
+// Пароли, ключи и токены в записи не попадают (см. redactAttr).
+func NewLogger(w io.Writer, level slog.Level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+	if format == LogFormatJSON {
+		return slog.New(requestIDHandler{slog.NewJSONHandler(w, opts)})
+	}
+	return slog.New(requestIDHandler{slog.NewTextHandler(w, opts)})
+}
+
+// sensitiveKeys - части имен атрибутов журнала, значения которых заменяются
+// на "***": пароли, ключи подписи, токены, куки и заголовок Authorization.
+var sensitiveKeys = []string{"password", "passwd", "secret", "token", "authorization", "cookie", "jwt"}
+
+// jwtPattern находит JWT в тексте записи: три части base64url через точку,
+// заголовок начинается с {" и поэтому с eyJ.
+var jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+
+// redactAttr скрывает секреты в атрибуте записи журнала: значения атрибутов
+// с именами из sensitiveKeys, pass и dsn целиком, а JWT - в любом тексте,
+// включая сообщение и ошибки. Так секрет не попадет в журнал, даже если его
+// по ошибке передадут атрибутом.
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	if key == "pass" || key == "dsn" || slices.ContainsFunc(sensitiveKeys, func(s string) bool {
+		return strings.Contains(key, s)
+	}) {
+		if a.Value.Kind() == slog.KindGroup {
+			return a
+		}
+		return slog.String(a.Key, redactedValue)
+	}
+
+	var text string
+	switch a.Value.Kind() {
+	case slog.KindString:
+		text = a.Value.String()
+	case slog.KindAny:
+		err, ok := a.Value.Any().(error)
+		if !ok {
+			return a
+		}
+		text = err.Error()
+	default:
+		return a
+	}
+	if jwtPattern.MatchString(text) {
+		return slog.String(a.Key, jwtPattern.ReplaceAllString(text, redactedValue))
+	}
+	return a
+}
+
+// requestIDKey - ключ ID запроса в контексте.
+type requestIDKey struct{}
+
+// WithRequestID возвращает копию ctx с ID запроса id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID возвращает ID запроса из ctx или пустую строку.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDHandler добавляет к записям журнала ID запроса из их контекста,
+// чтобы все записи об одном запросе находились по ID из ответа клиенту.
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r = r.Clone()
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
 }
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileKeys - параметры файла конфигурации TODO_CONFIG и переменные окружения,
+// которые они задают. Параметры разделов записываются через точку:
+//
+//	port: 7540
+//	tls:
+//	  cert: /etc/todo/cert.pem
+var fileKeys = map[string]string{
+	"port":             "TODO_PORT",
+	"listen_addr":      "TODO_LISTEN_ADDR",
+	"db_file":          "TODO_DBFILE",
+	"db_driver":        "TODO_DB_DRIVER",
+	"dsn":              "TODO_DSN",
+	"limit_tasks":      "TODO_LIMIT_TASKS",
+	"max_limit":        "TODO_MAX_LIMIT",
+	"max_body_kb":      "TODO_MAX_BODY_KB",
+	"max_body_bytes":   "TODO_MAX_BODY_BYTES",
+	"password":         "TODO_PASSWORD",
+	"password_hash":    "TODO_PASSWORD_HASH",
+	"jwt_secret":       "TODO_JWT_SECRET",
+	"jwt_key_file":     "TODO_JWT_KEY_FILE",
+	"token_ttl":        "TODO_TOKEN_TTL",
+	"timezone":         "TODO_TIMEZONE",
+	"web_dir":          "TODO_WEB_DIR",
+	"maintenance":      "TODO_MAINTENANCE",
+	"metrics":          "TODO_METRICS",
+	"debug":            "TODO_DEBUG",
+	"sql_debug":        "TODO_SQL_DEBUG",
+	"sql_slow_ms":      "TODO_SQL_SLOW_MS",
+	"request_timeout":  "TODO_REQUEST_TIMEOUT",
+	"shutdown_timeout": "TODO_SHUTDOWN_TIMEOUT",
+	"log_level":        "TODO_LOG_LEVEL",
+	"log_format":       "TODO_LOG_FORMAT",
+	"trust_proxy":      "TODO_TRUST_PROXY",
+	"client_ip_header": "TODO_CLIENT_IP_HEADER",
+	"restore_from":     "TODO_RESTORE_FROM",
+
+	"tls.cert":          "TODO_TLS_CERT",
+	"tls.key":           "TODO_TLS_KEY",
+	"tls.redirect_port": "TODO_HTTP_REDIRECT_PORT",
+
+	"cors.origins":     "TODO_CORS_ORIGINS",
+	"cors.credentials": "TODO_CORS_CREDENTIALS",
+
+	"signin.max_failures": "TODO_SIGNIN_MAX_FAILURES",
+	"signin.window":       "TODO_SIGNIN_WINDOW",
+
+	"backup.dir":      "TODO_BACKUP_DIR",
+	"backup.interval": "TODO_BACKUP_INTERVAL",
+	"backup.keep":     "TODO_BACKUP_KEEP",
+
+	"webhook.queue":    "TODO_WEBHOOK_QUEUE",
+	"webhook.attempts": "TODO_WEBHOOK_ATTEMPTS",
+
+	"telegram.token":    "TODO_TELEGRAM_TOKEN",
+	"telegram.chat_id":  "TODO_TELEGRAM_CHAT_ID",
+	"telegram.interval": "TODO_TELEGRAM_INTERVAL",
+
+	"smtp.host": "TODO_SMTP_HOST",
+	"smtp.port": "TODO_SMTP_PORT",
+	"smtp.user": "TODO_SMTP_USER",
+	"smtp.pass": "TODO_SMTP_PASS",
+	"smtp.from": "TODO_SMTP_FROM",
+	"smtp.to":   "TODO_SMTP_TO",
+	"smtp.at":   "TODO_SMTP_AT",
+}
+
+// loadFile читает файл конфигурации YAML из TODO_CONFIG, если он задан,
+// и записывает его значения в переменные окружения, которые еще не заданы:
+// переменные окружения и флаги важнее файла, а значения проверяются в Load
+// так же, как значения из окружения. Списки (cors.origins, smtp.to)
+// записываются через запятую.
+//
+// Возвращает параметры файла, которых нет в fileKeys, чтобы Load
+// предупредил об опечатках, и ошибку, если файл не читается или не разбирается.
+func loadFile() (unknown []string, err error) {
+	path := os.Getenv("TODO_CONFIG")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, varError("TODO_CONFIG", path, "%w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, varError("TODO_CONFIG", path, "неверный YAML: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil // пустой файл
+	}
+
+	values := make(map[string]string)
+	if err := fileValues(doc.Content[0], "", values, &unknown); err != nil {
+		return nil, varError("TODO_CONFIG", path, "%w", err)
+	}
+	for key, value := range values {
+		if env := fileKeys[key]; os.Getenv(env) == "" {
+			if err := setFromFile(env, value); err != nil {
+				return nil, varError("TODO_CONFIG", path, "%w", err)
+			}
+		}
+	}
+	slices.Sort(unknown)
+	return unknown, nil
+}
+
+// fileValues собирает в values значения параметров из узла YAML node,
+// ключи которого начинаются с prefix, а неизвестные ключи - в unknown.
+func fileValues(node *yaml.Node, prefix string, values map[string]string, unknown *[]string) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("строка %d: ожидаются параметры вида ключ: значение", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := prefix+node.Content[i].Value, node.Content[i+1]
+		if _, ok := fileKeys[key]; !ok {
+			if value.Kind == yaml.MappingNode && prefix == "" && isSection(key) {
+				if err := fileValues(value, key+".", values, unknown); err != nil {
+					return err
+				}
+				continue
+			}
+			*unknown = append(*unknown, key)
+			continue
+		}
+
+		switch value.Kind {
+		case yaml.ScalarNode:
+			if value.Tag != "!!null" {
+				values[key] = value.Value
+			}
+		case yaml.SequenceNode:
+			items := make([]string, 0, len(value.Content))
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode {
+					return fmt.Errorf("строка %d: %s: ожидается список значений", item.Line, key)
+				}
+				items = append(items, item.Value)
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			return fmt.Errorf("строка %d: %s: ожидается значение", value.Line, key)
+		}
+	}
+	return nil
+}
+
+// isSection сообщает, есть ли в fileKeys параметры раздела name, например tls.
+func isSection(name string) bool {
+	for key := range fileKeys {
+		if strings.HasPrefix(key, name+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// flagVars - флаги командной строки и переменные окружения, которые они заменяют.
+var flagVars = []struct {
+	name, env, usage string
+}{
+	{"port", "TODO_PORT", "`порт` HTTP-сервера"},
+	{"db", "TODO_DBFILE", "`путь` к файлу БД SQLite"},
+	{"limit", "TODO_LIMIT_TASKS", "`число` задач в списке"},
+	{"password", "TODO_PASSWORD", "`пароль` для входа"},
+	{"web-dir", "TODO_WEB_DIR", "`каталог` веб-интерфейса вместо встроенного"},
+	{"log-level", "TODO_LOG_LEVEL", "`уровень` журнала: debug, info, warn или error"},
+	{"config", "TODO_CONFIG", "`файл` конфигурации YAML"},
+}
+
+// BindFlags регистрирует в fs флаги --port, --db, --limit, --password,
+// --web-dir, --log-level и --config, заменяющие переменные окружения.
+//
+// Значение флага при разборе записывается в его переменную окружения, поэтому
+// Load проверяет его так же, как значение из окружения, а флаг имеет
+// приоритет над окружением и .env. Вызывается до fs.Parse и Load.
+func BindFlags(fs *flag.FlagSet) {
+	for _, f := range flagVars {
+		fs.Func(f.name, fmt.Sprintf("%s (вместо %s)", f.usage, f.env), func(value string) error {
+			return os.Setenv(f.env, value)
+		})
+	}
+}
+
+// redactedValue заменяет секреты в выводе WriteJSON.
+const redactedValue = "***"
+
+// Vars возвращает действующую конфигурацию в виде переменных окружения,
+// которые её задают, со значениями по умолчанию для незаданных.
+// Пароли, ключи и токены заменены на "***", если заданы.
+func (c Config) Vars() map[string]string {
+	secret := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return redactedValue
+	}
+	at := time.Time{}.Add(c.SMTP.At)
+	return map[string]string{
+		"TODO_LIMIT_TASKS":         strconv.Itoa(c.LimitTask),
+		"TODO_MAX_LIMIT":           strconv.Itoa(c.MaxLimit),
+		"TODO_MAX_BODY_KB":         strconv.FormatInt(c.MaxBodySize>>10, 10),
+		"TODO_MAX_BODY_BYTES":      strconv.FormatInt(c.MaxRequestBody, 10),
+		"TODO_DBFILE":              c.PathToDB,
+		"TODO_DB_DRIVER":           c.DBDriver,
+		"TODO_DSN":                 secret(c.DSN),
+		"TODO_PORT":                c.PortServ,
+		"TODO_LISTEN_ADDR":         c.ListenAddr,
+		"TODO_TLS_CERT":            c.TLS.Cert,
+		"TODO_TLS_KEY":             c.TLS.Key,
+		"TODO_HTTP_REDIRECT_PORT":  c.TLS.RedirectPort,
+		"TODO_WEB_DIR":             c.WebDir,
+		"TODO_PASSWORD":            secret(c.PasswordTest),
+		"TODO_PASSWORD_HASH":       secret(c.PasswordHash),
+		"TODO_SQL_DEBUG":           strconv.FormatBool(c.SQLDebug),
+		"TODO_SQL_SLOW_MS":         strconv.FormatInt(c.SQLSlow.Milliseconds(), 10),
+		"TODO_MAINTENANCE":         strconv.FormatBool(c.Maintenance),
+		"TODO_REQUEST_TIMEOUT":     c.Timeout.String(),
+		"TODO_SHUTDOWN_TIMEOUT":    c.ShutdownTimeout.String(),
+		"TODO_METRICS":             strconv.FormatBool(c.Metrics),
+		"TODO_DEBUG":               strconv.FormatBool(c.Debug),
+		"TODO_LOG_LEVEL":           strings.ToLower(c.LogLevel.String()),
+		"TODO_LOG_FORMAT":          c.LogFormat,
+		"TODO_TOKEN_TTL":           c.TokenTTL.String(),
+		"TODO_SIGNIN_MAX_FAILURES": strconv.Itoa(c.SigninFailures),
+		"TODO_SIGNIN_WINDOW":       c.SigninWindow.String(),
+		"TODO_TRUST_PROXY":         strconv.FormatBool(c.TrustProxy),
+		"TODO_CLIENT_IP_HEADER":    c.ClientIPHeader,
+		"TODO_CORS_ORIGINS":        strings.Join(c.CORS.Origins, ","),
+		"TODO_CORS_CREDENTIALS":    strconv.FormatBool(c.CORS.Credentials),
+		"TODO_JWT_SECRET":          secret(c.JWTSecret),
+		"TODO_JWT_KEY_FILE":        c.JWTKeyFile,
+		"TODO_TIMEZONE":            c.TimeZone().String(),
+		"TODO_BACKUP_DIR":          c.BackupDir,
+		"TODO_RESTORE_FROM":        c.RestoreFrom,
+		"TODO_BACKUP_INTERVAL":     c.BackupInterval.String(),
+		"TODO_BACKUP_KEEP":         strconv.Itoa(c.BackupKeep),
+		"TODO_WEBHOOK_QUEUE":       strconv.Itoa(c.WebhookQueue),
+		"TODO_WEBHOOK_ATTEMPTS":    strconv.Itoa(c.WebhookAttempts),
+		"TODO_TELEGRAM_TOKEN":      secret(c.TelegramToken),
+		"TODO_TELEGRAM_CHAT_ID":    c.TelegramChatID,
+		"TODO_TELEGRAM_INTERVAL":   c.TelegramEvery.String(),
+		"TODO_SMTP_HOST":           c.SMTP.Host,
+		"TODO_SMTP_PORT":           c.SMTP.Port,
+		"TODO_SMTP_USER":           c.SMTP.User,
+		"TODO_SMTP_PASS":           secret(c.SMTP.Pass),
+		"TODO_SMTP_FROM":           c.SMTP.From,
+		"TODO_SMTP_TO":             strings.Join(c.SMTP.To, ","),
+		"TODO_SMTP_AT":             at.Format("15:04"),
+	}
+}
+
+// WriteJSON записывает в w действующую конфигурацию (см. Vars) объектом JSON
+// с переменными по алфавиту, например для вопроса в поддержку.
+func (c Config) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c.Vars())
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// reloadable - переменные, которые Reload применяет без перезапуска сервера.
+// Остальные (порт, путь к БД, TLS и т.д.) используются только при запуске.
+var reloadable = []string{
+	"TODO_LIMIT_TASKS",
+	"TODO_MAX_LIMIT",
+	"TODO_LOG_LEVEL",
+	"TODO_LOG_FORMAT",
+	"TODO_BACKUP_INTERVAL",
+	"TODO_BACKUP_KEEP",
+	"TODO_SIGNIN_MAX_FAILURES",
+	"TODO_SIGNIN_WINDOW",
+}
+
+// Change - изменение переменной конфигурации при перечитывании.
+type Change struct {
+	Var string // имя переменной окружения
+	Old string // прежнее значение, секреты заменены на "***" (см. Vars)
+	New string // новое значение
+}
+
+// String возвращает изменение в виде TODO_LIMIT_TASKS: "50" -> "100".
+func (ch Change) String() string {
+	return fmt.Sprintf("%s: %q -> %q", ch.Var, ch.Old, ch.New)
+}
+
+// Reload возвращает конфигурацию c, в которой параметры из reloadable
+// (лимиты списка задач, уровень и формат журнала, интервал и число
+// резервных копий, ограничение попыток входа) заменены на значения из next, перечитанной Load.
+//
+// applied - изменения, которые попали в результат, ignored - изменения
+// остальных параметров: они вступят в силу только после перезапуска.
+// Оба списка отсортированы по имени переменной. Смена секрета на другой
+// секрет в ignored не попадает: Vars их не различает.
+func (c Config) Reload(next Config) (reloaded Config, applied, ignored []Change) {
+	prev, vars := c.Vars(), next.Vars()
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if prev[name] == vars[name] {
+			continue
+		}
+		ch := Change{Var: name, Old: prev[name], New: vars[name]}
+		if slices.Contains(reloadable, name) {
+			applied = append(applied, ch)
+		} else {
+			ignored = append(ignored, ch)
+		}
+	}
+
+	c.LimitTask = next.LimitTask
+	c.MaxLimit = next.MaxLimit
+	c.LogLevel = next.LogLevel
+	c.LogFormat = next.LogFormat
+	c.BackupInterval = next.BackupInterval
+	c.BackupKeep = next.BackupKeep
+	c.SigninFailures = next.SigninFailures
+	c.SigninWindow = next.SigninWindow
+	return c, applied, ignored
+}
@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotSupported возвращается операциями, которых нет для текущей СУБД.
+var ErrNotSupported = errors.New("not supported by the database driver")
+
+// ErrUIDTaken возвращается при импорте задачи с UID задачи другого пользователя.
+var ErrUIDTaken = errors.New("uid belongs to another user")
+
+// ErrCorrupted возвращается, если проверка целостности нашла повреждения БД.
+var ErrCorrupted = errors.New("database is corrupted")
+
+// Backup сохраняет согласованную копию базы данных в файл destPath
+// с помощью VACUUM INTO. Файл назначения не должен существовать.
+// Для PostgreSQL возвращает ErrNotSupported: копию делает pg_dump.
+func (s *Store) Backup(ctx context.Context, destPath string) error {
+	if s.dialect != sqliteDialect {
+		return fmt.Errorf("backup: %w", ErrNotSupported)
+	}
+	if s.keep != nil {
+		// БД в памяти открыта через VFS memdb, и без явного vfs копия тоже осталась бы в памяти
+		destPath = "file:" + (&url.URL{Path: destPath}).EscapedPath() + "?vfs=unix"
+	}
+	if _, err := s.execSQL(ctx, `VACUUM INTO :path`, sql.Named("path", destPath)); err != nil {
+		return fmt.Errorf("failed to backup database: %w", err)
+	}
+	return nil
+}
+
+// CheckIntegrity выполняет PRAGMA integrity_check и возвращает ошибку
+// ErrCorrupted со списком найденных проблем, если база данных повреждена.
+// Файл, который SQLite не может прочитать, тоже считается поврежденным.
+// Для PostgreSQL целостность обеспечивает сервер, проверка не выполняется.
+func (s *Store) CheckIntegrity(ctx context.Context) error {
+	if s.dialect != sqliteDialect {
+		return nil
+	}
+	rows, err := s.querySQL(ctx, `PRAGMA integrity_check`)
+	if isCorrupt(err) {
+		return fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check integrity: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return fmt.Errorf("failed to scan integrity result: %w", err)
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	if err := rows.Err(); isCorrupt(err) {
+		return fmt.Errorf("%w: %w", ErrCorrupted, err)
+	} else if err != nil {
+		return fmt.Errorf("error during rows iteration: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrCorrupted, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkFile проверяет целостность существующего файла SQLite path (см. CheckIntegrity)
+// до применения миграций, чтобы поврежденная БД не изменялась при открытии.
+func checkFile(path string) error {
+	conn, err := sql.Open(sqliteDialect.driver, path)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+	return (&Store{db: conn, dialect: sqliteDialect}).CheckIntegrity(context.Background())
+}
+
+// ImportTasks добавляет задачи в базу данных в одной транзакции.
+//
+// Задачи добавляются пользователю из ctx.
+// Задачи сопоставляются по UID: задача с уже существующим UID обновляется
+// (удаленная задача восстанавливается), поэтому повторный импорт той же
+// выгрузки не создает дубликатов.
+// Задачам без UID назначается новый. ID задач из выгрузки игнорируются.
+// При ошибке транзакция откатывается и ни одна задача не добавляется.
+// Возвращает количество добавленных или обновленных задач.
+func (s *Store) ImportTasks(ctx context.Context, tasks []*Task) (int, error) {
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		seq, err := nextSeq(ctx, tx)
+		if err != nil {
+			return err
+		}
+		return importTasks(ctx, tx, tasks, seq)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(tasks), nil
+}
+
+// ReplaceTasks удаляет все задачи пользователя из ctx и импортирует tasks
+// так же, как ImportTasks, в одной транзакции: при ошибке прежние задачи остаются.
+// Удаление мягкое, как в DeleteTaskID, поэтому клиенты синхронизации узнают о нем,
+// а задачи с прежними UID из выгрузки восстанавливаются.
+// Возвращает количество импортированных задач.
+func (s *Store) ReplaceTasks(ctx context.Context, tasks []*Task) (int, error) {
+	scope, user := userScope(ctx)
+	live := "SELECT id FROM scheduler WHERE deleted_at IS NULL AND " + scope
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		seq, err := nextSeq(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if err := addTombstones(ctx, tx, seq, "deleted_at IS NULL AND "+scope, user); err != nil {
+			return err
+		}
+		if _, err := execOn(ctx, tx, "DELETE FROM task_trigrams WHERE task_id IN ("+live+")", user); err != nil {
+			return fmt.Errorf("failed to delete trigrams: %w", err)
+		}
+		if _, err := execOn(ctx, tx, "DELETE FROM task_tags WHERE task_id IN ("+live+")", user); err != nil {
+			return fmt.Errorf("failed to delete tags: %w", err)
+		}
+		_, err = execOn(ctx, tx, `
+		UPDATE scheduler
+		SET deleted_at = :now, updated_at = :now, version = version + 1, seq = :seq
+		WHERE deleted_at IS NULL AND `+scope, sql.Named("now", timeNow().UnixMilli()), sql.Named("seq", seq), user)
+		if err != nil {
+			return fmt.Errorf("failed to delete tasks: %w", err)
+		}
+		return importTasks(ctx, tx, tasks, seq)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(tasks), nil
+}
+
+// importTasks добавляет или обновляет по UID задачи tasks в транзакции tx
+// изменением с номером seq.
+func importTasks(ctx context.Context, tx *sql.Tx, tasks []*Task, seq int64) error {
+	query := `
+	INSERT INTO scheduler (date, title, comment, title_fold, comment_fold, repeat, uid, completed, priority, exclude, user_id, updated_at, seq)
+	VALUES (:date, :title, :comment, :title_fold, :comment_fold, :repeat, :uid, :completed, :priority, :exclude, :user_id, :now, :seq)
+	ON CONFLICT (uid) DO UPDATE SET
+		date = excluded.date,
+		title = excluded.title,
+		comment = excluded.comment,
+		title_fold = excluded.title_fold,
+		comment_fold = excluded.comment_fold,
+		repeat = excluded.repeat,
+		completed = excluded.completed,
+		priority = excluded.priority,
+		exclude = excluded.exclude,
+		updated_at = excluded.updated_at,
+		deleted_at = NULL,
+		version = scheduler.version + 1,
+		seq = excluded.seq
+	WHERE scheduler.user_id = excluded.user_id
+	RETURNING id`
+
+	for _, task := range tasks {
+		if task.UID == "" {
+			task.UID = uuid.NewString()
+		}
+		var id int64
+		err := queryRowOn(ctx, tx, query,
+			sql.Named("date", task.Date),
+			sql.Named("title", task.Title),
+			sql.Named("comment", task.Comment),
+			sql.Named("title_fold", foldText(task.Title)),
+			sql.Named("comment_fold", foldText(task.Comment)),
+			sql.Named("repeat", task.Repeat),
+			sql.Named("uid", task.UID),
+			sql.Named("completed", task.Completed),
+			sql.Named("priority", task.Priority),
+			sql.Named("exclude", strings.Join(task.Exclude, excludeSeparator)),
+			sql.Named("user_id", UserID(ctx)),
+			sql.Named("seq", seq),
+			sql.Named("now", timeNow().UnixMilli())).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to import task %s: %w", task.UID, ErrUIDTaken)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to import task: %w", err)
+		}
+		// восстановленная задача больше не числится удаленной
+		if _, err := execOn(ctx, tx, "DELETE FROM deleted_tasks WHERE id = :id", sql.Named("id", id)); err != nil {
+			return fmt.Errorf("failed to restore task: %w", err)
+		}
+		if err := replaceTags(ctx, tx, id, task.Tags); err != nil {
+			return err
+		}
+		if err := updateTrigrams(ctx, tx, id, task.Title, task.Comment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
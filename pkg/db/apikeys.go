@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// APIKey - ключ API для скриптов и cron-задач.
+// В БД хранится только SHA-256 ключа, сам ключ показывается один раз при создании.
+type APIKey struct {
+	ID        int64
+	UserID    int64     // владелец ключа, запросы с ключом работают с его задачами
+	Name      string    // подпись ключа, например "cron"
+	CanAdmin  bool      // ключ может управлять ключами API
+	CreatedAt time.Time // время создания
+}
+
+// ErrAPIKeyNotFound возвращается, если ключ API не найден.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// CreateAPIKey сохраняет ключ API пользователя из ctx по хешу hash.
+func (s *Store) CreateAPIKey(ctx context.Context, hash, name string, canAdmin bool) (APIKey, error) {
+	key := APIKey{UserID: UserID(ctx), Name: name, CanAdmin: canAdmin, CreatedAt: timeNow()}
+	err := s.queryRowSQL(ctx, `
+	INSERT INTO api_keys (user_id, key_hash, name, can_admin, created_at)
+	VALUES (:user_id, :hash, :name, :can_admin, :now)
+	RETURNING id`,
+		sql.Named("user_id", key.UserID),
+		sql.Named("hash", hash),
+		sql.Named("name", name),
+		sql.Named("can_admin", canAdmin),
+		sql.Named("now", key.CreatedAt.UnixMilli())).Scan(&key.ID)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("failed to create api key: %w", err)
+	}
+	return key, nil
+}
+
+// APIKeyByHash возвращает ключ API по SHA-256 ключа.
+// Поиск идет по всем пользователям: ключ сам определяет пользователя.
+// Возвращает ErrAPIKeyNotFound, если ключ не найден или отозван.
+func (s *Store) APIKeyByHash(ctx context.Context, hash string) (APIKey, error) {
+	var key APIKey
+	var created int64
+	err := s.queryRowSQL(ctx, `
+	SELECT id, user_id, name, can_admin, created_at FROM api_keys WHERE key_hash = :hash`,
+		sql.Named("hash", hash)).Scan(&key.ID, &key.UserID, &key.Name, &key.CanAdmin, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return APIKey{}, fmt.Errorf("failed to read api key: %w", err)
+	}
+	key.CreatedAt = time.UnixMilli(created)
+	return key, nil
+}
+
+// DeleteAPIKey отзывает ключ API пользователя из ctx.
+// Возвращает ErrAPIKeyNotFound, если ключ не найден или принадлежит другому пользователю.
+func (s *Store) DeleteAPIKey(ctx context.Context, id string) error {
+	scope, user := userScope(ctx)
+	res, err := s.execSQL(ctx, `DELETE FROM api_keys WHERE id = :id AND `+scope,
+		sql.Named("id", id), user)
+	if err != nil {
+		return fmt.Errorf("failed to delete api key: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
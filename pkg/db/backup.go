@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Имена файлов резервных копий: backup-YYYYMMDD-HHMMSS.db по времени создания.
+const (
+	backupPrefix = "backup-"
+	backupSuffix = ".db"
+	backupStamp  = "20060102-150405"
+)
+
+// ErrBackupExists возвращается, если копия с таким именем уже есть.
+var ErrBackupExists = errors.New("backup already exists")
+
+// BackupName возвращает имя файла резервной копии, созданной в момент t.
+func BackupName(t time.Time) string {
+	return backupPrefix + t.Format(backupStamp) + backupSuffix
+}
+
+// IsBackupName сообщает, что name - имя файла резервной копии (см. BackupName).
+// Пути и другие файлы каталога копий, в том числе сама БД, не подходят.
+func IsBackupName(name string) bool {
+	stamp, ok := strings.CutPrefix(name, backupPrefix)
+	if !ok {
+		return false
+	}
+	stamp, ok = strings.CutSuffix(stamp, backupSuffix)
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(backupStamp, stamp)
+	return err == nil
+}
+
+// BackupToDir сохраняет резервную копию БД (см. Backup) в каталог dir
+// под именем BackupName(now) и возвращает это имя. Каталог создается,
+// если его нет. Возвращает ErrBackupExists, если копия с таким именем уже есть.
+// Недописанная из-за ошибки или отмены ctx копия удаляется.
+func (s *Store) BackupToDir(ctx context.Context, dir string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create backup dir: %w", err)
+	}
+	name := BackupName(now)
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s: %w", name, ErrBackupExists)
+	}
+	if err := s.Backup(ctx, path); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return name, nil
+}
+
+// RotateBackups оставляет в каталоге dir только keep последних резервных копий
+// (см. IsBackupName) и возвращает имена удаленных. Имена копий упорядочены
+// по времени создания, поэтому удаляются первые по алфавиту. Другие файлы
+// каталога не затрагиваются; keep <= 0 ничего не удаляет.
+func RotateBackups(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup dir: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && IsBackupName(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) <= keep {
+		return nil, nil
+	}
+
+	var removed []string
+	for _, name := range names[:len(names)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return removed, fmt.Errorf("failed to remove old backup: %w", err)
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
+
+// RestoreBackup копирует резервную копию src в файл БД dest, если его еще нет.
+// Копия пишется во временный файл рядом с dest и переименовывается после
+// записи на диск, поэтому прерванное восстановление не оставляет неполной БД.
+// Возвращает false без ошибки, если dest уже существует.
+func RestoreBackup(src, dest string) (bool, error) {
+	if _, err := os.Stat(dest); err == nil {
+		return false, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return false, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), filepath.Base(dest)+".restore-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create database file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // после переименования ничего не удаляет
+
+	_, err = io.Copy(tmp, in)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to copy backup: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return false, fmt.Errorf("failed to restore backup: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrCalendarTokenNotFound возвращается, если токен календаря не найден.
+var ErrCalendarTokenNotFound = errors.New("calendar token not found")
+
+// calendarTokensSQL создает таблицу токенов календаря: у пользователя не больше
+// одного токена, в БД хранится только SHA-256 токена, как у ключей API.
+const calendarTokensSQL = `
+	CREATE TABLE IF NOT EXISTS calendar_tokens (
+		user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		token_hash TEXT NOT NULL UNIQUE,
+		created_at BIGINT NOT NULL
+	);`
+
+// migrateCalendarTokens - миграция 4: таблица токенов календаря.
+func migrateCalendarTokens(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	if _, err := execOn(ctx, tx, calendarTokensSQL); err != nil {
+		return fmt.Errorf("failed to create calendar_tokens: %w", err)
+	}
+	return nil
+}
+
+// SetCalendarToken сохраняет токен календаря пользователя из ctx по хешу hash.
+// Прежний токен пользователя перестает действовать.
+func (s *Store) SetCalendarToken(ctx context.Context, hash string) error {
+	_, err := s.execSQL(ctx, `
+	INSERT INTO calendar_tokens (user_id, token_hash, created_at)
+	VALUES (:user_id, :hash, :now)
+	ON CONFLICT (user_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at`,
+		sql.Named("user_id", UserID(ctx)),
+		sql.Named("hash", hash),
+		sql.Named("now", timeNow().UnixMilli()))
+	if err != nil {
+		return fmt.Errorf("failed to save calendar token: %w", err)
+	}
+	return nil
+}
+
+// DeleteCalendarToken отзывает токен календаря пользователя из ctx.
+// Возвращает ErrCalendarTokenNotFound, если токена нет.
+func (s *Store) DeleteCalendarToken(ctx context.Context) error {
+	res, err := s.execSQL(ctx, `DELETE FROM calendar_tokens WHERE user_id = :user_id`,
+		sql.Named("user_id", UserID(ctx)))
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar token: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrCalendarTokenNotFound
+	}
+	return nil
+}
+
+// CalendarTokenUser возвращает ID пользователя, которому выдан токен
+// календаря с SHA-256 hash. Возвращает ErrCalendarTokenNotFound,
+// если токен не найден или отозван.
+func (s *Store) CalendarTokenUser(ctx context.Context, hash string) (int64, error) {
+	var id int64
+	err := s.queryRowSQL(ctx, `SELECT user_id FROM calendar_tokens WHERE token_hash = :hash`,
+		sql.Named("hash", hash)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrCalendarTokenNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read calendar token: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// changesSQL создает счетчик изменений и таблицу удаленных задач для
+// синхронизации по номерам изменений (см. GetChangesSince). Существующим
+// задачам присваиваются номера, равные id, а уже удаленным - записи
+// в deleted_tasks, чтобы первая синхронизация вернула полное состояние.
+const changesSQL = `
+	CREATE TABLE IF NOT EXISTS change_counter (
+		seq BIGINT NOT NULL -- Последний выданный номер изменения
+	);
+	CREATE TABLE IF NOT EXISTS deleted_tasks (
+		id BIGINT PRIMARY KEY, -- ID удаленной задачи
+		user_id BIGINT NOT NULL,
+		seq BIGINT NOT NULL -- Номер изменения, которым задача удалена
+	);
+	CREATE INDEX IF NOT EXISTS idx_deleted_tasks_seq ON deleted_tasks(user_id, seq);
+	CREATE INDEX IF NOT EXISTS idx_scheduler_seq ON scheduler(user_id, seq);
+	UPDATE scheduler SET seq = id WHERE seq = 0;
+	INSERT INTO deleted_tasks (id, user_id, seq)
+	SELECT id, user_id, id FROM scheduler WHERE deleted_at IS NOT NULL
+	ON CONFLICT (id) DO NOTHING;
+	INSERT INTO change_counter (seq)
+	SELECT COALESCE(MAX(id), 0) FROM scheduler
+	WHERE NOT EXISTS (SELECT 1 FROM change_counter);`
+
+// migrateChanges - миграция 11: номер изменения seq у задач, счетчик
+// номеров и таблица удаленных задач.
+func migrateChanges(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	if d == sqliteDialect {
+		if err := addColumnIfMissing(ctx, tx, "scheduler", "seq", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return err
+		}
+	} else if _, err := execOn(ctx, tx, `ALTER TABLE scheduler ADD COLUMN IF NOT EXISTS seq BIGINT NOT NULL DEFAULT 0`); err != nil {
+		return fmt.Errorf("failed to add seq column: %w", err)
+	}
+	if _, err := execOn(ctx, tx, changesSQL); err != nil {
+		return fmt.Errorf("failed to create change tracking tables: %w", err)
+	}
+	return nil
+}
+
+// nextSeq выдает номер следующего изменения задач в транзакции tx.
+// Вызывается в начале пишущей транзакции, один раз на транзакцию: строка
+// счетчика остается заблокированной до её завершения, поэтому номера
+// изменений возрастают в порядке фиксации транзакций.
+func nextSeq(ctx context.Context, tx *sql.Tx) (int64, error) {
+	var seq int64
+	err := queryRowOn(ctx, tx, `UPDATE change_counter SET seq = seq + 1 RETURNING seq`).Scan(&seq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get next change number: %w", err)
+	}
+	return seq, nil
+}
+
+// ChangeSeq возвращает номер последнего изменения задач. Номер растет
+// при каждом создании, изменении и удалении задачи любого пользователя,
+// поэтому по нему можно понять, что список задач не менялся (см. ETag
+// в пакете api).
+func (s *Store) ChangeSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	if err := s.queryRowSQL(ctx, `SELECT seq FROM change_counter`).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("failed to read change counter: %w", err)
+	}
+	return seq, nil
+}
+
+// addTombstones записывает в deleted_tasks задачи scheduler, отобранные
+// условием where, как удаленные изменением seq. Вызывается до удаления.
+func addTombstones(ctx context.Context, tx *sql.Tx, seq int64, where string, args ...any) error {
+	_, err := execOn(ctx, tx, `
+	INSERT INTO deleted_tasks (id, user_id, seq)
+	SELECT id, user_id, :seq FROM scheduler WHERE `+where+`
+	ON CONFLICT (id) DO UPDATE SET seq = excluded.seq`,
+		append(args, sql.Named("seq", seq))...)
+	if err != nil {
+		return fmt.Errorf("failed to record deleted tasks: %w", err)
+	}
+	return nil
+}
+
+// SeqChanges - изменения задач после номера изменения since.
+type SeqChanges struct {
+	Tasks   []*Task  // задачи, созданные или измененные после since, в порядке изменений
+	Deleted []string // ID задач, удаленных после since
+	Cursor  int64    // номер, который клиент передает как since в следующий раз
+}
+
+// GetChangesSince возвращает задачи пользователя из ctx, созданные,
+// измененные или удаленные после номера изменения since; since = 0 -
+// все задачи. Задача попадает только в один из списков - по последнему
+// изменению, поэтому клиент может применять ответы по порядку.
+//
+// Cursor читается в той же транзакции до списков, а изменения с большими
+// номерами в ответ не попадают: они вернутся в следующий раз целиком.
+// Транзакция только читает (см. inReadTx), поэтому частые опросы клиентов
+// не мешают записи.
+func (s *Store) GetChangesSince(ctx context.Context, since int64) (*SeqChanges, error) {
+	changes := &SeqChanges{}
+	scope, user := userScope(ctx)
+
+	err := s.inReadTx(ctx, func(tx *sql.Tx) error {
+		if err := queryRowOn(ctx, tx, `SELECT seq FROM change_counter`).Scan(&changes.Cursor); err != nil {
+			return fmt.Errorf("failed to read change counter: %w", err)
+		}
+		if since > changes.Cursor {
+			since = 0 // курсор от другой БД, например после восстановления из копии
+		}
+		span := []any{sql.Named("since", since), sql.Named("cursor", changes.Cursor), user}
+
+		rows, err := queryOn(ctx, tx, `
+		SELECT `+taskColumns+` FROM scheduler
+		WHERE deleted_at IS NULL AND seq > :since AND seq <= :cursor AND `+scope+`
+		ORDER BY seq, id`, span...)
+		if err != nil {
+			return fmt.Errorf("failed to query changed tasks: %w", err)
+		}
+		if changes.Tasks, err = scanTasks(rows); err != nil {
+			return err
+		}
+
+		rows, err = queryOn(ctx, tx, `
+		SELECT id FROM deleted_tasks
+		WHERE seq > :since AND seq <= :cursor AND `+scope+`
+		ORDER BY seq, id`, span...)
+		if err != nil {
+			return fmt.Errorf("failed to query deleted tasks: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return fmt.Errorf("failed to scan deleted id: %w", err)
+			}
+			changes.Deleted = append(changes.Deleted, id)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowQuery выполняется несколько минут, если его не прервать.
+const slowQuery = `
+WITH RECURSIVE counter(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM counter WHERE x < 1000000000)
+SELECT COUNT(*) FROM counter`
+
+func TestContextCancelsQuery(t *testing.T) {
+	store := OpenForTest(t)
+	seedTasks(t, store, Task{Date: "20240101", Title: "Задача"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	var count int
+	err := store.queryRowSQL(ctx, slowQuery).Scan(&count)
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "запрос должен прерваться по сроку контекста")
+
+	// уже отмененный контекст не выполняет запрос
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = store.GetTasks(ctx, 10)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = store.AddTask(ctx, &Task{Date: "20240102", Title: "Не добавится"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	tasks, err := store.GetTasks(t.Context(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Задача"}, titles(tasks))
+}
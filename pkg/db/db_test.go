@@ -0,0 +1,960 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go1f/pkg/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupDBFile открывает для теста БД в файле path, возвращает её хранилище
+// и закрывает по завершении.
+func setupDBFile(t *testing.T, path string) *Store {
+	t.Helper()
+	store, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// taskID возвращает ID задачи числом, как его принимают функции пакета.
+func taskID(t *testing.T, id string) int64 {
+	t.Helper()
+	n, err := strconv.ParseInt(id, 10, 64)
+	require.NoError(t, err)
+	return n
+}
+
+// seedTasks добавляет задачи в store и возвращает их id.
+func seedTasks(t *testing.T, store *Store, tasks ...Task) []int64 {
+	t.Helper()
+	ids := make([]int64, 0, len(tasks))
+	for i := range tasks {
+		id, err := store.AddTask(t.Context(), &tasks[i])
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestGetFacets(t *testing.T) {
+	store := OpenForTest(t)
+	seedTasks(t, store,
+		Task{Date: "20240101", Title: "Разовая"},
+		Task{Date: "20240102", Title: "Разовая 2"},
+		Task{Date: "20240103", Title: "Каждый день", Repeat: "d 1"},
+		Task{Date: "20240104", Title: "Каждые 7 дней", Repeat: "d 7"},
+		Task{Date: "20240105", Title: "По понедельникам", Repeat: "w 1"},
+		Task{Date: "20240106", Title: "Последний день", Repeat: "m -1"},
+		Task{Date: "20240107", Title: "День рождения", Repeat: "y", Priority: 3},
+	)
+
+	facets, err := store.GetFacets(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"none": 2, "d": 2, "w": 1, "m": 1, "y": 1}, facets["repeat"])
+	assert.Equal(t, map[string]int{"0": 6, "3": 1}, facets["priority"])
+}
+
+func TestTaskPriority(t *testing.T) {
+	store := OpenForTest(t)
+	seedTasks(t, store,
+		Task{Date: "20240101", Title: "Обычная"},
+		Task{Date: "20240102", Title: "Срочная", Priority: 3},
+		Task{Date: "20240103", Title: "Важная", Priority: 2},
+		Task{Date: "20240104", Title: "Тоже срочная", Priority: 3},
+	)
+	high := 3
+
+	tasks, err := store.GetTasksPage(t.Context(), 10, 0, TaskFilter{Sort: SortPriority})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Срочная", "Тоже срочная", "Важная", "Обычная"}, titles(tasks))
+
+	tasks, err = store.GetTasksPage(t.Context(), 10, 0, TaskFilter{Priority: &high})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Срочная", "Тоже срочная"}, titles(tasks))
+	total, err := store.CountTasks(t.Context(), TaskFilter{Priority: &high})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+
+	tasks, err = store.SearchTasks(t.Context(), "ная", 10, TaskFilter{Sort: SortPriority})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Срочная", "Тоже срочная", "Важная", "Обычная"}, titles(tasks))
+
+	task, err := store.GetTaskID(t.Context(), taskID(t, tasks[2].ID))
+	require.NoError(t, err)
+	task.Priority = 1
+	require.NoError(t, store.PutTaskID(t.Context(), &task))
+	task, err = store.GetTaskID(t.Context(), taskID(t, task.ID))
+	require.NoError(t, err)
+	assert.Equal(t, 1, task.Priority)
+}
+
+func TestPutTaskIDNotFound(t *testing.T) {
+	store := OpenForTest(t)
+	err := store.PutTaskID(t.Context(), &Task{ID: "100500", Date: "20240101", Title: "Нет такой"})
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+}
+
+func TestPutTaskIDVersion(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store, Task{Date: "20240101", Title: "Исходная"})
+	task, err := store.GetTaskID(t.Context(), ids[0])
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, task.Version)
+
+	// сохранение с текущей версией увеличивает её
+	first := task
+	first.Title = "Из первой вкладки"
+	require.NoError(t, store.PutTaskID(t.Context(), &first))
+	assert.EqualValues(t, 2, first.Version)
+
+	// сохранение с устаревшей версией отклоняется, задача не меняется
+	second := task
+	second.Title = "Из второй вкладки"
+	assert.ErrorIs(t, store.PutTaskID(t.Context(), &second), ErrVersionConflict)
+	saved, err := store.GetTaskID(t.Context(), ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, "Из первой вкладки", saved.Title)
+	assert.EqualValues(t, 2, saved.Version)
+
+	// без версии задача сохраняется без проверки
+	second.Version = 0
+	require.NoError(t, store.PutTaskID(t.Context(), &second))
+	assert.EqualValues(t, 3, second.Version)
+
+	// другие изменения тоже увеличивают версию
+	require.NoError(t, store.SetCompleted(t.Context(), ids[0], true))
+	saved, err = store.GetTaskID(t.Context(), ids[0])
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, saved.Version)
+
+	// у удаленной задачи не конфликт, а ErrTaskNotFound
+	require.NoError(t, store.DeleteTaskID(t.Context(), ids[0]))
+	assert.ErrorIs(t, store.PutTaskID(t.Context(), &saved), ErrTaskNotFound)
+}
+
+func TestReadsWithExtraColumn(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store, Task{Date: "20240101", Title: "Купить молоко", Comment: "2 литра", Repeat: "d 1"})
+
+	// колонка, о которой код не знает, не ломает чтение задач
+	_, err := store.db.Exec(`ALTER TABLE scheduler ADD COLUMN throwaway TEXT NOT NULL DEFAULT 'лишнее'`)
+	require.NoError(t, err)
+
+	tasks, err := store.GetTasks(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "Купить молоко", tasks[0].Title)
+	assert.Equal(t, "d 1", tasks[0].Repeat)
+
+	tasks, err = store.SearchTasks(t.Context(), "молоко", 10, TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Купить молоко"}, titles(tasks))
+
+	task, err := store.GetTaskID(t.Context(), ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, "2 литра", task.Comment)
+}
+
+func TestMigrateUID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scheduler.db")
+
+	// БД в формате предыдущей версии, без колонки uid
+	old, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = old.Exec(`
+	CREATE TABLE scheduler (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		date TEXT NOT NULL,
+		title TEXT NOT NULL,
+		comment TEXT,
+		repeat VARCHAR(128)
+	);
+	INSERT INTO scheduler (date, title, comment, repeat) VALUES
+		('20240101', 'Первая', '', ''),
+		('20240102', 'Вторая', '', 'd 1');`)
+	require.NoError(t, err)
+	require.NoError(t, old.Close())
+
+	store := setupDBFile(t, path)
+
+	tasks, err := store.GetTasks(t.Context(), 10)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.False(t, tasks[0].Completed)
+	assert.Zero(t, tasks[0].Priority)
+	assert.NotEmpty(t, tasks[0].UID)
+	assert.NotEmpty(t, tasks[1].UID)
+	assert.NotEqual(t, tasks[0].UID, tasks[1].UID)
+
+	id, err := store.TaskIDByUID(t.Context(), tasks[1].UID)
+	require.NoError(t, err)
+	assert.Equal(t, taskID(t, tasks[1].ID), id)
+
+	_, err = store.TaskIDByUID(t.Context(), "нет такого")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+}
+
+func TestInitDBCreatesDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "data", "scheduler.db")
+	store, err := InitDB(config.Config{PathToDB: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { CloseDB() })
+	seedTasks(t, store, Task{Date: "20240101", Title: "Задача"})
+
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+	info, err := os.Stat(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o750), info.Mode().Perm())
+}
+
+func TestInitDBCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scheduler.db")
+	s, err := Open(path)
+	require.NoError(t, err)
+	for i := range 200 {
+		_, err := s.AddTask(context.Background(), &Task{Date: "20240101", Title: "Задача " + strconv.Itoa(i), Comment: strings.Repeat("комментарий ", 20)})
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.Close())
+
+	// обрезанный файл: страницы таблиц потеряны
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()/2))
+
+	store, err := InitDB(config.Config{PathToDB: path})
+	assert.Nil(t, store)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrCorrupted)
+	assert.Contains(t, err.Error(), path)
+	_, err = os.Stat(filepath.Join(filepath.Dir(path), "scheduler.db-wal"))
+	assert.ErrorIs(t, err, os.ErrNotExist, "поврежденная БД не открывается на запись")
+}
+
+func TestMigrateUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scheduler.db")
+
+	// БД предыдущей версии, без пользователей
+	old, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = old.Exec(`
+	CREATE TABLE scheduler (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		date TEXT NOT NULL,
+		title TEXT NOT NULL,
+		comment TEXT,
+		repeat VARCHAR(128)
+	);
+	INSERT INTO scheduler (date, title, comment, repeat) VALUES ('20240101', 'Старая', '', '');`)
+	require.NoError(t, err)
+	require.NoError(t, old.Close())
+
+	store := setupDBFile(t, path)
+
+	admin, err := store.UserByLogin(t.Context(), DefaultUserLogin)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultUserID, admin.ID)
+
+	var owner int64
+	require.NoError(t, store.db.QueryRow(`SELECT user_id FROM scheduler`).Scan(&owner))
+	assert.Equal(t, DefaultUserID, owner)
+}
+
+func TestMigrateWeekRules(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store,
+		Task{Date: "20240101", Title: "Пятницы февраля", Repeat: "w 5 2"},
+		Task{Date: "20240101", Title: "Зимние субботы", Repeat: "w 6 11,12,1"},
+		Task{Date: "20240101", Title: "Понедельники", Repeat: "w 1"},
+		Task{Date: "20240101", Title: "Ежедневно", Repeat: "d 5 2"})
+	tmplID, err := store.AddTemplate(t.Context(), &Template{Title: "Шаблон", Repeat: "w 6 1,2,3 count=4"})
+	require.NoError(t, err)
+
+	tx, err := store.db.BeginTx(t.Context(), nil)
+	require.NoError(t, err)
+	require.NoError(t, migrateWeekRules(t.Context(), tx, sqliteDialect))
+	require.NoError(t, tx.Commit())
+
+	for i, want := range []string{"w 1 5 2", "w 6 11,12,1", "w 1", "d 5 2"} {
+		task, err := store.GetTaskID(t.Context(), ids[i])
+		require.NoError(t, err)
+		assert.Equal(t, want, task.Repeat, task.Title)
+	}
+	tmpl, err := store.GetTemplate(t.Context(), tmplID)
+	require.NoError(t, err)
+	assert.Equal(t, "w 1 6 1,2,3 count=4", tmpl.Repeat)
+}
+
+func TestUserIsolation(t *testing.T) {
+	store := OpenForTest(t)
+	ctx := context.Background()
+
+	other, err := store.CreateUser(t.Context(), "partner", "$2a$10$hash")
+	require.NoError(t, err)
+	_, err = store.CreateUser(t.Context(), "partner", "$2a$10$hash")
+	assert.ErrorIs(t, err, ErrUserExists)
+	found, err := store.UserByID(t.Context(), other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "partner", found.Login)
+	_, err = store.UserByLogin(t.Context(), "нет такого")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	mine := WithUser(ctx, DefaultUserID)
+	theirs := WithUser(ctx, other.ID)
+
+	_, err = store.AddTask(mine, &Task{Date: "20240101", Title: "Моя", Tags: []string{"дом"}})
+	require.NoError(t, err)
+	foreign := Task{Date: "20240101", Title: "Чужая", Tags: []string{"дом"}}
+	_, err = store.AddTask(theirs, &foreign)
+	require.NoError(t, err)
+
+	tasks, err := store.GetTasksPage(mine, 10, 0, TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Моя"}, titles(tasks))
+	tasks, err = store.SearchTasks(theirs, "я", 10, TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Чужая"}, titles(tasks))
+
+	// чужая задача для пользователя не существует
+	_, err = store.GetTaskID(mine, taskID(t, foreign.ID))
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	_, err = store.TaskIDByUID(mine, foreign.UID)
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	edited := foreign
+	edited.Title = "Перехвачена"
+	assert.ErrorIs(t, store.PutTaskID(mine, &edited), ErrTaskNotFound)
+	assert.ErrorIs(t, store.SetCompleted(mine, taskID(t, foreign.ID), true), ErrTaskNotFound)
+	assert.ErrorIs(t, store.DeleteTaskID(mine, taskID(t, foreign.ID)), ErrTaskNotFound)
+	_, missing, err := store.DeleteTasks(mine, []string{foreign.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{foreign.ID}, missing)
+	_, err = store.ImportTasks(mine, []*Task{{Date: "20240101", Title: "Перехвачена", UID: foreign.UID}})
+	assert.Error(t, err)
+
+	got, err := store.GetTaskID(theirs, taskID(t, foreign.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "Чужая", got.Title)
+
+	facets, err := store.GetFacets(mine)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"дом": 1}, facets["tag"])
+
+	changes, err := store.GetChanges(theirs, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Чужая"}, titles(changes.Changed))
+
+	var streamed []*Task
+	require.NoError(t, store.StreamTasks(theirs, func(task *Task) error {
+		streamed = append(streamed, task)
+		return nil
+	}))
+	assert.Equal(t, []string{"Чужая"}, titles(streamed))
+
+	// без пользователя в контексте работаем с задачами пользователя по умолчанию
+	total, err := store.CountTasks(t.Context(), TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	total, err = store.CountTasks(WithAllUsers(ctx), TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+}
+
+func TestImportTasksByUID(t *testing.T) {
+	store := OpenForTest(t)
+	export := []*Task{
+		{Date: "20240101", Title: "Первая", UID: "11111111-1111-4111-8111-111111111111"},
+		{Date: "20240102", Title: "Вторая", UID: "22222222-2222-4222-8222-222222222222"},
+	}
+	_, err := store.ImportTasks(t.Context(), export)
+	require.NoError(t, err)
+
+	export[0].Title = "Первая (изменена)"
+	_, err = store.ImportTasks(t.Context(), export)
+	require.NoError(t, err)
+
+	tasks, err := store.GetTasks(t.Context(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Первая (изменена)", "Вторая"}, titles(tasks))
+}
+
+func TestReplaceTasks(t *testing.T) {
+	store := OpenForTest(t)
+	ctx := context.Background()
+	kept := Task{Date: "20240101", Title: "Остается", Tags: []string{"дом"}}
+	seedTasks(t, store, kept, Task{Date: "20240102", Title: "Удаляется"})
+	tasks, err := store.GetTasks(t.Context(), 10)
+	require.NoError(t, err)
+	kept = *tasks[0]
+
+	other, err := store.CreateUser(t.Context(), "partner", "$2a$10$hash")
+	require.NoError(t, err)
+	foreign := Task{Date: "20240101", Title: "Чужая"}
+	_, err = store.AddTask(WithUser(ctx, other.ID), &foreign)
+	require.NoError(t, err)
+
+	// при ошибке прежние задачи остаются
+	_, err = store.ReplaceTasks(t.Context(), []*Task{{Date: "20240103", Title: "Новая"}, {Date: "20240103", Title: "Захват", UID: foreign.UID}})
+	assert.ErrorIs(t, err, ErrUIDTaken)
+	tasks, err = store.GetTasks(t.Context(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Остается", "Удаляется"}, titles(tasks))
+
+	n, err := store.ReplaceTasks(t.Context(), []*Task{{Date: "20240103", Title: "Новая"}, &kept})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	tasks, err = store.GetTasks(t.Context(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Остается", "Новая"}, titles(tasks))
+	assert.Equal(t, []string{"дом"}, tasks[0].Tags)
+
+	// удаление видно клиентам синхронизации, задачи другого пользователя не тронуты
+	changes, err := store.GetChanges(t.Context(), time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, changes.Deleted, 1)
+	total, err := store.CountTasks(WithUser(ctx, other.ID), TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
+
+func TestTaskNotFoundErrors(t *testing.T) {
+	store := OpenForTest(t)
+	_, err := store.GetTaskID(t.Context(), 100500)
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	assert.ErrorIs(t, store.DeleteTaskID(t.Context(), 100500), ErrTaskNotFound)
+}
+
+func TestSetCompleted(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store, Task{Date: "20240101", Title: "Разовая"}, Task{Date: "20240102", Title: "Другая"})
+	id := ids[0]
+
+	require.NoError(t, store.SetCompleted(t.Context(), id, true))
+	task, err := store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	assert.True(t, task.Completed)
+
+	// PUT не сбрасывает отметку о выполнении
+	task.Title = "Разовая (изменена)"
+	require.NoError(t, store.PutTaskID(t.Context(), &task))
+
+	done, err := store.GetTasksPage(t.Context(), 10, 0, TaskFilter{Completed: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Разовая (изменена)"}, titles(done))
+	total, err := store.CountTasks(t.Context(), TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	assert.ErrorIs(t, store.SetCompleted(t.Context(), 100500, true), ErrTaskNotFound)
+}
+
+func TestCompleteTask(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store,
+		Task{Date: "20240101", Title: "Разовая"},
+		Task{Date: "20240101", Title: "Трижды", Repeat: "d 1 count=3"})
+	once, repeat := ids[0], ids[1]
+
+	require.NoError(t, store.CompleteTask(t.Context(), once, func(task Task) (string, error) {
+		assert.Equal(t, "Разовая", task.Title)
+		return "", nil
+	}))
+	task, err := store.GetTaskID(t.Context(), once)
+	require.NoError(t, err)
+	assert.True(t, task.Completed)
+
+	require.NoError(t, store.CompleteTask(t.Context(), repeat, func(Task) (string, error) { return "20240102", nil }))
+	task, err = store.GetTaskID(t.Context(), repeat)
+	require.NoError(t, err)
+	assert.Equal(t, "20240102", task.Date)
+	assert.Equal(t, "d 1 count=2", task.Repeat)
+	assert.False(t, task.Completed)
+
+	// ошибка расчета даты откатывает транзакцию
+	errDate := errors.New("неверное правило")
+	assert.ErrorIs(t, store.CompleteTask(t.Context(), repeat, func(Task) (string, error) { return "", errDate }), errDate)
+	task, err = store.GetTaskID(t.Context(), repeat)
+	require.NoError(t, err)
+	assert.Equal(t, "20240102", task.Date)
+	assert.False(t, task.Completed)
+
+	// удаленная задача не читается и не обновляется
+	require.NoError(t, store.DeleteTaskID(t.Context(), repeat))
+	called := false
+	err = store.CompleteTask(t.Context(), repeat, func(Task) (string, error) {
+		called = true
+		return "20240103", nil
+	})
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+	assert.False(t, called)
+	assert.ErrorIs(t, store.CompleteTask(t.Context(), 100500, func(Task) (string, error) { return "", nil }), ErrTaskNotFound)
+}
+
+func TestCompleteTaskConcurrent(t *testing.T) {
+	store := setupDBFile(t, filepath.Join(t.TempDir(), "scheduler.db"))
+	ids := seedTasks(t, store, Task{Date: "20240101", Title: "Ежедневная", Repeat: "d 1"})
+	id := ids[0]
+
+	const workers = 8
+	var mu sync.Mutex
+	winners := map[string]int{} // дата задачи -> сколько выполнений её перенесли
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var seen string
+			err := store.CompleteTask(t.Context(), id, func(task Task) (string, error) {
+				seen = task.Date
+				date, err := time.Parse("20060102", task.Date)
+				if err != nil {
+					return "", err
+				}
+				return date.AddDate(0, 0, 1).Format("20060102"), nil
+			})
+			if err == nil {
+				mu.Lock()
+				winners[seen]++
+				mu.Unlock()
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// каждое состояние задачи перенесено ровно одним выполнением, ни одно не потеряно
+	require.Len(t, winners, workers)
+	for date, n := range winners {
+		assert.Equal(t, 1, n, date)
+	}
+	task, err := store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "20240109", task.Date)
+}
+
+func TestDeleteTasks(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store,
+		Task{Date: "20240101", Title: "Первая"},
+		Task{Date: "20240102", Title: "Вторая"},
+		Task{Date: "20240103", Title: "Третья"},
+	)
+	first, second, third := strconv.FormatInt(ids[0], 10), strconv.FormatInt(ids[1], 10), strconv.FormatInt(ids[2], 10)
+
+	deleted, missing, err := store.DeleteTasks(t.Context(), []string{first, "100500"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.Equal(t, []string{"100500"}, missing)
+
+	// Ошибка на второй задаче откатывает удаление первой
+	_, err = store.execSQL(context.Background(), `
+	CREATE TRIGGER fail_delete BEFORE UPDATE OF deleted_at ON scheduler
+	WHEN OLD.id = `+third+`
+	BEGIN SELECT RAISE(ABORT, 'boom'); END`)
+	require.NoError(t, err)
+	_, _, err = store.DeleteTasks(t.Context(), []string{second, third})
+	require.Error(t, err)
+
+	tasks, err := store.GetTasks(t.Context(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Вторая", "Третья"}, titles(tasks))
+}
+
+func TestTaskTags(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store,
+		Task{Date: "20240101", Title: "Отчет", Tags: []string{"work", "urgent"}},
+		Task{Date: "20240102", Title: "Покупки", Tags: []string{"home"}},
+		Task{Date: "20240103", Title: "Без тегов"},
+	)
+	id := ids[0]
+
+	task, err := store.GetTaskID(t.Context(), id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"urgent", "work"}, task.Tags)
+
+	tasks, err := store.GetTasksPage(t.Context(), 10, 0, TaskFilter{Tag: "work"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Отчет"}, titles(tasks))
+
+	// поиск находит задачу по имени тега
+	tasks, err = store.SearchTasks(t.Context(), "hom", 10, TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Покупки"}, titles(tasks))
+
+	// PutTaskID заменяет набор тегов целиком
+	task.Tags = []string{"home"}
+	require.NoError(t, store.PutTaskID(t.Context(), &task))
+	tasks, err = store.GetTasksPage(t.Context(), 10, 0, TaskFilter{Tag: "home"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Отчет", "Покупки"}, titles(tasks))
+
+	facets, err := store.GetFacets(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"home": 2}, facets["tag"])
+
+	// удаление задачи удаляет её теги, окончательное удаление - каскадом
+	require.NoError(t, store.DeleteTaskID(t.Context(), id))
+	var count int
+	require.NoError(t, store.db.QueryRow(`SELECT COUNT(*) FROM task_tags WHERE task_id = ?`, id).Scan(&count))
+	assert.Zero(t, count)
+
+	_, err = store.db.Exec(`DELETE FROM scheduler WHERE id = ?`, ids[1])
+	require.NoError(t, err)
+	require.NoError(t, store.db.QueryRow(`SELECT COUNT(*) FROM task_tags`).Scan(&count))
+	assert.Zero(t, count)
+}
+
+func TestInMemoryStore(t *testing.T) {
+	store := OpenForTest(t)
+	assert.Equal(t, 1, store.db.Stats().MaxOpenConnections)
+	ids := seedTasks(t, store, Task{Date: "20240101", Title: "В памяти"})
+
+	// Все запросы идут через одно соединение, поэтому видят одну и ту же БД.
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			task, err := store.GetTaskID(t.Context(), ids[0])
+			if assert.NoError(t, err) {
+				assert.Equal(t, "В памяти", task.Title)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestOpenIndependentStores(t *testing.T) {
+	ctx := context.Background()
+	first := OpenForTest(t)
+	second := OpenForTest(t)
+
+	_, err := first.AddTask(ctx, &Task{Date: "20240101", Title: "Только в первой"})
+	require.NoError(t, err)
+
+	tasks, err := first.GetTasks(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Только в первой"}, titles(tasks))
+	tasks, err = second.GetTasks(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func TestOpenWALAndBusyTimeout(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "shared.db")
+	first, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { first.Close() })
+	second, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { second.Close() })
+
+	var mode string
+	var timeout int64
+	require.NoError(t, first.DB().QueryRow(`PRAGMA journal_mode`).Scan(&mode))
+	require.NoError(t, first.DB().QueryRow(`PRAGMA busy_timeout`).Scan(&timeout))
+	assert.Equal(t, "wal", mode)
+	assert.Equal(t, busyTimeout.Milliseconds(), timeout)
+
+	// запись из второго процесса ждет, пока первый держит блокировку записи
+	tx, err := first.DB().BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.Exec(`UPDATE scheduler SET title = title`)
+	require.NoError(t, err)
+	time.AfterFunc(50*time.Millisecond, func() { tx.Commit() })
+
+	_, err = second.AddTask(ctx, &Task{Date: "20240101", Title: "После блокировки"})
+	require.NoError(t, err)
+	tasks, err := first.GetTasks(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"После блокировки"}, titles(tasks))
+}
+
+func TestSchemaMigrations(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "scheduler.db")
+	all := migrations
+	t.Cleanup(func() { migrations = all })
+	// текущая схема - "старая" версия, к ней добавляются шаги новых версий
+	base := migrations[:len(migrations):len(migrations)]
+	last := base[len(base)-1].version
+	upTo := func(version int) []int {
+		var list []int
+		for v := 1; v <= version; v++ {
+			list = append(list, v)
+		}
+		return list
+	}
+
+	versions := func(s *Store) []int {
+		rows, err := s.DB().Query(`SELECT version FROM schema_migrations ORDER BY version`)
+		require.NoError(t, err)
+		defer rows.Close()
+		var list []int
+		for rows.Next() {
+			var v int
+			require.NoError(t, rows.Scan(&v))
+			list = append(list, v)
+		}
+		return list
+	}
+
+	// БД текущей версии
+	s, err := Open(path)
+	require.NoError(t, err)
+	assert.Equal(t, upTo(last), versions(s))
+	_, err = s.DB().Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES ('20240101', 'До обновления', '', '')`)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	// новая версия приложения добавляет шаг
+	calls := 0
+	migrations = append(base, migration{last + 1, "заметки", func(ctx context.Context, tx *sql.Tx, _ *dialect) error {
+		calls++
+		_, err := tx.ExecContext(ctx, `ALTER TABLE scheduler ADD COLUMN note TEXT NOT NULL DEFAULT ''`)
+		return err
+	}})
+	for range 2 {
+		s, err = Open(path)
+		require.NoError(t, err)
+		assert.Equal(t, upTo(last+1), versions(s))
+		tasks, err := s.GetTasks(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"До обновления"}, titles(tasks))
+		require.NoError(t, s.Close())
+	}
+	assert.Equal(t, 1, calls, "шаг применяется один раз")
+
+	// ошибка шага откатывает его целиком
+	migrations = append(migrations, migration{last + 2, "сломанный", func(ctx context.Context, tx *sql.Tx, _ *dialect) error {
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE half_done (id INTEGER)`); err != nil {
+			return err
+		}
+		return errors.New("сбой миграции")
+	}})
+	_, err = Open(path)
+	require.Error(t, err)
+	migrations = migrations[:len(base)+1]
+	s, err = Open(path)
+	require.NoError(t, err)
+	var n int
+	require.NoError(t, s.DB().QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'half_done'`).Scan(&n))
+	assert.Zero(t, n)
+	require.NoError(t, s.Close())
+
+	// старое приложение не запускается с БД, обновленной новым
+	migrations = base
+	_, err = Open(path)
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+}
+
+func TestStreamTasks(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store,
+		Task{Date: "20240103", Title: "Первая", Tags: []string{"дом"}},
+		Task{Date: "20240102", Title: "Выполненная"},
+		Task{Date: "20240101", Title: "Удаленная"},
+		Task{Date: "20240101", Title: "Последняя"},
+	)
+	require.NoError(t, store.SetCompleted(t.Context(), ids[1], true))
+	require.NoError(t, store.DeleteTaskID(t.Context(), ids[2]))
+
+	// выполненные задачи выгружаются, удаленные - нет; порядок по id
+	var tasks []*Task
+	require.NoError(t, store.StreamTasks(t.Context(), func(task *Task) error {
+		tasks = append(tasks, task)
+		return nil
+	}))
+	assert.Equal(t, []string{"Первая", "Выполненная", "Последняя"}, titles(tasks))
+	assert.True(t, tasks[1].Completed)
+	assert.Equal(t, []string{"дом"}, tasks[0].Tags)
+
+	// ошибка обработчика прерывает обход
+	stop := errors.New("хватит")
+	n := 0
+	err := store.StreamTasks(t.Context(), func(*Task) error {
+		n++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, n)
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	for _, path := range []string{MemoryPath, filepath.Join(t.TempDir(), "scheduler.db")} {
+		store := setupDBFile(t, path)
+		seedTasks(t, store, Task{Date: "20240101", Title: "Сохраненная"})
+
+		dir := filepath.Join(t.TempDir(), "backups")
+		now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+		name, err := store.BackupToDir(context.Background(), dir, now)
+		require.NoError(t, err, path)
+		assert.Equal(t, "backup-20250701-100000.db", name)
+		assert.True(t, IsBackupName(name))
+		_, err = store.BackupToDir(context.Background(), dir, now)
+		assert.ErrorIs(t, err, ErrBackupExists)
+
+		// восстановленная копия открывается как обычная БД
+		dest := filepath.Join(t.TempDir(), "restored.db")
+		restored, err := RestoreBackup(filepath.Join(dir, name), dest)
+		require.NoError(t, err)
+		assert.True(t, restored)
+		s, err := Open(dest)
+		require.NoError(t, err)
+		tasks, err := s.GetTasks(context.Background(), 10)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Сохраненная"}, titles(tasks))
+		require.NoError(t, s.Close())
+
+		// существующая БД не перезаписывается
+		restored, err = RestoreBackup(filepath.Join(dir, name), dest)
+		require.NoError(t, err)
+		assert.False(t, restored)
+	}
+
+	_, err := RestoreBackup(filepath.Join(t.TempDir(), "нет.db"), filepath.Join(t.TempDir(), "new.db"))
+	assert.Error(t, err)
+	for _, name := range []string{"scheduler.db", "../backup-20250701-100000.db", "backup-2025.db", "backup-20250701-100000.db.tmp"} {
+		assert.False(t, IsBackupName(name), name)
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	var names []string
+	for i := range 5 {
+		name := BackupName(start.Add(time.Duration(i) * time.Hour))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+		names = append(names, name)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "scheduler.db"), nil, 0o600))
+
+	removed, err := RotateBackups(dir, 2)
+	require.NoError(t, err)
+	assert.Equal(t, names[:3], removed)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var left []string
+	for _, entry := range entries {
+		left = append(left, entry.Name())
+	}
+	assert.Equal(t, append(names[3:], "scheduler.db"), left)
+
+	// keep = 0 хранит все копии
+	removed, err = RotateBackups(dir, 0)
+	require.NoError(t, err)
+	assert.Empty(t, removed)
+}
+
+func TestRescheduleTasks(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store,
+		Task{Date: "20240101", Title: "Просрочена"},
+		Task{Date: "20240105", Title: "Просрочена повторяющаяся", Repeat: "d 7"},
+		Task{Date: "20240110", Title: "Сегодня"},
+		Task{Date: "20240120", Title: "Будущая"},
+		Task{Date: "20240102", Title: "Выполнена"},
+	)
+	require.NoError(t, store.SetCompleted(t.Context(), ids[4], true))
+	dates := func() []string {
+		var result []string
+		for _, id := range ids {
+			task, err := store.GetTaskID(t.Context(), id)
+			require.NoError(t, err)
+			result = append(result, task.Date)
+		}
+		return result
+	}
+
+	// Ошибка на повторяющейся задаче откатывает перенос разовой
+	_, err := store.RescheduleTasks(t.Context(), "20240110", func(Task) (string, error) { return "", errors.New("boom") })
+	require.Error(t, err)
+	assert.Equal(t, []string{"20240101", "20240105", "20240110", "20240120", "20240102"}, dates())
+
+	// без nextDate повторяющиеся задачи не переносятся
+	moved, err := store.RescheduleTasks(t.Context(), "20240110", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{strconv.FormatInt(ids[0], 10)}, moved)
+	assert.Equal(t, []string{"20240110", "20240105", "20240110", "20240120", "20240102"}, dates())
+
+	moved, err = store.RescheduleTasks(t.Context(), "20240110", func(task Task) (string, error) {
+		assert.Equal(t, "d 7", task.Repeat)
+		return "20240112", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{strconv.FormatInt(ids[1], 10)}, moved)
+	assert.Equal(t, []string{"20240110", "20240112", "20240110", "20240120", "20240102"}, dates())
+
+	moved, err = store.RescheduleTasks(t.Context(), "20240110", nil)
+	require.NoError(t, err)
+	assert.Empty(t, moved)
+}
+
+func TestTasksToNotify(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store,
+		Task{Date: "20240105", Title: "Сегодня"},
+		Task{Date: "20240101", Title: "Просрочена"},
+		Task{Date: "20240106", Title: "Завтра"},
+		Task{Date: "20240102", Title: "Выполнена"},
+	)
+	require.NoError(t, store.SetCompleted(t.Context(), ids[3], true))
+	titles := func(today string) []string {
+		tasks, err := store.TasksToNotify(t.Context(), today)
+		require.NoError(t, err)
+		result := []string{}
+		for _, task := range tasks {
+			result = append(result, task.Title)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"Просрочена", "Сегодня"}, titles("20240105"))
+
+	// отмеченные задачи не возвращаются до следующего дня, версия не меняется
+	before, err := store.GetTaskID(t.Context(), ids[1])
+	require.NoError(t, err)
+	require.NoError(t, store.MarkNotified(t.Context(), "20240105", []string{strconv.FormatInt(ids[1], 10)}))
+	assert.Equal(t, []string{"Сегодня"}, titles("20240105"))
+	assert.Equal(t, []string{"Просрочена", "Сегодня", "Завтра"}, titles("20240106"))
+	after, err := store.GetTaskID(t.Context(), ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, before.Version, after.Version)
+}
+
+func TestLastRun(t *testing.T) {
+	store := OpenForTest(t)
+	day, err := store.LastRun(t.Context(), "email_digest")
+	require.NoError(t, err)
+	assert.Empty(t, day)
+
+	require.NoError(t, store.SetLastRun(t.Context(), "email_digest", "20250701"))
+	require.NoError(t, store.SetLastRun(t.Context(), "email_digest", "20250702"))
+	day, err = store.LastRun(t.Context(), "email_digest")
+	require.NoError(t, err)
+	assert.Equal(t, "20250702", day)
+
+	day, err = store.LastRun(t.Context(), "other")
+	require.NoError(t, err)
+	assert.Empty(t, day)
+}
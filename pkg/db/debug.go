@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLogArgLen ограничивает длину строковых аргументов в логе SQL-запросов.
+const maxLogArgLen = 64
+
+// Настройки логирования SQL-запросов, выставляются в InitDB из конфигурации.
+var (
+	sqlDebug bool          // логировать каждый запрос
+	sqlSlow  time.Duration // порог медленного запроса, 0 - не логировать
+)
+
+// execer выполняет запросы без возврата строк: *sql.DB или *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// queryer выполняет запросы с возвратом строк: *sql.DB или *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// execSQL выполняет запрос через s.db.ExecContext и логирует его при необходимости.
+// Если БД занята, запрос повторяется (см. retryBusy).
+func (s *Store) execSQL(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var res sql.Result
+	err := retryBusy(ctx, func() (err error) {
+		res, err = execOn(ctx, s.db, query, args...)
+		return err
+	})
+	return res, err
+}
+
+// querySQL выполняет запрос через s.db.QueryContext и логирует его при необходимости.
+func (s *Store) querySQL(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return queryOn(ctx, s.db, query, args...)
+}
+
+// queryRowSQL выполняет запрос через s.db.QueryRowContext и логирует его при необходимости.
+func (s *Store) queryRowSQL(ctx context.Context, query string, args ...any) *sql.Row {
+	return queryRowOn(ctx, s.db, query, args...)
+}
+
+// execOn выполняет запрос в контексте ctx через ex (БД или транзакцию) и логирует его при необходимости.
+func execOn(ctx context.Context, ex execer, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	res, err := ex.ExecContext(ctx, query, args...)
+	rows := int64(-1)
+	if err == nil {
+		rows, _ = res.RowsAffected()
+	}
+	logQuery(ctx, query, args, time.Since(start), rows)
+	return res, err
+}
+
+// queryOn выполняет запрос в контексте ctx через q (БД или транзакцию) и логирует его при необходимости.
+func queryOn(ctx context.Context, q queryer, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args...)
+	logQuery(ctx, query, args, time.Since(start), -1)
+	return rows, err
+}
+
+// queryRowOn выполняет запрос в контексте ctx через q (БД или транзакцию) и логирует его при необходимости.
+func queryRowOn(ctx context.Context, q queryer, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := q.QueryRowContext(ctx, query, args...)
+	logQuery(ctx, query, args, time.Since(start), -1)
+	return row
+}
+
+// logQuery пишет в лог запрос, его аргументы, длительность и число затронутых строк.
+// Медленные запросы логируются с уровнем WARN, при TODO_SQL_DEBUG каждый
+// запрос логируется с уровнем INFO, а при уровне журнала debug - с уровнем DEBUG.
+// Значение rows < 0 означает, что число строк неизвестно.
+// Запись получает ID запроса API из ctx, если он там есть.
+func logQuery(ctx context.Context, query string, args []any, d time.Duration, rows int64) {
+	msg, level := "SQL debug", slog.LevelDebug
+	switch {
+	case sqlSlow > 0 && d >= sqlSlow:
+		msg, level = "SQL slow", slog.LevelWarn
+	case sqlDebug:
+		level = slog.LevelInfo
+	}
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("query", compactSQL(query)),
+		slog.String("args", formatArgs(args)),
+		slog.Duration("duration", d),
+	}
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", rows))
+	}
+	slog.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// compactSQL схлопывает пробельные символы запроса в одну строку.
+func compactSQL(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// formatArgs форматирует аргументы запроса, обрезая длинные строки.
+func formatArgs(args []any) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		if named, ok := arg.(sql.NamedArg); ok {
+			parts = append(parts, named.Name+"="+formatArg(named.Value))
+			continue
+		}
+		parts = append(parts, formatArg(arg))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatArg форматирует одно значение аргумента для лога.
+func formatArg(v any) string {
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	if utf8.RuneCountInString(s) > maxLogArgLen {
+		s = string([]rune(s)[:maxLogArgLen]) + "..."
+	}
+	return fmt.Sprintf("%q", s)
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLog перенаправляет логгер slog по умолчанию в буфер на время теста.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	return captureLogLevel(t, slog.LevelInfo)
+}
+
+// captureLogLevel - captureLog с записями не ниже level.
+func captureLogLevel(t *testing.T, level slog.Level) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+// setSQLLogging выставляет настройки логирования SQL и восстанавливает их после теста.
+func setSQLLogging(t *testing.T, debug bool, slow time.Duration) {
+	t.Helper()
+	oldDebug, oldSlow := sqlDebug, sqlSlow
+	sqlDebug, sqlSlow = debug, slow
+	t.Cleanup(func() { sqlDebug, sqlSlow = oldDebug, oldSlow })
+}
+
+func TestSQLLogging(t *testing.T) {
+	store := OpenForTest(t)
+	long := strings.Repeat("я", 100)
+
+	t.Run("disabled", func(t *testing.T) {
+		setSQLLogging(t, false, time.Hour)
+		buf := captureLog(t)
+		seedTasks(t, store, Task{Date: "20240101", Title: long})
+		_, err := store.GetTasks(t.Context(), 10)
+		require.NoError(t, err)
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("slow query", func(t *testing.T) {
+		setSQLLogging(t, false, time.Nanosecond)
+		buf := captureLog(t)
+		_, err := store.GetTasks(t.Context(), 10)
+		require.NoError(t, err)
+		// разделитель тегов в тексте журнала экранируется
+		columns := strings.ReplaceAll(taskColumns, tagSeparator, `\x1f`)
+		assert.Contains(t, buf.String(), `level=WARN msg="SQL slow" query="SELECT `+columns+" FROM scheduler")
+		assert.Contains(t, buf.String(), "limit=10")
+	})
+
+	t.Run("debug", func(t *testing.T) {
+		setSQLLogging(t, true, 0)
+		buf := captureLog(t)
+		seedTasks(t, store, Task{Date: "20240101", Title: long, Tags: []string{"тег"}})
+		out := buf.String()
+		assert.Contains(t, out, `msg="SQL debug" query="INSERT INTO scheduler`)
+		assert.Contains(t, out, "rows=1")
+		assert.Contains(t, out, strings.Repeat("я", maxLogArgLen)+`...\"`)
+		assert.NotContains(t, out, long)
+	})
+
+	t.Run("debug level", func(t *testing.T) {
+		// уровень журнала debug включает журнал запросов без TODO_SQL_DEBUG
+		setSQLLogging(t, false, 0)
+		buf := captureLogLevel(t, slog.LevelDebug)
+		_, err := store.GetTasks(t.Context(), 10)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), `level=DEBUG msg="SQL debug" query="SELECT `)
+		assert.Contains(t, buf.String(), "limit=10")
+	})
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"go1f/pkg/config"
+)
+
+// defaultStore - хранилище, открытое InitDB.
+// Используется GetDB и CloseDB.
+var defaultStore *Store
+
+// InitDB открывает базу данных из конфигурации c и делает её
+// хранилищем по умолчанию для функций пакета.
+// По умолчанию это файл SQLite по пути TODO_DBFILE, при TODO_DB_DRIVER=postgres -
+// PostgreSQL по строке подключения TODO_DSN.
+// Недостающие каталоги пути к файлу SQLite создаются. Если файла нет,
+// а TODO_RESTORE_FROM задает резервную копию, БД сначала восстанавливается
+// из неё (см. RestoreBackup). Целостность уже существующего файла проверяется
+// (см. CheckIntegrity), поврежденная БД не открывается.
+// Возвращает ошибку, если БД открыть не удалось; завершать ли процесс, решает вызывающий.
+func InitDB(c config.Config) (*Store, error) {
+
+	// Настраиваем логирование SQL-запросов
+	sqlDebug = c.SQLDebug
+	sqlSlow = c.SQLSlow
+
+	var store *Store
+	var err error
+	if c.DBDriver == config.DBDriverPostgres {
+		store, err = OpenPostgres(c.DSN)
+	} else {
+		store, err = initSQLite(c.PathToDB, c.RestoreFrom) // путь из env или по умолчанию
+	}
+	if err != nil {
+		return nil, err
+	}
+	defaultStore = store
+
+	slog.Info("База данных успешно инициализирована")
+	return store, nil
+}
+
+// initSQLite открывает файл SQLite dbPath для InitDB: создает каталоги,
+// при необходимости восстанавливает БД из резервной копии restoreFrom
+// (TODO_RESTORE_FROM) и проверяет целостность существующего файла.
+func initSQLite(dbPath, restoreFrom string) (*Store, error) {
+	if dbPath == MemoryPath {
+		slog.Warn("БД создана в памяти: задачи пропадут после остановки сервера")
+		return Open(dbPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create database dir: %w", err)
+	}
+	exists := false
+	if _, err := os.Stat(dbPath); err == nil {
+		exists = true
+		slog.Info("Файл БД уже существует, проверяем целостность...", "path", dbPath)
+		if restoreFrom != "" {
+			slog.Warn("TODO_RESTORE_FROM не используется: файл БД уже существует", "from", restoreFrom)
+		}
+	} else if from := restoreFrom; from != "" {
+		if _, err := RestoreBackup(from, dbPath); err != nil {
+			return nil, fmt.Errorf("failed to restore database from %s: %w", from, err)
+		}
+		slog.Info("БД восстановлена из резервной копии", "from", from, "path", dbPath)
+	}
+
+	if exists {
+		if err := checkFile(dbPath); err != nil {
+			return nil, fmt.Errorf("database %s: %w", dbPath, err)
+		}
+	}
+	store, err := Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("database %s: %w", dbPath, err)
+	}
+	return store, nil
+}
+
+// GetDB возвращает экземпляр подключения к базе данных (опционально).
+// Паникует, если база данных не была инициализирована.
+func GetDB() *sql.DB {
+	if defaultStore == nil {
+		panic("База данных не инициализирована. Сначала вызывается InitDB()")
+	}
+	return defaultStore.db
+}
+
+// CloseDB закрывает соединение с базой данных.
+// Возвращает nil, если соединение уже закрыто.
+func CloseDB() error {
+	if defaultStore != nil {
+		return defaultStore.Close()
+	}
+	return nil
+}
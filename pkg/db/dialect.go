@@ -0,0 +1,98 @@
+package db
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// dialect описывает различия SQL между поддерживаемыми СУБД.
+// Запросы пишутся на общем подмножестве SQL с именованными параметрами :name,
+// а отличающиеся части берутся отсюда.
+type dialect struct {
+	driver     string // имя драйвера database/sql
+	schema     string // схема БД для миграции 1
+	repeatKind string // первое слово правила повторения (колонка repeat)
+	forUpdate  string // блокировка прочитанной строки до конца транзакции
+}
+
+// sqliteDialect - SQLite, СУБД по умолчанию.
+// Блокировку записи транзакция берет сразу (BEGIN IMMEDIATE), поэтому FOR UPDATE не нужен.
+var sqliteDialect = &dialect{
+	driver:     "sqlite",
+	schema:     schemaSQL,
+	repeatKind: "substr(repeat, 1, instr(repeat || ' ', ' ') - 1)",
+}
+
+// postgresDialect - PostgreSQL через драйвер с поддержкой именованных параметров.
+var postgresDialect = &dialect{
+	driver:     postgresDriverName,
+	schema:     postgresSchemaSQL,
+	repeatKind: "split_part(repeat, ' ', 1)",
+	forUpdate:  " FOR UPDATE",
+}
+
+// postgresSchemaSQL создает таблицы и индексы в PostgreSQL.
+// Соответствует schemaSQL; время хранится в unix мс, поэтому BIGINT.
+const postgresSchemaSQL = `
+	CREATE TABLE IF NOT EXISTS users (
+		id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		login TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE TABLE IF NOT EXISTS scheduler (
+		id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		date TEXT NOT NULL,
+		title TEXT NOT NULL,
+		comment TEXT,
+		repeat VARCHAR(128),
+		uid TEXT UNIQUE,
+		updated_at BIGINT NOT NULL DEFAULT 0,
+		deleted_at BIGINT,
+		needs_attention INTEGER NOT NULL DEFAULT 0,
+		completed INTEGER NOT NULL DEFAULT 0,
+		priority INTEGER NOT NULL DEFAULT 0,
+		user_id BIGINT NOT NULL DEFAULT 1,
+		exclude TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_scheduler_date ON scheduler(date);
+	CREATE INDEX IF NOT EXISTS idx_scheduler_updated_at ON scheduler(updated_at);
+	CREATE INDEX IF NOT EXISTS idx_scheduler_user_date ON scheduler(user_id, date);
+
+	CREATE TABLE IF NOT EXISTS task_trigrams (
+		task_id BIGINT NOT NULL,
+		trigram TEXT NOT NULL,
+		PRIMARY KEY (task_id, trigram)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_trigrams_trigram ON task_trigrams(trigram);
+
+	CREATE TABLE IF NOT EXISTS task_tags (
+		task_id BIGINT NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (task_id, tag)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_tags_tag ON task_tags(tag);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		key_hash TEXT NOT NULL UNIQUE,
+		name TEXT NOT NULL DEFAULT '',
+		can_admin INTEGER NOT NULL DEFAULT 0,
+		created_at BIGINT NOT NULL
+	);
+	`
+
+// isUniqueViolation сообщает, что запрос нарушил ограничение уникальности.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505" // unique_violation
+	}
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Facets содержит количество задач, сгруппированных по фильтруемым измерениям.
+// Ключ верхнего уровня — измерение (например, "repeat"), вложенный — значение измерения.
+type Facets map[string]map[string]int
+
+// facetQueries описывает GROUP BY запросы для каждого фильтруемого измерения.
+// Каждый запрос должен возвращать две колонки: значение измерения и количество задач.
+// Удаленные и выполненные задачи не учитываются, как и задачи других пользователей:
+// условие на :user_id совпадает с userScope.
+//
+// Вид повторения берется из префикса правила ("d", "w", "m", "y"),
+// для разовых задач используется значение "none". Приоритет - число от 0 до 3.
+// Выражение REPEAT_KIND заменяется на dialect.repeatKind.
+var facetQueries = map[string]string{
+	"repeat": `
+	SELECT CASE
+		WHEN repeat IS NULL OR repeat = '' THEN 'none'
+		ELSE REPEAT_KIND
+	END AS value, COUNT(*)
+	FROM scheduler
+	WHERE deleted_at IS NULL AND completed = 0 AND (:user_id = 0 OR user_id = :user_id)
+	GROUP BY value`,
+	"priority": `
+	SELECT CAST(priority AS TEXT) AS value, COUNT(*)
+	FROM scheduler
+	WHERE deleted_at IS NULL AND completed = 0 AND (:user_id = 0 OR user_id = :user_id)
+	GROUP BY value`,
+	"tag": `
+	SELECT tag AS value, COUNT(*)
+	FROM task_tags
+	JOIN scheduler ON scheduler.id = task_tags.task_id
+	WHERE deleted_at IS NULL AND completed = 0 AND (:user_id = 0 OR user_id = :user_id)
+	GROUP BY value`,
+}
+
+// GetFacets возвращает количество задач пользователя из ctx по каждому фильтруемому измерению.
+// Используется для построения выпадающих списков фильтров без загрузки всех задач.
+func (s *Store) GetFacets(ctx context.Context) (Facets, error) {
+	facets := make(Facets, len(facetQueries))
+	for name, query := range facetQueries {
+		query = strings.ReplaceAll(query, "REPEAT_KIND", s.dialect.repeatKind)
+		counts, err := s.facetCounts(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count facet %s: %w", name, err)
+		}
+		facets[name] = counts
+	}
+	return facets, nil
+}
+
+// facetCounts выполняет GROUP BY запрос и собирает результат в map значение -> количество.
+func (s *Store) facetCounts(ctx context.Context, query string) (map[string]int, error) {
+	_, user := userScope(ctx)
+	rows, err := s.querySQL(ctx, query, user)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var value string
+		var count int
+		if err := rows.Scan(&value, &count); err != nil {
+			return nil, err
+		}
+		counts[value] = count
+	}
+	return counts, rows.Err()
+}
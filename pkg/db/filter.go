@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Допустимые значения TaskFilter.Sort.
+const (
+	SortDefault   = ""          // по дате, затем по id
+	SortPriority  = "priority"  // сначала более высокий приоритет, затем по дате
+	SortDate      = "date"      // по дате от ранних к поздним
+	SortDateDesc  = "-date"     // по дате от поздних к ранним
+	SortTitle     = "title"     // по заголовку без учета регистра
+	SortTitleDesc = "-title"    // по заголовку в обратном порядке
+	SortID        = "id"        // в порядке создания
+	SortIDDesc    = "-id"       // сначала новые
+	SortRelevance = "relevance" // сначала лучше подходящие под поиск, только для SearchTasks
+)
+
+// sortOrders - выражения ORDER BY для значений TaskFilter.Sort.
+// В запрос попадают только они, а не строка от пользователя.
+var sortOrders = map[string]string{
+	SortDefault:   "date ASC, id ASC",
+	SortPriority:  "priority DESC, date ASC, id ASC",
+	SortDate:      "date ASC, id ASC",
+	SortDateDesc:  "date DESC, id DESC",
+	SortTitle:     "title_fold ASC, id ASC",
+	SortTitleDesc: "title_fold DESC, id DESC",
+	SortID:        "id ASC",
+	SortIDDesc:    "id DESC",
+	SortRelevance: "date ASC, id ASC", // без поиска по индексу - как по умолчанию
+}
+
+// ValidSort сообщает, что sort - допустимое значение TaskFilter.Sort.
+func ValidSort(sort string) bool {
+	_, ok := sortOrders[sort]
+	return ok
+}
+
+// Допустимые значения TaskFilter.Due.
+const (
+	DueAny     = ""        // без отбора по дате
+	DueOverdue = "overdue" // просроченные: дата раньше TaskFilter.Today
+	DueToday   = "today"   // на сегодня: дата равна TaskFilter.Today
+)
+
+// TaskFilter - условия отбора задач для списков и поиска.
+// Нулевое значение отбирает невыполненные задачи.
+type TaskFilter struct {
+	Completed bool   // true - только выполненные задачи, false - только невыполненные
+	Priority  *int   // если задан, отбираются задачи только с этим приоритетом
+	Sort      string // порядок задач, одна из констант Sort*
+	Tag       string // если задан, отбираются задачи только с этим тегом
+	Due       string // отбор по дате относительно Today: DueAny, DueOverdue или DueToday
+	Today     string // сегодняшняя дата YYYYMMDD в часовом поясе сервера, нужна для Due
+}
+
+// where возвращает условие WHERE для фильтра и его именованные параметры.
+// Удаленные задачи и задачи других пользователей (см. UserID) исключаются всегда.
+func (f TaskFilter) where(ctx context.Context) (string, []any) {
+	completed := 0
+	if f.Completed {
+		completed = 1
+	}
+	scope, user := userScope(ctx)
+	where := "deleted_at IS NULL AND completed = :completed AND " + scope
+	args := []any{sql.Named("completed", completed), user}
+
+	if f.Priority != nil {
+		where += " AND priority = :priority"
+		args = append(args, sql.Named("priority", *f.Priority))
+	}
+	if f.Tag != "" {
+		where += " AND id IN (SELECT task_id FROM task_tags WHERE tag = :tag)"
+		args = append(args, sql.Named("tag", f.Tag))
+	}
+	switch f.Due {
+	case DueOverdue:
+		where += " AND date < :today"
+	case DueToday:
+		where += " AND date = :today"
+	}
+	if f.Due != DueAny {
+		args = append(args, sql.Named("today", f.Today))
+	}
+	return where, args
+}
+
+// orderBy возвращает выражение ORDER BY для фильтра.
+// Неизвестное значение Sort дает порядок по умолчанию.
+func (f TaskFilter) orderBy() string {
+	if order, ok := sortOrders[f.Sort]; ok {
+		return order
+	}
+	return sortOrders[SortDefault]
+}
@@ -0,0 +1,255 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ftsMinTerm - минимальная длина слова для полнотекстового поиска.
+// Индекс построен по триграммам, более короткие слова ищутся через LIKE.
+const ftsMinTerm = 3
+
+// ftsSchemaSQL создает индекс полнотекстового поиска scheduler_fts по title
+// и comment и триггеры, которые поддерживают его при любом изменении задач.
+// Индекс хранит триграммы без учета регистра, поэтому MATCH находит подстроки,
+// как и LIKE, но по индексу.
+const ftsSchemaSQL = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_fts USING fts5(
+		title, comment, content='scheduler', content_rowid='id', tokenize='trigram'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_insert AFTER INSERT ON scheduler BEGIN
+		INSERT INTO scheduler_fts(rowid, title, comment) VALUES (new.id, new.title, new.comment);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_delete AFTER DELETE ON scheduler BEGIN
+		INSERT INTO scheduler_fts(scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_update AFTER UPDATE OF title, comment ON scheduler BEGIN
+		INSERT INTO scheduler_fts(scheduler_fts, rowid, title, comment) VALUES ('delete', old.id, old.title, old.comment);
+		INSERT INTO scheduler_fts(rowid, title, comment) VALUES (new.id, new.title, new.comment);
+	END;
+
+	INSERT INTO scheduler_fts(scheduler_fts) VALUES ('rebuild');`
+
+// ftsFoldSchemaSQL - индекс scheduler_fts по колонкам title_fold и comment_fold
+// (см. foldText) вместо title и comment.
+const ftsFoldSchemaSQL = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_fts USING fts5(
+		title_fold, comment_fold, content='scheduler', content_rowid='id', tokenize='trigram'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_insert AFTER INSERT ON scheduler BEGIN
+		INSERT INTO scheduler_fts(rowid, title_fold, comment_fold) VALUES (new.id, new.title_fold, new.comment_fold);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_delete AFTER DELETE ON scheduler BEGIN
+		INSERT INTO scheduler_fts(scheduler_fts, rowid, title_fold, comment_fold)
+		VALUES ('delete', old.id, old.title_fold, old.comment_fold);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS scheduler_fts_update AFTER UPDATE OF title_fold, comment_fold ON scheduler BEGIN
+		INSERT INTO scheduler_fts(scheduler_fts, rowid, title_fold, comment_fold)
+		VALUES ('delete', old.id, old.title_fold, old.comment_fold);
+		INSERT INTO scheduler_fts(rowid, title_fold, comment_fold) VALUES (new.id, new.title_fold, new.comment_fold);
+	END;
+
+	INSERT INTO scheduler_fts(scheduler_fts) VALUES ('rebuild');`
+
+// migrateFTS - миграция 2: индекс полнотекстового поиска для SQLite
+// с заполнением по существующим задачам. Если драйвер собран без FTS5,
+// индекс не создается и поиск работает через LIKE.
+// В PostgreSQL поиск всегда идет через LIKE.
+func migrateFTS(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	if d != sqliteDialect {
+		return nil
+	}
+	return createFTS(ctx, tx, ftsSchemaSQL)
+}
+
+// migrateFold - миграция 3: колонки title_fold и comment_fold с текстом задачи
+// в виде для поиска (см. foldText), заполненные для существующих задач.
+// Индекс полнотекстового поиска перестраивается по этим колонкам.
+func migrateFold(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	if d == sqliteDialect {
+		_, err := execOn(ctx, tx, `
+			DROP TRIGGER IF EXISTS scheduler_fts_insert;
+			DROP TRIGGER IF EXISTS scheduler_fts_delete;
+			DROP TRIGGER IF EXISTS scheduler_fts_update;
+			DROP TABLE IF EXISTS scheduler_fts;`)
+		if err != nil {
+			return fmt.Errorf("failed to drop full-text search index: %w", err)
+		}
+		for _, column := range []string{"title_fold", "comment_fold"} {
+			if err := addColumnIfMissing(ctx, tx, "scheduler", column, "TEXT NOT NULL DEFAULT ''"); err != nil {
+				return err
+			}
+		}
+	} else {
+		_, err := execOn(ctx, tx, `
+			ALTER TABLE scheduler ADD COLUMN IF NOT EXISTS title_fold TEXT NOT NULL DEFAULT '';
+			ALTER TABLE scheduler ADD COLUMN IF NOT EXISTS comment_fold TEXT NOT NULL DEFAULT '';`)
+		if err != nil {
+			return fmt.Errorf("failed to add search columns: %w", err)
+		}
+	}
+
+	type text struct {
+		id             int64
+		title, comment string
+	}
+	rows, err := queryOn(ctx, tx, `SELECT id, title, COALESCE(comment, '') FROM scheduler`)
+	if err != nil {
+		return fmt.Errorf("failed to query tasks: %w", err)
+	}
+	var texts []text
+	for rows.Next() {
+		var t text
+		if err := rows.Scan(&t.id, &t.title, &t.comment); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan task: %w", err)
+		}
+		texts = append(texts, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %w", err)
+	}
+
+	for _, t := range texts {
+		_, err := execOn(ctx, tx, `UPDATE scheduler SET title_fold = :title_fold, comment_fold = :comment_fold WHERE id = :id`,
+			sql.Named("title_fold", foldText(t.title)),
+			sql.Named("comment_fold", foldText(t.comment)),
+			sql.Named("id", t.id))
+		if err != nil {
+			return fmt.Errorf("failed to backfill search columns: %w", err)
+		}
+	}
+
+	if d != sqliteDialect {
+		return nil
+	}
+	return createFTS(ctx, tx, ftsFoldSchemaSQL)
+}
+
+// createFTS создает индекс полнотекстового поиска по схеме schema.
+// Если драйвер собран без FTS5, индекс не создается.
+func createFTS(ctx context.Context, tx *sql.Tx, schema string) error {
+	_, err := execOn(ctx, tx, schema)
+	if err != nil && strings.Contains(err.Error(), "no such module") {
+		slog.Warn("SQLite без FTS5: поиск задач работает без индекса", "error", err)
+		return nil
+	}
+	return err
+}
+
+// foldText приводит текст к виду для поиска: нижний регистр по правилам
+// Unicode и "е" вместо "ё". Так же приводится строка поиска, поэтому
+// "магазин" находит "Магазин", а "елка" - "Ёлку".
+// SQLite сравнивает без учета регистра только латинские буквы.
+func foldText(s string) string {
+	return strings.ReplaceAll(strings.ToLower(s), "ё", "е")
+}
+
+// hasFTS сообщает, есть ли в БД индекс полнотекстового поиска.
+func (s *Store) hasFTS(ctx context.Context) (bool, error) {
+	if s.dialect != sqliteDialect {
+		return false, nil
+	}
+	var n int
+	err := s.queryRowSQL(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'scheduler_fts'`).Scan(&n)
+	return n > 0, err
+}
+
+// parseSearch разбивает строку поиска на слова и фразы в двойных кавычках.
+// Незакрытая кавычка относится к фразе до конца строки.
+func parseSearch(search string) []string {
+	var terms []string
+	for search != "" {
+		quote := strings.IndexByte(search, '"')
+		if quote < 0 {
+			return append(terms, strings.Fields(search)...)
+		}
+		terms = append(terms, strings.Fields(search[:quote])...)
+
+		search = search[quote+1:]
+		phrase := search
+		if end := strings.IndexByte(search, '"'); end >= 0 {
+			phrase, search = search[:end], search[end+1:]
+		} else {
+			search = ""
+		}
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			terms = append(terms, phrase)
+		}
+	}
+	return terms
+}
+
+// likeEscape - символ экранирования в шаблонах LIKE, см. escapeLike.
+const likeEscape = `\`
+
+// escapeLike экранирует в s символы шаблона LIKE (%, _ и сам likeEscape),
+// чтобы они искались буквально. Шаблон нужно сравнивать с ESCAPE likeEscape.
+func escapeLike(s string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(s)
+}
+
+// ftsPhrase записывает term как фразу запроса FTS5, чтобы операторы
+// и спецсимволы в нем искались как обычный текст.
+func ftsPhrase(term string) string {
+	return `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+}
+
+// textSearch - условие поиска задач по тексту для SearchTasks.
+type textSearch struct {
+	where string // условие на задачу: все слова найдены
+	join  string // подзапрос с релевантностью, если в поиске участвует индекс
+	args  []any
+}
+
+// textSearch строит условие поиска по словам и фразам search: каждое
+// должно встречаться в title, comment или в одном из тегов задачи.
+// Регистр и разница между "е" и "ё" не учитываются (см. foldText).
+// Длинные слова ищутся по индексу scheduler_fts, если он есть.
+func (s *Store) textSearch(search string) textSearch {
+	var (
+		conds   []string
+		matches []string
+		args    []any
+	)
+	for i, term := range parseSearch(foldText(search)) {
+		name := "term" + strconv.Itoa(i)
+		args = append(args, sql.Named(name, escapeLike(term)))
+		contains := "LIKE '%' || :" + name + " || '%' ESCAPE '" + likeEscape + "'"
+		// теги хранятся в нижнем регистре
+		inTags := "id IN (SELECT task_id FROM task_tags WHERE replace(tag, 'ё', 'е') " + contains + ")"
+
+		if s.fts && utf8.RuneCountInString(term) >= ftsMinTerm {
+			match := ftsPhrase(term)
+			matches = append(matches, match)
+			args = append(args, sql.Named("match"+strconv.Itoa(i), match))
+			conds = append(conds, fmt.Sprintf(
+				"(id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH :match%d) OR %s)", i, inTags))
+			continue
+		}
+		conds = append(conds, fmt.Sprintf("(title_fold %[1]s OR comment_fold %[1]s OR %[2]s)", contains, inTags))
+	}
+
+	ts := textSearch{where: "1 = 1", args: args}
+	if len(conds) > 0 {
+		ts.where = strings.Join(conds, " AND ")
+	}
+	if len(matches) > 0 {
+		ts.join = `LEFT JOIN (SELECT rowid AS fts_id, rank AS fts_rank FROM scheduler_fts WHERE scheduler_fts MATCH :match)
+		AS fts ON fts.fts_id = scheduler.id`
+		ts.args = append(ts.args, sql.Named("match", strings.Join(matches, " AND ")))
+	}
+	return ts
+}
@@ -0,0 +1,236 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// fuzzyCandidatesLimit ограничивает количество задач-кандидатов,
+// отобранных по триграммам перед точной проверкой расстояния.
+const fuzzyCandidatesLimit = 1000
+
+// fuzzyMatch - задача, найденная нечетким поиском, с суммарным расстоянием до запроса.
+type fuzzyMatch struct {
+	task     *Task
+	distance int
+}
+
+// SearchTasksFuzzy выполняет поиск задач с учетом опечаток.
+//
+// Задача подходит, если для каждого слова запроса в title или comment есть слово
+// на расстоянии редактирования не больше maxDistance (зависит от длины слова).
+// Сначала кандидаты отбираются по общим триграммам из таблицы task_trigrams,
+// затем расстояние проверяется в Go.
+//
+// Результат отсортирован по близости к запросу, затем по дате.
+// Параметр limit ограничивает количество результатов, filter - какие задачи отбирать.
+func (s *Store) SearchTasksFuzzy(ctx context.Context, search string, limit int, filter TaskFilter) ([]*Task, error) {
+
+	queryTokens := tokenize(search)
+	if len(queryTokens) == 0 {
+		return nil, nil
+	}
+
+	where, args := filter.where(ctx)
+	var grams []any
+	for gram := range trigrams(queryTokens) {
+		grams = append(grams, gram)
+	}
+
+	query := fmt.Sprintf(`
+	SELECT `+taskColumns+`
+	FROM scheduler
+	WHERE `+where+` AND id IN (
+		SELECT task_id FROM task_trigrams
+		WHERE trigram IN (%s)
+		GROUP BY task_id
+		ORDER BY COUNT(*) DESC
+		LIMIT %d
+	)`, placeholders(len(grams)), fuzzyCandidatesLimit)
+
+	rows, err := s.querySQL(ctx, query, append(args, grams...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fuzzy candidates: %w", err)
+	}
+	candidates, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []fuzzyMatch
+	for _, task := range candidates {
+		if distance, ok := matchTokens(queryTokens, tokenize(task.Title+" "+task.Comment)); ok {
+			matches = append(matches, fuzzyMatch{task: task, distance: distance})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].task.Date < matches[j].task.Date
+	})
+
+	tasks := make([]*Task, 0, min(limit, len(matches)))
+	for _, m := range matches {
+		if len(tasks) == limit {
+			break
+		}
+		tasks = append(tasks, m.task)
+	}
+	return tasks, nil
+}
+
+// matchTokens проверяет, что каждому слову запроса соответствует близкое слово задачи.
+// Возвращает суммарное минимальное расстояние и признак совпадения.
+func matchTokens(query, text []string) (int, bool) {
+	total := 0
+	for _, q := range query {
+		best := -1
+		limit := maxDistance(q)
+		for _, word := range text {
+			if d := editDistance(q, word); d <= limit && (best < 0 || d < best) {
+				best = d
+			}
+		}
+		if best < 0 {
+			return 0, false
+		}
+		total += best
+	}
+	return total, true
+}
+
+// maxDistance возвращает допустимое число опечаток в зависимости от длины слова.
+func maxDistance(word string) int {
+	switch n := len([]rune(word)); {
+	case n <= 2:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance вычисляет расстояние Дамерау-Левенштейна (вариант с ограничением
+// на транспозиции): вставка, удаление, замена и перестановка соседних букв
+// стоят по единице.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// tokenize разбивает текст на слова в нижнем регистре.
+// Словом считается последовательность букв и цифр.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// trigrams возвращает множество триграмм слов.
+// Слова дополняются пробелами по краям, чтобы короткие слова
+// и начала слов тоже давали триграммы.
+func trigrams(tokens []string) map[string]struct{} {
+	grams := make(map[string]struct{})
+	for _, token := range tokens {
+		r := []rune("  " + token + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			grams[string(r[i:i+3])] = struct{}{}
+		}
+	}
+	return grams
+}
+
+// updateTrigrams перестраивает триграммы задачи внутри транзакции или БД.
+func updateTrigrams(ctx context.Context, ex execer, id any, title, comment string) error {
+	if _, err := execOn(ctx, ex, `DELETE FROM task_trigrams WHERE task_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete trigrams: %w", err)
+	}
+
+	grams := trigrams(tokenize(title + " " + comment))
+	if len(grams) == 0 {
+		return nil
+	}
+	args := make([]any, 0, 2*len(grams))
+	values := make([]string, 0, len(grams))
+	for gram := range grams {
+		args = append(args, id, gram)
+		values = append(values, "(?, ?)")
+	}
+
+	query := `INSERT INTO task_trigrams (task_id, trigram) VALUES ` + strings.Join(values, ", ")
+	if _, err := execOn(ctx, ex, query, args...); err != nil {
+		return fmt.Errorf("failed to insert trigrams: %w", err)
+	}
+	return nil
+}
+
+// backfillTrigrams строит триграммы для задач, у которых их еще нет
+// (например, созданных до появления нечеткого поиска).
+func (s *Store) backfillTrigrams(ctx context.Context) error {
+	// Читаются только нужные колонки: индекс строится и в БД, где еще
+	// не применены миграции, добавляющие колонки задачи
+	rows, err := s.querySQL(ctx, `
+	SELECT id, title, COALESCE(comment, '') FROM scheduler
+	WHERE deleted_at IS NULL AND id NOT IN (SELECT DISTINCT task_id FROM task_trigrams)`)
+	if err != nil {
+		return fmt.Errorf("failed to query tasks without trigrams: %w", err)
+	}
+	var tasks []Task
+	for rows.Next() {
+		var task Task
+		if err := rows.Scan(&task.ID, &task.Title, &task.Comment); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %w", err)
+	}
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, task := range tasks {
+			if err := updateTrigrams(ctx, tx, task.ID, task.Title, task.Comment); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// placeholders возвращает строку "?, ?, ..." из n позиционных параметров.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditDistance(t *testing.T) {
+	tbl := []struct {
+		a, b string
+		want int
+	}{
+		{"пылесос", "пылесос", 0},
+		{"пылесос", "пылсеос", 1}, // перестановка соседних букв
+		{"пылесос", "пылсос", 1},  // пропущенная буква
+		{"пылесос", "пылесосы", 1},
+		{"пылесос", "пылесас", 1},
+		{"пылесос", "пелисос", 2},
+		{"", "abc", 3},
+	}
+	for _, v := range tbl {
+		assert.Equal(t, v.want, editDistance(v.a, v.b), "%s -> %s", v.a, v.b)
+	}
+}
+
+func TestSearchTasksFuzzy(t *testing.T) {
+	store := OpenForTest(t)
+	seedTasks(t, store,
+		Task{Date: "20240103", Title: "Купить пылесос", Comment: "Робот"},
+		Task{Date: "20240101", Title: "Почистить пылесосы"},
+		Task{Date: "20240102", Title: "Помыть посуду"},
+	)
+
+	tbl := []struct {
+		search string
+		want   []string
+	}{
+		{"пылсеос", []string{"Купить пылесос", "Почистить пылесосы"}},
+		{"пылсос", []string{"Купить пылесос", "Почистить пылесосы"}},
+		{"пылесосы", []string{"Почистить пылесосы", "Купить пылесос"}},
+		{"купит пылсос", []string{"Купить пылесос"}},
+		{"робт", []string{"Купить пылесос"}},
+		{"посуда", []string{"Помыть посуду"}},
+		{"телевизор", []string{}},
+	}
+	for _, v := range tbl {
+		tasks, err := store.SearchTasksFuzzy(t.Context(), v.search, 10, TaskFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, v.want, titles(tasks), v.search)
+	}
+
+	// точный поиск без флага не находит опечатки
+	tasks, err := store.SearchTasks(t.Context(), "пылсос", 10, TaskFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func TestTrigramsMaintained(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store, Task{Date: "20240101", Title: "Полить цветы"})
+	id := strconv.FormatInt(ids[0], 10)
+
+	require.NoError(t, store.PutTaskID(t.Context(), &Task{ID: id, Date: "20240101", Title: "Покормить кота"}))
+	tasks, err := store.SearchTasksFuzzy(t.Context(), "цвиты", 10, TaskFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, tasks, "после обновления старые триграммы должны удаляться")
+	tasks, err = store.SearchTasksFuzzy(t.Context(), "кота", 10, TaskFilter{})
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+
+	require.NoError(t, store.DeleteTaskID(t.Context(), taskID(t, id)))
+	var count int
+	require.NoError(t, store.db.QueryRow(`SELECT COUNT(*) FROM task_trigrams`).Scan(&count))
+	assert.Zero(t, count)
+}
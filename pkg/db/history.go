@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Completion - отметка о выполнении задачи из таблицы task_history.
+type Completion struct {
+	TaskID      int64
+	CompletedAt time.Time // время отметки
+	Date        string    // дата выполнения задачи по расписанию, YYYYMMDD
+}
+
+// historySQL создает таблицу истории выполнений в SQLite.
+// Внешнего ключа на scheduler нет: история удаленных задач сохраняется.
+const historySQL = `
+	CREATE TABLE IF NOT EXISTS task_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		completed_at INTEGER NOT NULL, -- Время отметки, unix мс
+		date TEXT NOT NULL             -- Дата выполнения по расписанию
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_history_user ON task_history(user_id, completed_at);
+	CREATE INDEX IF NOT EXISTS idx_task_history_task ON task_history(task_id);`
+
+// postgresHistorySQL создает таблицу истории выполнений в PostgreSQL.
+const postgresHistorySQL = `
+	CREATE TABLE IF NOT EXISTS task_history (
+		id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		task_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		completed_at BIGINT NOT NULL,
+		date TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_task_history_user ON task_history(user_id, completed_at);
+	CREATE INDEX IF NOT EXISTS idx_task_history_task ON task_history(task_id);`
+
+// migrateHistory - миграция 7: таблица истории выполнений задач.
+func migrateHistory(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	query := historySQL
+	if d == postgresDialect {
+		query = postgresHistorySQL
+	}
+	if _, err := execOn(ctx, tx, query); err != nil {
+		return fmt.Errorf("failed to create task_history: %w", err)
+	}
+	return nil
+}
+
+// addCompletion записывает в историю выполнение задачи task внутри транзакции.
+// Владелец берется из строки задачи, а не из контекста: контекст может
+// снимать отбор по пользователю (см. WithAllUsers).
+func addCompletion(ctx context.Context, ex execer, task *Task, now time.Time) error {
+	_, err := execOn(ctx, ex, `
+	INSERT INTO task_history (task_id, user_id, completed_at, date)
+	SELECT id, user_id, :now, :date FROM scheduler WHERE id = :task_id`,
+		sql.Named("task_id", task.ID),
+		sql.Named("now", now.UnixMilli()),
+		sql.Named("date", task.Date))
+	if err != nil {
+		return fmt.Errorf("failed to add completion: %w", err)
+	}
+	return nil
+}
+
+// GetHistory возвращает выполнения задач пользователя из ctx в порядке времени
+// отметки, в том числе выполнения удаленных задач. При taskID = 0 - всех задач.
+func (s *Store) GetHistory(ctx context.Context, taskID int64) ([]Completion, error) {
+	scope, user := userScope(ctx)
+	rows, err := s.querySQL(ctx, `
+	SELECT task_id, completed_at, date FROM task_history
+	WHERE (:task_id = 0 OR task_id = :task_id) AND `+scope+`
+	ORDER BY completed_at, id`,
+		sql.Named("task_id", taskID), user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []Completion{}
+	for rows.Next() {
+		var c Completion
+		var completed int64
+		if err := rows.Scan(&c.TaskID, &completed, &c.Date); err != nil {
+			return nil, fmt.Errorf("failed to scan completion: %w", err)
+		}
+		c.CompletedAt = time.UnixMilli(completed)
+		history = append(history, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return history, nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// jobRunsSQL создает таблицу последних запусков ежедневных заданий сервера:
+// по ней задание после перезапуска узнает, что сегодня уже выполнено.
+const jobRunsSQL = `
+	CREATE TABLE IF NOT EXISTS job_runs (
+		name TEXT PRIMARY KEY,
+		day TEXT NOT NULL -- День последнего выполнения, YYYYMMDD
+	);`
+
+// migrateJobRuns - миграция 10: таблица последних запусков заданий.
+func migrateJobRuns(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	if _, err := execOn(ctx, tx, jobRunsSQL); err != nil {
+		return fmt.Errorf("failed to create job_runs: %w", err)
+	}
+	return nil
+}
+
+// LastRun возвращает день (YYYYMMDD) последнего выполнения задания name
+// или пустую строку, если задание еще не выполнялось.
+func (s *Store) LastRun(ctx context.Context, name string) (string, error) {
+	var day string
+	err := s.queryRowSQL(ctx, `SELECT day FROM job_runs WHERE name = :name`,
+		sql.Named("name", name)).Scan(&day)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get last run of %s: %w", name, err)
+	}
+	return day, nil
+}
+
+// SetLastRun запоминает day (YYYYMMDD) как день последнего выполнения задания name.
+func (s *Store) SetLastRun(ctx context.Context, name, day string) error {
+	_, err := s.execSQL(ctx, `
+	INSERT INTO job_runs (name, day) VALUES (:name, :day)
+	ON CONFLICT (name) DO UPDATE SET day = excluded.day`,
+		sql.Named("name", name),
+		sql.Named("day", day))
+	if err != nil {
+		return fmt.Errorf("failed to save last run of %s: %w", name, err)
+	}
+	return nil
+}
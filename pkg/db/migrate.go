@@ -0,0 +1,299 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"go1f/pkg/taskdate"
+
+	"github.com/google/uuid"
+)
+
+// ErrSchemaTooNew возвращается, если БД обновлена более новой версией приложения:
+// её схему текущая версия может не понимать.
+var ErrSchemaTooNew = errors.New("database schema is newer than the application")
+
+// migration - шаг изменения схемы БД. Шаги применяются по порядку версий,
+// каждый в своей транзакции, и отмечаются в таблице schema_migrations.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx *sql.Tx, d *dialect) error
+}
+
+// migrations - все изменения схемы по порядку. Версии идут подряд с 1.
+// Уже выпущенные шаги не меняются: новое изменение схемы - новый шаг в конце.
+var migrations = []migration{
+	{1, "начальная схема", migrateBaseline},
+	{2, "полнотекстовый поиск", migrateFTS},
+	{3, "поиск без учета регистра", migrateFold},
+	{4, "токены календаря", migrateCalendarTokens},
+	{5, "версии задач", migrateVersion},
+	{6, "шаблоны задач", migrateTemplates},
+	{7, "история выполнений", migrateHistory},
+	{8, "вебхуки", migrateWebhooks},
+	{9, "отметки уведомлений", migrateNotified},
+	{10, "запуски заданий", migrateJobRuns},
+	{11, "номера изменений", migrateChanges},
+	{12, "интервал недельных правил", migrateWeekRules},
+}
+
+// migrationsSQL создает таблицу примененных миграций.
+const migrationsSQL = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at BIGINT NOT NULL -- Время применения, unix мс
+	);`
+
+// applyMigrations применяет к БД еще не примененные шаги из migrations.
+// Возвращает ErrSchemaTooNew, если в БД есть версия новее последнего шага.
+func (s *Store) applyMigrations(ctx context.Context) error {
+	if _, err := s.execSQL(ctx, migrationsSQL); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := s.queryRowSQL(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	latest := migrations[len(migrations)-1].version
+	if current > latest {
+		return fmt.Errorf("%w: version %d, supported %d", ErrSchemaTooNew, current, latest)
+	}
+
+	for _, m := range migrations[current:] {
+		applied := false
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			// Другой процесс мог применить шаг, пока ждали блокировку
+			var n int
+			err := queryRowOn(ctx, tx, `SELECT COUNT(*) FROM schema_migrations WHERE version = :version`,
+				sql.Named("version", m.version)).Scan(&n)
+			if err != nil || n > 0 {
+				return err
+			}
+			if err := m.up(ctx, tx, s.dialect); err != nil {
+				return err
+			}
+			_, err = execOn(ctx, tx, `INSERT INTO schema_migrations (version, applied_at) VALUES (:version, :now)`,
+				sql.Named("version", m.version),
+				sql.Named("now", timeNow().UnixMilli()))
+			applied = err == nil
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+		}
+		if applied {
+			slog.Info("Применена миграция схемы БД", "version", m.version, "name", m.name)
+		}
+	}
+	return nil
+}
+
+// migrateBaseline - миграция 1: схема на момент появления schema_migrations.
+// Создает таблицы новой БД, а БД SQLite предыдущих версий без schema_migrations
+// доводит до той же схемы, добавляя недостающие колонки и индексы.
+func migrateBaseline(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	if _, err := execOn(ctx, tx, d.schema); err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	if d == postgresDialect {
+		if err := createDefaultUser(ctx, tx); err != nil {
+			return err
+		}
+		// Пользователь по умолчанию добавлен с явным id, последовательность надо сдвинуть
+		_, err := execOn(ctx, tx, `SELECT setval(pg_get_serial_sequence('users', 'id'), (SELECT MAX(id) FROM users))`)
+		return err
+	}
+
+	// Назначаем UUID задачам, созданным до появления колонки uid
+	if err := migrateUID(ctx, tx); err != nil {
+		return fmt.Errorf("failed to migrate uid: %w", err)
+	}
+
+	// Добавляем колонки времени изменения и мягкого удаления
+	if err := migrateSync(ctx, tx); err != nil {
+		return fmt.Errorf("failed to migrate sync columns: %w", err)
+	}
+
+	// Добавляем признак выполненной задачи, приоритет, даты исключений
+	// и признак задачи с неисправимой датой
+	columns := []struct{ name, definition string }{
+		{"completed", "INTEGER NOT NULL DEFAULT 0"},
+		{"priority", "INTEGER NOT NULL DEFAULT 0"},
+		{"exclude", "TEXT NOT NULL DEFAULT ''"},
+		{"needs_attention", "INTEGER NOT NULL DEFAULT 0"},
+	}
+	for _, c := range columns {
+		if err := addColumnIfMissing(ctx, tx, "scheduler", c.name, c.definition); err != nil {
+			return fmt.Errorf("failed to migrate %s column: %w", c.name, err)
+		}
+	}
+
+	// Добавляем владельца задач, существующие задачи достаются пользователю по умолчанию
+	if err := migrateUsers(ctx, tx); err != nil {
+		return fmt.Errorf("failed to migrate users: %w", err)
+	}
+	return nil
+}
+
+// addColumnIfMissing добавляет колонку в таблицу, если её еще нет.
+// Нужна для БД, созданных предыдущими версиями приложения.
+func addColumnIfMissing(ctx context.Context, tx *sql.Tx, table, column, definition string) error {
+	rows, err := queryOn(ctx, tx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name, typ string
+			notNull   bool
+			dflt      sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return fmt.Errorf("failed to scan column info: %w", err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %w", err)
+	}
+	rows.Close()
+
+	_, err = execOn(ctx, tx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// migrateUID добавляет колонку uid, назначает UUID существующим задачам
+// и создает уникальный индекс по uid.
+func migrateUID(ctx context.Context, tx *sql.Tx) error {
+	if err := addColumnIfMissing(ctx, tx, "scheduler", "uid", "TEXT"); err != nil {
+		return err
+	}
+
+	rows, err := queryOn(ctx, tx, `SELECT id FROM scheduler WHERE uid IS NULL OR uid = ''`)
+	if err != nil {
+		return fmt.Errorf("failed to query tasks without uid: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan task id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error during rows iteration: %w", err)
+	}
+
+	for _, id := range ids {
+		_, err := execOn(ctx, tx, `UPDATE scheduler SET uid = :uid WHERE id = :id`,
+			sql.Named("uid", uuid.NewString()),
+			sql.Named("id", id))
+		if err != nil {
+			return fmt.Errorf("failed to backfill uid: %w", err)
+		}
+	}
+
+	_, err = execOn(ctx, tx, `CREATE UNIQUE INDEX IF NOT EXISTS idx_scheduler_uid ON scheduler(uid)`)
+	return err
+}
+
+// migrateSync добавляет колонки updated_at и deleted_at, нужные для
+// разностной синхронизации, и индекс по времени изменения.
+// Задачи, созданные до миграции, получают updated_at = 0 и попадают
+// в первую синхронизацию любого клиента.
+func migrateSync(ctx context.Context, tx *sql.Tx) error {
+	if err := addColumnIfMissing(ctx, tx, "scheduler", "updated_at", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(ctx, tx, "scheduler", "deleted_at", "INTEGER"); err != nil {
+		return err
+	}
+
+	_, err := execOn(ctx, tx, `CREATE INDEX IF NOT EXISTS idx_scheduler_updated_at ON scheduler(updated_at)`)
+	return err
+}
+
+// migrateVersion добавляет колонку version для оптимистичной блокировки
+// (см. Store.PutTaskID). Существующие задачи получают версию 1.
+func migrateVersion(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	if d == sqliteDialect {
+		return addColumnIfMissing(ctx, tx, "scheduler", "version", "INTEGER NOT NULL DEFAULT 1")
+	}
+	_, err := execOn(ctx, tx, `ALTER TABLE scheduler ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1`)
+	if err != nil {
+		return fmt.Errorf("failed to add version column: %w", err)
+	}
+	return nil
+}
+
+// migrateWeekRules переписывает недельные правила задач и шаблонов, которые
+// после появления интервала "w N D" читались бы иначе: "w 5 2" (пятницы
+// в феврале) становится "w 1 5 2" (см. taskdate.LegacyWeekRule).
+func migrateWeekRules(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	for _, table := range []string{"scheduler", "templates"} {
+		rows, err := queryOn(ctx, tx, `SELECT id, repeat FROM `+table+` WHERE repeat LIKE 'w %'`)
+		if err != nil {
+			return fmt.Errorf("failed to query week rules: %w", err)
+		}
+		rules := make(map[int64]string)
+		for rows.Next() {
+			var id int64
+			var repeat string
+			if err := rows.Scan(&id, &repeat); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan week rule: %w", err)
+			}
+			if fixed := taskdate.LegacyWeekRule(repeat); fixed != repeat {
+				rules[id] = fixed
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error during rows iteration: %w", err)
+		}
+
+		for id, repeat := range rules {
+			_, err := execOn(ctx, tx, `UPDATE `+table+` SET repeat = :repeat WHERE id = :id`,
+				sql.Named("repeat", repeat),
+				sql.Named("id", id))
+			if err != nil {
+				return fmt.Errorf("failed to rewrite week rule: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// migrateDates проверяет даты задач при запуске.
+// Исправленные и неисправимые даты попадают в журнал.
+func (s *Store) migrateDates(ctx context.Context) error {
+	report, err := s.RepairDates(ctx)
+	if err != nil {
+		return err
+	}
+	if report.Fixed > 0 {
+		slog.Info("Исправлен формат даты задач", "count", report.Fixed)
+	}
+	if len(report.Invalid) > 0 {
+		slog.Warn("Задачи с неверной датой требуют исправления", "id", strings.Join(report.Invalid, ", "))
+	}
+	return nil
+}
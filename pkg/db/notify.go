@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migrateNotified - миграция 9: колонка notified_on - день (YYYYMMDD), когда
+// о задаче последний раз отправлялось уведомление. Пустая строка - не отправлялось.
+func migrateNotified(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	if d == sqliteDialect {
+		return addColumnIfMissing(ctx, tx, "scheduler", "notified_on", "TEXT NOT NULL DEFAULT ''")
+	}
+	_, err := execOn(ctx, tx, `ALTER TABLE scheduler ADD COLUMN IF NOT EXISTS notified_on TEXT NOT NULL DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("failed to add notified_on column: %w", err)
+	}
+	return nil
+}
+
+// TasksToNotify возвращает невыполненные задачи пользователя из ctx с датой
+// today (YYYYMMDD) или раньше, о которых сегодня еще не было уведомления,
+// в порядке даты и id.
+func (s *Store) TasksToNotify(ctx context.Context, today string) ([]*Task, error) {
+	scope, user := userScope(ctx)
+	rows, err := s.querySQL(ctx, "SELECT "+taskColumns+`
+	FROM scheduler
+	WHERE deleted_at IS NULL AND completed = 0 AND date <= :today AND notified_on <> :today AND `+scope+`
+	ORDER BY date, id`,
+		sql.Named("today", today), user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks to notify: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []*Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return tasks, nil
+}
+
+// MarkNotified отмечает, что о задачах ids пользователя из ctx уведомили в день
+// today (YYYYMMDD). Версия и время изменения задач не меняются: отметка
+// не изменяет задачу для клиентов и синхронизации.
+func (s *Store) MarkNotified(ctx context.Context, today string, ids []string) error {
+	scope, user := userScope(ctx)
+	query := `UPDATE scheduler SET notified_on = :today WHERE id = :id AND ` + scope
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			if _, err := execOn(ctx, tx, query, sql.Named("today", today), sql.Named("id", id), user); err != nil {
+				return fmt.Errorf("failed to mark task %s notified: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
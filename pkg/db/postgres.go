@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// postgresDriverName - драйвер database/sql для PostgreSQL, который принимает
+// запросы с параметрами :name и ? и передает их lib/pq как $1, $2, ...
+const postgresDriverName = "todo-postgres"
+
+func init() {
+	sql.Register(postgresDriverName, postgresDriver{})
+}
+
+// postgresDriver открывает соединения lib/pq с переводом параметров запросов.
+type postgresDriver struct{}
+
+// Open открывает соединение с PostgreSQL по строке подключения dsn.
+func (postgresDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := pq.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresConn{Conn: conn}, nil
+}
+
+// postgresConn - соединение lib/pq, которое переводит параметры перед запросом.
+type postgresConn struct {
+	driver.Conn
+}
+
+// CheckNamedValue разрешает именованные параметры, которые lib/pq отвергает.
+// Логические значения передаются как 0 и 1: флаги в схеме хранятся в INTEGER.
+func (c *postgresConn) CheckNamedValue(nv *driver.NamedValue) error {
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	if b, ok := v.(bool); ok {
+		v = int64(0)
+		if b {
+			v = int64(1)
+		}
+	}
+	nv.Value = v
+	return nil
+}
+
+// ExecContext выполняет запрос без возврата строк.
+func (c *postgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	query, args, err := bindParams(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+// QueryContext выполняет запрос с возвратом строк.
+func (c *postgresConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	query, args, err := bindParams(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+// Prepare готовит запрос. Подготовленные запросы хранилище не использует,
+// поэтому поддерживаются только запросы без параметров :name.
+func (c *postgresConn) Prepare(query string) (driver.Stmt, error) {
+	query, refs := compileParams(query)
+	for _, ref := range refs {
+		if ref.name != "" {
+			return nil, fmt.Errorf("named parameter :%s in prepared statement is not supported", ref.name)
+		}
+	}
+	return c.Conn.Prepare(query)
+}
+
+// BeginTx начинает транзакцию.
+func (c *postgresConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+// Ping проверяет соединение.
+func (c *postgresConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+// paramRef - параметр запроса: именованный (:name) или позиционный (?).
+type paramRef struct {
+	name string // пусто для позиционного параметра
+}
+
+// compileParams заменяет параметры :name и ? в запросе на $1, $2, ...
+// Повторы одного имени получают один номер. Строки в кавычках,
+// комментарии -- и приведения типов :: не изменяются.
+// Возвращает новый текст запроса и параметры по порядку номеров.
+func compileParams(query string) (string, []paramRef) {
+	var b strings.Builder
+	var refs []paramRef
+	numbers := map[string]int{}
+
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			end := strings.IndexByte(query[i+1:], ch)
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String(), refs
+			}
+			b.WriteString(query[i : i+end+2])
+			i += end + 1
+		case ch == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String(), refs
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case ch == ':' && i+1 < len(query) && query[i+1] == ':':
+			b.WriteString("::")
+			i++
+		case ch == ':' && i+1 < len(query) && isParamStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && isParamChar(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			n, ok := numbers[name]
+			if !ok {
+				refs = append(refs, paramRef{name: name})
+				n = len(refs)
+				numbers[name] = n
+			}
+			b.WriteString("$" + strconv.Itoa(n))
+			i = j - 1
+		case ch == '?':
+			refs = append(refs, paramRef{})
+			b.WriteString("$" + strconv.Itoa(len(refs)))
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String(), refs
+}
+
+// bindParams переводит запрос с параметрами :name и ? в запрос с $1, $2, ...
+// и раскладывает аргументы по номерам.
+func bindParams(query string, args []driver.NamedValue) (string, []driver.NamedValue, error) {
+	query, refs := compileParams(query)
+
+	named := map[string]driver.NamedValue{}
+	var positional []driver.NamedValue
+	for _, arg := range args {
+		if arg.Name != "" {
+			named[arg.Name] = arg
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+
+	bound := make([]driver.NamedValue, len(refs))
+	for i, ref := range refs {
+		var arg driver.NamedValue
+		if ref.name != "" {
+			v, ok := named[ref.name]
+			if !ok {
+				return "", nil, fmt.Errorf("missing argument for parameter :%s", ref.name)
+			}
+			arg = v
+		} else {
+			if len(positional) == 0 {
+				return "", nil, fmt.Errorf("missing argument for parameter $%d", i+1)
+			}
+			arg, positional = positional[0], positional[1:]
+		}
+		bound[i] = driver.NamedValue{Ordinal: i + 1, Value: arg.Value}
+	}
+	if len(positional) > 0 {
+		return "", nil, fmt.Errorf("%d unused positional arguments", len(positional))
+	}
+	return query, bound, nil
+}
+
+// isParamStart сообщает, что с символа c может начинаться имя параметра.
+func isParamStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// isParamChar сообщает, что символ c может входить в имя параметра.
+func isParamChar(c byte) bool {
+	return isParamStart(c) || '0' <= c && c <= '9'
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileParams(t *testing.T) {
+	query, refs := compileParams(`SELECT id FROM scheduler
+	WHERE id = :id AND (:user_id = 0 OR user_id = :user_id) -- комментарий с :id
+	  AND title = ':title' AND CAST(x AS TEXT)::text = ? AND trigram IN (?, ?)`)
+	assert.Equal(t, `SELECT id FROM scheduler
+	WHERE id = $1 AND ($2 = 0 OR user_id = $2) -- комментарий с :id
+	  AND title = ':title' AND CAST(x AS TEXT)::text = $3 AND trigram IN ($4, $5)`, query)
+	assert.Equal(t, []paramRef{{name: "id"}, {name: "user_id"}, {}, {}, {}}, refs)
+}
+
+func TestBindParams(t *testing.T) {
+	args := []driver.NamedValue{
+		{Name: "user_id", Ordinal: 1, Value: int64(7)},
+		{Name: "id", Ordinal: 2, Value: "42"},
+		{Ordinal: 3, Value: "абв"},
+		{Ordinal: 4, Value: "где"},
+	}
+	query, bound, err := bindParams(`SELECT 1 WHERE id = :id AND user_id = :user_id AND t IN (?, ?) AND u = :user_id`, args)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT 1 WHERE id = $1 AND user_id = $2 AND t IN ($3, $4) AND u = $2`, query)
+	assert.Equal(t, []driver.NamedValue{
+		{Ordinal: 1, Value: "42"},
+		{Ordinal: 2, Value: int64(7)},
+		{Ordinal: 3, Value: "абв"},
+		{Ordinal: 4, Value: "где"},
+	}, bound)
+
+	_, _, err = bindParams(`SELECT :missing`, args[:1])
+	assert.Error(t, err)
+	_, _, err = bindParams(`SELECT ?`, args[2:])
+	assert.Error(t, err, "лишний позиционный аргумент")
+
+	conn := &postgresConn{}
+	flag := driver.NamedValue{Name: "can_admin", Value: true}
+	require.NoError(t, conn.CheckNamedValue(&flag))
+	assert.Equal(t, int64(1), flag.Value)
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go1f/pkg/taskdate"
+)
+
+// Результат проверки значения даты задачи.
+const (
+	dateValid   = iota // дата в формате YYYYMMDD
+	dateFixable        // дата в другом известном формате, её можно переписать
+	dateInvalid        // дату восстановить нельзя
+)
+
+// legacyDateFormats - форматы дат, которые встречались в старых версиях клиентов.
+var legacyDateFormats = []string{
+	"2006-01-02",
+	"2006/01/02",
+	"02.01.2006",
+}
+
+// DateRepair - отчет о проверке дат задач.
+type DateRepair struct {
+	Checked int      // количество проверенных задач
+	Fixed   int      // количество переписанных дат
+	Invalid []string // ID задач с датой, которую нельзя восстановить
+}
+
+// classifyDate определяет, корректна ли дата задачи.
+// Для исправимой даты возвращает её в формате YYYYMMDD.
+func classifyDate(s string) (string, int) {
+	if _, err := time.Parse(taskdate.DateFormat, s); err == nil {
+		return s, dateValid
+	}
+
+	trimmed := strings.TrimSpace(s)
+	formats := append([]string{taskdate.DateFormat}, legacyDateFormats...)
+	for _, layout := range formats {
+		if t, err := time.Parse(layout, trimmed); err == nil {
+			return t.Format(taskdate.DateFormat), dateFixable
+		}
+	}
+	return s, dateInvalid
+}
+
+// RepairDates проверяет даты всех задач и исправляет значения в устаревших
+// форматах (например, "2025-07-01"). Задачи с датой, которую нельзя восстановить
+// (пустая строка, 20250230), помечаются флагом needs_attention, у остальных
+// флаг снимается. Все изменения выполняются в одной транзакции, повторный
+// запуск ничего не меняет.
+func (s *Store) RepairDates(ctx context.Context) (*DateRepair, error) {
+	report := &DateRepair{Invalid: []string{}}
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := queryOn(ctx, tx, `SELECT id, date FROM scheduler`)
+		if err != nil {
+			return fmt.Errorf("failed to query task dates: %w", err)
+		}
+
+		type fix struct{ id, date string }
+		var fixes []fix
+		var invalid []string
+		for rows.Next() {
+			var id, date string
+			if err := rows.Scan(&id, &date); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan task date: %w", err)
+			}
+			report.Checked++
+
+			switch fixed, status := classifyDate(date); status {
+			case dateFixable:
+				fixes = append(fixes, fix{id: id, date: fixed})
+			case dateInvalid:
+				invalid = append(invalid, id)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error during rows iteration: %w", err)
+		}
+
+		var seq int64
+		if len(fixes) > 0 {
+			if seq, err = nextSeq(ctx, tx); err != nil {
+				return err
+			}
+		}
+		for _, f := range fixes {
+			_, err := execOn(ctx, tx, `UPDATE scheduler SET date = :date, updated_at = :now, version = version + 1, seq = :seq WHERE id = :id`,
+				sql.Named("date", f.date),
+				sql.Named("seq", seq),
+				sql.Named("now", timeNow().UnixMilli()),
+				sql.Named("id", f.id))
+			if err != nil {
+				return fmt.Errorf("failed to fix task date: %w", err)
+			}
+		}
+
+		if _, err := execOn(ctx, tx, `UPDATE scheduler SET needs_attention = 0 WHERE needs_attention != 0`); err != nil {
+			return fmt.Errorf("failed to reset attention flag: %w", err)
+		}
+		for _, id := range invalid {
+			if _, err := execOn(ctx, tx, `UPDATE scheduler SET needs_attention = 1 WHERE id = :id`, sql.Named("id", id)); err != nil {
+				return fmt.Errorf("failed to flag task: %w", err)
+			}
+		}
+
+		report.Fixed = len(fixes)
+		report.Invalid = append(report.Invalid, invalid...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// TasksNeedingAttention возвращает ID неудаленных задач, помеченных
+// при проверке дат как требующие ручного исправления.
+func (s *Store) TasksNeedingAttention(ctx context.Context) ([]string, error) {
+	rows, err := s.querySQL(ctx, `
+	SELECT id FROM scheduler
+	WHERE needs_attention = 1 AND deleted_at IS NULL
+	ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flagged tasks: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan task id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyDate(t *testing.T) {
+	tbl := []struct {
+		date, want string
+		status     int
+	}{
+		{"20250701", "20250701", dateValid},
+		{"2025-07-01", "20250701", dateFixable},
+		{"2025/07/01", "20250701", dateFixable},
+		{"01.07.2025", "20250701", dateFixable},
+		{" 20250701 ", "20250701", dateFixable},
+		{"", "", dateInvalid},
+		{"20250230", "20250230", dateInvalid},
+		{"2025-02-30", "2025-02-30", dateInvalid},
+		{"завтра", "завтра", dateInvalid},
+	}
+	for _, v := range tbl {
+		got, status := classifyDate(v.date)
+		assert.Equal(t, v.status, status, v.date)
+		assert.Equal(t, v.want, got, v.date)
+	}
+}
+
+func TestRepairDates(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store,
+		Task{Date: "20250701", Title: "Верная"},
+		Task{Date: "2025-07-02", Title: "С дефисами"},
+		Task{Date: "03.07.2025", Title: "С точками"},
+		Task{Date: "", Title: "Пустая"},
+		Task{Date: "20250230", Title: "30 февраля"},
+	)
+
+	report, err := store.RepairDates(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Checked)
+	assert.Equal(t, 2, report.Fixed)
+	assert.Len(t, report.Invalid, 2)
+
+	tasks, err := store.GetTasks(t.Context(), 10)
+	require.NoError(t, err)
+	dates := make(map[string]string)
+	for _, task := range tasks {
+		dates[task.Title] = task.Date
+	}
+	assert.Equal(t, "20250702", dates["С дефисами"])
+	assert.Equal(t, "20250703", dates["С точками"])
+
+	flagged, err := store.TasksNeedingAttention(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, report.Invalid, flagged)
+
+	// Повторный запуск ничего не меняет
+	report, err = store.RepairDates(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Fixed)
+	assert.Equal(t, flagged, report.Invalid)
+
+	// После исправления даты флаг снимается
+	task, err := store.GetTaskID(t.Context(), taskID(t, flagged[0]))
+	require.NoError(t, err)
+	task.Date = "20250704"
+	require.NoError(t, store.PutTaskID(t.Context(), &task))
+	_, err = store.RepairDates(t.Context())
+	require.NoError(t, err)
+	flagged, err = store.TasksNeedingAttention(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{strconv.FormatInt(ids[4], 10)}, flagged)
+}
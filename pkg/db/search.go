@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+)
+
+// regexBatchSize - количество задач, читаемых из БД за один проход при поиске по регулярному выражению.
+const regexBatchSize = 500
+
+// SearchTasksRegex ищет задачи, у которых title или comment соответствуют регулярному выражению.
+//
+// SQLite не поддерживает регулярные выражения без расширений, поэтому задачи читаются
+// из БД пачками по regexBatchSize в порядке даты и фильтруются в Go.
+// Этот режим не использует индексы и быстрый поиск через LIKE и может работать медленнее.
+// Параметр limit ограничивает количество найденных задач и применяется после фильтрации,
+// filter задает, какие задачи отбирать.
+func (s *Store) SearchTasksRegex(ctx context.Context, re *regexp.Regexp, limit int, filter TaskFilter) ([]*Task, error) {
+
+	where, filterArgs := filter.where(ctx)
+	query := `
+	SELECT ` + taskColumns + `
+	FROM scheduler
+	WHERE ` + where + `
+	ORDER BY ` + filter.orderBy() + `
+	LIMIT :limit OFFSET :offset`
+
+	var tasks []*Task
+	for offset := 0; len(tasks) < limit; offset += regexBatchSize {
+		args := append(filterArgs,
+			sql.Named("limit", regexBatchSize),
+			sql.Named("offset", offset))
+		rows, err := s.querySQL(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tasks: %w", err)
+		}
+		batch, err := scanTasks(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, task := range batch {
+			if re.MatchString(task.Title) || re.MatchString(task.Comment) {
+				tasks = append(tasks, task)
+				if len(tasks) == limit {
+					break
+				}
+			}
+		}
+
+		// Последняя неполная пачка - задач в БД больше нет
+		if len(batch) < regexBatchSize {
+			break
+		}
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,314 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// titles возвращает заголовки задач в порядке выдачи.
+func titles(tasks []*Task) []string {
+	result := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		result = append(result, task.Title)
+	}
+	return result
+}
+
+func TestSearchTasksRegex(t *testing.T) {
+	store := OpenForTest(t)
+	seedTasks(t, store,
+		Task{Date: "20240101", Title: "PROJ-123 починить сборку"},
+		Task{Date: "20240102", Title: "Ревью PROJ-124"},
+		Task{Date: "20240103", Title: "Купить молоко", Comment: "PROJ-7 не забыть"},
+		Task{Date: "20240104", Title: "Write report"},
+		Task{Date: "20240105", Title: "PROJ-125 релиз"},
+	)
+
+	tbl := []struct {
+		pattern string
+		limit   int
+		want    []string
+	}{
+		{`^PROJ-\d+`, 10, []string{"PROJ-123 починить сборку", "Купить молоко", "PROJ-125 релиз"}},
+		{`^PROJ-\d+`, 2, []string{"PROJ-123 починить сборку", "Купить молоко"}},
+		{`PROJ-\d+$`, 10, []string{"Ревью PROJ-124"}},
+		{`^\p{Cyrillic}+\s`, 10, []string{"Ревью PROJ-124", "Купить молоко"}},
+		{`^[А-Яа-я]+$`, 10, []string{}},
+		{`(?i)^write`, 10, []string{"Write report"}},
+	}
+	for _, v := range tbl {
+		tasks, err := store.SearchTasksRegex(t.Context(), regexp.MustCompile(v.pattern), v.limit, TaskFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, v.want, titles(tasks), v.pattern)
+	}
+}
+
+func TestSearchTasksRegexBatches(t *testing.T) {
+	store := OpenForTest(t)
+	tasks := make([]Task, regexBatchSize+10)
+	for i := range tasks {
+		tasks[i] = Task{Date: "20240101", Title: "обычная задача"}
+	}
+	tasks[len(tasks)-1].Title = "последняя особенная"
+	seedTasks(t, store, tasks...)
+
+	found, err := store.SearchTasksRegex(t.Context(), regexp.MustCompile(`особенная$`), 10, TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"последняя особенная"}, titles(found))
+}
+
+func TestParseSearch(t *testing.T) {
+	tbl := []struct {
+		search string
+		want   []string
+	}{
+		{"купить молоко", []string{"купить", "молоко"}},
+		{`  "купить молоко"  хлеб `, []string{"купить молоко", "хлеб"}},
+		{`хлеб"у дома"`, []string{"хлеб", "у дома"}},
+		{`"незакрытая фраза`, []string{"незакрытая фраза"}},
+		{`"" "  "`, nil},
+		{"", nil},
+	}
+	for _, v := range tbl {
+		assert.Equal(t, v.want, parseSearch(v.search), v.search)
+	}
+}
+
+func TestSearchTasksFullText(t *testing.T) {
+	store := OpenForTest(t)
+	require.True(t, store.fts, "драйвер SQLite собран с FTS5")
+	seedTasks(t, store,
+		Task{Date: "20240101", Title: "Срочно купить молоко", Comment: "в магазине у дома"},
+		Task{Date: "20240102", Title: "Хлеб и молоко купить"},
+		Task{Date: "20240103", Title: "Срочная задача", Comment: "позвонить Ивану"},
+		Task{Date: "20240104", Title: "Отчёт", Tags: []string{"работа"}},
+		Task{Date: "20240105", Title: "Разобрать задачи по работе"},
+	)
+
+	// Одинаково с индексом и без него (поиск через LIKE)
+	tbl := []struct {
+		search string
+		want   []string
+	}{
+		{"купить молоко", []string{"Срочно купить молоко", "Хлеб и молоко купить"}},
+		{`"купить молоко"`, []string{"Срочно купить молоко"}},
+		{`"молоко купить"`, []string{"Хлеб и молоко купить"}},
+		{`"у дома" купить`, []string{"Срочно купить молоко"}},
+		{"ная", []string{"Срочная задача"}},
+		{"работ", []string{"Отчёт", "Разобрать задачи по работе"}},
+		{"по задач", []string{"Срочная задача", "Разобрать задачи по работе"}},
+		{"молоко кефир", []string{}},
+	}
+	for _, fts := range []bool{true, false} {
+		store.fts = fts
+		for _, v := range tbl {
+			tasks, err := store.SearchTasks(t.Context(), v.search, 10, TaskFilter{})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, v.want, titles(tasks), "fts=%v search=%q", fts, v.search)
+		}
+	}
+}
+
+func TestSearchTasksCaseInsensitive(t *testing.T) {
+	store := OpenForTest(t)
+	seedTasks(t, store,
+		Task{Date: "20240101", Title: "Магазин", Comment: "Купить ХЛЕБ"},
+		Task{Date: "20240102", Title: "Нарядить ёлку"},
+		Task{Date: "20240103", Title: "ЁЖИК в тумане"},
+		Task{Date: "20240104", Title: "Code Review", Comment: "PR #42"},
+		Task{Date: "20240105", Title: "Отчёт", Tags: []string{"учёба"}},
+	)
+
+	tbl := []struct {
+		search string
+		want   []string
+	}{
+		{"магазин", []string{"Магазин"}},
+		{"МАГАЗИН", []string{"Магазин"}},
+		{"хлеб", []string{"Магазин"}},
+		{"елку", []string{"Нарядить ёлку"}},
+		{"ЕЛКУ", []string{"Нарядить ёлку"}},
+		{"ёжик", []string{"ЁЖИК в тумане"}},
+		{"Ежик", []string{"ЁЖИК в тумане"}},
+		{"ё", []string{"Магазин", "Нарядить ёлку", "ЁЖИК в тумане", "Отчёт"}}, // как и "е"
+		{"code review", []string{"Code Review"}},
+		{"pr", []string{"Code Review"}},
+		{"отчет", []string{"Отчёт"}},
+		{"Учеба", []string{"Отчёт"}},
+	}
+	for _, fts := range []bool{true, false} {
+		store.fts = fts
+		for _, v := range tbl {
+			tasks, err := store.SearchTasks(t.Context(), v.search, 10, TaskFilter{})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, v.want, titles(tasks), "fts=%v search=%q", fts, v.search)
+		}
+	}
+}
+
+func TestFoldText(t *testing.T) {
+	assert.Equal(t, "елка в магазине", foldText("Ёлка в МАГАЗИНЕ"))
+	assert.Equal(t, "code review", foldText("Code Review"))
+}
+
+func TestSearchTasksFullTextRank(t *testing.T) {
+	store := OpenForTest(t)
+	seedTasks(t, store,
+		Task{Date: "20240102", Title: "Отчет"},
+		Task{Date: "20240101", Title: "Разное", Comment: "созвон, письма, отчет и еще много другой работы на неделе"},
+	)
+
+	tasks, err := store.SearchTasks(t.Context(), "отчет", 10, TaskFilter{Sort: SortRelevance})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Отчет", "Разное"}, titles(tasks), "более релевантная задача первой, хотя она позже")
+
+	tasks, err = store.SearchTasks(t.Context(), "отчет", 10, TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Разное", "Отчет"}, titles(tasks), "по умолчанию по дате")
+}
+
+func TestSearchTasksFullTextSync(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store,
+		Task{Date: "20240101", Title: "Починить кран"},
+		Task{Date: "20240102", Title: "Полить цветы", Comment: "на балконе"},
+	)
+	search := func(s string) []string {
+		t.Helper()
+		tasks, err := store.SearchTasks(t.Context(), s, 10, TaskFilter{})
+		require.NoError(t, err)
+		return titles(tasks)
+	}
+
+	task, err := store.GetTaskID(t.Context(), ids[0])
+	require.NoError(t, err)
+	task.Title = "Вызвать сантехника"
+	require.NoError(t, store.PutTaskID(t.Context(), &task))
+	assert.Empty(t, search("кран"))
+	assert.Equal(t, []string{"Вызвать сантехника"}, search("сантехник"))
+
+	require.NoError(t, store.DeleteTaskID(t.Context(), ids[1]))
+	assert.Empty(t, search("балкон"))
+	_, err = store.PurgeDeleted(t.Context(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// индекс совпадает с таблицей задач
+	_, err = store.db.Exec(`INSERT INTO scheduler_fts(scheduler_fts) VALUES ('integrity-check')`)
+	assert.NoError(t, err)
+}
+
+func TestSearchTasksFullTextBackfill(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "scheduler.db")
+	all := migrations
+	t.Cleanup(func() { migrations = all })
+
+	// БД предыдущей версии без индекса
+	migrations = all[:1]
+	s, err := Open(path)
+	require.NoError(t, err)
+	assert.False(t, s.fts)
+	_, err = s.DB().Exec(`INSERT INTO scheduler (date, title, comment, repeat)
+		VALUES ('20240101', 'Записаться к врачу', 'Терапевт, каб. 5', '')`)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	migrations = all
+	s, err = Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	assert.True(t, s.fts)
+	for _, search := range []string{"терапевт", "каб"} {
+		tasks, err := s.SearchTasks(ctx, search, 10, TaskFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Записаться к врачу"}, titles(tasks), search)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\%`, escapeLike("100%"))
+	assert.Equal(t, `file\_name`, escapeLike("file_name"))
+	assert.Equal(t, `C:\\temp`, escapeLike(`C:\temp`))
+	assert.Equal(t, "молоко", escapeLike("молоко"))
+}
+
+func TestSearchTasksLiteralWildcards(t *testing.T) {
+	store := OpenForTest(t)
+	seedTasks(t, store,
+		Task{Date: "20240101", Title: "Скидка 100%"},
+		Task{Date: "20240102", Title: "Скидка 1000 рублей"},
+		Task{Date: "20240103", Title: "Переименовать file_name.txt"},
+		Task{Date: "20240104", Title: "Переименовать filename.txt"},
+		Task{Date: "20240105", Title: `Очистить C:\temp`},
+		Task{Date: "20240106", Title: "Очистить C:temp", Tags: []string{"50%_off"}},
+	)
+
+	tbl := []struct {
+		search string
+		want   []string
+	}{
+		{"100%", []string{"Скидка 100%"}},
+		{"%", []string{"Скидка 100%", "Очистить C:temp"}},
+		{"_", []string{"Переименовать file_name.txt", "Очистить C:temp"}},
+		{"e_n", []string{"Переименовать file_name.txt"}},
+		{`\`, []string{`Очистить C:\temp`}},
+		{`:\t`, []string{`Очистить C:\temp`}},
+		{"%_", []string{"Очистить C:temp"}},
+		{`\%`, []string{}},
+	}
+	for _, fts := range []bool{true, false} {
+		store.fts = fts
+		for _, v := range tbl {
+			tasks, err := store.SearchTasks(t.Context(), v.search, 10, TaskFilter{})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, v.want, titles(tasks), "fts=%v search=%q", fts, v.search)
+		}
+	}
+
+	// дата по-прежнему ищется точным совпадением
+	tasks, err := store.SearchTasks(t.Context(), "03.01.2024", 10, TaskFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Переименовать file_name.txt"}, titles(tasks))
+}
+
+func TestCountSearchTasks(t *testing.T) {
+	store := OpenForTest(t)
+	high := 3
+	seedTasks(t, store,
+		Task{Date: "20240101", Title: "Купить молоко", Priority: high},
+		Task{Date: "20240102", Title: "Купить хлеб", Tags: []string{"магазин"}},
+		Task{Date: "20240102", Title: "Позвонить маме"},
+		Task{Date: "20240103", Title: "Молоко для кота", Priority: high},
+	)
+
+	for _, v := range []struct {
+		search string
+		filter TaskFilter
+	}{
+		{"купить", TaskFilter{}},
+		{"молоко", TaskFilter{Priority: &high}},
+		{"магаз", TaskFilter{}},
+		{"02.01.2024", TaskFilter{}},
+		{"купить", TaskFilter{Completed: true}},
+		{"молоко", TaskFilter{Sort: SortRelevance}},
+		{"о", TaskFilter{}},
+	} {
+		tasks, err := store.SearchTasks(t.Context(), v.search, 100, v.filter)
+		require.NoError(t, err)
+		total, err := store.CountSearchTasks(t.Context(), v.search, v.filter)
+		require.NoError(t, err)
+		assert.Equal(t, len(tasks), total, v.search)
+
+		if total > 1 {
+			page, err := store.SearchTasks(t.Context(), v.search, 1, v.filter)
+			require.NoError(t, err)
+			assert.Len(t, page, 1, "limit не влияет на количество")
+		}
+	}
+}
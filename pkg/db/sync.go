@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Changes - изменения задач с момента предыдущей синхронизации.
+type Changes struct {
+	Changed    []*Task   // задачи, созданные или измененные после since
+	Deleted    []string  // UID задач, удаленных после since
+	ServerTime time.Time // момент, который клиент передает как since в следующий раз
+}
+
+// GetChanges возвращает задачи пользователя из ctx, измененные или удаленные начиная с момента since.
+//
+// Граница включительная: задача, измененная ровно в since, вернется повторно,
+// но не потеряется. Оба списка читаются в одной транзакции, а ServerTime
+// фиксируется до чтения, поэтому изменения, сделанные во время запроса,
+// попадут в следующую синхронизацию.
+func (s *Store) GetChanges(ctx context.Context, since time.Time) (*Changes, error) {
+	changes := &Changes{ServerTime: timeNow()}
+	sinceMs := sql.Named("since", since.UnixMilli())
+	scope, user := userScope(ctx)
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := queryOn(ctx, tx, `
+		SELECT `+taskColumns+` FROM scheduler
+		WHERE deleted_at IS NULL AND updated_at >= :since AND `+scope+`
+		ORDER BY updated_at ASC, id ASC`, sinceMs, user)
+		if err != nil {
+			return fmt.Errorf("failed to query changed tasks: %w", err)
+		}
+		if changes.Changed, err = scanTasks(rows); err != nil {
+			return err
+		}
+
+		rows, err = queryOn(ctx, tx, `
+		SELECT uid FROM scheduler
+		WHERE deleted_at IS NOT NULL AND updated_at >= :since AND `+scope+`
+		ORDER BY updated_at ASC, id ASC`, sinceMs, user)
+		if err != nil {
+			return fmt.Errorf("failed to query deleted tasks: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var uid string
+			if err := rows.Scan(&uid); err != nil {
+				return fmt.Errorf("failed to scan deleted uid: %w", err)
+			}
+			changes.Deleted = append(changes.Deleted, uid)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// PurgeDeleted окончательно удаляет задачи, помеченные удаленными раньше before.
+// Клиенты, не синхронизировавшиеся с этого момента, об удалении не узнают,
+// поэтому before стоит выбирать с запасом.
+// Возвращает количество удаленных записей.
+func (s *Store) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.execSQL(ctx, `DELETE FROM scheduler WHERE deleted_at IS NOT NULL AND deleted_at < :before`,
+		sql.Named("before", before.UnixMilli()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted tasks: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setClock подменяет текущее время БД на время, возвращаемое now.
+func setClock(t *testing.T, now *time.Time) {
+	t.Helper()
+	timeNow = func() time.Time { return *now }
+	t.Cleanup(func() { timeNow = time.Now })
+}
+
+func TestGetChanges(t *testing.T) {
+	store := OpenForTest(t)
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	setClock(t, &now)
+
+	ids := seedTasks(t, store,
+		Task{Date: "20250701", Title: "Первая"},
+		Task{Date: "20250702", Title: "Вторая"},
+		Task{Date: "20250703", Title: "Третья"},
+	)
+
+	// Первая синхронизация получает все задачи
+	changes, err := store.GetChanges(t.Context(), time.UnixMilli(0))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Первая", "Вторая", "Третья"}, titles(changes.Changed))
+	assert.Empty(t, changes.Deleted)
+	since := changes.ServerTime
+
+	now = now.Add(time.Minute)
+	task, err := store.GetTaskID(t.Context(), ids[1])
+	require.NoError(t, err)
+	task.Title = "Вторая (изменена)"
+	require.NoError(t, store.PutTaskID(t.Context(), &task))
+	deleted, err := store.GetTaskID(t.Context(), ids[2])
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteTaskID(t.Context(), taskID(t, deleted.ID)))
+
+	// Вторая синхронизация получает только изменения
+	changes, err = store.GetChanges(t.Context(), since.Add(time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Вторая (изменена)"}, titles(changes.Changed))
+	assert.Equal(t, []string{deleted.UID}, changes.Deleted)
+
+	// Удаленная задача не видна в обычных выборках
+	_, err = store.GetTaskID(t.Context(), taskID(t, deleted.ID))
+	assert.Error(t, err)
+	tasks, err := store.GetTasks(t.Context(), 10)
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Error(t, store.DeleteTaskID(t.Context(), taskID(t, deleted.ID)))
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	store := OpenForTest(t)
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	setClock(t, &now)
+
+	ids := seedTasks(t, store, Task{Date: "20250701", Title: "Старая"}, Task{Date: "20250702", Title: "Новая"})
+	require.NoError(t, store.DeleteTaskID(t.Context(), ids[0]))
+	now = now.Add(48 * time.Hour)
+	require.NoError(t, store.DeleteTaskID(t.Context(), ids[1]))
+
+	n, err := store.PurgeDeleted(t.Context(), now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+
+	changes, err := store.GetChanges(t.Context(), time.UnixMilli(0))
+	require.NoError(t, err)
+	assert.Len(t, changes.Deleted, 1)
+}
+
+// replica - копия задач клиента, который применяет ответы GetChangesSince.
+type replica struct {
+	tasks  map[string]string // ID -> заголовок
+	cursor int64
+}
+
+// pull применяет к копии изменения store после её курсора.
+func (r *replica) pull(t *testing.T, store *Store) {
+	t.Helper()
+	changes, err := store.GetChangesSince(t.Context(), r.cursor)
+	require.NoError(t, err)
+	for _, task := range changes.Tasks {
+		r.tasks[task.ID] = task.Title
+	}
+	for _, id := range changes.Deleted {
+		delete(r.tasks, id)
+	}
+	assert.GreaterOrEqual(t, changes.Cursor, r.cursor)
+	r.cursor = changes.Cursor
+}
+
+func TestGetChangesSinceReplay(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store,
+		Task{Date: "20250701", Title: "Первая"},
+		Task{Date: "20250702", Title: "Вторая"},
+	)
+
+	// одна копия синхронизируется после каждого шага, другая - в середине,
+	// третья - только в конце
+	often := &replica{tasks: map[string]string{}}
+	rarely := &replica{tasks: map[string]string{}}
+	once := &replica{tasks: map[string]string{}}
+	often.pull(t, store)
+	rarely.pull(t, store)
+	assert.Len(t, often.tasks, 2)
+
+	var third int64
+	steps := []func(){
+		func() {
+			task, err := store.GetTaskID(t.Context(), ids[0])
+			require.NoError(t, err)
+			task.Title = "Первая (изменена)"
+			require.NoError(t, store.PutTaskID(t.Context(), &task))
+		},
+		func() { require.NoError(t, store.DeleteTaskID(t.Context(), ids[1])) },
+		func() { third = seedTasks(t, store, Task{Date: "20250703", Title: "Третья"})[0] },
+		func() { require.NoError(t, store.SetCompleted(t.Context(), ids[0], true)) },
+		func() {
+			fourth := seedTasks(t, store, Task{Date: "20250704", Title: "Четвертая"})
+			_, _, err := store.DeleteTasks(t.Context(), []string{strconv.FormatInt(fourth[0], 10)})
+			require.NoError(t, err)
+		},
+		func() {
+			task, err := store.GetTaskID(t.Context(), ids[0])
+			require.NoError(t, err)
+			task.Title = "Первая (еще раз)"
+			require.NoError(t, store.PutTaskID(t.Context(), &task))
+		},
+	}
+	for i, step := range steps {
+		step()
+		often.pull(t, store)
+		if i == len(steps)/2 {
+			rarely.pull(t, store)
+		}
+	}
+	rarely.pull(t, store)
+	once.pull(t, store)
+
+	want := map[string]string{}
+	err := store.StreamTasks(t.Context(), func(task *Task) error {
+		want[task.ID] = task.Title
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		strconv.FormatInt(ids[0], 10): "Первая (еще раз)",
+		strconv.FormatInt(third, 10):  "Третья",
+	}, want)
+	for _, r := range []*replica{often, rarely, once} {
+		assert.Equal(t, want, r.tasks)
+	}
+
+	// без новых изменений ответ пустой, а курсор тот же
+	changes, err := store.GetChangesSince(t.Context(), often.cursor)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Tasks)
+	assert.Empty(t, changes.Deleted)
+	assert.Equal(t, often.cursor, changes.Cursor)
+
+	seq, err := store.ChangeSeq(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, changes.Cursor, seq)
+	require.NoError(t, store.DeleteTaskID(t.Context(), ids[0]))
+	next, err := store.ChangeSeq(t.Context())
+	require.NoError(t, err)
+	assert.Greater(t, next, seq, "номер растет при каждом изменении")
+}
+
+func TestGetChangesSinceRestore(t *testing.T) {
+	store := OpenForTest(t)
+	ids := seedTasks(t, store, Task{Date: "20250701", Title: "Первая"})
+	task, err := store.GetTaskID(t.Context(), ids[0])
+	require.NoError(t, err)
+	changes, err := store.GetChangesSince(t.Context(), 0)
+	require.NoError(t, err)
+	cursor := changes.Cursor
+
+	// задача, восстановленная импортом по UID, больше не числится удаленной
+	require.NoError(t, store.DeleteTaskID(t.Context(), ids[0]))
+	_, err = store.ImportTasks(t.Context(), []*Task{{UID: task.UID, Date: "20250701", Title: "Восстановлена"}})
+	require.NoError(t, err)
+
+	for _, since := range []int64{0, cursor} {
+		changes, err := store.GetChangesSince(t.Context(), since)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Восстановлена"}, titles(changes.Tasks))
+		assert.Empty(t, changes.Deleted)
+	}
+}
+
+func TestGetChangesSinceDuringWrite(t *testing.T) {
+	store := setupDBFile(t, filepath.Join(t.TempDir(), "scheduler.db"))
+	seedTasks(t, store, Task{Date: "20250701", Title: "Первая"})
+
+	// пишущая транзакция держит блокировку записи, опрос её не ждет
+	tx, err := store.db.BeginTx(t.Context(), nil)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	_, err = tx.Exec(`UPDATE scheduler SET title = 'Изменена'`)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	changes, err := store.GetChangesSince(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Первая"}, titles(changes.Tasks))
+}
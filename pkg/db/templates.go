@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Template - шаблон задачи: заголовок, комментарий и правило повторения,
+// из которых по запросу создается задача с нужной датой.
+type Template struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Comment string `json:"comment"`
+	Repeat  string `json:"repeat"`
+}
+
+// ErrTemplateNotFound возвращается, если шаблон не найден.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// templatesSQL создает таблицу шаблонов задач в SQLite.
+const templatesSQL = `
+	CREATE TABLE IF NOT EXISTS templates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		comment TEXT NOT NULL DEFAULT '',
+		repeat VARCHAR(128) NOT NULL DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_templates_user ON templates(user_id);`
+
+// postgresTemplatesSQL создает таблицу шаблонов задач в PostgreSQL.
+const postgresTemplatesSQL = `
+	CREATE TABLE IF NOT EXISTS templates (
+		id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		title TEXT NOT NULL,
+		comment TEXT NOT NULL DEFAULT '',
+		repeat VARCHAR(128) NOT NULL DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_templates_user ON templates(user_id);`
+
+// migrateTemplates - миграция 6: таблица шаблонов задач.
+func migrateTemplates(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	query := templatesSQL
+	if d == postgresDialect {
+		query = postgresTemplatesSQL
+	}
+	if _, err := execOn(ctx, tx, query); err != nil {
+		return fmt.Errorf("failed to create templates: %w", err)
+	}
+	return nil
+}
+
+// AddTemplate сохраняет шаблон пользователя из ctx и заполняет его ID.
+func (s *Store) AddTemplate(ctx context.Context, tmpl *Template) (int64, error) {
+	var id int64
+	err := s.queryRowSQL(ctx, `
+	INSERT INTO templates (user_id, title, comment, repeat)
+	VALUES (:user_id, :title, :comment, :repeat)
+	RETURNING id`,
+		sql.Named("user_id", UserID(ctx)),
+		sql.Named("title", tmpl.Title),
+		sql.Named("comment", tmpl.Comment),
+		sql.Named("repeat", tmpl.Repeat)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add template: %w", err)
+	}
+	tmpl.ID = fmt.Sprint(id)
+	return id, nil
+}
+
+// GetTemplates возвращает шаблоны пользователя из ctx в порядке заголовков.
+func (s *Store) GetTemplates(ctx context.Context) ([]*Template, error) {
+	scope, user := userScope(ctx)
+	rows, err := s.querySQL(ctx, `
+	SELECT id, title, comment, repeat FROM templates
+	WHERE `+scope+`
+	ORDER BY title, id`, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []*Template{}
+	for rows.Next() {
+		var tmpl Template
+		if err := rows.Scan(&tmpl.ID, &tmpl.Title, &tmpl.Comment, &tmpl.Repeat); err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		templates = append(templates, &tmpl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return templates, nil
+}
+
+// GetTemplate возвращает шаблон пользователя из ctx по ID.
+// Возвращает ErrTemplateNotFound, если шаблон не найден.
+func (s *Store) GetTemplate(ctx context.Context, id int64) (Template, error) {
+	var tmpl Template
+	scope, user := userScope(ctx)
+	err := s.queryRowSQL(ctx, `
+	SELECT id, title, comment, repeat FROM templates WHERE id = :id AND `+scope,
+		sql.Named("id", id), user).Scan(&tmpl.ID, &tmpl.Title, &tmpl.Comment, &tmpl.Repeat)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Template{}, ErrTemplateNotFound
+	}
+	if err != nil {
+		return Template{}, fmt.Errorf("failed to read template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// PutTemplate обновляет шаблон пользователя из ctx.
+// Возвращает ErrTemplateNotFound, если шаблон не найден.
+func (s *Store) PutTemplate(ctx context.Context, tmpl *Template) error {
+	scope, user := userScope(ctx)
+	res, err := s.execSQL(ctx, `
+	UPDATE templates
+	SET title = :title, comment = :comment, repeat = :repeat
+	WHERE id = :id AND `+scope,
+		sql.Named("id", tmpl.ID),
+		sql.Named("title", tmpl.Title),
+		sql.Named("comment", tmpl.Comment),
+		sql.Named("repeat", tmpl.Repeat),
+		user)
+	if err != nil {
+		return fmt.Errorf("failed to update template: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// DeleteTemplate удаляет шаблон пользователя из ctx. Задачи, созданные
+// из шаблона, остаются.
+// Возвращает ErrTemplateNotFound, если шаблон не найден.
+func (s *Store) DeleteTemplate(ctx context.Context, id int64) error {
+	scope, user := userScope(ctx)
+	res, err := s.execSQL(ctx, `DELETE FROM templates WHERE id = :id AND `+scope,
+		sql.Named("id", id), user)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
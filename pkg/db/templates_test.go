@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplatesUserScope(t *testing.T) {
+	store := OpenForTest(t)
+	ctx := context.Background()
+	other, err := store.CreateUser(t.Context(), "other", "hash")
+	require.NoError(t, err)
+	mine, theirs := WithUser(ctx, DefaultUserID), WithUser(ctx, other.ID)
+
+	tmpl := Template{Title: "Оплатить интернет", Repeat: "m 5"}
+	id, err := store.AddTemplate(mine, &tmpl)
+	require.NoError(t, err)
+	assert.Equal(t, taskID(t, tmpl.ID), id)
+
+	// чужой шаблон не виден, не меняется и не удаляется
+	templates, err := store.GetTemplates(theirs)
+	require.NoError(t, err)
+	assert.Empty(t, templates)
+	_, err = store.GetTemplate(theirs, id)
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+	assert.ErrorIs(t, store.PutTemplate(theirs, &Template{ID: tmpl.ID, Title: "x"}), ErrTemplateNotFound)
+	assert.ErrorIs(t, store.DeleteTemplate(theirs, id), ErrTemplateNotFound)
+
+	tmpl.Comment = "кв. 12"
+	require.NoError(t, store.PutTemplate(mine, &tmpl))
+	got, err := store.GetTemplate(mine, id)
+	require.NoError(t, err)
+	assert.Equal(t, tmpl, got)
+
+	require.NoError(t, store.DeleteTemplate(mine, id))
+	templates, err = store.GetTemplates(mine)
+	require.NoError(t, err)
+	assert.Empty(t, templates)
+}
@@ -0,0 +1,15 @@
+package db
+
+import "testing"
+
+// OpenForTest открывает пустое хранилище SQLite в памяти и закрывает его
+// по завершении теста. Хранилища разных вызовов независимы друг от друга.
+func OpenForTest(tb testing.TB) *Store {
+	tb.Helper()
+	s, err := Open(MemoryPath)
+	if err != nil {
+		tb.Fatalf("failed to open in-memory database: %v", err)
+	}
+	tb.Cleanup(func() { s.Close() })
+	return s
+}
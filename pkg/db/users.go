@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Пользователь по умолчанию. Ему принадлежат задачи, созданные до появления
+// пользователей, и задачи, добавленные без аутентификации.
+const (
+	DefaultUserID    int64 = 1
+	DefaultUserLogin       = "admin"
+)
+
+// User - пользователь сервиса.
+type User struct {
+	ID    int64
+	Login string
+	// PasswordHash - bcrypt-хеш пароля. Пустой у пользователя по умолчанию:
+	// его пароль задается TODO_PASSWORD или TODO_PASSWORD_HASH.
+	PasswordHash string
+}
+
+// ErrUserNotFound возвращается, если пользователь не найден.
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrUserExists возвращается при создании пользователя с занятым логином.
+var ErrUserExists = errors.New("user already exists")
+
+// userKey - ключ пользователя в контексте.
+type userKey struct{}
+
+// allUsers - значение userKey, снимающее отбор задач по пользователю.
+const allUsers int64 = 0
+
+// WithUser возвращает контекст, в котором операции Store работают
+// только с задачами пользователя userID.
+func WithUser(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// WithAllUsers возвращает контекст, в котором операции Store видят задачи
+// всех пользователей. Нужен для служебных подсчетов, например метрик.
+func WithAllUsers(ctx context.Context) context.Context {
+	return context.WithValue(ctx, userKey{}, allUsers)
+}
+
+// UserID возвращает пользователя из контекста.
+// Если пользователь не задан, возвращает DefaultUserID.
+func UserID(ctx context.Context) int64 {
+	if id, ok := ctx.Value(userKey{}).(int64); ok {
+		return id
+	}
+	return DefaultUserID
+}
+
+// userScope возвращает условие отбора задач пользователя из ctx
+// и его именованный параметр :user_id.
+func userScope(ctx context.Context) (string, sql.NamedArg) {
+	return "(:user_id = 0 OR user_id = :user_id)", sql.Named("user_id", UserID(ctx))
+}
+
+// CreateUser добавляет пользователя с логином login и bcrypt-хешем пароля hash.
+// Возвращает ErrUserExists, если логин занят.
+func (s *Store) CreateUser(ctx context.Context, login, hash string) (User, error) {
+	var id int64
+	err := s.queryRowSQL(ctx, `INSERT INTO users (login, password_hash) VALUES (:login, :hash) RETURNING id`,
+		sql.Named("login", login), sql.Named("hash", hash)).Scan(&id)
+	if isUniqueViolation(err) {
+		return User{}, ErrUserExists
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("failed to create user: %w", err)
+	}
+	return User{ID: id, Login: login, PasswordHash: hash}, nil
+}
+
+// UserByLogin возвращает пользователя по логину.
+// Возвращает ErrUserNotFound, если пользователь не найден.
+func (s *Store) UserByLogin(ctx context.Context, login string) (User, error) {
+	return s.scanUser(s.queryRowSQL(ctx, `SELECT id, login, password_hash FROM users WHERE login = :login`,
+		sql.Named("login", login)))
+}
+
+// UserByID возвращает пользователя по ID.
+// Возвращает ErrUserNotFound, если пользователь не найден.
+func (s *Store) UserByID(ctx context.Context, id int64) (User, error) {
+	return s.scanUser(s.queryRowSQL(ctx, `SELECT id, login, password_hash FROM users WHERE id = :id`,
+		sql.Named("id", id)))
+}
+
+// scanUser читает пользователя из строки результата запроса.
+func (s *Store) scanUser(row *sql.Row) (User, error) {
+	var user User
+	err := row.Scan(&user.ID, &user.Login, &user.PasswordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrUserNotFound
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("failed to read user: %w", err)
+	}
+	return user, nil
+}
+
+// migrateUsers создает пользователя по умолчанию и добавляет колонку user_id.
+// Задачи, созданные до появления пользователей, достаются пользователю по умолчанию.
+func migrateUsers(ctx context.Context, tx *sql.Tx) error {
+	if err := createDefaultUser(ctx, tx); err != nil {
+		return err
+	}
+
+	definition := fmt.Sprintf("INTEGER NOT NULL DEFAULT %d", DefaultUserID)
+	if err := addColumnIfMissing(ctx, tx, "scheduler", "user_id", definition); err != nil {
+		return err
+	}
+
+	_, err := execOn(ctx, tx, `CREATE INDEX IF NOT EXISTS idx_scheduler_user_date ON scheduler(user_id, date)`)
+	return err
+}
+
+// createDefaultUser создает пользователя по умолчанию, если его еще нет.
+func createDefaultUser(ctx context.Context, tx *sql.Tx) error {
+	_, err := execOn(ctx, tx, `INSERT INTO users (id, login, password_hash) VALUES (:id, :login, '') ON CONFLICT DO NOTHING`,
+		sql.Named("id", DefaultUserID), sql.Named("login", DefaultUserLogin))
+	if err != nil {
+		return fmt.Errorf("failed to create default user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Webhook - адрес, на который отправляются события задач пользователя.
+type Webhook struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // ключ подписи HMAC-SHA256, в списке не возвращается
+	Events []string `json:"events"`           // события: created, updated, deleted, done
+}
+
+// ErrWebhookNotFound возвращается, если вебхук не найден.
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// eventSeparator разделяет события вебхука в колонке events.
+const eventSeparator = ","
+
+// webhooksSQL создает таблицу вебхуков в SQLite.
+const webhooksSQL = `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL DEFAULT '',
+		events TEXT NOT NULL -- События через запятую
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id);`
+
+// postgresWebhooksSQL создает таблицу вебхуков в PostgreSQL.
+const postgresWebhooksSQL = `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL DEFAULT '',
+		events TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id);`
+
+// migrateWebhooks - миграция 8: таблица вебхуков.
+func migrateWebhooks(ctx context.Context, tx *sql.Tx, d *dialect) error {
+	query := webhooksSQL
+	if d == postgresDialect {
+		query = postgresWebhooksSQL
+	}
+	if _, err := execOn(ctx, tx, query); err != nil {
+		return fmt.Errorf("failed to create webhooks: %w", err)
+	}
+	return nil
+}
+
+// AddWebhook сохраняет вебхук пользователя из ctx и заполняет его ID.
+func (s *Store) AddWebhook(ctx context.Context, hook *Webhook) (int64, error) {
+	var id int64
+	err := s.queryRowSQL(ctx, `
+	INSERT INTO webhooks (user_id, url, secret, events)
+	VALUES (:user_id, :url, :secret, :events)
+	RETURNING id`,
+		sql.Named("user_id", UserID(ctx)),
+		sql.Named("url", hook.URL),
+		sql.Named("secret", hook.Secret),
+		sql.Named("events", strings.Join(hook.Events, eventSeparator))).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add webhook: %w", err)
+	}
+	hook.ID = fmt.Sprint(id)
+	return id, nil
+}
+
+// GetWebhooks возвращает вебхуки пользователя из ctx в порядке id
+// вместе с ключами подписи.
+func (s *Store) GetWebhooks(ctx context.Context) ([]*Webhook, error) {
+	scope, user := userScope(ctx)
+	rows, err := s.querySQL(ctx, `
+	SELECT id, url, secret, events FROM webhooks WHERE `+scope+` ORDER BY id`, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []*Webhook{}
+	for rows.Next() {
+		var hook Webhook
+		var events string
+		if err := rows.Scan(&hook.ID, &hook.URL, &hook.Secret, &events); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hook.Events = strings.Split(events, eventSeparator)
+		hooks = append(hooks, &hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during rows iteration: %w", err)
+	}
+	return hooks, nil
+}
+
+// PutWebhook обновляет вебхук пользователя из ctx. Пустой Secret оставляет
+// прежний ключ подписи.
+// Возвращает ErrWebhookNotFound, если вебхук не найден.
+func (s *Store) PutWebhook(ctx context.Context, hook *Webhook) error {
+	scope, user := userScope(ctx)
+	res, err := s.execSQL(ctx, `
+	UPDATE webhooks
+	SET url = :url, secret = CASE WHEN :secret = '' THEN secret ELSE :secret END, events = :events
+	WHERE id = :id AND `+scope,
+		sql.Named("id", hook.ID),
+		sql.Named("url", hook.URL),
+		sql.Named("secret", hook.Secret),
+		sql.Named("events", strings.Join(hook.Events, eventSeparator)),
+		user)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// DeleteWebhook удаляет вебхук пользователя из ctx.
+// Возвращает ErrWebhookNotFound, если вебхук не найден.
+func (s *Store) DeleteWebhook(ctx context.Context, id int64) error {
+	scope, user := userScope(ctx)
+	res, err := s.execSQL(ctx, `DELETE FROM webhooks WHERE id = :id AND `+scope,
+		sql.Named("id", id), user)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Package mail отправляет письма через SMTP-сервер с STARTTLS.
+//
+// Письмо содержит текстовую и HTML-версии (multipart/alternative).
+// Соединение без STARTTLS не используется: пароль и задачи не уходят
+// по сети открытым текстом.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Timeout - наибольшее время на соединение с сервером и отправку одного письма.
+const Timeout = 30 * time.Second
+
+// Message - письмо с текстовой и HTML-версиями.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Bytes возвращает письмо в формате RFC 5322 с телом multipart/alternative
+// в кодировке quoted-printable.
+func (m Message) Bytes() []byte {
+	var b bytes.Buffer
+	boundary := newBoundary()
+	fmt.Fprintf(&b, "From: %s\r\n", m.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", m.Text},
+		{"text/html", m.HTML},
+	} {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		w := quotedprintable.NewWriter(&b)
+		w.Write([]byte(part.body))
+		w.Close()
+		b.WriteString("\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes()
+}
+
+// newBoundary возвращает случайный разделитель частей письма.
+func newBoundary() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "todo-" + hex.EncodeToString(buf)
+}
+
+// Client отправляет письма через SMTP-сервер Host:Port. Если User задан,
+// после STARTTLS выполняется аутентификация PLAIN.
+type Client struct {
+	Host string
+	Port string
+	User string
+	Pass string
+}
+
+// Send отправляет письмо msg. Соединение прерывается по истечении Timeout
+// или при отмене ctx. Сервер без STARTTLS - ошибка.
+func (c *Client) Send(ctx context.Context, msg Message) error {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(c.Host, c.Port))
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	// net/smtp не принимает контекст: отмена закрывает соединение
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp: %w", err)
+	}
+	defer client.Close()
+
+	if err := c.send(client, msg); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("smtp: %w", ctx.Err())
+		}
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
+
+// send выполняет SMTP-диалог на установленном соединении.
+func (c *Client) send(client *smtp.Client, msg Message) error {
+	if ok, _ := client.Extension("STARTTLS"); !ok {
+		return errors.New("сервер не поддерживает STARTTLS")
+	}
+	if err := client.StartTLS(&tls.Config{ServerName: c.Host}); err != nil {
+		return err
+	}
+	if c.User != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.User, c.Pass, c.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(msg.From); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := Message{
+		From:    "todo@example.com",
+		To:      []string{"me@example.com", "wife@example.com"},
+		Subject: "Задачи на 01.07.2025",
+		Text:    "Позвонить маме",
+		HTML:    "<p>Позвонить маме</p>",
+	}
+	parsed, err := netmail.ReadMessage(bytes.NewReader(msg.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, "me@example.com, wife@example.com", parsed.Header.Get("To"))
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, msg.Subject, subject)
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		part, err := parts.NextRawPart()
+		require.NoError(t, err)
+		assert.Equal(t, want.contentType, part.Header.Get("Content-Type"))
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		require.NoError(t, err)
+		assert.Equal(t, want.body, strings.TrimRight(string(body), "\r\n"))
+	}
+	_, err = parts.NextPart()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestSendRequiresSTARTTLS(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		io.WriteString(conn, "220 localhost ESMTP\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				io.WriteString(conn, "250-localhost\r\n250 AUTH PLAIN\r\n")
+			case strings.HasPrefix(line, "QUIT"):
+				io.WriteString(conn, "221 bye\r\n")
+				return
+			default:
+				io.WriteString(conn, "502 not implemented\r\n")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	require.NoError(t, err)
+	c := &Client{Host: host, Port: port, User: "me", Pass: "secret"}
+	err = c.Send(context.Background(), Message{From: "todo@example.com", To: []string{"me@example.com"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "STARTTLS")
+}
@@ -0,0 +1,194 @@
+// Package metrics собирает статистику запросов и задач и отдает её
+// в текстовом формате Prometheus.
+//
+// Метрики хранятся в памяти процесса и сбрасываются при перезапуске.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets - верхние границы корзин гистограммы времени обработки, секунды.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// requestKey - набор меток счетчика HTTP-запросов.
+type requestKey struct {
+	path   string
+	method string
+	status int
+}
+
+// histogram - гистограмма времени обработки запросов одного пути.
+type histogram struct {
+	counts []uint64 // количество наблюдений в каждой корзине latencyBuckets (не накопительно)
+	count  uint64   // общее количество наблюдений
+	sum    float64  // сумма наблюдений, секунды
+}
+
+var (
+	mu        sync.Mutex
+	requests  = make(map[requestKey]uint64)
+	latencies = make(map[string]*histogram)
+
+	tasksTotal     atomic.Int64  // текущее количество невыполненных задач
+	tasksOverdue   atomic.Int64  // текущее количество просроченных задач
+	tasksCreated   atomic.Uint64 // задачи, созданные через API
+	tasksCompleted atomic.Uint64 // задачи, отмеченные выполненными через API
+)
+
+// ObserveRequest учитывает обработанный HTTP-запрос.
+// path - шаблон маршрута, а не фактический URL, чтобы число меток было ограничено.
+func ObserveRequest(path, method string, status int, d time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	requests[requestKey{path: path, method: method, status: status}]++
+
+	h := latencies[path]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		latencies[path] = h
+	}
+	seconds := d.Seconds()
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// SetTasks обновляет количество невыполненных и просроченных задач.
+func SetTasks(total, overdue int) {
+	tasksTotal.Store(int64(total))
+	tasksOverdue.Store(int64(overdue))
+}
+
+// TaskCreated учитывает созданную задачу.
+func TaskCreated() {
+	tasksCreated.Add(1)
+}
+
+// TaskCompleted учитывает задачу, отмеченную выполненной.
+func TaskCompleted() {
+	tasksCompleted.Add(1)
+}
+
+// Instrument оборачивает обработчик маршрута path: считает запросы
+// по пути, методу и статусу и время их обработки.
+func Instrument(path string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		ObserveRequest(path, r.Method, rec.status, time.Since(start))
+	})
+}
+
+// statusRecorder запоминает код ответа обработчика.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader запоминает и отправляет код ответа.
+func (rec *statusRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap возвращает исходный ResponseWriter для http.ResponseController.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter { return rec.ResponseWriter }
+
+// Handler возвращает обработчик GET /metrics.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w)
+	})
+}
+
+// Write пишет все метрики в текстовом формате Prometheus.
+// Серии выводятся в отсортированном порядке.
+func Write(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP todo_http_requests_total Количество обработанных HTTP-запросов.")
+	fmt.Fprintln(w, "# TYPE todo_http_requests_total counter")
+	keys := make([]requestKey, 0, len(requests))
+	for k := range requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].path != keys[j].path {
+			return keys[i].path < keys[j].path
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "todo_http_requests_total{path=%s,method=%s,status=\"%d\"} %d\n",
+			quote(k.path), quote(k.method), k.status, requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP todo_http_request_duration_seconds Время обработки HTTP-запросов.")
+	fmt.Fprintln(w, "# TYPE todo_http_request_duration_seconds histogram")
+	paths := make([]string, 0, len(latencies))
+	for p := range latencies {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		h := latencies[p]
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "todo_http_request_duration_seconds_bucket{path=%s,le=\"%s\"} %d\n",
+				quote(p), strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "todo_http_request_duration_seconds_bucket{path=%s,le=\"+Inf\"} %d\n", quote(p), h.count)
+		fmt.Fprintf(w, "todo_http_request_duration_seconds_sum{path=%s} %s\n", quote(p), strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(w, "todo_http_request_duration_seconds_count{path=%s} %d\n", quote(p), h.count)
+	}
+
+	writeValue(w, "todo_tasks", "gauge", "Количество невыполненных задач.", tasksTotal.Load())
+	writeValue(w, "todo_tasks_overdue", "gauge", "Количество просроченных задач.", tasksOverdue.Load())
+	writeValue(w, "todo_tasks_created_total", "counter", "Количество задач, созданных через API.", int64(tasksCreated.Load()))
+	writeValue(w, "todo_tasks_completed_total", "counter", "Количество задач, отмеченных выполненными.", int64(tasksCompleted.Load()))
+}
+
+// writeValue пишет метрику из одного значения без меток.
+func writeValue(w io.Writer, name, typ, help string, value int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, typ, name, value)
+}
+
+// quote возвращает значение метки в кавычках с экранированием по правилам Prometheus.
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// reset очищает все метрики; используется в тестах.
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	requests = make(map[requestKey]uint64)
+	latencies = make(map[string]*histogram)
+	tasksTotal.Store(0)
+	tasksOverdue.Store(0)
+	tasksCreated.Store(0)
+	tasksCompleted.Store(0)
+}
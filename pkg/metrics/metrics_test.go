@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	reset()
+	t.Cleanup(reset)
+
+	h := Instrument("/api/task", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	for _, target := range []string{"/api/task?id=1", "/api/task?id=2", "/api/task"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	ObserveRequest("/api/tasks", http.MethodGet, http.StatusOK, 300*time.Millisecond)
+	SetTasks(10, 3)
+	TaskCreated()
+	TaskCreated()
+	TaskCompleted()
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain"))
+
+	body := w.Body.String()
+	for _, line := range []string{
+		`todo_http_requests_total{path="/api/task",method="GET",status="200"} 2`,
+		`todo_http_requests_total{path="/api/task",method="GET",status="400"} 1`,
+		`todo_http_request_duration_seconds_bucket{path="/api/tasks",le="0.25"} 0`,
+		`todo_http_request_duration_seconds_bucket{path="/api/tasks",le="0.5"} 1`,
+		`todo_http_request_duration_seconds_bucket{path="/api/tasks",le="+Inf"} 1`,
+		`todo_http_request_duration_seconds_count{path="/api/task"} 3`,
+		"todo_tasks 10",
+		"todo_tasks_overdue 3",
+		"todo_tasks_created_total 2",
+		"todo_tasks_completed_total 1",
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, `"a\"b\\c\nd"`, quote("a\"b\\c\nd"))
+}
@@ -0,0 +1,233 @@
+// Package quickparse разбирает строку быстрого добавления задачи, например
+// "Заплатить за свет 25.06 каждый месяц", на заголовок, дату и правило повторения.
+//
+// Распознаются:
+//   - даты: "DD.MM", "DD.MM.YYYY", "сегодня", "завтра", "послезавтра",
+//     "через N дней" и английские "today", "tomorrow", "in N days";
+//   - подсказки повторения: "каждый день", "каждую неделю", "каждый месяц",
+//     "каждый год", "ежедневно", "еженедельно", "ежемесячно", "ежегодно"
+//     и английские "every day", "daily" и т.д.
+//
+// Остальные слова становятся заголовком. Строку, которую нельзя понять
+// однозначно, Parse не отклоняет: задача получает сегодняшнюю дату, а заголовком
+// становится вся строка.
+package quickparse
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go1f/pkg/taskdate"
+)
+
+// Kind - вид распознанного фрагмента строки.
+type Kind string
+
+const (
+	KindDate   Kind = "date"   // дата задачи
+	KindRepeat Kind = "repeat" // правило повторения
+)
+
+// Token - распознанный фрагмент строки.
+type Token struct {
+	Kind  Kind   `json:"kind"`
+	Text  string `json:"text"`  // фрагмент, как его ввел пользователь
+	Value string `json:"value"` // дата YYYYMMDD или правило повторения
+}
+
+// Result - задача, понятая из строки.
+type Result struct {
+	Title  string
+	Date   string  // YYYYMMDD
+	Repeat string  // правило повторения, пусто для разовой задачи
+	Tokens []Token // распознанные фрагменты в порядке следования в строке
+}
+
+// maxDaysAhead - наибольшее N во фразе "через N дней" (10 лет).
+const maxDaysAhead = 3660
+
+// dotDate - дата DD.MM или DD.MM.YYYY.
+var dotDate = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})(?:\.(\d{4}))?$`)
+
+// relativeDays - слова, означающие дату через несколько дней после сегодняшней.
+var relativeDays = map[string]int{
+	"сегодня":     0,
+	"today":       0,
+	"завтра":      1,
+	"tomorrow":    1,
+	"послезавтра": 2,
+}
+
+// dayWords - слова "день" во фразах "через N дней" и "in N days".
+var dayWords = map[string]bool{
+	"день": true, "дня": true, "дней": true,
+	"day": true, "days": true,
+}
+
+// repeatHints - подсказки повторения и единица правила, в которое они
+// переводятся (см. repeatRule).
+var repeatHints = []struct {
+	words []string
+	unit  byte
+}{
+	{[]string{"каждый", "день"}, 'd'},
+	{[]string{"ежедневно"}, 'd'},
+	{[]string{"every", "day"}, 'd'},
+	{[]string{"daily"}, 'd'},
+	{[]string{"каждую", "неделю"}, 'w'},
+	{[]string{"еженедельно"}, 'w'},
+	{[]string{"every", "week"}, 'w'},
+	{[]string{"weekly"}, 'w'},
+	{[]string{"каждый", "месяц"}, 'm'},
+	{[]string{"ежемесячно"}, 'm'},
+	{[]string{"every", "month"}, 'm'},
+	{[]string{"monthly"}, 'm'},
+	{[]string{"каждый", "год"}, 'y'},
+	{[]string{"ежегодно"}, 'y'},
+	{[]string{"every", "year"}, 'y'},
+	{[]string{"yearly"}, 'y'},
+}
+
+// Parse разбирает строку text. Относительные даты считаются от today,
+// дата DD.MM без года - ближайшая не раньше today.
+//
+// Правило повторения строится от даты задачи: "каждую неделю" с датой
+// в среду - "w 3", "каждый месяц" с датой 25-го числа - "m 25".
+//
+// Если в строке несколько дат или несколько подсказок повторения или
+// кроме них нет слов для заголовка, Parse возвращает сегодняшнюю дату,
+// всю строку заголовком и ни одного распознанного фрагмента.
+func Parse(text string, today time.Time) Result {
+	words := strings.Fields(text)
+	fallback := Result{Title: strings.Join(words, " "), Date: today.Format(taskdate.DateFormat)}
+
+	// нормализованные слова для сравнения: без регистра и знаков препинания
+	norm := make([]string, len(words))
+	for i, word := range words {
+		norm[i] = strings.TrimRight(strings.ToLower(word), ",;:!?.")
+	}
+
+	var (
+		title   []string
+		tokens  []Token
+		date    time.Time
+		dated   bool
+		unit    byte
+		repeatN = -1 // индекс токена повторения
+	)
+	for i := 0; i < len(words); {
+		if d, n, ok := matchDate(norm[i:], today); ok {
+			if dated {
+				return fallback
+			}
+			date, dated = d, true
+			tokens = append(tokens, Token{Kind: KindDate, Text: strings.Join(words[i:i+n], " "), Value: d.Format(taskdate.DateFormat)})
+			i += n
+			continue
+		}
+		if u, n, ok := matchRepeat(norm[i:]); ok {
+			if unit != 0 {
+				return fallback
+			}
+			unit, repeatN = u, len(tokens)
+			tokens = append(tokens, Token{Kind: KindRepeat, Text: strings.Join(words[i:i+n], " ")})
+			i += n
+			continue
+		}
+		title = append(title, words[i])
+		i++
+	}
+	if len(title) == 0 {
+		return fallback
+	}
+
+	if !dated {
+		date = today
+	}
+	result := Result{
+		Title:  strings.Join(title, " "),
+		Date:   date.Format(taskdate.DateFormat),
+		Tokens: tokens,
+	}
+	if unit != 0 {
+		result.Repeat = repeatRule(unit, date)
+		result.Tokens[repeatN].Value = result.Repeat
+	}
+	return result
+}
+
+// matchDate распознает дату в начале words и возвращает её и количество
+// занятых ею слов.
+func matchDate(words []string, today time.Time) (time.Time, int, bool) {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+
+	if days, ok := relativeDays[words[0]]; ok {
+		return today.AddDate(0, 0, days), 1, true
+	}
+	if (words[0] == "через" || words[0] == "in") && len(words) >= 3 && dayWords[words[2]] {
+		days, err := strconv.Atoi(words[1])
+		if err == nil && days >= 0 && days <= maxDaysAhead {
+			return today.AddDate(0, 0, days), 3, true
+		}
+		return time.Time{}, 0, false
+	}
+
+	m := dotDate.FindStringSubmatch(words[0])
+	if m == nil {
+		return time.Time{}, 0, false
+	}
+	day, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	if m[3] != "" {
+		year, _ := strconv.Atoi(m[3])
+		date, ok := calendarDate(year, month, day, today.Location())
+		return date, 1, ok
+	}
+
+	// дата без года - ближайшая, которая еще не прошла;
+	// 29.02 ищется в ближайшем високосном году
+	for year := today.Year(); year <= today.Year()+8; year++ {
+		if date, ok := calendarDate(year, month, day, today.Location()); ok && !date.Before(today) {
+			return date, 1, true
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+// calendarDate возвращает дату day.month.year, если она существует (не 31.04).
+func calendarDate(year, month, day int, loc *time.Location) (time.Time, bool) {
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+	return date, date.Year() == year && int(date.Month()) == month && date.Day() == day
+}
+
+// matchRepeat распознает подсказку повторения в начале words и возвращает
+// её единицу и количество занятых ею слов.
+func matchRepeat(words []string) (byte, int, bool) {
+	for _, hint := range repeatHints {
+		if len(words) >= len(hint.words) && slices.Equal(words[:len(hint.words)], hint.words) {
+			return hint.unit, len(hint.words), true
+		}
+	}
+	return 0, 0, false
+}
+
+// repeatRule переводит единицу повторения в правило NextDate для задачи с датой date.
+func repeatRule(unit byte, date time.Time) string {
+	switch unit {
+	case 'd':
+		return "d 1"
+	case 'w':
+		weekday := int(date.Weekday())
+		if weekday == 0 {
+			weekday = 7 // воскресенье
+		}
+		return "w " + strconv.Itoa(weekday)
+	case 'm':
+		return "m " + strconv.Itoa(date.Day())
+	default:
+		return "y"
+	}
+}
@@ -0,0 +1,223 @@
+package quickparse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	// среда, 18 июня 2025
+	today := time.Date(2025, 6, 18, 15, 30, 0, 0, time.UTC)
+
+	tbl := []struct {
+		name   string
+		text   string
+		title  string
+		date   string
+		repeat string
+		tokens []Token
+	}{
+		{
+			name: "title only", text: "  Купить   молоко ",
+			title: "Купить молоко", date: "20250618",
+		},
+		{
+			name: "date and monthly", text: "Заплатить за свет 25.06 каждый месяц",
+			title: "Заплатить за свет", date: "20250625", repeat: "m 25",
+			tokens: []Token{
+				{KindDate, "25.06", "20250625"},
+				{KindRepeat, "каждый месяц", "m 25"},
+			},
+		},
+		{
+			name: "english repeat", text: "Заплатить за свет 25.06 every month",
+			title: "Заплатить за свет", date: "20250625", repeat: "m 25",
+			tokens: []Token{
+				{KindDate, "25.06", "20250625"},
+				{KindRepeat, "every month", "m 25"},
+			},
+		},
+		{
+			name: "date without year passed", text: "Продлить страховку 01.03",
+			title: "Продлить страховку", date: "20260301",
+			tokens: []Token{{KindDate, "01.03", "20260301"}},
+		},
+		{
+			name: "date is today", text: "Созвон 18.6",
+			title: "Созвон", date: "20250618",
+			tokens: []Token{{KindDate, "18.6", "20250618"}},
+		},
+		{
+			name: "leap day", text: "День рождения 29.02 ежегодно",
+			title: "День рождения", date: "20280229", repeat: "y",
+			tokens: []Token{
+				{KindDate, "29.02", "20280229"},
+				{KindRepeat, "ежегодно", "y"},
+			},
+		},
+		{
+			name: "full date", text: "Отчет 05.01.2026",
+			title: "Отчет", date: "20260105",
+			tokens: []Token{{KindDate, "05.01.2026", "20260105"}},
+		},
+		{
+			name: "full date in past kept", text: "Отчет 05.01.2024",
+			title: "Отчет", date: "20240105",
+			tokens: []Token{{KindDate, "05.01.2024", "20240105"}},
+		},
+		{
+			name: "date first", text: "31.12 Нарядить елку",
+			title: "Нарядить елку", date: "20251231",
+			tokens: []Token{{KindDate, "31.12", "20251231"}},
+		},
+		{
+			name: "date with punctuation", text: "Сдать отчет 30.06, срочно",
+			title: "Сдать отчет срочно", date: "20250630",
+			tokens: []Token{{KindDate, "30.06,", "20250630"}},
+		},
+		{
+			name: "nonexistent date is title", text: "Позвонить 31.04",
+			title: "Позвонить 31.04", date: "20250618",
+		},
+		{
+			name: "version number is title", text: "Обновить до 1.2.3",
+			title: "Обновить до 1.2.3", date: "20250618",
+		},
+		{
+			name: "today", text: "Полить цветы сегодня",
+			title: "Полить цветы", date: "20250618",
+			tokens: []Token{{KindDate, "сегодня", "20250618"}},
+		},
+		{
+			name: "tomorrow capitalized", text: "Завтра забрать посылку",
+			title: "забрать посылку", date: "20250619",
+			tokens: []Token{{KindDate, "Завтра", "20250619"}},
+		},
+		{
+			name: "day after tomorrow", text: "Стрижка послезавтра",
+			title: "Стрижка", date: "20250620",
+			tokens: []Token{{KindDate, "послезавтра", "20250620"}},
+		},
+		{
+			name: "english tomorrow", text: "Call mom tomorrow",
+			title: "Call mom", date: "20250619",
+			tokens: []Token{{KindDate, "tomorrow", "20250619"}},
+		},
+		{
+			name: "in n days", text: "Вернуть книгу через 14 дней",
+			title: "Вернуть книгу", date: "20250702",
+			tokens: []Token{{KindDate, "через 14 дней", "20250702"}},
+		},
+		{
+			name: "in 2 days", text: "через 2 дня проверить почту",
+			title: "проверить почту", date: "20250620",
+			tokens: []Token{{KindDate, "через 2 дня", "20250620"}},
+		},
+		{
+			name: "in 1 day", text: "Напомнить через 1 день",
+			title: "Напомнить", date: "20250619",
+			tokens: []Token{{KindDate, "через 1 день", "20250619"}},
+		},
+		{
+			name: "english in n days", text: "Renew in 3 days",
+			title: "Renew", date: "20250621",
+			tokens: []Token{{KindDate, "in 3 days", "20250621"}},
+		},
+		{
+			name: "через without number is title", text: "Пройти через парк",
+			title: "Пройти через парк", date: "20250618",
+		},
+		{
+			name: "через too far is title", text: "Пенсия через 99999 дней",
+			title: "Пенсия через 99999 дней", date: "20250618",
+		},
+		{
+			name: "daily", text: "Зарядка каждый день",
+			title: "Зарядка", date: "20250618", repeat: "d 1",
+			tokens: []Token{{KindRepeat, "каждый день", "d 1"}},
+		},
+		{
+			name: "daily one word", text: "Зарядка ежедневно",
+			title: "Зарядка", date: "20250618", repeat: "d 1",
+			tokens: []Token{{KindRepeat, "ежедневно", "d 1"}},
+		},
+		{
+			name: "weekly from today", text: "Вынести мусор каждую неделю",
+			title: "Вынести мусор", date: "20250618", repeat: "w 3",
+			tokens: []Token{{KindRepeat, "каждую неделю", "w 3"}},
+		},
+		{
+			name: "weekly from sunday", text: "Каждую неделю уборка 22.06",
+			title: "уборка", date: "20250622", repeat: "w 7",
+			tokens: []Token{
+				{KindRepeat, "Каждую неделю", "w 7"},
+				{KindDate, "22.06", "20250622"},
+			},
+		},
+		{
+			name: "weekly english", text: "Team sync weekly tomorrow",
+			title: "Team sync", date: "20250619", repeat: "w 4",
+			tokens: []Token{
+				{KindRepeat, "weekly", "w 4"},
+				{KindDate, "tomorrow", "20250619"},
+			},
+		},
+		{
+			name: "monthly from today", text: "Показания счетчиков ежемесячно",
+			title: "Показания счетчиков", date: "20250618", repeat: "m 18",
+			tokens: []Token{{KindRepeat, "ежемесячно", "m 18"}},
+		},
+		{
+			name: "yearly", text: "Техосмотр каждый год 10.09",
+			title: "Техосмотр", date: "20250910", repeat: "y",
+			tokens: []Token{
+				{KindRepeat, "каждый год", "y"},
+				{KindDate, "10.09", "20250910"},
+			},
+		},
+		{
+			name: "repeat word alone is title", text: "Каждый может ошибиться",
+			title: "Каждый может ошибиться", date: "20250618",
+		},
+		// неоднозначные строки - вся строка в заголовке, дата сегодняшняя
+		{
+			name: "two dates", text: "Отпуск 01.07 15.07",
+			title: "Отпуск 01.07 15.07", date: "20250618",
+		},
+		{
+			name: "date and relative date", text: "Завтра встреча 20.06",
+			title: "Завтра встреча 20.06", date: "20250618",
+		},
+		{
+			name: "two repeats", text: "Зарядка каждый день ежемесячно",
+			title: "Зарядка каждый день ежемесячно", date: "20250618",
+		},
+		{
+			name: "nothing left for title", text: "завтра каждый день",
+			title: "завтра каждый день", date: "20250618",
+		},
+		{
+			name: "empty", text: "   ",
+			title: "", date: "20250618",
+		},
+	}
+	for _, v := range tbl {
+		t.Run(v.name, func(t *testing.T) {
+			got := Parse(v.text, today)
+			assert.Equal(t, v.title, got.Title)
+			assert.Equal(t, v.date, got.Date)
+			assert.Equal(t, v.repeat, got.Repeat)
+			assert.Equal(t, v.tokens, got.Tokens)
+		})
+	}
+}
+
+func TestParseTimeZone(t *testing.T) {
+	// даты считаются в часовом поясе today
+	msk := time.FixedZone("MSK", 3*60*60)
+	today := time.Date(2025, 6, 18, 23, 30, 0, 0, msk)
+	got := Parse("Позвонить завтра", today)
+	assert.Equal(t, "20250619", got.Date)
+}
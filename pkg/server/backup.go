@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"go1f/pkg/db"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// backupStore - часть хранилища задач, которая нужна для автоматических резервных копий.
+type backupStore interface {
+	BackupToDir(ctx context.Context, dir string, now time.Time) (string, error)
+}
+
+// backupScheduler раз в interval сохраняет резервную копию БД в каталог dir
+// и оставляет в нем только keep последних копий (см. db.RotateBackups).
+type backupScheduler struct {
+	store    backupStore
+	dir      string
+	interval time.Duration
+	keep     int
+	now      func() time.Time // время в имени копии
+
+	running atomic.Bool    // копирование идет, следующий запуск пропускается
+	wg      sync.WaitGroup // начатые копирования
+}
+
+// run запускает копирование по таймеру до отмены ctx.
+// Если предыдущее копирование еще идет, очередной запуск пропускается.
+// После отмены ctx ждет завершения начатого копирования: оно прерывается
+// тем же ctx, а недописанный файл удаляется.
+func (b *backupScheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.wg.Wait()
+			return
+		case <-ticker.C:
+		}
+
+		if !b.running.CompareAndSwap(false, true) {
+			slog.Warn("Предыдущее резервное копирование еще идет, запуск пропущен")
+			continue
+		}
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			defer b.running.Store(false)
+			b.backup(ctx)
+		}()
+	}
+}
+
+// backup сохраняет одну резервную копию и удаляет устаревшие.
+func (b *backupScheduler) backup(ctx context.Context) {
+	name, err := b.store.BackupToDir(ctx, b.dir, b.now())
+	if err != nil {
+		if ctx.Err() != nil {
+			slog.Warn("Резервное копирование прервано остановкой сервера")
+			return
+		}
+		slog.Error("Ошибка автоматического резервного копирования", "err", err)
+		return
+	}
+	slog.Info("Создана автоматическая резервная копия БД", "name", name)
+
+	removed, err := db.RotateBackups(b.dir, b.keep)
+	for _, old := range removed {
+		slog.Info("Удалена устаревшая резервная копия БД", "name", old)
+	}
+	if err != nil {
+		slog.Error("Ошибка удаления устаревших резервных копий", "err", err)
+	}
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"go1f/pkg/db"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fileBackupStore вместо копии БД создает пустой файл копии и считает вызовы.
+// Если block задан, копирование ждет отмены ctx.
+type fileBackupStore struct {
+	calls atomic.Int32
+	block bool
+}
+
+func (s *fileBackupStore) BackupToDir(ctx context.Context, dir string, now time.Time) (string, error) {
+	s.calls.Add(1)
+	if s.block {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	name := db.BackupName(now)
+	return name, os.WriteFile(filepath.Join(dir, name), nil, 0o600)
+}
+
+// tickingClock возвращает время, которое с каждым вызовом сдвигается на секунду,
+// чтобы частые копии получали разные имена.
+func tickingClock() func() time.Time {
+	var mu sync.Mutex
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+// runScheduler запускает b до вызова возвращаемой функции остановки,
+// которая ждет завершения run.
+func runScheduler(t *testing.T, b *backupScheduler) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.run(ctx)
+	}()
+	return func() {
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("копирование не остановилось после отмены")
+		}
+	}
+}
+
+func TestBackupSchedulerRotation(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "scheduler.db"), nil, 0o600))
+	store := &fileBackupStore{}
+	stop := runScheduler(t, &backupScheduler{store: store, dir: dir, interval: 5 * time.Millisecond, keep: 2, now: tickingClock()})
+
+	require.Eventually(t, func() bool { return store.calls.Load() >= 4 }, 5*time.Second, time.Millisecond)
+	stop()
+
+	// остаются две последние копии, файл БД не трогается
+	n := int(store.calls.Load())
+	start := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.Equal(t, []string{
+		db.BackupName(start.Add(time.Duration(n-1) * time.Second)),
+		db.BackupName(start.Add(time.Duration(n) * time.Second)),
+		"scheduler.db",
+	}, names)
+}
+
+func TestBackupSchedulerSkipsOverlap(t *testing.T) {
+	store := &fileBackupStore{block: true}
+	stop := runScheduler(t, &backupScheduler{store: store, dir: t.TempDir(), interval: time.Millisecond, keep: 2, now: tickingClock()})
+
+	require.Eventually(t, func() bool { return store.calls.Load() == 1 }, 5*time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	// пока первое копирование не завершено, новые не начинаются
+	assert.Equal(t, int32(1), store.calls.Load())
+
+	// остановка прерывает начатое копирование и дожидается его
+	stop()
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go1f/pkg/db"
+	"go1f/pkg/taskdate"
+	"go1f/pkg/telegram"
+)
+
+// notifyDateFormat - формат даты задачи в сводке.
+const notifyDateFormat = "02.01.2006"
+
+// notifyStore - часть хранилища задач, которая нужна для уведомлений.
+type notifyStore interface {
+	TasksToNotify(ctx context.Context, today string) ([]*db.Task, error)
+	MarkNotified(ctx context.Context, today string, ids []string) error
+}
+
+// sender отправляет текст сообщения, например *telegram.Client.
+type sender interface {
+	Send(ctx context.Context, text string) error
+}
+
+// notifier сразу после запуска и затем раз в interval отправляет одной сводкой
+// задачи администратора на сегодня и просроченные. Задачи из отправленной
+// сводки отмечаются в БД и до следующего дня не повторяются.
+type notifier struct {
+	store    notifyStore
+	sender   sender
+	interval time.Duration
+	now      func() time.Time // текущее время в часовом поясе дат задач
+}
+
+// run отправляет сводки до отмены ctx. Отправка прерывается тем же ctx.
+func (n *notifier) run(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		n.notify(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// notify отправляет одну сводку, если есть задачи, о которых сегодня
+// еще не уведомляли. При ошибке отправки задачи не отмечаются и попадут
+// в следующую сводку.
+func (n *notifier) notify(ctx context.Context) {
+	ctx = db.WithUser(ctx, db.DefaultUserID)
+	today := n.now().Format(taskdate.DateFormat)
+	tasks, err := n.store.TasksToNotify(ctx, today)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("Ошибка при получении задач для уведомления", "err", err)
+		}
+		return
+	}
+	if len(tasks) == 0 {
+		return
+	}
+
+	if err := n.sender.Send(ctx, digest(tasks, today)); err != nil {
+		if ctx.Err() != nil {
+			slog.Warn("Отправка уведомления прервана остановкой сервера")
+			return
+		}
+		slog.Error("Ошибка отправки уведомления", "err", err)
+		return
+	}
+
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+	if err := n.store.MarkNotified(ctx, today, ids); err != nil {
+		slog.Error("Ошибка при отметке отправленных уведомлений", "err", err)
+		return
+	}
+	slog.Info("Отправлено уведомление о задачах", "tasks", len(tasks))
+}
+
+// digest составляет текст сводки: задачи на сегодня (today) и просроченные
+// по одной на строку с датой. Задачи, не уместившиеся в одно сообщение
+// Telegram, только подсчитываются в последней строке.
+func digest(tasks []*db.Task, today string) string {
+	var b strings.Builder
+	b.WriteString("Задачи на сегодня и просроченные:\n")
+	for i, task := range tasks {
+		date := task.Date
+		if t, err := time.Parse(taskdate.DateFormat, task.Date); err == nil {
+			date = t.Format(notifyDateFormat)
+		}
+		mark := ""
+		if task.Date < today {
+			mark = " (просрочена)"
+		}
+		line := fmt.Sprintf("\n• %s - %s%s", date, task.Title, mark)
+
+		more := fmt.Sprintf("\n… и еще %d", len(tasks)-i)
+		if utf8.RuneCountInString(b.String())+utf8.RuneCountInString(line)+utf8.RuneCountInString(more) > telegram.MaxMessageLen {
+			b.WriteString(more)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"go1f/pkg/db"
+	"go1f/pkg/telegram"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memNotifyStore хранит задачи и отметки уведомлений в памяти.
+type memNotifyStore struct {
+	mu       sync.Mutex
+	tasks    []*db.Task
+	notified map[string]string // id задачи -> день уведомления
+}
+
+func (s *memNotifyStore) TasksToNotify(ctx context.Context, today string) ([]*db.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*db.Task
+	for _, task := range s.tasks {
+		if task.Date <= today && s.notified[task.ID] != today {
+			due = append(due, task)
+		}
+	}
+	return due, nil
+}
+
+func (s *memNotifyStore) MarkNotified(ctx context.Context, today string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		s.notified[id] = today
+	}
+	return nil
+}
+
+// recordSender запоминает отправленные сообщения; с fail возвращает ошибку.
+type recordSender struct {
+	mu   sync.Mutex
+	sent []string
+	fail bool
+}
+
+func (s *recordSender) Send(ctx context.Context, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("сеть недоступна")
+	}
+	s.sent = append(s.sent, text)
+	return nil
+}
+
+func (s *recordSender) messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.sent...)
+}
+
+func TestNotifierDigest(t *testing.T) {
+	store := &memNotifyStore{
+		tasks: []*db.Task{
+			{ID: "1", Date: "20250630", Title: "Оплатить интернет"},
+			{ID: "2", Date: "20250701", Title: "Позвонить маме"},
+			{ID: "3", Date: "20250705", Title: "Будущая"},
+		},
+		notified: map[string]string{},
+	}
+	sender := &recordSender{fail: true}
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	n := &notifier{store: store, sender: sender, interval: time.Hour, now: func() time.Time { return now }}
+
+	// неудачная отправка не отмечает задачи
+	n.notify(context.Background())
+	assert.Empty(t, store.notified)
+
+	sender.fail = false
+	n.notify(context.Background())
+	require.Len(t, sender.messages(), 1)
+	assert.Equal(t, "Задачи на сегодня и просроченные:\n"+
+		"\n• 30.06.2025 - Оплатить интернет (просрочена)"+
+		"\n• 01.07.2025 - Позвонить маме", sender.messages()[0])
+
+	// в тот же день сводка не повторяется, на следующий - приходит снова
+	n.notify(context.Background())
+	assert.Len(t, sender.messages(), 1)
+	now = now.AddDate(0, 0, 1)
+	n.notify(context.Background())
+	assert.Len(t, sender.messages(), 2)
+}
+
+func TestDigestLimit(t *testing.T) {
+	var tasks []*db.Task
+	for range 100 {
+		tasks = append(tasks, &db.Task{Date: "20250701", Title: strings.Repeat("я", 100)})
+	}
+	text := digest(tasks, "20250701")
+	assert.LessOrEqual(t, utf8.RuneCountInString(text), telegram.MaxMessageLen)
+	assert.Contains(t, text, "… и еще ")
+}
+
+func TestNotifierStops(t *testing.T) {
+	store := &memNotifyStore{notified: map[string]string{}}
+	n := &notifier{store: store, sender: &recordSender{}, interval: time.Millisecond, now: time.Now}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.run(ctx)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("уведомления не остановились после отмены")
+	}
+}
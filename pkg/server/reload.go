@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"go1f/pkg/api"
+	"go1f/pkg/config"
+	"log/slog"
+	"os"
+)
+
+// reloader перечитывает конфигурацию по SIGHUP и применяет параметры,
+// которые меняются без перезапуска (см. config.Config.Reload): лимиты списка
+// задач, уровень и формат журнала, расписание резервного копирования,
+// ограничение попыток входа.
+// Порт, путь к БД и остальные параметры меняются только перезапуском.
+//
+// Все поля, кроме load, принадлежат горутине run.
+type reloader struct {
+	conf  config.Config                 // действующая конфигурация
+	load  func() (config.Config, error) // config.Load, в тестах - заглушка
+	store api.TaskStore
+
+	ctx         context.Context    // контекст сервера
+	stopBackups context.CancelFunc // останавливает текущее резервное копирование
+	backupsDone <-chan struct{}
+}
+
+// newReloader создает reloader с конфигурацией c и запускает резервное
+// копирование store до отмены ctx.
+func newReloader(ctx context.Context, c config.Config, store api.TaskStore) *reloader {
+	r := &reloader{conf: c, load: config.Load, store: store, ctx: ctx}
+	r.startBackups()
+	return r
+}
+
+// startBackups запускает резервное копирование по настройкам r.conf.
+func (r *reloader) startBackups() {
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.stopBackups, r.backupsDone = cancel, startBackups(ctx, r.conf, r.store)
+}
+
+// run перечитывает конфигурацию при каждом сигнале из hup до отмены
+// контекста сервера. Возвращаемый канал закрывается, когда резервное
+// копирование остановлено.
+func (r *reloader) run(hup <-chan os.Signal) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-r.ctx.Done():
+				<-r.backupsDone
+				return
+			case <-hup:
+				r.reload()
+			}
+		}
+	}()
+	return done
+}
+
+// reload перечитывает конфигурацию и применяет изменившиеся параметры.
+// Если новая конфигурация неверна, продолжает работать прежняя, в том числе
+// журнал, который Load успел перенастроить.
+func (r *reloader) reload() {
+	logger := slog.Default()
+	next, err := r.load()
+	if err != nil {
+		slog.SetDefault(logger)
+		slog.Error("Конфигурация не перечитана, действуют прежние настройки", "err", err)
+		return
+	}
+
+	prev := r.conf
+	conf, applied, ignored := prev.Reload(next)
+	if len(ignored) > 0 {
+		slog.Warn("Изменения конфигурации вступят в силу после перезапуска", "changes", ignored)
+	}
+	if len(applied) == 0 {
+		slog.Info("Конфигурация перечитана, изменений нет")
+		return
+	}
+
+	r.conf = conf
+	api.UpdateConfig(conf)
+	if conf.SigninFailures != prev.SigninFailures || conf.SigninWindow != prev.SigninWindow {
+		api.SetSigninLimit(conf.SigninFailures, conf.SigninWindow)
+	}
+	if conf.BackupInterval != prev.BackupInterval || conf.BackupKeep != prev.BackupKeep {
+		// начатая копия прерывается и удаляется, следующая будет по новому расписанию
+		r.stopBackups()
+		<-r.backupsDone
+		r.startBackups()
+	}
+	slog.Info("Конфигурация перечитана", "changes", applied)
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go1f/pkg/api"
+	"go1f/pkg/config"
+	"go1f/pkg/db"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listTasks возвращает число задач в ответе GET /api/tasks обработчика h.
+func listTasks(t *testing.T, h http.Handler) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tasks", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Tasks []json.RawMessage `json:"tasks"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return len(resp.Tasks)
+}
+
+func TestReloadLimit(t *testing.T) {
+	c := config.Config{PathToDB: db.MemoryPath, PortServ: "7540", LimitTask: 2}
+	store, err := db.InitDB(c)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.CloseDB() })
+	for range 5 {
+		_, err := store.AddTask(context.Background(), &db.Task{Date: "20240101", Title: "Задача"})
+		require.NoError(t, err)
+	}
+
+	h := api.Init(store, nil, c)
+	require.Equal(t, 2, listTasks(t, h))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := newReloader(ctx, c, store)
+
+	// неверная конфигурация не применяется
+	r.load = func() (config.Config, error) {
+		return config.Config{}, errors.New("TODO_LIMIT_TASKS: неверное значение")
+	}
+	r.reload()
+	assert.Equal(t, 2, listTasks(t, h))
+
+	next := c
+	next.LimitTask = 4
+	next.PortServ = "8080"
+	r.load = func() (config.Config, error) { return next, nil }
+	r.reload()
+	assert.Equal(t, 4, listTasks(t, h), "новый лимит действует со следующего запроса")
+	assert.Equal(t, "7540", r.conf.PortServ, "порт меняется только перезапуском")
+
+	// SIGHUP перечитывает конфигурацию, пока работает сервер
+	next.LimitTask = 3
+	hup := make(chan os.Signal)
+	done := r.run(hup)
+	hup <- syscall.SIGHUP
+	hup <- syscall.SIGHUP // второй сигнал принимается после обработки первого
+	assert.Equal(t, 3, listTasks(t, h))
+	cancel()
+	<-done
+}
+
+func TestReloadSigninLimit(t *testing.T) {
+	c := config.Config{PathToDB: db.MemoryPath, PortServ: "7540", PasswordTest: "secret"}
+	store, err := db.InitDB(c)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.CloseDB() })
+
+	h := api.Init(store, nil, c)
+	signin := func() int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/signin", strings.NewReader(`{"password":"wrong"}`)))
+		return w.Code
+	}
+	require.Equal(t, http.StatusUnauthorized, signin())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	r := newReloader(ctx, c, store)
+	next := c
+	next.SigninFailures = 2
+	next.SigninWindow = time.Hour
+	r.load = func() (config.Config, error) { return next, nil }
+	r.reload()
+
+	// неудача до перечитывания учитывается в новом лимите
+	assert.Equal(t, http.StatusUnauthorized, signin())
+	assert.Equal(t, http.StatusTooManyRequests, signin())
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	htmltemplate "html/template"
+	"log/slog"
+	"text/template"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/mail"
+	"go1f/pkg/taskdate"
+)
+
+// Параметры ежедневной сводки по почте.
+const (
+	emailDigestJob  = "email_digest" // имя задания в таблице job_runs
+	maxDigestTasks  = 100            // задач в одном разделе письма, остальные только считаются
+	digestRetryWait = 5 * time.Minute
+)
+
+// digestTask - задача в письме.
+type digestTask struct {
+	Date  string // DD.MM.YYYY
+	Title string
+}
+
+// digestData - данные шаблонов письма.
+type digestData struct {
+	Today   string // DD.MM.YYYY
+	Due     []digestTask
+	Overdue []digestTask
+	More    int // задач, не попавших в письмо
+}
+
+// digestText - текстовая версия письма.
+var digestText = template.Must(template.New("text").Parse(`Задачи на {{.Today}}
+{{if .Due}}
+Сегодня:
+{{range .Due}}- {{.Title}}
+{{end}}{{end}}{{if .Overdue}}
+Просрочено:
+{{range .Overdue}}- {{.Date}} {{.Title}}
+{{end}}{{end}}{{if .More}}
+... и еще {{.More}}
+{{end}}`))
+
+// digestHTML - HTML-версия письма. Заголовки задач экранируются.
+var digestHTML = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html><body>
+<h2>Задачи на {{.Today}}</h2>
+{{if .Due}}<h3>Сегодня</h3>
+<ul>{{range .Due}}
+<li>{{.Title}}</li>{{end}}
+</ul>
+{{end}}{{if .Overdue}}<h3>Просрочено</h3>
+<ul>{{range .Overdue}}
+<li>{{.Date}} &mdash; {{.Title}}</li>{{end}}
+</ul>
+{{end}}{{if .More}}<p>... и еще {{.More}}</p>
+{{end}}</body></html>
+`))
+
+// renderDigest возвращает текстовую и HTML-версии письма.
+func renderDigest(data digestData) (text, html string, err error) {
+	var t, h bytes.Buffer
+	if err := digestText.Execute(&t, data); err != nil {
+		return "", "", err
+	}
+	if err := digestHTML.Execute(&h, data); err != nil {
+		return "", "", err
+	}
+	return t.String(), h.String(), nil
+}
+
+// reminderStore - часть хранилища задач, которая нужна для сводки по почте.
+type reminderStore interface {
+	GetTasksPage(ctx context.Context, limit, offset int, filter db.TaskFilter) ([]*db.Task, error)
+	CountTasks(ctx context.Context, filter db.TaskFilter) (int, error)
+	LastRun(ctx context.Context, name string) (string, error)
+	SetLastRun(ctx context.Context, name, day string) error
+}
+
+// mailer отправляет письмо, например *mail.Client.
+type mailer interface {
+	Send(ctx context.Context, msg mail.Message) error
+}
+
+// reminder раз в день в момент at после полуночи отправляет письмо с задачами
+// администратора на сегодня и просроченными - теми же, что отбирают
+// фильтры filter=today и filter=overdue списка задач. День отправки хранится в БД,
+// поэтому после перезапуска сервера письмо за тот же день не повторяется,
+// а пропущенное сегодня отправляется сразу.
+type reminder struct {
+	store  reminderStore
+	mailer mailer
+	from   string
+	to     []string
+	at     time.Duration    // время отправки от полуночи
+	retry  time.Duration    // пауза перед повторной попыткой
+	now    func() time.Time // текущее время в часовом поясе дат задач
+}
+
+// run отправляет письма до отмены ctx.
+func (r *reminder) run(ctx context.Context) {
+	for {
+		next := r.now().Add(r.retry)
+		last, err := r.store.LastRun(ctx, emailDigestJob)
+		if err == nil {
+			next = nextDigest(r.now(), last, r.at)
+		} else if ctx.Err() == nil {
+			slog.Error("Ошибка при чтении времени последней сводки", "err", err)
+		}
+		if !sleepCtx(ctx, next.Sub(r.now())) {
+			return
+		}
+		if err == nil {
+			r.deliver(ctx)
+		}
+	}
+}
+
+// nextDigest возвращает, когда отправить следующее письмо, если последнее
+// отправлено в день last (YYYYMMDD): сегодня в at, сразу, если это время
+// прошло, или завтра в at, если сегодня письмо уже было.
+func nextDigest(now time.Time, last string, at time.Duration) time.Time {
+	sendAt := time.Date(now.Year(), now.Month(), now.Day(), 0, int(at/time.Minute), 0, 0, now.Location())
+	switch {
+	case last == now.Format(taskdate.DateFormat):
+		return sendAt.AddDate(0, 0, 1)
+	case now.Before(sendAt):
+		return sendAt
+	}
+	return now
+}
+
+// deliver отправляет письмо за сегодня и при ошибке повторяет отправку
+// один раз через retry. Затем день отмечается выполненным, даже если
+// письмо так и не ушло: следующая попытка - завтра.
+func (r *reminder) deliver(ctx context.Context) {
+	today := r.now()
+	err := r.send(ctx, today)
+	if err != nil && ctx.Err() == nil {
+		slog.Warn("Сводка по почте не отправлена, повтор позже", "retry", r.retry, "err", err)
+		if !sleepCtx(ctx, r.retry) {
+			return
+		}
+		err = r.send(ctx, today)
+	}
+	if ctx.Err() != nil {
+		slog.Warn("Отправка сводки по почте прервана остановкой сервера")
+		return
+	}
+	if err != nil {
+		slog.Error("Сводка по почте не отправлена", "err", err)
+	}
+	if err := r.store.SetLastRun(ctx, emailDigestJob, today.Format(taskdate.DateFormat)); err != nil {
+		slog.Error("Ошибка при сохранении времени последней сводки", "err", err)
+	}
+}
+
+// send составляет и отправляет письмо за день now. Если задач нет,
+// письмо не отправляется.
+func (r *reminder) send(ctx context.Context, now time.Time) error {
+	ctx = db.WithUser(ctx, db.DefaultUserID)
+	today := now.Format(taskdate.DateFormat)
+	data := digestData{Today: now.Format(notifyDateFormat)}
+	for _, section := range []struct {
+		due   string
+		tasks *[]digestTask
+	}{
+		{db.DueToday, &data.Due},
+		{db.DueOverdue, &data.Overdue},
+	} {
+		filter := db.TaskFilter{Due: section.due, Today: today}
+		tasks, err := r.store.GetTasksPage(ctx, maxDigestTasks, 0, filter)
+		if err != nil {
+			return err
+		}
+		total, err := r.store.CountTasks(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			date := task.Date
+			if t, err := time.Parse(taskdate.DateFormat, task.Date); err == nil {
+				date = t.Format(notifyDateFormat)
+			}
+			*section.tasks = append(*section.tasks, digestTask{Date: date, Title: task.Title})
+		}
+		data.More += total - len(tasks)
+	}
+	if len(data.Due) == 0 && len(data.Overdue) == 0 {
+		return nil
+	}
+
+	text, html, err := renderDigest(data)
+	if err != nil {
+		return err
+	}
+	msg := mail.Message{From: r.from, To: r.to, Subject: "Задачи на " + data.Today, Text: text, HTML: html}
+	if err := r.mailer.Send(ctx, msg); err != nil {
+		return err
+	}
+	slog.Info("Отправлена сводка по почте", "today", len(data.Due), "overdue", len(data.Overdue))
+	return nil
+}
+
+// sleepCtx ждет d и возвращает false, если раньше отменен ctx.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go1f/pkg/db"
+	"go1f/pkg/mail"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderDigest(t *testing.T) {
+	text, html, err := renderDigest(digestData{
+		Today:   "01.07.2025",
+		Due:     []digestTask{{Date: "01.07.2025", Title: "Позвонить маме"}},
+		Overdue: []digestTask{{Date: "28.06.2025", Title: "Оплатить <интернет>"}, {Date: "30.06.2025", Title: "Вынести мусор"}},
+		More:    3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `Задачи на 01.07.2025
+
+Сегодня:
+- Позвонить маме
+
+Просрочено:
+- 28.06.2025 Оплатить <интернет>
+- 30.06.2025 Вынести мусор
+
+... и еще 3
+`, text)
+	assert.Equal(t, `<!DOCTYPE html>
+<html><body>
+<h2>Задачи на 01.07.2025</h2>
+<h3>Сегодня</h3>
+<ul>
+<li>Позвонить маме</li>
+</ul>
+<h3>Просрочено</h3>
+<ul>
+<li>28.06.2025 &mdash; Оплатить &lt;интернет&gt;</li>
+<li>30.06.2025 &mdash; Вынести мусор</li>
+</ul>
+<p>... и еще 3</p>
+</body></html>
+`, html)
+}
+
+func TestNextDigest(t *testing.T) {
+	at := 8 * time.Hour
+	morning := time.Date(2025, 7, 1, 7, 0, 0, 0, time.UTC)
+	noon := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 7, 1, 8, 0, 0, 0, time.UTC), nextDigest(morning, "20250630", at))
+	// время прошло, а письма сегодня не было (сервер был выключен) - сразу
+	assert.Equal(t, noon, nextDigest(noon, "20250630", at))
+	assert.Equal(t, noon, nextDigest(noon, "", at))
+	// после перезапуска в тот же день - только завтра
+	assert.Equal(t, time.Date(2025, 7, 2, 8, 0, 0, 0, time.UTC), nextDigest(noon, "20250701", at))
+}
+
+// memReminderStore отдает задачи по фильтру Due и хранит день последней сводки.
+type memReminderStore struct {
+	mu    sync.Mutex
+	tasks []*db.Task
+	last  string
+}
+
+func (s *memReminderStore) match(filter db.TaskFilter) []*db.Task {
+	var tasks []*db.Task
+	for _, task := range s.tasks {
+		if filter.Due == db.DueToday && task.Date == filter.Today ||
+			filter.Due == db.DueOverdue && task.Date < filter.Today {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+func (s *memReminderStore) GetTasksPage(ctx context.Context, limit, offset int, filter db.TaskFilter) ([]*db.Task, error) {
+	return s.match(filter), nil
+}
+
+func (s *memReminderStore) CountTasks(ctx context.Context, filter db.TaskFilter) (int, error) {
+	return len(s.match(filter)), nil
+}
+
+func (s *memReminderStore) LastRun(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last, nil
+}
+
+func (s *memReminderStore) SetLastRun(ctx context.Context, name, day string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = day
+	return nil
+}
+
+// flakyMailer запоминает письма; первые fail отправок завершаются ошибкой.
+type flakyMailer struct {
+	mu    sync.Mutex
+	fail  int
+	calls int
+	sent  []mail.Message
+}
+
+func (m *flakyMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	if m.calls <= m.fail {
+		return errors.New("421 попробуйте позже")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestReminderDeliver(t *testing.T) {
+	now := time.Date(2025, 7, 1, 8, 0, 0, 0, time.UTC)
+	store := &memReminderStore{tasks: []*db.Task{
+		{ID: "1", Date: "20250630", Title: "Оплатить интернет"},
+		{ID: "2", Date: "20250701", Title: "Позвонить маме"},
+		{ID: "3", Date: "20250702", Title: "Будущая"},
+	}}
+	mailer := &flakyMailer{fail: 1}
+	r := &reminder{store: store, mailer: mailer, from: "todo@example.com", to: []string{"me@example.com"},
+		at: 8 * time.Hour, retry: time.Millisecond, now: func() time.Time { return now }}
+
+	// первая ошибка повторяется один раз
+	r.deliver(context.Background())
+	assert.Equal(t, 2, mailer.calls)
+	require.Len(t, mailer.sent, 1)
+	msg := mailer.sent[0]
+	assert.Equal(t, "Задачи на 01.07.2025", msg.Subject)
+	assert.Equal(t, []string{"me@example.com"}, msg.To)
+	assert.Contains(t, msg.Text, "- Позвонить маме")
+	assert.Contains(t, msg.Text, "- 30.06.2025 Оплатить интернет")
+	assert.NotContains(t, msg.Text, "Будущая")
+	assert.Equal(t, "20250701", store.last)
+
+	// после второй ошибки день все равно отмечается
+	now = now.AddDate(0, 0, 1)
+	mailer.fail, mailer.calls = 2, 0
+	r.deliver(context.Background())
+	assert.Equal(t, 2, mailer.calls)
+	assert.Len(t, mailer.sent, 1)
+	assert.Equal(t, "20250702", store.last)
+}
+
+func TestReminderNoRepeatAfterRestart(t *testing.T) {
+	now := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	store := &memReminderStore{tasks: []*db.Task{{ID: "1", Date: "20250701", Title: "Позвонить маме"}}, last: "20250701"}
+	mailer := &flakyMailer{}
+	r := &reminder{store: store, mailer: mailer, at: 8 * time.Hour, retry: time.Millisecond,
+		now: func() time.Time { return now }}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	r.run(ctx)
+	assert.Zero(t, mailer.calls)
+}
@@ -1,24 +1,259 @@
 // Package server предоставляет функционал для запуска HTTP-сервера приложения.
-// Сервер использует порт, указанный в переменной окружения TODO_PORT.
+// Сервер слушает адрес TODO_LISTEN_ADDR или порт TODO_PORT на всех интерфейсах.
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"go1f/pkg/api"
 	"go1f/pkg/config"
+	"go1f/pkg/mail"
+	"go1f/pkg/telegram"
+	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-// Run запускает HTTP-сервер приложения.
-// Инициализирует API и начинает прослушивание указанного порта.
+// Run запускает HTTP-сервер приложения с конфигурацией c.
+// Инициализирует API с хранилищем задач store и файлами веб-интерфейса files и начинает прослушивание указанного порта.
 // Возвращает ошибку в случае проблем с запуском сервера.
 //
-// Порт для прослушивания берется из переменной окружения TODO_PORT.
-func Run() error {
+// При получении SIGINT или SIGTERM сервер перестает принимать соединения и ждет
+// завершения начатых запросов не дольше TODO_SHUTDOWN_TIMEOUT.
+// При получении SIGHUP конфигурация перечитывается без остановки сервера
+// (см. reloader).
+// Run возвращает управление только после остановки сервера, поэтому после него
+// можно безопасно закрыть БД.
+//
+// Пока работает сервер, события задач отправляются на вебхуки (см. api.StartWebhooks)
+// и клиентам потока /api/events, которые отключаются в начале остановки (см. api.StartEvents).
+// При остановке события, уже поставленные в очередь, отправляются в пределах
+// того же TODO_SHUTDOWN_TIMEOUT.
+//
+// Если задан TODO_BACKUP_INTERVAL, пока работает сервер, в TODO_BACKUP_DIR
+// по расписанию сохраняются резервные копии БД (см. backupScheduler).
+//
+// Если заданы TODO_TELEGRAM_TOKEN и TODO_TELEGRAM_CHAT_ID, бот раз
+// в TODO_TELEGRAM_INTERVAL присылает сводку задач на сегодня и просроченных
+// (см. notifier).
+//
+// Если заданы TODO_SMTP_HOST, TODO_SMTP_FROM и TODO_SMTP_TO, каждый день
+// в TODO_SMTP_AT на почту приходит такая же сводка (см. reminder).
+//
+// Адрес для прослушивания берется из TODO_LISTEN_ADDR, без него - порт TODO_PORT.
+//
+// Если заданы TODO_TLS_CERT и TODO_TLS_KEY, сервер работает по HTTPS, а с порта
+// TODO_HTTP_REDIRECT_PORT, если он задан, перенаправляет HTTP-запросы на HTTPS.
+//
+// Файлы веб-интерфейса сервер отдает из files.
+func Run(c config.Config, store api.TaskStore, files fs.FS) error {
+
+	addr := c.ListenAddr
+	if addr == "" {
+		addr = ":" + c.PortServ
+	}
+
+	var tlsConfig *tls.Config
+	if c.TLS.Enabled() {
+		var err error
+		if tlsConfig, err = loadTLS(c.TLS.Cert, c.TLS.Key); err != nil {
+			return err
+		}
+	}
+
+	h := api.Init(store, files, c)
+
+	ln, err := listen(addr)
+	if err != nil {
+		return err
+	}
+	var redirectLn net.Listener
+	if tlsConfig != nil && c.TLS.RedirectPort != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		if redirectLn, err = listen(net.JoinHostPort(host, c.TLS.RedirectPort)); err != nil {
+			ln.Close()
+			return fmt.Errorf("порт перенаправления на HTTPS: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	stopWebhooks := api.StartWebhooks()
+	api.StartEvents(ctx)
+	reloadDone := newReloader(ctx, c, store).run(hup)
+	notifyDone := startNotifier(ctx, c, store)
+	reminderDone := startReminder(ctx, c, store)
+	redirectDone := startRedirect(ctx, redirectLn, ln, c.ShutdownTimeout)
+	err = serve(ctx, ln, h, c.ShutdownTimeout, tlsConfig)
+
+	// копирование, уведомления и отправка вебхуков останавливаются вместе
+	// с сервером и до закрытия БД
+	stop()
+	webhooksCtx, cancel := context.WithTimeout(context.Background(), c.ShutdownTimeout)
+	stopWebhooks(webhooksCtx)
+	cancel()
+	<-reloadDone
+	<-notifyDone
+	<-reminderDone
+	<-redirectDone
+	return err
+}
+
+// startRedirect обслуживает на ln перенаправление HTTP-запросов на HTTPS-сервер,
+// который слушает httpsLn, до отмены ctx; при остановке начатые запросы
+// ждут не дольше grace. При ln == nil перенаправления нет.
+// Возвращаемый канал закрывается, когда перенаправление остановлено.
+func startRedirect(ctx context.Context, ln, httpsLn net.Listener, grace time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	if ln == nil {
+		close(done)
+		return done
+	}
+
+	_, port, _ := net.SplitHostPort(httpsLn.Addr().String())
+	go func() {
+		defer close(done)
+		if err := serve(ctx, ln, redirectHandler(port), grace, nil); err != nil {
+			slog.Error("Ошибка перенаправления на HTTPS", "err", err)
+		}
+	}()
+	return done
+}
+
+// startNotifier запускает уведомления Telegram о задачах на сегодня
+// и просроченных по настройкам TODO_TELEGRAM_TOKEN, TODO_TELEGRAM_CHAT_ID
+// и TODO_TELEGRAM_INTERVAL из c до отмены ctx. Возвращаемый канал закрывается,
+// когда уведомления остановлены.
+func startNotifier(ctx context.Context, c config.Config, store api.TaskStore) <-chan struct{} {
+	done := make(chan struct{})
+	if c.TelegramToken == "" {
+		close(done)
+		return done
+	}
+
+	n := &notifier{
+		store:    store,
+		sender:   telegram.New(c.TelegramToken, c.TelegramChatID),
+		interval: c.TelegramEvery,
+		now:      func() time.Time { return time.Now().In(c.TimeZone()) },
+	}
+	go func() {
+		defer close(done)
+		n.run(ctx)
+	}()
+	return done
+}
+
+// startBackups запускает автоматическое резервное копирование store по настройкам
+// TODO_BACKUP_INTERVAL и TODO_BACKUP_KEEP из c до отмены ctx. Возвращаемый канал
+// закрывается, когда копирование остановлено.
+func startBackups(ctx context.Context, c config.Config, store api.TaskStore) <-chan struct{} {
+	done := make(chan struct{})
+	interval := c.BackupInterval
+	if interval <= 0 {
+		close(done)
+		return done
+	}
+	if c.DBDriver == config.DBDriverPostgres {
+		slog.Warn("Автоматическое резервное копирование не поддерживается для PostgreSQL, используйте pg_dump")
+		close(done)
+		return done
+	}
+
+	b := &backupScheduler{
+		store:    store,
+		dir:      c.BackupDir,
+		interval: interval,
+		keep:     c.BackupKeep,
+		now:      func() time.Time { return time.Now().In(c.TimeZone()) },
+	}
+	go func() {
+		defer close(done)
+		b.run(ctx)
+	}()
+	return done
+}
+
+// startReminder запускает ежедневную сводку задач по почте по настройкам
+// TODO_SMTP_* из conf до отмены ctx. Возвращаемый канал закрывается, когда сводка
+// остановлена.
+func startReminder(ctx context.Context, conf config.Config, store api.TaskStore) <-chan struct{} {
+	done := make(chan struct{})
+	c := conf.SMTP
+	if !c.Enabled() {
+		close(done)
+		return done
+	}
+
+	r := &reminder{
+		store:  store,
+		mailer: &mail.Client{Host: c.Host, Port: c.Port, User: c.User, Pass: c.Pass},
+		from:   c.From,
+		to:     c.To,
+		at:     c.At,
+		retry:  digestRetryWait,
+		now:    func() time.Time { return time.Now().In(conf.TimeZone()) },
+	}
+	go func() {
+		defer close(done)
+		r.run(ctx)
+	}()
+	return done
+}
+
+// listen начинает прослушивание addr и сообщает в журнал адрес, который
+// получил сервер: для порта 0 он выбирается системой.
+func listen(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Сервер слушает адрес", "addr", ln.Addr().String())
+	return ln, nil
+}
+
+// serve обслуживает соединения из ln обработчиком h до отмены ctx,
+// затем плавно останавливает сервер, давая начатым запросам до grace на завершение.
+// С tlsConfig соединения обслуживаются по HTTPS, при nil - по HTTP.
+// Возвращает ошибку, если сервер упал или запросы не успели завершиться.
+func serve(ctx context.Context, ln net.Listener, h http.Handler, grace time.Duration, tlsConfig *tls.Config) error {
+	srv := &http.Server{Handler: h, TLSConfig: tlsConfig}
 
-	port := config.App.PortServ
+	errCh := make(chan error, 1)
+	go func() {
+		if tlsConfig != nil {
+			errCh <- srv.ServeTLS(ln, "", "") // сертификат уже в tlsConfig
+			return
+		}
+		errCh <- srv.Serve(ln)
+	}()
 
-	api.Init()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
 
-	return http.ListenAndServe(fmt.Sprintf(":%s", port), nil)
+	slog.Info("Получен сигнал остановки, ждем завершения запросов...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("остановка сервера: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("Сервер остановлен")
+	return nil
 }
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert создает самоподписанный сертификат для 127.0.0.1 во временном
+// каталоге и возвращает пути к файлам сертификата и ключа.
+func writeCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestServeTLS(t *testing.T) {
+	certFile, keyFile := writeCert(t)
+	tlsConfig, err := loadTLS(certFile, keyFile)
+	require.NoError(t, err)
+
+	ln, err := listen("127.0.0.1:0")
+	require.NoError(t, err)
+	redirectLn, err := listen("127.0.0.1:0")
+	require.NoError(t, err)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotNil(t, r.TLS)
+		io.WriteString(w, "защищено")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, ln, h, time.Second, tlsConfig) }()
+	redirectDone := startRedirect(ctx, redirectLn, ln, time.Second)
+	defer func() {
+		cancel()
+		assert.NoError(t, <-done)
+		<-redirectDone
+	}()
+
+	certPEM, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(certPEM))
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}},
+		Timeout:   5 * time.Second,
+	}
+
+	resp, err := client.Get("https://" + ln.Addr().String() + "/api/tasks")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "защищено", string(body))
+	assert.GreaterOrEqual(t, resp.TLS.Version, uint16(tls.VersionTLS12))
+
+	// клиенты старше TLS 1.2 не подключаются
+	old := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}
+	_, err = tls.Dial("tcp", ln.Addr().String(), old)
+	assert.Error(t, err)
+
+	// HTTP на порту перенаправления ведет на тот же путь по HTTPS
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err = client.Get("http://" + redirectLn.Addr().String() + "/api/tasks?limit=5")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "https://"+ln.Addr().String()+"/api/tasks?limit=5", resp.Header.Get("Location"))
+}
+
+func TestRedirectHandler(t *testing.T) {
+	for _, tc := range []struct{ port, host, want string }{
+		{"443", "example.com", "https://example.com/web/?x=1"},
+		{"443", "example.com:80", "https://example.com/web/?x=1"},
+		{"8443", "example.com:8080", "https://example.com:8443/web/?x=1"},
+		{"443", "[::1]:80", "https://[::1]/web/?x=1"},
+		{"8443", "[::1]", "https://[::1]:8443/web/?x=1"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/web/?x=1", nil)
+		r.Host = tc.host
+		w := httptest.NewRecorder()
+		redirectHandler(tc.port).ServeHTTP(w, r)
+		assert.Equal(t, http.StatusMovedPermanently, w.Code, tc.host)
+		assert.Equal(t, tc.want, w.Header().Get("Location"), tc.host)
+	}
+}
+
+func TestLoadTLSErrors(t *testing.T) {
+	certFile, keyFile := writeCert(t)
+	_, err := loadTLS(certFile, filepath.Join(t.TempDir(), "missing.pem"))
+	assert.ErrorContains(t, err, "missing.pem")
+	_, err = loadTLS(keyFile, certFile)
+	assert.Error(t, err)
+}
@@ -0,0 +1,36 @@
+package taskdate
+
+import (
+	"fmt"
+	"time"
+)
+
+// ISOFormat - формат даты ISO 8601 (YYYY-MM-DD), который API принимает
+// наравне с DateFormat и по запросу возвращает в ответах.
+const ISOFormat = "2006-01-02"
+
+// inputFormats - форматы дат, которые принимаются на входе API.
+// Длина у них разная, поэтому строка подходит не больше чем под один.
+// DD.MM.YYYY понимает только поиск задач: в полях задачи его отклоняют тесты API.
+var inputFormats = []string{DateFormat, ISOFormat}
+
+// NormalizeDate разбирает дату в формате YYYYMMDD или YYYY-MM-DD
+// и возвращает её во внутреннем формате DateFormat.
+func NormalizeDate(s string) (string, error) {
+	for _, layout := range inputFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(DateFormat), nil
+		}
+	}
+	return "", fmt.Errorf("неверная дата %q: ожидается YYYYMMDD или YYYY-MM-DD", s)
+}
+
+// ISODate переводит дату из формата DateFormat в ISOFormat.
+// Строка в другом формате возвращается без изменений.
+func ISODate(s string) string {
+	t, err := time.Parse(DateFormat, s)
+	if err != nil {
+		return s
+	}
+	return t.Format(ISOFormat)
+}
@@ -0,0 +1,120 @@
+package taskdate
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRepeatFinished возвращается NextDate, когда правило с ограничением
+// until= или count= исчерпано и следующего выполнения нет.
+var ErrRepeatFinished = errors.New("repeat rule finished")
+
+// Ограничения правила повтора, записываются в конце правила через пробел.
+const (
+	untilPrefix = "until=" // "until=YYYYMMDD" - последняя допустимая дата выполнения
+	countPrefix = "count=" // "count=N" - сколько выполнений осталось, считая текущее
+	max_count   = 10000    // Максимальное значение count
+)
+
+// repeatLimits - ограничения правила повтора.
+type repeatLimits struct {
+	until time.Time // нулевое значение - без ограничения
+	count int       // 0 - без ограничения
+}
+
+// splitLimits отделяет от правила repeat ограничения until= и count=.
+// Возвращает само правило без ограничений.
+func splitLimits(repeat string) (string, repeatLimits, error) {
+	var limits repeatLimits
+	parts := strings.Split(repeat, " ")
+	end := len(parts)
+	for end > 1 {
+		part := parts[end-1]
+		switch {
+		case strings.HasPrefix(part, untilPrefix) && limits.until.IsZero():
+			until, err := time.Parse(DateFormat, part[len(untilPrefix):])
+			if err != nil {
+				return "", limits, repeatError("end date %q must be in YYYYMMDD format", part)
+			}
+			limits.until = until
+		case strings.HasPrefix(part, countPrefix) && limits.count == 0:
+			count, err := strconv.Atoi(part[len(countPrefix):])
+			if err != nil || count < 1 || count > max_count {
+				return "", limits, repeatError("occurrence count %q must be from 1 to %d", part, max_count)
+			}
+			limits.count = count
+		case strings.HasPrefix(part, untilPrefix) || strings.HasPrefix(part, countPrefix):
+			return "", limits, repeatError("limit %q is repeated", part)
+		default:
+			return strings.Join(parts[:end], " "), limits, nil
+		}
+		end--
+	}
+	if strings.HasPrefix(parts[0], untilPrefix) || strings.HasPrefix(parts[0], countPrefix) {
+		return "", limits, repeatError("limit %q needs a repeat rule before it", parts[0])
+	}
+	return strings.Join(parts[:end], " "), limits, nil
+}
+
+// NextRepeat возвращает правило для следующего выполнения задачи:
+// ограничение count= уменьшается на единицу, остальное не меняется.
+// Вызывается после успешного NextDate, поэтому count не становится меньше 1.
+func NextRepeat(repeat string) string {
+	_, limits, err := splitLimits(repeat)
+	if err != nil || limits.count < 2 {
+		return repeat
+	}
+	parts := strings.Split(repeat, " ")
+	for i, part := range parts {
+		if strings.HasPrefix(part, countPrefix) {
+			parts[i] = countPrefix + strconv.Itoa(limits.count-1)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// RepeatEnded сообщает, что правило repeat не допускает выполнений после dstart:
+// например, дата until= раньше даты задачи.
+func RepeatEnded(dstart, repeat string) bool {
+	_, limits, err := splitLimits(repeat)
+	if err != nil || limits.until.IsZero() {
+		return false
+	}
+	date, err := time.Parse(DateFormat, dstart)
+	return err == nil && date.After(limits.until)
+}
+
+// ErrAllExcluded возвращается NextDateExcluding, когда за max_wsteps
+// выполнений подряд не нашлось даты вне списка исключений.
+var ErrAllExcluded = errors.New("all repeat dates are excluded")
+
+// NextDateExcluding вычисляет следующую дату как NextDate, пропуская даты
+// из exclude (например, праздники): с каждой исключенной даты правило
+// применяется снова. Число пропусков ограничено max_wsteps.
+// Пропущенные даты не уменьшают ограничение count=.
+func NextDateExcluding(now time.Time, dstart, repeat string, exclude []string) (string, error) {
+	next, err := NextDate(now, dstart, repeat)
+	if err != nil || len(exclude) == 0 {
+		return next, err
+	}
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, date := range exclude {
+		excluded[date] = true
+	}
+	for i := 0; excluded[next]; i++ {
+		if i == max_wsteps {
+			return "", ErrAllExcluded
+		}
+		date, err := time.ParseInLocation(DateFormat, next, now.Location())
+		if err != nil {
+			return "", errForamt
+		}
+		if next, err = NextDate(date, next, repeat); err != nil {
+			return "", err
+		}
+	}
+	return next, nil
+}
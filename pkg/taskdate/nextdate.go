@@ -12,9 +12,14 @@
 //   - Правила повторения:
 //   - "y"       — ежегодно.
 //   - "d N"     — каждые N дней (1 ≤ N ≤ 400).
-//   - "w D1,D2" — по дням недели (1-7, где 1-понедельник, 7-воскресенье).
-//   - "m D1,D2 [M1,M2]" — по дням месяца (1-31, -1 — последний день, -2 — предпоследний)
+//   - "w [N] D1,D2 [M1,M2]" — по дням недели (1-7, где 1-понедельник, 7-воскресенье)
+//     каждые N недель (1 ≤ N ≤ 52, по умолчанию 1) с опциональным списком месяцев (1-12).
+//   - "m D1,D2 [M1,M2 | /N]" — по дням месяца (1-31, -1 — последний день, -2 — предпоследний)
+//     с опциональным списком месяцев (1-12) или каждые N месяцев (1 ≤ N ≤ 24).
+//   - "mw N D [M1,M2]" — N-й день недели D месяца (N 1-5, -1 — последний; D 1-7)
 //     с опциональным списком месяцев (1-12).
+//   - В конце любого правила можно указать ограничения "until=YYYYMMDD" (последняя дата)
+//     и "count=N" (сколько выполнений осталось), например "d 1 count=14".
 package taskdate
 
 import (
@@ -30,22 +35,33 @@ var errForamt = fmt.Errorf("error format dstart or repeat")
 
 // Константы для валидации:
 const (
-	DateFormat = "20060102" // Формат даты (YYYYMMDD)
-	max_day    = 400        // Максимальный интервал для ежедневного повтора
-	max_wday   = 7          // Максимальное количество дней в неделе
-	max_month  = 12         // Максимальное количество месяцев
+	DateFormat  = "20060102" // Формат даты (YYYYMMDD)
+	max_day     = 400        // Максимальный интервал для ежедневного повтора
+	max_wday    = 7          // Максимальное количество дней в неделе
+	max_month   = 12         // Максимальное количество месяцев
+	max_wsteps  = 400        // Предел шагов поиска по дням недели (больше года с запасом)
+	max_wweeks  = 52         // Максимальный интервал недельного правила в неделях
+	max_mmonths = 24         // Максимальный интервал месячного правила в месяцах
+	max_nth     = 5          // Максимальный номер дня недели в месяце
+	max_nsteps  = 12 * 29    // Предел месяцев поиска по n-му дню недели (5-е воскресенье февраля бывает раз в 28 лет)
+	max_msteps  = 12 * 9     // Предел месяцев поиска по дням месяца (29 февраля бывает раз в 8 лет)
+
+	secondsPerDay = 24 * 60 * 60
 )
 
 // nextDate рассчитывает следующую дату выполнения задачи на основе правила повтора.
 //
 // Параметры:
-//   - now: текущее время для сравнения
+//   - now: текущее время для сравнения; даты считаются в его часовом поясе
 //   - dstart: начальная дата в формате "YYYYMMDD"
 //   - repeat: правило повтора в формате:
 //   - "y" - ежегодно
 //   - "d N" - каждые N дней (1 ≤ N ≤ 400)
-//   - "w D1,D2,..." - по дням недели (1-7, где 1-понедельник, 7-воскресенье)
-//   - "m D1,D2,... [M1,M2,...]" - по дням месяца (1-31, -1 - последний день, -2 - предпоследний)
+//   - "w [N] D1,D2,... [M1,M2,...]" - по дням недели (1-7, где 1-понедельник, 7-воскресенье)
+//     каждые N недель, считая от недели dstart, с опциональным списком месяцев (1-12)
+//   - "m D1,D2,... [M1,M2,... | /N]" - по дням месяца (1-31, -1 - последний день, -2 - предпоследний)
+//     с опциональным списком месяцев (1-12) или каждые N месяцев, считая от месяца dstart
+//   - "mw N D [M1,M2,...]" - N-й (1-5, -1 - последний) день недели D (1-7) месяца
 //     с опциональным списком месяцев (1-12)
 //
 // Возвращает:
@@ -56,9 +72,38 @@ func NextDate(now time.Time, dstart string, repeat string) (string, error) {
 	if repeat == "" { // разовая задача,  будет удалена после
 		return "", nil
 	}
+	if err := ValidateRepeat(repeat); err != nil {
+		return "", err
+	}
+	rule, limits, err := splitLimits(repeat)
+	if err != nil {
+		return "", err
+	}
+	// текущее выполнение последнее
+	if limits.count == 1 {
+		return "", ErrRepeatFinished
+	}
+
+	next, err := nextDate(now, dstart, rule)
+	if err != nil {
+		return "", err
+	}
+	// после 9999 года дата не укладывается в формат YYYYMMDD
+	if len(next) != len(DateFormat) {
+		return "", errForamt
+	}
+	if !limits.until.IsZero() && next > limits.until.Format(DateFormat) {
+		return "", ErrRepeatFinished
+	}
+	return next, nil
+}
+
+// nextDate вычисляет следующую дату по проверенному правилу repeat.
+func nextDate(now time.Time, dstart string, repeat string) (string, error) {
 
 	// париснг repeat, dstart
-	date, err := time.Parse(DateFormat, dstart)
+	// дата задачи - полночь в часовом поясе now
+	date, err := time.ParseInLocation(DateFormat, dstart, now.Location())
 	if err != nil {
 		return "", errForamt
 	}
@@ -68,55 +113,210 @@ func NextDate(now time.Time, dstart string, repeat string) (string, error) {
 
 	switch rule[0] {
 	case "y":
-		for {
-			date = date.AddDate(1, 0, 0)
-			if afterNow(date, now) {
-				return date.Format(DateFormat), nil
-			}
-		}
+		return nextYear(date, now).Format(DateFormat), nil
 	case "d":
 		if ruleLen < 2 {
 			return "", errForamt
 		}
 		interval, err := strconv.Atoi(rule[1])
-		if err != nil || interval < 0 || interval > max_day {
+		if err != nil || interval < 1 || interval > max_day {
 			return "", errForamt
 		}
-
-		for {
-			date = date.AddDate(0, 0, interval)
-			if afterNow(date, now) {
-				return date.Format(DateFormat), nil
-			}
-		}
+		return nextInterval(date, now, interval).Format(DateFormat), nil
 	case "w":
-		if ruleLen < 2 {
+		interval, args, err := weekInterval(rule[1:])
+		if err != nil {
+			return "", err
+		}
+		if len(args) < 1 || len(args) > 2 {
 			return "", errForamt
 		}
-		dmap, err := parseWeek(rule[1])
+		dmap, err := parseWeek(args[0])
 		if err != nil {
 			return "", err
 		}
-		for {
-			date = date.AddDate(0, 0, 1)
-			weekday := int(date.Weekday())
-			if weekday == 0 {
-				weekday = 7
-			}
-			if dmap[weekday] && afterNow(date, now) {
-				return date.Format(DateFormat), nil
-			}
+		month, err := parseMonth(args[1:])
+		if err != nil {
+			return "", err
 		}
+		return findWeekDay(now, date, dmap, month, interval)
 	case "m":
 		if ruleLen < 2 {
 			return "", errForamt
 		}
-		return findMonthDay(now, date, rule[1], rule[2:]...)
+		interval, months, err := monthInterval(rule[2:])
+		if err != nil {
+			return "", err
+		}
+		return findMonthDay(now, date, rule[1], interval, months...)
+	case "mw":
+		if ruleLen < 3 || ruleLen > 4 {
+			return "", errForamt
+		}
+		n, err := strconv.Atoi(rule[1])
+		if err != nil {
+			return "", errForamt
+		}
+		weekday, err := strconv.Atoi(rule[2])
+		if err != nil {
+			return "", errForamt
+		}
+		month, err := parseMonth(rule[3:])
+		if err != nil {
+			return "", err
+		}
+		return findNthWeekday(now, date, n, weekday, month)
 	default:
 		return "", errForamt
 	}
 }
 
+// nextYear возвращает первую годовщину date после now.
+//
+// Число лет вычисляется сразу, а не прибавлением по одному году.
+// Результат совпадает с пошаговым прибавлением: 29 февраля на первом
+// шаге становится 1 марта и дальше остается 1 марта.
+func nextYear(date, now time.Time) time.Time {
+	years := max(1, now.Year()-date.Year()-1)
+	next := addYears(date, years)
+	for !afterNow(next, now) {
+		years++
+		next = addYears(date, years)
+	}
+	return next
+}
+
+// addYears прибавляет к date years лет так же, как years раз date.AddDate(1, 0, 0).
+func addYears(date time.Time, years int) time.Time {
+	if date.Month() == time.February && date.Day() == 29 {
+		return time.Date(date.Year()+years, time.March, 1, 0, 0, 0, 0, date.Location())
+	}
+	return date.AddDate(years, 0, 0)
+}
+
+// nextInterval возвращает первую дату date + k*interval дней (k ≥ 1) после now.
+// Число интервалов вычисляется по количеству календарных дней между date и now,
+// цикл после этого делает не больше пары шагов.
+func nextInterval(date, now time.Time, interval int) time.Time {
+	steps := 1
+	if now.After(date) {
+		days := daysBetween(date, now)
+		steps = days/interval + 1
+	}
+	next := date.AddDate(0, 0, steps*interval)
+	for !afterNow(next, now) {
+		next = next.AddDate(0, 0, interval)
+	}
+	return next
+}
+
+// weekInterval отделяет от аргументов недельного правила интервал N (1 ≤ N ≤ 52).
+// Без интервала правило срабатывает каждую неделю (N = 1).
+// Прежняя запись интервала "/N" тоже принимается.
+func weekInterval(args []string) (int, []string, error) {
+	var number string
+	switch {
+	case len(args) > 0 && strings.HasPrefix(args[0], "/"):
+		number = args[0][1:]
+	case hasWeekInterval(args):
+		number = args[0]
+	default:
+		return 1, args, nil
+	}
+	interval, err := strconv.Atoi(number)
+	if err != nil || interval < 1 || interval > max_wweeks {
+		return 0, nil, repeatError("week interval %q must be from 1 to %d", args[0], max_wweeks)
+	}
+	return interval, args[1:], nil
+}
+
+// hasWeekInterval сообщает, начинаются ли аргументы недельного правила с интервала.
+// Из трех аргументов первый - всегда интервал ("w N D M"). Два аргумента
+// "w A B" читаются как интервал A и дни недели B, если A - одно число,
+// а B - список дней недели, иначе как дни недели A в месяцах B:
+// "w 2 1" - каждый второй понедельник, "w 6 11,12,1" - субботы с ноября по январь.
+// Один день недели в месяцах с 1 по 7 записывается с интервалом: "w 1 6 1,2,3".
+func hasWeekInterval(args []string) bool {
+	switch len(args) {
+	case 3:
+		return true
+	case 2:
+		_, err := validateList(args[1], "week day", 1, max_wday)
+		return !strings.Contains(args[0], ",") && err == nil
+	}
+	return false
+}
+
+// LegacyWeekRule переводит правило "w D M", записанное до появления интервала
+// "w N D", в однозначную запись "w 1 D M". Правило с одним днем недели D
+// и месяцами M от 1 до 7 теперь читалось бы как каждые D недель по дням M
+// (см. hasWeekInterval). Остальные правила возвращаются без изменений.
+func LegacyWeekRule(repeat string) string {
+	fields := strings.Split(repeat, " ")
+	args := fields[1:]
+	positional := 0
+	for positional < len(args) && !strings.Contains(args[positional], "=") {
+		positional++
+	}
+	if fields[0] != "w" || positional != 2 || strings.HasPrefix(args[0], "/") || !hasWeekInterval(args[:2]) {
+		return repeat
+	}
+	return "w 1 " + strings.Join(args, " ")
+}
+
+// weekStart возвращает понедельник недели, на которую приходится t.
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+// findWeekDay находит следующую дату для недельного правила повтора.
+//
+// Если дата задачи в прошлом, поиск начинается с текущего дня, а не перебирает
+// все дни от даты задачи. Недопустимые месяцы пропускаются целиком.
+// При интервале больше недели подходят только недели, отстоящие от недели
+// даты задачи на кратное interval число недель, остальные пропускаются целиком.
+// Любая комбинация дней недели и месяцев дает дату в пределах года на каждую
+// неделю интервала, поэтому число шагов ограничено max_wsteps*interval.
+func findWeekDay(now, date time.Time, dmap, month map[int]bool, interval int) (string, error) {
+
+	anchor := weekStart(date)
+	if now.After(date) {
+		date = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, date.Location())
+	}
+
+	for i := 0; i < max_wsteps*interval; i++ {
+		date = date.AddDate(0, 0, 1)
+		if !month[int(date.Month())] {
+			// переходим к последнему дню месяца, следующий шаг попадет на 1-е число
+			date = lastDayOfMonth(date)
+			continue
+		}
+		weeks := daysBetween(anchor, weekStart(date)) / 7
+		if weeks%interval != 0 {
+			// переходим к воскресенью, следующий шаг попадет на понедельник
+			date = weekStart(date).AddDate(0, 0, 6)
+			continue
+		}
+		weekday := int(date.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		if dmap[weekday] && afterNow(date, now) {
+			return date.Format(DateFormat), nil
+		}
+	}
+	return "", errForamt
+}
+
+// daysBetween возвращает число календарных дней от даты a до даты b.
+// Время суток и переходы на летнее время не влияют на результат.
+func daysBetween(a, b time.Time) int {
+	ua := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	ub := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int((ub.Unix() - ua.Unix()) / secondsPerDay)
+}
+
 // afterNow проверяет, что дата находится после текущего времени.
 func afterNow(date, now time.Time) bool {
 	return date.After(now)
@@ -151,6 +351,10 @@ func parseWeek(s string) (map[int]bool, error) {
 // Возвращает ошибку при неверном формате.
 func parseMonth(months []string) (map[int]bool, error) {
 	monthMap := make(map[int]bool)
+	if len(months) > 1 {
+		// например, "m 15 3,6 /2": список месяцев и интервал взаимоисключающие
+		return monthMap, repeatError("month list %q cannot be combined with %q", months[0], months[1])
+	}
 	if len(months) > 0 {
 		monthStrs := strings.Split(months[0], ",")
 		for _, monthStr := range monthStrs {
@@ -169,6 +373,19 @@ func parseMonth(months []string) (map[int]bool, error) {
 
 }
 
+// monthInterval отделяет от аргументов месячного правила интервал "/N" (1 ≤ N ≤ 24),
+// который заменяет список месяцев. Без интервала правило срабатывает каждый месяц (N = 1).
+func monthInterval(args []string) (int, []string, error) {
+	if len(args) != 1 || !strings.HasPrefix(args[0], "/") {
+		return 1, args, nil
+	}
+	interval, err := strconv.Atoi(args[0][1:])
+	if err != nil || interval < 1 || interval > max_mmonths {
+		return 0, nil, repeatError("month interval %q must be from /1 to /%d", args[0], max_mmonths)
+	}
+	return interval, nil, nil
+}
+
 // arrangeSpecialDays упорядочивает дни месяца, помещая специальные дни (-1, -2) в конец.
 func arrangeSpecialDays(days []int) []int {
 	var regularDays, minusTwo, minusOne []int
@@ -219,7 +436,7 @@ func stringsToInts(strs []string) ([]int, error) {
 	ints := make([]int, 0, len(strs))
 	for _, s := range strs {
 		num, err := strconv.Atoi(s)
-		if err != nil || num < -2 || num > 32 || num == 0 {
+		if err != nil || num < -2 || num > 31 || num == 0 {
 			return nil, errForamt
 		}
 		ints = append(ints, num)
@@ -232,8 +449,13 @@ func stringsToInts(strs []string) ([]int, error) {
 // Алгоритм:
 // 1. Проверяет доступные месяцы
 // 2. Для каждого месяца проверяет указанные дни
-// 3. Возвращает первую дату после now
-func findMonthDay(now, date time.Time, daysStr string, months ...string) (string, error) {
+// 3. Возвращает первую дату после now и после date
+//
+// При интервале больше месяца подходят только месяцы, отстоящие от месяца
+// date на кратное interval число месяцев.
+// Для правил без подходящих дней (например, "m 31 2" или "m 31 /12" от апреля)
+// поиск ограничен max_msteps*interval месяцами и возвращает ошибку формата.
+func findMonthDay(now, date time.Time, daysStr string, interval int, months ...string) (string, error) {
 
 	month, err := parseMonth(months)
 	if err != nil {
@@ -245,17 +467,20 @@ func findMonthDay(now, date time.Time, daysStr string, months ...string) (string
 		return "", err
 	}
 
-	// Начинаем с первого дня следующего месяца, если день начала уже прошел в текущем месяце
-	// напрмиер  Текущая дата: 15 января, а дата задачи 10 январи
-	// и повтор каждый месяц, мы сразу перейдем на месяц вперед
-	if !afterNow(date, now) {
-		date = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location()).AddDate(0, 1, 0)
+	// Следующая дата должна быть позже и текущей даты, и даты начала задачи.
+	// Поиск начинаем с первого дня месяца той из них, что позже:
+	// например, текущая дата 15 января, а дата задачи 10 января -
+	// проверяем январь начиная с 16-го, затем следующие месяцы
+	anchor := monthIndex(date)
+	if afterNow(date, now) {
+		now = date
 	}
+	date = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, date.Location())
 
-	for {
+	for i := 0; i < max_msteps*interval; i++ {
 		currentMonth := int(date.Month())
-		// проверяем доступность месяца
-		if !month[currentMonth] {
+		// проверяем доступность месяца и интервал от месяца даты задачи
+		if !month[currentMonth] || (monthIndex(date)-anchor)%interval != 0 {
 			date = date.AddDate(0, 1, 0)
 			continue
 		}
@@ -286,4 +511,56 @@ func findMonthDay(now, date time.Time, daysStr string, months ...string) (string
 		// Переход к следующему месяцу
 		date = date.AddDate(0, 1, 0)
 	}
+	return "", errForamt
+}
+
+// monthIndex возвращает порядковый номер месяца t от начала летоисчисления.
+func monthIndex(t time.Time) int {
+	return t.Year()*max_month + int(t.Month()) - 1
+}
+
+// findNthWeekday находит следующую дату для правила "mw" - n-го дня недели weekday месяца.
+//
+// n от 1 до 5 или -1 для последнего такого дня месяца. Месяцы, в которых
+// n-го дня недели нет (например, пятого вторника), пропускаются.
+// Как и findMonthDay, возвращает первую дату после now и после date.
+func findNthWeekday(now, date time.Time, n, weekday int, month map[int]bool) (string, error) {
+
+	if afterNow(date, now) {
+		now = date
+	}
+	date = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, date.Location())
+
+	for i := 0; i < max_nsteps; i++ {
+		if month[int(date.Month())] {
+			target, ok := nthWeekday(date, n, weekday)
+			if ok && target.After(now) {
+				return target.Format(DateFormat), nil
+			}
+		}
+		date = date.AddDate(0, 1, 0)
+	}
+	return "", errForamt
+}
+
+// nthWeekday возвращает n-й день недели weekday (1-понедельник, 7-воскресенье)
+// месяца, на первое число которого приходится first; n = -1 - последний.
+// ok = false, если такого дня в месяце нет.
+func nthWeekday(first time.Time, n, weekday int) (time.Time, bool) {
+	if n == -1 {
+		last := lastDayOfMonth(first)
+		offset := (isoWeekday(last) - weekday + 7) % 7
+		return last.AddDate(0, 0, -offset), true
+	}
+	offset := (weekday - isoWeekday(first) + 7) % 7
+	target := first.AddDate(0, 0, offset+(n-1)*7)
+	return target, target.Month() == first.Month()
+}
+
+// isoWeekday возвращает день недели t от 1 (понедельник) до 7 (воскресенье).
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
 }
@@ -0,0 +1,564 @@
+package taskdate
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mustDate разбирает дату в формате YYYYMMDD.
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(DateFormat, s)
+	require.NoError(t, err)
+	return d
+}
+
+func TestNextDateMonth(t *testing.T) {
+	tbl := []struct {
+		now, date, repeat, want string
+	}{
+		// следующая дата в том же месяце не пропускается
+		{"20240101", "20240101", "m 1,15", "20240115"},
+		{"20240126", "20240110", "m 5,28", "20240128"},
+		// дата задачи в будущем - результат позже даты задачи
+		{"20240126", "20240320", "m 5", "20240405"},
+		{"20240126", "20240320", "m 5,25", "20240325"},
+		{"20240126", "20240409", "m 31", "20240531"},
+	}
+	for _, v := range tbl {
+		got, err := NextDate(mustDate(t, v.now), v.date, v.repeat)
+		require.NoError(t, err)
+		assert.Equal(t, v.want, got, "%v", v)
+	}
+}
+
+func TestNextDateWeekMonths(t *testing.T) {
+	tbl := []struct {
+		now, date, repeat, want string
+	}{
+		// суббота, только с ноября по март: из апреля переходим в ноябрь
+		{"20250405", "20250301", "w 6 11,12,1,2,3", "20251101"},
+		{"20250329", "20250301", "w 6 11,12,1,2,3", "20251101"},
+		// внутри разрешенного месяца работает как обычное недельное правило
+		{"20251101", "20251101", "w 6 11,12,1,2,3", "20251108"},
+		{"20251227", "20251101", "w 6 11,12,1,2,3", "20260103"},
+		// дата задачи в будущем
+		{"20250101", "20250601", "w 1,3 7", "20250702"},
+		// один день недели в одном разрешенном месяце - с явным интервалом
+		{"20240301", "20240101", "w 1 5 2", "20250207"},
+		// без списка месяцев - прежнее поведение
+		{"20240126", "20240101", "w 1", "20240129"},
+	}
+	for _, v := range tbl {
+		got, err := NextDate(mustDate(t, v.now), v.date, v.repeat)
+		require.NoError(t, err)
+		assert.Equal(t, v.want, got, "%v", v)
+	}
+
+	for _, repeat := range []string{"w 6 0", "w 6 13", "w 6 ,", "w 6 1,,2", "w 6 1 2 3"} {
+		_, err := NextDate(mustDate(t, "20250101"), "20250101", repeat)
+		assert.Error(t, err, repeat)
+	}
+}
+
+func TestNextDateWeekInterval(t *testing.T) {
+	tbl := []struct {
+		now, date, repeat, want string
+	}{
+		// дата задачи - выбранный понедельник: следующий через две недели
+		{"20250101", "20250106", "w 2 1", "20250120"},
+		// неделя 13 января не по графику, отсчет от недели даты задачи, а не от now
+		{"20250115", "20250106", "w 2 1", "20250120"},
+		{"20250120", "20250106", "w 2 1", "20250203"},
+		// переход через год
+		{"20241231", "20241230", "w 2 1,5", "20250103"},
+		{"20250103", "20241230", "w 2 1,5", "20250113"},
+		{"20241225", "20241222", "w 3 7", "20250112"},
+		{"20250112", "20241222", "w 3 7", "20250202"},
+		// с месяцами
+		{"20250110", "20250104", "w 2 6 2", "20250201"},
+		// интервал 1 - прежнее правило
+		{"20240126", "20240101", "w 1 1", "20240129"},
+		{"20240126", "20240101", "w 1 1 1", "20240129"},
+		// дни недели в месяцах без интервала
+		{"20250110", "20250104", "w 6,7 2", "20250201"},
+		// прежняя запись интервала через "/"
+		{"20250115", "20250106", "w /2 1", "20250120"},
+		{"20250110", "20250104", "w /2 6 2", "20250201"},
+	}
+	for _, v := range tbl {
+		got, err := NextDate(mustDate(t, v.now), v.date, v.repeat)
+		require.NoError(t, err)
+		assert.Equal(t, v.want, got, "%v", v)
+	}
+
+	for _, repeat := range []string{"w 0 1", "w 53 1", "w x 1 2", "w 2 1 2 3", "w /0 1", "w /53 1", "w /x 1", "w /2", "w /2 1 2 3", "w 1 /2"} {
+		_, err := NextDate(mustDate(t, "20250101"), "20250101", repeat)
+		assert.Error(t, err, repeat)
+	}
+}
+
+func TestLegacyWeekRule(t *testing.T) {
+	for repeat, want := range map[string]string{
+		"w 5 2":              "w 1 5 2",
+		"w 6 1,2,3":          "w 1 6 1,2,3",
+		"w 6 1,2,3 count=4":  "w 1 6 1,2,3 count=4",
+		"w 6 11,12,1,2,3":    "w 6 11,12,1,2,3",
+		"w 1,3 7":            "w 1,3 7",
+		"w 1":                "w 1",
+		"w /2 1":             "w /2 1",
+		"w 1 1,12":           "w 1 1,12",
+		"w 1 6 2":            "w 1 6 2",
+		"d 5 2":              "d 5 2",
+		"w 7 until=20250101": "w 7 until=20250101",
+	} {
+		assert.Equal(t, want, LegacyWeekRule(repeat), repeat)
+	}
+}
+
+func TestNextDateMonthInterval(t *testing.T) {
+	tbl := []struct {
+		now, date, repeat, want string
+	}{
+		{"20250115", "20250115", "m 15 /2", "20250315"},
+		// отсчет от месяца даты задачи, а не от now
+		{"20250401", "20250115", "m 15 /2", "20250515"},
+		// переход через год
+		{"20241201", "20241115", "m 15 /3", "20250215"},
+		{"20241231", "20240810", "m 10 /5", "20250110"},
+		// 31-е число в коротких месяцах пропускается
+		{"20250131", "20250131", "m 31 /2", "20250331"},
+		{"20250301", "20250228", "m 31 /2", "20250831"},
+		{"20250201", "20250201", "m 31 /3", "20250531"},
+		{"20250331", "20250331", "m -1 /2", "20250531"},
+		// интервал 1 - прежнее правило
+		{"20250115", "20250115", "m 15 /1", "20250215"},
+	}
+	for _, v := range tbl {
+		got, err := NextDate(mustDate(t, v.now), v.date, v.repeat)
+		require.NoError(t, err)
+		assert.Equal(t, v.want, got, "%v", v)
+	}
+
+	for _, repeat := range []string{"m 15 /0", "m 15 /25", "m 15 /x", "m 15 3,6 /2", "m 15 /2 3", "m 31 /12"} {
+		_, err := NextDate(mustDate(t, "20250401"), "20250401", repeat)
+		assert.Error(t, err, repeat)
+	}
+	err := ValidateRepeat("m 15 3,6 /2")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be combined")
+}
+
+func TestNextDateNthWeekday(t *testing.T) {
+	tbl := []struct {
+		now, date, repeat, want string
+	}{
+		// третий четверг
+		{"20250101", "20250101", "mw 3 4", "20250116"},
+		{"20250116", "20250116", "mw 3 4", "20250220"},
+		// последняя пятница февраля в високосном и обычном году
+		{"20240101", "20240101", "mw -1 5 2", "20240223"},
+		{"20240223", "20240223", "mw -1 5 2", "20250228"},
+		// пятого вторника нет в мае и июне
+		{"20250430", "20250101", "mw 5 2", "20250729"},
+		// дата задачи в будущем
+		{"20250101", "20250301", "mw 1 1", "20250303"},
+		// переход через год
+		{"20251220", "20251201", "mw 2 3 12,1", "20260114"},
+	}
+	for _, v := range tbl {
+		got, err := NextDate(mustDate(t, v.now), v.date, v.repeat)
+		require.NoError(t, err)
+		assert.Equal(t, v.want, got, "%v", v)
+	}
+
+	for _, repeat := range []string{"mw", "mw 3", "mw 0 1", "mw 6 1", "mw -2 1", "mw 1 8", "mw 1 1,2", "mw 1 1 13", "mw 1 1 1 1"} {
+		_, err := NextDate(mustDate(t, "20250101"), "20250101", repeat)
+		assert.Error(t, err, repeat)
+	}
+}
+
+func TestRepeatLimits(t *testing.T) {
+	now := mustDate(t, "20250110")
+
+	tbl := []struct {
+		now, date, repeat, want string
+	}{
+		{"20250110", "20250110", "d 1 until=20250112", "20250111"},
+		// последнее выполнение в дату until
+		{"20250111", "20250111", "d 1 until=20250112", "20250112"},
+		{"20250110", "20250110", "d 1 count=2", "20250111"},
+		{"20250110", "20250110", "w 1,5 count=3 until=20251231", "20250113"},
+	}
+	for _, v := range tbl {
+		got, err := NextDate(mustDate(t, v.now), v.date, v.repeat)
+		require.NoError(t, err, v.repeat)
+		assert.Equal(t, v.want, got, "%v", v)
+	}
+
+	for _, v := range [][2]string{
+		{"20250112", "d 1 until=20250112"},
+		{"20250110", "d 1 count=1"},
+		{"20250110", "y until=20250601"},
+		{"20250110", "mw -1 5 count=1 until=20300101"},
+	} {
+		_, err := NextDate(now, v[0], v[1])
+		assert.ErrorIs(t, err, ErrRepeatFinished, v[1])
+	}
+
+	assert.Equal(t, "d 1 count=13 until=20250601", NextRepeat("d 1 count=14 until=20250601"))
+	assert.Equal(t, "d 1 until=20250601", NextRepeat("d 1 until=20250601"))
+	assert.Equal(t, "d 1 count=1", NextRepeat("d 1 count=1"))
+
+	assert.True(t, RepeatEnded("20250110", "d 1 until=20250109"))
+	assert.False(t, RepeatEnded("20250110", "d 1 until=20250110"))
+	assert.False(t, RepeatEnded("20250110", "d 1 count=1"))
+
+	got, err := NextDates(now, "20250110", "d 1 count=3", 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20250111", "20250112"}, got)
+
+	budget := 100
+	got, err = Occurrences("20250101", "d 1 count=14", mustDate(t, "20250110"), mustDate(t, "20250131"), &budget)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20250110", "20250111", "20250112", "20250113", "20250114"}, got)
+	budget = 100
+	got, err = Occurrences("20250101", "d 7 until=20250120", mustDate(t, "20250110"), mustDate(t, "20250131"), &budget)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"20250115"}, got)
+
+	for _, repeat := range []string{"count=3", "until=20250101", "d 1 count=0", "d 1 count=x", "d 1 until=2025",
+		"d 1 count=2 count=3", "d 1 count=2 x", "d count=2"} {
+		assert.Error(t, ValidateRepeat(repeat), repeat)
+	}
+}
+
+func TestNextDateExcluding(t *testing.T) {
+	now := mustDate(t, "20250101")
+
+	// понедельники, 6 и 13 января - праздники
+	got, err := NextDateExcluding(now, "20241230", "w 1", []string{"20250106", "20250113"})
+	require.NoError(t, err)
+	assert.Equal(t, "20250120", got)
+
+	got, err = NextDateExcluding(now, "20241230", "w 1", []string{"20250107"})
+	require.NoError(t, err)
+	assert.Equal(t, "20250106", got)
+
+	// исключенные даты не уменьшают count=, но until= соблюдается
+	got, err = NextDateExcluding(now, "20250101", "d 1 count=2", []string{"20250102"})
+	require.NoError(t, err)
+	assert.Equal(t, "20250103", got)
+	_, err = NextDateExcluding(now, "20250101", "d 1 until=20250103", []string{"20250102", "20250103"})
+	assert.ErrorIs(t, err, ErrRepeatFinished)
+
+	// исключены все даты на max_wsteps выполнений вперед
+	var all []string
+	for date := now.AddDate(0, 0, 1); len(all) <= max_wsteps; date = date.AddDate(0, 0, 1) {
+		all = append(all, date.Format(DateFormat))
+	}
+	_, err = NextDateExcluding(now, "20250101", "d 1", all)
+	assert.ErrorIs(t, err, ErrAllExcluded)
+}
+
+func TestOccurrences(t *testing.T) {
+	from, to := mustDate(t, "20250120"), mustDate(t, "20250310")
+
+	tbl := []struct {
+		date, repeat string
+		want         []string
+	}{
+		{"20250131", "m -1", []string{"20250131", "20250228"}},
+		{"20241231", "m -1", []string{"20250131", "20250228"}},
+		{"20250101", "d 14", []string{"20250129", "20250212", "20250226"}},
+		{"20250303", "w 1", []string{"20250303", "20250310"}},
+		{"20250205", "", []string{"20250205"}},
+		{"20250311", "", nil},
+		{"20250401", "d 1", nil},
+	}
+	for _, v := range tbl {
+		budget := 100
+		got, err := Occurrences(v.date, v.repeat, from, to, &budget)
+		require.NoError(t, err)
+		assert.Equal(t, v.want, got, "%v", v)
+	}
+
+	budget := 10
+	_, err := Occurrences("20250120", "d 1", from, to, &budget)
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+
+	budget = 10
+	_, err = Occurrences("20250120", "q 1", from, to, &budget)
+	assert.Error(t, err)
+}
+
+func TestNextDates(t *testing.T) {
+	now := mustDate(t, "20250115")
+
+	tbl := []struct {
+		date, repeat string
+		n            int
+		want         []string
+	}{
+		{"20250110", "d 7", 3, []string{"20250117", "20250124", "20250131"}},
+		{"20250131", "m -1", 3, []string{"20250228", "20250331", "20250430"}},
+		{"20240229", "y", 2, []string{"20250301", "20260301"}},
+		{"20250101", "w 1,5", 4, []string{"20250117", "20250120", "20250124", "20250127"}},
+		{"20250101", "", 5, []string{}},
+	}
+	for _, v := range tbl {
+		got, err := NextDates(now, v.date, v.repeat, v.n)
+		require.NoError(t, err, v.repeat)
+		assert.Equal(t, v.want, got, "%v", v)
+	}
+
+	// правила, которые никогда не дают дату, не зацикливаются
+	for _, repeat := range []string{"d 0", "m 31 2", "m 30,31 2,2", "m 32", "x"} {
+		_, err := NextDates(now, "20250101", repeat, 5)
+		assert.Error(t, err, repeat)
+	}
+}
+
+func TestValidateRepeat(t *testing.T) {
+	for _, repeat := range []string{
+		"", "y", "d 1", "d 400", "w 7", "w 1,3,5", "w 1 1,12", "w 2 1", "w 52 1,7 1,12", "w 1 6 1,2", "w /2 1", "w /52 1,7 1,12",
+		"mw 1 1", "mw -1 5 2", "mw 5 7 2", "m 15 /2", "m -1 /24",
+		"m 1", "m -1,-2,31", "m 29 2", "m 31 1,2", "m 15 3,6",
+	} {
+		assert.NoError(t, ValidateRepeat(repeat), repeat)
+	}
+
+	tbl := []struct {
+		repeat, part string
+	}{
+		{"y 1", `"y"`},
+		{"d", `"d"`},
+		{"d 0", `"0"`},
+		{"d 9999", `"9999"`},
+		{"d x", `"x"`},
+		{"d 1 2", `"d"`},
+		{"w", `"w"`},
+		{"w 0", `"0"`},
+		{"w 8", `"8"`},
+		{"w 1,,2", `""`},
+		{"w 1 13", `"13"`},
+		{"w 1 2 3 4", `"w"`},
+		{"w 0 1", `"0"`},
+		{"w 53 1", `"53"`},
+		{"w /0 1", `"/0"`},
+		{"w /53 1", `"/53"`},
+		{"m", `"m"`},
+		{"m 0", `"0"`},
+		{"m 32", `"32"`},
+		{"m -3", `"-3"`},
+		{"m 1 0", `"0"`},
+		{"m 31 2,4", `"m 31 2,4"`},
+		{"m 30 2", `"m 30 2"`},
+		{"x 1", `"x"`},
+		{"q 5", `"q"`},
+		{" d 1", `""`},
+	}
+	for _, v := range tbl {
+		err := ValidateRepeat(v.repeat)
+		require.Error(t, err, v.repeat)
+		assert.Contains(t, err.Error(), v.part, v.repeat)
+	}
+}
+
+func TestNextDateFarPast(t *testing.T) {
+	now := mustDate(t, "20250115")
+	for _, v := range []struct{ date, repeat, want string }{
+		{"00010101", "d 1", "20250116"},
+		{"00010101", "d 400", "20251216"},
+		{"00010101", "y", "20260101"},
+		{"20250114", "d 1", "20250116"},
+	} {
+		got, err := NextDate(now, v.date, v.repeat)
+		require.NoError(t, err, v)
+		assert.Equal(t, v.want, got, "%v", v)
+	}
+}
+
+func FuzzNextDate(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"20240126", "d 0"}, {"00010101", "d 1"}, {"99991231", "y"}, {"20240101", "m 31 2"},
+		{"20240101", "m 29 2"}, {"20240101", "w 7 2"}, {"20240101", "m -2,-1 1,12"}, {"bad", "q"},
+		{"20240101", "mw 5 7 2"}, {"20240101", "mw -1 5"},
+		{"20240101", "d 1 count=3 until=20240102"},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+	now := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	f.Fuzz(func(t *testing.T, dstart, repeat string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			next, err := NextDate(now, dstart, repeat)
+			if err == nil && repeat != "" {
+				date, perr := time.Parse(DateFormat, next)
+				if perr != nil || !date.After(now) {
+					t.Errorf("NextDate(%q, %q) = %q, want date after now", dstart, repeat, next)
+				}
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("NextDate(%q, %q) did not terminate", dstart, repeat)
+		}
+	})
+}
+
+// loopNextDate - прежняя реализация правил "y" и "d N" прибавлением
+// по одному шагу, эталон для сравнения с вычислением без цикла.
+func loopNextDate(now time.Time, dstart, repeat string) string {
+	date, _ := time.ParseInLocation(DateFormat, dstart, now.Location())
+	years, days := 1, 0
+	if repeat != "y" {
+		years = 0
+		days, _ = strconv.Atoi(strings.TrimPrefix(repeat, "d "))
+	}
+	for {
+		date = date.AddDate(years, 0, days)
+		if date.After(now) {
+			return date.Format(DateFormat)
+		}
+	}
+}
+
+func TestNextDateArithmetic(t *testing.T) {
+	starts := []string{
+		"20000229", "20040229", "20240229", "20231231", "20240101", "20240228",
+		"20250115", "20250116", "20260301", "19700101", "20050630",
+	}
+	nows := []time.Time{
+		mustDate(t, "20250115"),
+		mustDate(t, "20240229"),
+		mustDate(t, "20280228"),
+		mustDate(t, "20280229"),
+		time.Date(2025, 1, 15, 23, 59, 0, 0, time.UTC),
+		time.Date(2025, 1, 1, 0, 30, 0, 0, time.FixedZone("MSK", 3*60*60)),
+		time.Date(2024, 12, 31, 22, 0, 0, 0, time.FixedZone("PST", -8*60*60)),
+	}
+	repeats := []string{"y", "d 1", "d 2", "d 7", "d 30", "d 365", "d 400"}
+
+	for _, now := range nows {
+		for _, dstart := range starts {
+			for _, repeat := range repeats {
+				got, err := NextDate(now, dstart, repeat)
+				require.NoError(t, err)
+				assert.Equal(t, loopNextDate(now, dstart, repeat), got, "now=%v dstart=%s repeat=%s", now, dstart, repeat)
+			}
+		}
+	}
+}
+
+func TestNextDateTimeZone(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tbl := []struct {
+		now           time.Time
+		date, repeat  string
+		want, wantUTC string
+	}{
+		// 23:30 в день перехода на летнее время - в UTC уже следующие сутки
+		{time.Date(2025, 3, 9, 23, 30, 0, 0, ny), "20250308", "d 1", "20250310", "20250311"},
+		{time.Date(2025, 3, 9, 0, 30, 0, 0, ny), "20250308", "d 1", "20250310", "20250310"},
+		{time.Date(2025, 3, 8, 23, 30, 0, 0, ny), "20250301", "d 7", "20250315", "20250315"},
+		// переход на зимнее время: 25-часовые сутки не сдвигают даты
+		{time.Date(2025, 11, 2, 23, 30, 0, 0, ny), "20251101", "d 1", "20251103", "20251104"},
+		{time.Date(2025, 11, 2, 23, 30, 0, 0, ny), "20251020", "w 1", "20251103", "20251110"},
+		{time.Date(2025, 11, 1, 23, 30, 0, 0, ny), "20250101", "m 2", "20251102", "20251202"},
+	}
+	for _, v := range tbl {
+		got, err := NextDate(v.now, v.date, v.repeat)
+		require.NoError(t, err)
+		assert.Equal(t, v.want, got, "%v", v)
+
+		got, err = NextDate(v.now.UTC(), v.date, v.repeat)
+		require.NoError(t, err)
+		assert.Equal(t, v.wantUTC, got, "UTC %v", v)
+	}
+}
+
+func BenchmarkNextDateDaily(b *testing.B) {
+	now := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	b.Run("arithmetic", func(b *testing.B) {
+		for b.Loop() {
+			NextDate(now, "20050115", "d 1")
+		}
+	})
+	b.Run("loop", func(b *testing.B) {
+		for b.Loop() {
+			loopNextDate(now, "20050115", "d 1")
+		}
+	})
+}
+
+func BenchmarkNextDateYearly(b *testing.B) {
+	now := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	b.Run("arithmetic", func(b *testing.B) {
+		for b.Loop() {
+			NextDate(now, "20040229", "y")
+		}
+	})
+	b.Run("loop", func(b *testing.B) {
+		for b.Loop() {
+			loopNextDate(now, "20040229", "y")
+		}
+	})
+}
+
+func TestRRule(t *testing.T) {
+	tbl := []struct {
+		repeat, want string
+	}{
+		{"y", "FREQ=YEARLY"},
+		{"d 3", "FREQ=DAILY;INTERVAL=3"},
+		{"w 1,5", "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"w 2 7 6,7,8", "FREQ=WEEKLY;INTERVAL=2;WKST=MO;BYDAY=SU;BYMONTH=6,7,8"},
+		{"w /2 1", "FREQ=WEEKLY;INTERVAL=2;WKST=MO;BYDAY=MO"},
+		{"w 6 11,12,1", "FREQ=WEEKLY;BYDAY=SA;BYMONTH=11,12,1"},
+		{"m 1,-1", "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{"m 15 3,9", "FREQ=MONTHLY;BYMONTHDAY=15;BYMONTH=3,9"},
+		{"m -2 /3", "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=-2"},
+		{"mw 2 1", "FREQ=MONTHLY;BYDAY=2MO"},
+		{"mw -1 5 12", "FREQ=MONTHLY;BYDAY=-1FR;BYMONTH=12"},
+		{"d 1 count=5", "FREQ=DAILY;INTERVAL=1;COUNT=5"},
+		{"d 1 until=20250131 count=5", "FREQ=DAILY;INTERVAL=1;UNTIL=20250131"},
+	}
+	for _, tt := range tbl {
+		got, ok := RRule(tt.repeat)
+		assert.True(t, ok, tt.repeat)
+		assert.Equal(t, tt.want, got, tt.repeat)
+	}
+
+	// разовые задачи и неверные правила - одно событие
+	for _, repeat := range []string{"", "d 0", "x 1"} {
+		_, ok := RRule(repeat)
+		assert.False(t, ok, repeat)
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	for _, in := range []string{"20240601", "2024-06-01"} {
+		date, err := NormalizeDate(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, "20240601", date, in)
+	}
+
+	for _, in := range []string{"", "2024-6-1", "2024/06/01", "2024-13-01", "20240230", "01.06.2024", "1.6.2024", "завтра", " 20240601"} {
+		_, err := NormalizeDate(in)
+		assert.Error(t, err, in)
+	}
+
+	assert.Equal(t, "2024-06-01", ISODate("20240601"))
+	assert.Equal(t, "не дата", ISODate("не дата"))
+}
@@ -0,0 +1,119 @@
+package taskdate
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrBudgetExceeded возвращается, когда при развертывании повторений
+// исчерпан допустимый бюджет итераций.
+var ErrBudgetExceeded = errors.New("occurrence budget exceeded")
+
+// Occurrences возвращает все даты выполнения задачи в интервале [from, to] включительно.
+//
+// Параметры:
+//   - dstart: дата задачи в формате "YYYYMMDD", считается первым выполнением
+//   - repeat: правило повтора в формате NextDate, для разовой задачи - пустая строка
+//   - from, to: границы интервала; даты считаются в часовом поясе from
+//   - budget: общий бюджет итераций, уменьшается на каждую вычисленную дату;
+//     позволяет ограничить суммарную работу при развертывании многих задач
+//
+// Возвращает даты в формате "YYYYMMDD" по возрастанию,
+// ErrBudgetExceeded при исчерпании бюджета или ошибку разбора правила.
+func Occurrences(dstart, repeat string, from, to time.Time, budget *int) ([]string, error) {
+
+	date, err := time.ParseInLocation(DateFormat, dstart, from.Location())
+	if err != nil {
+		return nil, errForamt
+	}
+
+	var dates []string
+	if repeat == "" {
+		if !date.Before(from) && !date.After(to) {
+			dates = append(dates, dstart)
+		}
+		return dates, nil
+	}
+
+	// Пропускаем выполнения до начала интервала одним вызовом NextDate.
+	// С ограничением count= пропущенные выполнения нужно посчитать, поэтому идем по шагам
+	_, limits, err := splitLimits(repeat)
+	if err != nil {
+		return nil, err
+	}
+	if date.Before(from) && limits.count == 0 {
+		next, err := NextDate(from.AddDate(0, 0, -1), dstart, repeat)
+		if errors.Is(err, ErrRepeatFinished) {
+			return dates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if date, err = time.ParseInLocation(DateFormat, next, from.Location()); err != nil {
+			return nil, errForamt
+		}
+	}
+
+	for !date.After(to) {
+		if *budget <= 0 {
+			return nil, ErrBudgetExceeded
+		}
+		*budget--
+
+		if !date.Before(from) {
+			dates = append(dates, date.Format(DateFormat))
+		}
+
+		next, err := NextDate(date, date.Format(DateFormat), repeat)
+		if errors.Is(err, ErrRepeatFinished) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if date, err = time.ParseInLocation(DateFormat, next, from.Location()); err != nil {
+			return nil, errForamt
+		}
+		repeat = NextRepeat(repeat)
+	}
+	return dates, nil
+}
+
+// NextDates возвращает n ближайших дат выполнения задачи после now.
+//
+// Первая дата вычисляется NextDate от dstart, каждая следующая - от предыдущей.
+// Для разовой задачи (пустой repeat) возвращается пустой список,
+// для правила с ограничением until= или count= - не больше оставшихся дат.
+// Если правило перестает давать более поздние даты, возвращается ошибка,
+// а не бесконечный цикл.
+func NextDates(now time.Time, dstart, repeat string, n int) ([]string, error) {
+
+	dates := make([]string, 0, n)
+	if repeat == "" {
+		if _, err := time.Parse(DateFormat, dstart); err != nil {
+			return nil, errForamt
+		}
+		return dates, nil
+	}
+
+	for len(dates) < n {
+		next, err := NextDate(now, dstart, repeat)
+		if errors.Is(err, ErrRepeatFinished) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(dates) > 0 && next <= dates[len(dates)-1] {
+			return nil, errForamt
+		}
+		dates = append(dates, next)
+
+		if now, err = time.ParseInLocation(DateFormat, next, now.Location()); err != nil {
+			return nil, errForamt
+		}
+		dstart = next
+		repeat = NextRepeat(repeat)
+	}
+	return dates, nil
+}
@@ -0,0 +1,402 @@
+package taskdate
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrUnknownPhrase - фразу не удалось перевести в правило повтора.
+var ErrUnknownPhrase = errors.New("repeat phrase not recognized")
+
+// RepeatExample - фраза и правило повтора, в которое её переводит ParseRepeatPhrase.
+type RepeatExample struct {
+	Phrase string `json:"phrase"`
+	Repeat string `json:"repeat"`
+}
+
+// repeatExamples - примеры фраз для подсказок SuggestRepeat.
+// Тесты проверяют, что каждая фраза переводится в своё правило.
+var repeatExamples = []RepeatExample{
+	{"каждый день", "d 1"},
+	{"каждые 3 дня", "d 3"},
+	{"через день", "d 2"},
+	{"каждую неделю", "d 7"},
+	{"по понедельникам и пятницам", "w 1,5"},
+	{"каждые 2 недели по средам", "w 2 3"},
+	{"по будням", "w 1,2,3,4,5"},
+	{"по выходным", "w 6,7"},
+	{"15 числа каждого месяца", "m 15"},
+	{"1 и 15 числа", "m 1,15"},
+	{"последний день месяца", "m -1"},
+	{"каждые 3 месяца 10 числа", "m 10 /3"},
+	{"первый понедельник месяца", "mw 1 1"},
+	{"последняя пятница месяца", "mw -1 5"},
+	{"каждый год", "y"},
+	{"every day", "d 1"},
+	{"every 3 days", "d 3"},
+	{"every other day", "d 2"},
+	{"every week", "d 7"},
+	{"every monday and friday", "w 1,5"},
+	{"every 2 weeks on monday", "w 2 1"},
+	{"weekdays", "w 1,2,3,4,5"},
+	{"weekends", "w 6,7"},
+	{"every month on the 15th", "m 15"},
+	{"last day of the month", "m -1"},
+	{"first monday of the month", "mw 1 1"},
+	{"last friday of the month", "mw -1 5"},
+	{"every year", "y"},
+}
+
+// phraseFiller - слова, которые не меняют смысла фразы.
+var phraseFiller = map[string]bool{
+	"и": true, "а": true, "в": true, "во": true, "по": true, "на": true,
+	"дни": true, "дням": true, "числам": true,
+	"and": true, "on": true, "the": true, "of": true, "at": true, "a": true,
+	"каждый": true, "каждую": true, "каждое": true, "каждые": true, "каждого": true, "каждой": true,
+	"every": true, "each": true,
+}
+
+// phraseUnits - названия единиц интервала и наречия вроде "ежедневно".
+var phraseUnits = map[string]byte{
+	"день": 'd', "дня": 'd', "дней": 'd', "day": 'd', "days": 'd',
+	"неделя": 'w', "неделю": 'w', "недели": 'w', "недель": 'w', "week": 'w', "weeks": 'w',
+	"месяц": 'm', "месяца": 'm', "месяцев": 'm', "month": 'm', "months": 'm',
+	"год": 'y', "года": 'y', "лет": 'y', "year": 'y', "years": 'y',
+	"ежедневно": 'd', "daily": 'd',
+	"еженедельно": 'w', "weekly": 'w',
+	"ежемесячно": 'm', "monthly": 'm',
+	"ежегодно": 'y', "yearly": 'y', "annually": 'y',
+}
+
+// phraseOrdinals - порядковые номера дня недели в месяце или дня месяца
+// ("первый понедельник", "последний день").
+var phraseOrdinals = map[string]int{
+	"первый": 1, "первая": 1, "первую": 1, "первое": 1, "первого": 1, "первой": 1, "first": 1,
+	"второй": 2, "вторая": 2, "вторую": 2, "второе": 2, "второго": 2, "second": 2,
+	"третий": 3, "третья": 3, "третью": 3, "третье": 3, "третьего": 3, "третьей": 3, "third": 3,
+	"четвертый": 4, "четвертая": 4, "четвертую": 4, "четвертое": 4, "четвертого": 4, "четвертой": 4, "fourth": 4,
+	"пятый": 5, "пятая": 5, "пятую": 5, "пятое": 5, "пятого": 5, "пятой": 5, "fifth": 5,
+	"последний": -1, "последняя": -1, "последнюю": -1, "последнее": -1, "последнего": -1, "последней": -1, "last": -1,
+	"предпоследний": -2, "предпоследняя": -2, "предпоследнюю": -2, "предпоследнее": -2, "предпоследнего": -2,
+	"предпоследней": -2, "penultimate": -2,
+}
+
+// phraseWeekdays - начала русских названий дней недели во всех падежах
+// и числах ("пятница", "по пятницам").
+var phraseWeekdays = []string{"", "понедельник", "вторник", "сред", "четверг", "пятниц", "суббот", "воскресен"}
+
+// phraseWeekdaysShort - сокращенные русские названия дней недели.
+var phraseWeekdaysShort = []string{"", "пн", "вт", "ср", "чт", "пт", "сб", "вс"}
+
+// phraseWeekdaysEn - английские названия дней недели.
+var phraseWeekdaysEn = []string{"", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// phraseWeekGroups - слова, означающие несколько дней недели.
+var phraseWeekGroups = map[string][]int{
+	"будни": {1, 2, 3, 4, 5}, "будням": {1, 2, 3, 4, 5}, "будние": {1, 2, 3, 4, 5},
+	"рабочие": {1, 2, 3, 4, 5}, "рабочим": {1, 2, 3, 4, 5},
+	"weekday": {1, 2, 3, 4, 5}, "weekdays": {1, 2, 3, 4, 5}, "workdays": {1, 2, 3, 4, 5},
+	"выходные": {6, 7}, "выходным": {6, 7}, "weekend": {6, 7}, "weekends": {6, 7},
+}
+
+// phraseDaySuffixes - окончания числа, которые делают его днем месяца ("15-го", "15th").
+var phraseDaySuffixes = []string{"-ого", "-го", "го", "-е", "th", "st", "nd", "rd"}
+
+// repeatPhrase - то, что понято из фразы к текущему слову.
+type repeatPhrase struct {
+	unit     byte  // единица интервала: 'd', 'w', 'm' или 'y'
+	interval int   // "каждые N", 0 - не задан
+	pending  []int // числа, еще не отнесенные к единице или дням месяца
+	ordinals []int // порядковые номера, ждущие "день" или день недели
+	weekdays []int
+	days     []int // дни месяца, -1 и -2 - последний и предпоследний
+	nth      int   // "первый понедельник": номер
+	nthDay   int   // и день недели
+}
+
+// ParseRepeatPhrase переводит фразу на русском или английском в правило
+// повтора, например "по понедельникам и пятницам" в "w 1,5",
+// "каждые 3 дня" в "d 3", "последний день месяца" в "m -1",
+// "every 2 weeks on monday" в "w 2 1".
+// Возвращает ошибку ErrUnknownPhrase, если во фразе есть незнакомое слово
+// или слова не складываются в правило, которое принимает ValidateRepeat.
+func ParseRepeatPhrase(phrase string) (string, error) {
+	words := phraseWords(phrase)
+	if len(words) == 0 {
+		return "", fmt.Errorf("%w: empty phrase", ErrUnknownPhrase)
+	}
+
+	var p repeatPhrase
+	for _, word := range words {
+		if err := p.add(word); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrUnknownPhrase, err)
+		}
+	}
+	rule, err := p.rule()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnknownPhrase, err)
+	}
+	if err := ValidateRepeat(rule); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnknownPhrase, err)
+	}
+	return rule, nil
+}
+
+// phraseWords делит фразу на слова в нижнем регистре без знаков препинания.
+func phraseWords(phrase string) []string {
+	phrase = strings.ReplaceAll(strings.ToLower(phrase), "ё", "е")
+	return strings.FieldsFunc(phrase, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(",;:.!?()", r)
+	})
+}
+
+// add учитывает очередное слово фразы.
+func (p *repeatPhrase) add(word string) error {
+	if phraseFiller[word] {
+		return nil
+	}
+	if n, day, ok := phraseNumber(word); ok {
+		if day {
+			p.days = append(p.days, n)
+			return nil
+		}
+		p.pending = append(p.pending, n)
+		return nil
+	}
+	if word == "число" || word == "числа" {
+		if len(p.pending) == 0 && len(p.days) == 0 {
+			// "15-го числа" - день уже учтен
+			return fmt.Errorf("%q without a day number", word)
+		}
+		p.days = append(p.days, p.pending...)
+		p.pending = nil
+		return nil
+	}
+	if word == "через" || word == "other" {
+		p.interval = 2
+		return nil
+	}
+	if unit, ok := phraseUnits[word]; ok {
+		return p.addUnit(word, unit)
+	}
+	if n, ok := phraseOrdinals[word]; ok {
+		p.ordinals = append(p.ordinals, n)
+		return nil
+	}
+	if days, ok := phraseWeekGroups[word]; ok {
+		p.weekdays = append(p.weekdays, days...)
+		return nil
+	}
+	if day := phraseWeekday(word); day != 0 {
+		return p.addWeekday(day)
+	}
+	return fmt.Errorf("unknown word %q", word)
+}
+
+// addUnit учитывает единицу интервала: "3 дня", "последний день", "месяца".
+func (p *repeatPhrase) addUnit(word string, unit byte) error {
+	if unit == 'd' && len(p.ordinals) > 0 {
+		// "первый и последний день" - дни месяца
+		p.days = append(p.days, p.ordinals...)
+		p.ordinals = nil
+		return nil
+	}
+	if p.unit != 0 && p.unit != unit {
+		return fmt.Errorf("%q conflicts with an earlier unit", word)
+	}
+	p.unit = unit
+	switch len(p.pending) {
+	case 0:
+	case 1:
+		p.interval = p.pending[0]
+		p.pending = nil
+	default:
+		return fmt.Errorf("several intervals before %q", word)
+	}
+	return nil
+}
+
+// addWeekday учитывает день недели: "понедельник" или "первый понедельник".
+func (p *repeatPhrase) addWeekday(day int) error {
+	switch len(p.ordinals) {
+	case 0:
+		p.weekdays = append(p.weekdays, day)
+	case 1:
+		if p.nth != 0 {
+			return fmt.Errorf("more than one weekday of the month")
+		}
+		p.nth, p.nthDay = p.ordinals[0], day
+		p.ordinals = nil
+	default:
+		return fmt.Errorf("several numbers for one weekday")
+	}
+	return nil
+}
+
+// rule собирает правило из понятого во фразе.
+func (p *repeatPhrase) rule() (string, error) {
+	if len(p.pending) > 0 {
+		if p.unit != 'm' {
+			return "", fmt.Errorf("number %d without a unit", p.pending[0])
+		}
+		// "every month on 15"
+		p.days = append(p.days, p.pending...)
+	}
+	if len(p.ordinals) > 0 {
+		return "", fmt.Errorf("ordinal without a day")
+	}
+
+	switch {
+	case p.nth != 0:
+		if p.unit == 'd' || p.unit == 'w' || p.unit == 'y' || p.interval > 1 || len(p.weekdays)+len(p.days) > 0 {
+			return "", fmt.Errorf("weekday of the month cannot be combined with other parts")
+		}
+		return fmt.Sprintf("mw %d %d", p.nth, p.nthDay), nil
+	case len(p.weekdays) > 0:
+		if (p.unit != 0 && p.unit != 'w') || len(p.days) > 0 {
+			return "", fmt.Errorf("weekdays cannot be combined with days of the month")
+		}
+		rule := "w " + joinInts(p.weekdays)
+		if p.interval > 1 {
+			rule = fmt.Sprintf("w %d %s", p.interval, joinInts(p.weekdays))
+		}
+		return rule, nil
+	case len(p.days) > 0:
+		if p.unit != 0 && p.unit != 'm' {
+			return "", fmt.Errorf("days of the month need a monthly repeat")
+		}
+		rule := "m " + joinInts(p.days)
+		if p.interval > 1 {
+			rule += fmt.Sprintf(" /%d", p.interval)
+		}
+		return rule, nil
+	}
+
+	interval := max(p.interval, 1)
+	switch p.unit {
+	case 'd':
+		return fmt.Sprintf("d %d", interval), nil
+	case 'w':
+		return fmt.Sprintf("d %d", 7*interval), nil
+	case 'm':
+		return "", fmt.Errorf("monthly repeat needs a day of the month")
+	case 'y':
+		if interval > 1 {
+			return "", fmt.Errorf("yearly repeat has no interval")
+		}
+		return "y", nil
+	}
+	return "", fmt.Errorf("no repeat unit")
+}
+
+// phraseNumber распознает число: "3", а также день месяца "15-го" или "15th" (day = true).
+func phraseNumber(word string) (n int, day bool, ok bool) {
+	digits := word
+	for _, suffix := range phraseDaySuffixes {
+		if s, found := strings.CutSuffix(word, suffix); found {
+			digits, day = s, true
+			break
+		}
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n <= 0 || digits[0] == '+' {
+		return 0, false, false
+	}
+	return n, day, true
+}
+
+// phraseWeekday возвращает день недели (1-понедельник, 7-воскресенье)
+// или 0, если слово не день недели.
+func phraseWeekday(word string) int {
+	for day := 1; day <= max_wday; day++ {
+		en := phraseWeekdaysEn[day]
+		if strings.HasPrefix(word, phraseWeekdays[day]) || word == phraseWeekdaysShort[day] ||
+			word == en || word == en+"s" || word == en[:3] {
+			return day
+		}
+	}
+	return 0
+}
+
+// joinInts записывает дни без повторов через запятую: дни недели по порядку,
+// а последний и предпоследний день месяца - после остальных дней.
+func joinInts(days []int) string {
+	days = slices.Clone(days)
+	slices.SortFunc(days, func(a, b int) int {
+		if (a < 0) != (b < 0) {
+			return cmp.Compare(b, a)
+		}
+		return cmp.Compare(a, b)
+	})
+	days = slices.Compact(days)
+	parts := make([]string, len(days))
+	for i, day := range days {
+		parts[i] = strconv.Itoa(day)
+	}
+	return strings.Join(parts, ",")
+}
+
+// SuggestRepeat возвращает до n примеров фраз, похожих на phrase: сначала
+// те, где больше общих слов (последнее слово phrase может быть недописано).
+// Если общих слов нет, возвращает первые примеры на языке phrase.
+func SuggestRepeat(phrase string, n int) []RepeatExample {
+	words := slices.DeleteFunc(phraseWords(phrase), func(word string) bool { return phraseFiller[word] })
+
+	type scored struct {
+		RepeatExample
+		score int
+	}
+	var found []scored
+	for _, ex := range repeatExamples {
+		score := 0
+		exWords := phraseWords(ex.Phrase)
+		for _, word := range words {
+			if slices.ContainsFunc(exWords, func(exWord string) bool { return strings.HasPrefix(exWord, word) }) {
+				score++
+			}
+		}
+		if score > 0 {
+			found = append(found, scored{ex, score})
+		}
+	}
+	slices.SortStableFunc(found, func(a, b scored) int { return cmp.Compare(b.score, a.score) })
+
+	result := make([]RepeatExample, 0, n)
+	for _, s := range found {
+		if len(result) == n {
+			return result
+		}
+		result = append(result, s.RepeatExample)
+	}
+	if len(result) > 0 {
+		return result
+	}
+
+	latin := isLatin(phrase)
+	for _, ex := range repeatExamples {
+		if len(result) == n {
+			break
+		}
+		if isLatin(ex.Phrase) == latin {
+			result = append(result, ex)
+		}
+	}
+	return result
+}
+
+// isLatin сообщает, что в строке есть латинские буквы и нет кириллицы.
+func isLatin(s string) bool {
+	latin := false
+	for _, r := range s {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return false
+		}
+		latin = latin || unicode.Is(unicode.Latin, r)
+	}
+	return latin
+}
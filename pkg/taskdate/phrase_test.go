@@ -0,0 +1,155 @@
+package taskdate
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRepeatPhrase(t *testing.T) {
+	tbl := []struct {
+		phrase string
+		want   string
+	}{
+		{"Каждые 3 дня", "d 3"},
+		{"ежедневно", "d 1"},
+		{"через неделю", "d 14"},
+		{"каждые 2 недели", "d 14"},
+		{"по понедельникам и пятницам", "w 1,5"},
+		{"по пятницам, понедельникам и пятницам", "w 1,5"},
+		{"каждый вторник", "w 2"},
+		{"по субботам и воскресеньям", "w 6,7"},
+		{"по рабочим дням", "w 1,2,3,4,5"},
+		{"каждые 2 недели по пн и чт", "w 2 1,4"},
+		{"every 2 weeks on Monday", "w 2 1"},
+		{"every other week on tuesdays and thursdays", "w 2 2,4"},
+		{"каждое 15-е", "m 15"},
+		{"10-го числа", "m 10"},
+		{"последний день месяца", "m -1"},
+		{"первый и последний день месяца", "m 1,-1"},
+		{"предпоследний день", "m -2"},
+		{"every month on the 1st and 15th", "m 1,15"},
+		{"monthly on 20", "m 20"},
+		{"every 2 months on the 5th", "m 5 /2"},
+		{"вторая среда месяца", "mw 2 3"},
+		{"четвёртый четверг", "mw 4 4"},
+		{"the second sunday of the month", "mw 2 7"},
+		{"ежегодно", "y"},
+		{"annually", "y"},
+	}
+	for _, v := range tbl {
+		got, err := ParseRepeatPhrase(v.phrase)
+		require.NoError(t, err, v.phrase)
+		assert.Equal(t, v.want, got, v.phrase)
+	}
+}
+
+func TestParseRepeatPhraseErrors(t *testing.T) {
+	for _, phrase := range []string{
+		"",
+		" , ",
+		"каждые",
+		"каждые 3",
+		"каждый месяц",
+		"каждые 2 года",
+		"каждые 500 дней",
+		"каждый день по понедельникам",
+		"по понедельникам 15 числа",
+		"первый понедельник и вторник",
+		"первый второй понедельник",
+		"последний",
+		"числа",
+		"31 числа каждые 2 недели",
+		"каждый день недели",
+		"every blue moon",
+		"6 раз в неделю",
+	} {
+		_, err := ParseRepeatPhrase(phrase)
+		assert.ErrorIs(t, err, ErrUnknownPhrase, phrase)
+	}
+}
+
+func TestRepeatExamples(t *testing.T) {
+	// подсказки не должны расходиться с переводом и с правилами NextDate
+	for _, ex := range repeatExamples {
+		got, err := ParseRepeatPhrase(ex.Phrase)
+		require.NoError(t, err, ex.Phrase)
+		assert.Equal(t, ex.Repeat, got, ex.Phrase)
+		assert.NoError(t, ValidateRepeat(ex.Repeat), ex.Phrase)
+	}
+}
+
+func TestSuggestRepeat(t *testing.T) {
+	got := SuggestRepeat("каждые 2 нед", 3)
+	require.NotEmpty(t, got)
+	assert.Equal(t, RepeatExample{"каждые 2 недели по средам", "w 2 3"}, got[0])
+
+	got = SuggestRepeat("last fri", 5)
+	require.NotEmpty(t, got)
+	assert.Equal(t, RepeatExample{"last friday of the month", "mw -1 5"}, got[0])
+	assert.Contains(t, got, RepeatExample{"last day of the month", "m -1"})
+
+	// без общих слов - первые примеры на языке фразы
+	assert.Equal(t, repeatExamples[:2], SuggestRepeat("как-нибудь", 2))
+	got = SuggestRepeat("sometimes", 2)
+	require.Len(t, got, 2)
+	assert.Equal(t, "every day", got[0].Phrase)
+
+	assert.Len(t, SuggestRepeat("", 5), 5)
+}
+
+// TestParseRepeatPhrasePrefixes переводит все начала фраз-примеров, как при
+// вводе с подсказками: перевод не должен паниковать, а любое
+// полученное правило должно приниматься NextDate.
+func TestParseRepeatPhrasePrefixes(t *testing.T) {
+	for _, ex := range repeatExamples {
+		for i := range ex.Phrase {
+			for _, phrase := range []string{ex.Phrase[:i], ex.Phrase[i:]} {
+				checkPhrase(t, phrase)
+				checkPhrase(t, strings.ReplaceAll(phrase, " ", ", "))
+			}
+		}
+	}
+}
+
+func FuzzParseRepeatPhrase(f *testing.F) {
+	for _, ex := range repeatExamples {
+		f.Add(ex.Phrase)
+	}
+	for _, seed := range []string{"15-го", "0 дней", "-1 числа", "+5 days", "9999999999999999999 дней", "пятница пятница", "th"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, phrase string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			checkPhrase(t, phrase)
+			for _, ex := range SuggestRepeat(phrase, 3) {
+				assert.NotEmpty(t, ex.Repeat)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("ParseRepeatPhrase(%q) did not terminate", phrase)
+		}
+	})
+}
+
+// checkPhrase проверяет, что правило, полученное из phrase, допустимо.
+func checkPhrase(t *testing.T, phrase string) {
+	t.Helper()
+	if !utf8.ValidString(phrase) {
+		return
+	}
+	rule, err := ParseRepeatPhrase(phrase)
+	if err != nil {
+		assert.ErrorIs(t, err, ErrUnknownPhrase, phrase)
+		return
+	}
+	assert.NoError(t, ValidateRepeat(rule), "%q -> %q", phrase, rule)
+}
@@ -0,0 +1,74 @@
+package taskdate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// icalWeekdays - дни недели правила (1-понедельник, 7-воскресенье) в записи RFC 5545.
+var icalWeekdays = [max_wday + 1]string{"", "MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// RRule переводит правило повтора задачи в значение свойства RRULE
+// календаря iCalendar (RFC 5545), например "d 7" в "FREQ=DAILY;INTERVAL=7".
+// Ограничения until= и count= переводятся в UNTIL и COUNT; RFC 5545 не допускает
+// их вместе, поэтому при обоих ограничениях остается только UNTIL.
+// Возвращает false для пустого или неверного правила: такую задачу
+// календарь показывает одним событием.
+func RRule(repeat string) (string, bool) {
+	if repeat == "" || ValidateRepeat(repeat) != nil {
+		return "", false
+	}
+	repeat, limits, _ := splitLimits(repeat)
+
+	rule := strings.Split(repeat, " ")
+	var parts []string
+	switch rule[0] {
+	case "y":
+		parts = []string{"FREQ=YEARLY"}
+	case "d":
+		parts = []string{"FREQ=DAILY", "INTERVAL=" + rule[1]}
+	case "w":
+		interval, args, _ := weekInterval(rule[1:])
+		parts = []string{"FREQ=WEEKLY"}
+		if interval > 1 {
+			// недели правила начинаются с понедельника
+			parts = append(parts, "INTERVAL="+strconv.Itoa(interval), "WKST=MO")
+		}
+		days := strings.Split(args[0], ",")
+		for i, day := range days {
+			n, _ := strconv.Atoi(day)
+			days[i] = icalWeekdays[n]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+		if len(args) == 2 {
+			parts = append(parts, "BYMONTH="+args[1])
+		}
+	case "m":
+		interval, args, _ := monthInterval(rule[2:])
+		parts = []string{"FREQ=MONTHLY"}
+		if interval > 1 {
+			parts = append(parts, "INTERVAL="+strconv.Itoa(interval))
+		}
+		// -1 и -2 означают последний и предпоследний день и в RFC 5545
+		parts = append(parts, "BYMONTHDAY="+rule[1])
+		if len(args) == 1 {
+			parts = append(parts, "BYMONTH="+args[0])
+		}
+	case "mw":
+		day, _ := strconv.Atoi(rule[2])
+		parts = []string{"FREQ=MONTHLY", "BYDAY=" + rule[1] + icalWeekdays[day]}
+		if len(rule) == 4 {
+			parts = append(parts, "BYMONTH="+rule[3])
+		}
+	default:
+		return "", false
+	}
+
+	if !limits.until.IsZero() {
+		parts = append(parts, "UNTIL="+limits.until.Format(DateFormat))
+	}
+	if limits.count > 0 && limits.until.IsZero() {
+		parts = append(parts, "COUNT="+strconv.Itoa(limits.count))
+	}
+	return strings.Join(parts, ";"), true
+}
@@ -0,0 +1,139 @@
+package taskdate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// daysInMonth - наибольшее число дней в каждом месяце (февраль - в високосный год).
+var daysInMonth = [max_month + 1]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// ValidateRepeat проверяет правило повтора, не вычисляя дат.
+//
+// Пустое правило (разовая задача) допустимо. Для неверного правила
+// возвращается ошибка, называющая неверную часть, например
+// `error format dstart or repeat: interval "9999" must be from 1 to 400`.
+// Правило "m", которое не дает ни одной даты (например, "m 31 2"), тоже неверно.
+func ValidateRepeat(repeat string) error {
+	if repeat == "" {
+		return nil
+	}
+	repeat, _, err := splitLimits(repeat)
+	if err != nil {
+		return err
+	}
+
+	rule := strings.Split(repeat, " ")
+	switch rule[0] {
+	case "y":
+		if len(rule) != 1 {
+			return repeatError("rule %q takes no arguments", "y")
+		}
+	case "d":
+		if len(rule) != 2 {
+			return repeatError("rule %q expects an interval in days", "d")
+		}
+		interval, err := strconv.Atoi(rule[1])
+		if err != nil || interval < 1 || interval > max_day {
+			return repeatError("interval %q must be from 1 to %d", rule[1], max_day)
+		}
+	case "w":
+		_, args, err := weekInterval(rule[1:])
+		if err != nil {
+			return err
+		}
+		if len(args) < 1 || len(args) > 2 {
+			return repeatError("rule %q expects an optional interval, week days and optional months", "w")
+		}
+		if _, err := validateList(args[0], "week day", 1, max_wday); err != nil {
+			return err
+		}
+		if len(args) == 2 {
+			if _, err := validateList(args[1], "month", 1, max_month); err != nil {
+				return err
+			}
+		}
+	case "m":
+		if len(rule) < 2 || len(rule) > 4 {
+			return repeatError("rule %q expects month days and optional months or interval", "m")
+		}
+		days, err := validateList(rule[1], "month day", -2, 31)
+		if err != nil {
+			return err
+		}
+		for _, day := range days {
+			if day == 0 {
+				return repeatError("month day %q must be from 1 to 31, -1 or -2", "0")
+			}
+		}
+		_, args, err := monthInterval(rule[2:])
+		if err != nil {
+			return err
+		}
+		if len(args) > 1 {
+			_, err := parseMonth(args)
+			return err
+		}
+		months := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+		if len(args) == 1 {
+			if months, err = validateList(args[0], "month", 1, max_month); err != nil {
+				return err
+			}
+		}
+		if !monthDayExists(days, months) {
+			return repeatError("rule %q never produces a date", repeat)
+		}
+	case "mw":
+		if len(rule) < 3 || len(rule) > 4 {
+			return repeatError("rule %q expects a week number, a week day and optional months", "mw")
+		}
+		if n, err := strconv.Atoi(rule[1]); err != nil || n == 0 || n < -1 || n > max_nth {
+			return repeatError("week number %q must be from 1 to %d or -1", rule[1], max_nth)
+		}
+		if d, err := strconv.Atoi(rule[2]); err != nil || d < 1 || d > max_wday {
+			return repeatError("week day %q must be from 1 to %d", rule[2], max_wday)
+		}
+		if len(rule) == 4 {
+			if _, err := validateList(rule[3], "month", 1, max_month); err != nil {
+				return err
+			}
+		}
+	default:
+		return repeatError("unknown rule %q", rule[0])
+	}
+	return nil
+}
+
+// validateList разбирает список чисел "N1,N2,..." из диапазона [lo, hi].
+// name - название элемента списка для сообщения об ошибке.
+func validateList(s, name string, lo, hi int) ([]int, error) {
+	parts := strings.Split(s, ",")
+	nums := make([]int, 0, len(parts))
+	for _, part := range parts {
+		num, err := strconv.Atoi(part)
+		if err != nil || num < lo || num > hi {
+			return nil, repeatError("%s %q must be from %d to %d", name, part, lo, hi)
+		}
+		nums = append(nums, num)
+	}
+	return nums, nil
+}
+
+// monthDayExists сообщает, есть ли хотя бы один из дней days
+// хотя бы в одном из месяцев months.
+func monthDayExists(days, months []int) bool {
+	for _, day := range days {
+		for _, month := range months {
+			if day < 0 || day <= daysInMonth[month] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// repeatError возвращает ошибку формата правила с уточнением.
+func repeatError(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{errForamt}, args...)...)
+}
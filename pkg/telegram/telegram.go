@@ -0,0 +1,88 @@
+// Package telegram отправляет сообщения в чат через Bot API Telegram.
+//
+// Используется только метод sendMessage: бот пишет в один заранее заданный
+// чат и не читает входящие сообщения.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Параметры обращения к Bot API.
+const (
+	DefaultAPIURL = "https://api.telegram.org" // адрес Bot API
+	Timeout       = 10 * time.Second           // время ожидания ответа на один запрос
+	MaxMessageLen = 4096                       // символов в одном сообщении
+)
+
+// Client отправляет сообщения ботом с токеном token в чат chatID.
+type Client struct {
+	APIURL string // адрес Bot API, в тестах подменяется адресом httptest-сервера
+
+	token  string
+	chatID string
+	http   *http.Client
+}
+
+// New создает клиента бота с токеном token для чата chatID.
+func New(token, chatID string) *Client {
+	return &Client{
+		APIURL: DefaultAPIURL,
+		token:  token,
+		chatID: chatID,
+		http:   &http.Client{Timeout: Timeout},
+	}
+}
+
+// sendMessage - тело запроса sendMessage.
+type sendMessage struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+// apiResponse - общая часть ответа Bot API.
+type apiResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+// Send отправляет text в чат. Возвращает ошибку, если запрос не выполнен
+// или Bot API ответил отказом. Токен в текст ошибки не попадает.
+func (c *Client) Send(ctx context.Context, text string) error {
+	body, err := json.Marshal(sendMessage{ChatID: c.chatID, Text: text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.APIURL+"/bot"+c.token+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return errors.New("telegram: неверный адрес Bot API")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// *url.Error содержит адрес запроса вместе с токеном
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("telegram: ответ %d: %w", resp.StatusCode, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram: ответ %d: %s", resp.StatusCode, result.Description)
+	}
+	return nil
+}
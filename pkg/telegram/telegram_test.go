@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend(t *testing.T) {
+	var got sendMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bot123:secret/sendMessage" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"ok":false,"description":"Not Found"}`))
+			return
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		if got.ChatID != "42" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer srv.Close()
+
+	c := New("123:secret", "42")
+	c.APIURL = srv.URL
+	require.NoError(t, c.Send(context.Background(), "Привет"))
+	assert.Equal(t, sendMessage{ChatID: "42", Text: "Привет"}, got)
+
+	c = New("123:secret", "7")
+	c.APIURL = srv.URL
+	err := c.Send(context.Background(), "Привет")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "chat not found")
+
+	// адрес с токеном не попадает в ошибку соединения
+	c.APIURL = "http://127.0.0.1:1"
+	err = c.Send(context.Background(), "Привет")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+}
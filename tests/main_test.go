@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain пропускает тесты пакета, если сервер не запущен.
+//
+// Без TODO_DBFILE тесты работают с копией DBFile во временном каталоге:
+// эталонный scheduler.db в репозитории не меняется. Чтобы тесты видели
+// задачи сервера, TODO_DBFILE задает путь к его БД.
+func TestMain(m *testing.M) {
+	addr := strings.TrimSuffix(strings.TrimPrefix(getURL(""), "http://"), "/")
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		fmt.Printf("Сервер %s не запущен, тесты пропущены\n", addr)
+		os.Exit(0)
+	}
+	conn.Close()
+
+	if os.Getenv("TODO_DBFILE") == "" {
+		dir, err := os.MkdirTemp("", "todo-tests")
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		DBFile, err = copyFile(DBFile, filepath.Join(dir, filepath.Base(DBFile)))
+		if err != nil {
+			fmt.Println(err)
+			os.RemoveAll(dir)
+			os.Exit(1)
+		}
+		code := m.Run()
+		os.RemoveAll(dir)
+		os.Exit(code)
+	}
+	os.Exit(m.Run())
+}
+
+// copyFile копирует файл src в dst и возвращает dst.
+func copyFile(src, dst string) (string, error) {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", err
+	}
+	return dst, os.WriteFile(dst, data, 0o600)
+}
@@ -32,7 +32,10 @@ func TestDone(t *testing.T) {
 	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
 	assert.NoError(t, err)
 	assert.Empty(t, ret)
-	notFoundTask(t, id)
+	// разовая задача не удаляется, а отмечается выполненной
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret["completed"])
 
 	id = addTask(t, task{
 		title:  "Проверить работу /api/task/done",